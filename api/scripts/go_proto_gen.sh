@@ -46,3 +46,23 @@ cp -f bazel-bin/api/envoy/v12/http/grpc_metadata_scrubber/config_go_proto_/githu
 bazelisk build //api/envoy/v12/http/header_sanitizer:config_go_proto
 mkdir -p src/go/proto/api/envoy/v12/http/header_sanitizer
 cp -f bazel-bin/api/envoy/v12/http/header_sanitizer/config_go_proto_/github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/header_sanitizer/* src/go/proto/api/envoy/v12/http/header_sanitizer
+# HTTP filter token_introspection
+bazelisk build //api/envoy/v12/http/token_introspection:config_go_proto
+mkdir -p src/go/proto/api/envoy/v12/http/token_introspection
+cp -f bazel-bin/api/envoy/v12/http/token_introspection/config_go_proto_/github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/token_introspection/* src/go/proto/api/envoy/v12/http/token_introspection
+# HTTP filter api_key
+bazelisk build //api/envoy/v12/http/api_key:config_go_proto
+mkdir -p src/go/proto/api/envoy/v12/http/api_key
+cp -f bazel-bin/api/envoy/v12/http/api_key/config_go_proto_/github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/api_key/* src/go/proto/api/envoy/v12/http/api_key
+# HTTP filter body_size_limit
+bazelisk build //api/envoy/v12/http/body_size_limit:config_go_proto
+mkdir -p src/go/proto/api/envoy/v12/http/body_size_limit
+cp -f bazel-bin/api/envoy/v12/http/body_size_limit/config_go_proto_/github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/body_size_limit/* src/go/proto/api/envoy/v12/http/body_size_limit
+# HTTP filter sse_framing
+bazelisk build //api/envoy/v12/http/sse_framing:config_go_proto
+mkdir -p src/go/proto/api/envoy/v12/http/sse_framing
+cp -f bazel-bin/api/envoy/v12/http/sse_framing/config_go_proto_/github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/sse_framing/* src/go/proto/api/envoy/v12/http/sse_framing
+# HTTP filter grpc_json_reverse_transcoder
+bazelisk build //api/envoy/v12/http/grpc_json_reverse_transcoder:config_go_proto
+mkdir -p src/go/proto/api/envoy/v12/http/grpc_json_reverse_transcoder
+cp -f bazel-bin/api/envoy/v12/http/grpc_json_reverse_transcoder/config_go_proto_/github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/grpc_json_reverse_transcoder/* src/go/proto/api/envoy/v12/http/grpc_json_reverse_transcoder
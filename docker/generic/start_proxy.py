@@ -77,6 +77,9 @@ def gen_bootstrap_conf(args):
     if args.ads_named_pipe:
         cmd.extend(["--ads_named_pipe", args.ads_named_pipe])
 
+    if args.enable_operation_stats:
+        cmd.append("--enable_operation_stats")
+
     bootstrap_file = DEFAULT_CONFIG_DIR + BOOTSTRAP_CONFIG
     cmd.append(bootstrap_file)
     print(cmd)
@@ -306,6 +309,17 @@ environment variable or by passing "-k" flag to this script.
         It supports envoy variable defined at https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_conn_man/headers#custom-request-response-headers.
         This argument can be repeated multiple times to specify multiple headers.
         For example: --append_response_header=key1=value1 --append_response_header=key2=value2.''')
+    parser.add_argument('--header_rules_path', default=None, help='''
+        Path to a JSON file with request and response header rules scoped to
+        APIs or operations. A rule can add, append and remove request headers
+        and response headers on the routes of the APIs or operations in its
+        selectors, e.g. to remove response headers that leak backend details.
+        Values support the same envoy variables as --add_request_header, a
+        literal "%%" must be escaped as "%%%%". Rules for an API are applied
+        before rules for its operations. For example:
+        {"rules": [{"selectors": ["bookstore.Bookstore"],
+        "request_headers_to_add": {"x-client-ip": "%%DOWNSTREAM_REMOTE_ADDRESS%%"},
+        "response_headers_to_remove": ["server", "x-powered-by"]}]}''')
 
     parser.add_argument(
         '--enable_operation_name_header',
@@ -381,7 +395,9 @@ environment variable or by passing "-k" flag to this script.
         default='*',
         help='''
         Only works when --cors_preset is 'basic'. Configures the CORS header
-        Access-Control-Allow-Origin. Defaults to "*" which allows all origins.
+        Access-Control-Allow-Origin. Multiple origins can be separated by
+        comma, e.g. "https://a.example.com,https://b.example.com". Defaults to
+        "*" which allows all origins.
         ''')
     parser.add_argument(
         '--cors_allow_origin_regex',
@@ -433,6 +449,18 @@ environment variable or by passing "-k" flag to this script.
         optional fraction and a unit suffix, such as "300m", "1.5h" or "2h45m".
         Valid time units are "m" for minutes, "h" for hours.
         ''')
+    parser.add_argument(
+        '--cors_policies_path',
+        default=None,
+        help='''
+        Only works when --cors_preset is in use. Path to a JSON file with CORS
+        policies for APIs or operations. A policy overrides the global
+        --cors_* flags for the APIs or operations in its selectors, and can
+        also disable CORS for internal operations, e.g.
+        {"policies": [{"selectors": ["bookstore.Bookstore"],
+        "allow_origins": ["https://a.example.com"]},
+        {"selectors": ["bookstore.Bookstore.Internal"], "disabled": true}]}
+        ''')
     parser.add_argument(
         '--check_metadata',
         action='store_true',
@@ -652,6 +680,68 @@ environment variable or by passing "-k" flag to this script.
         When the "x-google-audiences" is not specified, normally the service name is used to check the JWT "aud" field.
         If this flag is true, the service name is not used, JWT "aud" field will not be checked.'''
     )
+    parser.add_argument(
+        '--jwks_local_files',
+        default=None,
+        help='''
+        Read the JWKS of authentication providers from local files instead of
+        fetching them from their jwks_uri, for offline or air-gapped deployments.
+        A semicolon separated list of "provider_id=path" pairs, e.g.
+        "auth0=/etc/espv2/auth0_jwks.json;firebase=/etc/espv2/firebase_jwks.json".
+        Providers whose jwks_uri uses the "file://" scheme are always read from
+        the local file. Local files are watched by the config manager, so keys
+        can be rotated by updating the mounted files. Envoy itself does not
+        watch them: with a static bootstrap config, keys are only reloaded
+        when the bootstrap is regenerated and Envoy restarts.'''
+    )
+    parser.add_argument(
+        '--jwt_authn_audit_only',
+        action='store_true',
+        default=False,
+        help='''
+        Run JWT authentication in audit only mode for all operations. Tokens are
+        still verified and the outcome is recorded in the "jwt_failed_status"
+        field of the JSON access log and the "jwt_audit_failed" stat of the
+        Service Control filter, but requests with missing or invalid tokens
+        are allowed.'''
+    )
+    parser.add_argument(
+        '--jwt_authn_audit_only_selectors',
+        default=None,
+        help='''
+        A comma separated list of operation selectors to run JWT authentication
+        in audit only mode for. See --jwt_authn_audit_only.'''
+    )
+    parser.add_argument(
+        '--jwt_provider_options_path',
+        default=None,
+        help='''
+        Path to a JSON file with extended validation options for authentication
+        providers, keyed by provider ID: clock skew, maximum token lifetime,
+        issuer regex, required claims and claim value constraints. Tokens that
+        fail the claim checks are rejected with 403.'''
+    )
+    parser.add_argument(
+        '--token_introspection_providers_path',
+        default=None,
+        help='''
+        Path to a JSON file that marks authentication providers as issuers of
+        opaque access tokens, keyed by provider ID, with their OAuth 2.0 token
+        introspection endpoint (RFC 7662), client credentials and cache
+        settings. Tokens for these providers are validated by calling the
+        introspection endpoint instead of being verified as JWTs. The endpoint
+        must use https unless it is a loopback address.'''
+    )
+    parser.add_argument(
+        '--api_key_store_path',
+        default=None,
+        help='''
+        Path to a JSON file with the API keys that are valid for this service,
+        stored as SHA-256 digests with their consumer label, allowed selectors
+        and allowed referrers. API keys are validated locally, without Service
+        Control. The consumer label is sent to the backend in the
+        X-Endpoint-API-Consumer header. The file is watched for changes.'''
+    )
     parser.add_argument(
         '--http_request_timeout_s',
         default=None, type=int,
@@ -668,6 +758,24 @@ environment variable or by passing "-k" flag to this script.
         Set the url of service control server. The default is
         "https://servicecontrol.googleapis.com" if not set.
         ''')
+    parser.add_argument(
+        '--service_control_local_sink',
+        default=None,
+        help='''
+        Run a local stand-in for Service Control and send the Check,
+        AllocateQuota and Report calls to it instead of the Service Control
+        server. All calls are allowed. The operations of each Report are
+        written as JSON lines to this file path, or to standard output if set
+        to "stdout", with API keys replaced by their SHA-256 fingerprint.
+        Useful for non-GCP or development deployments.
+        ''')
+    parser.add_argument(
+        '--service_control_local_sink_port',
+        default=None, type=int,
+        help='''
+        Port used to serve the local Service Control sink. The default is 8792
+        if not set. See --service_control_local_sink.
+        ''')
     parser.add_argument(
         '--service_control_check_timeout_ms',
         default=None,
@@ -755,6 +863,8 @@ environment variable or by passing "-k" flag to this script.
         '--access_log',
         help='''
         Path to a local file to which the access log entries will be written.
+        If set to "stdout", the access log entries are written to the standard
+        output.
         '''
     )
     parser.add_argument(
@@ -767,6 +877,65 @@ environment variable or by passing "-k" flag to this script.
         https://www.envoyproxy.io/docs/envoy/latest/configuration/observability/access_log#format-strings
         '''
     )
+    parser.add_argument(
+        '--access_log_json',
+        action='store_true',
+        default=False,
+        help='''
+        If set, access log entries are written as JSON objects with a default
+        ESPv2 field set: request, response, operation name, API consumer, JWT
+        subject, backend cluster, upstream latency and attempt count, gRPC
+        status and trace ID. The API consumer is only set when the local API
+        key store (--api_key_store_path) is enabled. Requires --access_log,
+        cannot be used with --access_log_format.
+        '''
+    )
+    parser.add_argument(
+        '--access_log_json_add_fields',
+        help='''
+        Comma-separated list of name=format to add or override fields of the
+        JSON and OpenTelemetry access logs, e.g. "region=%%REQ(X-REGION)%%".
+        '''
+    )
+    parser.add_argument(
+        '--access_log_json_remove_fields',
+        help='''
+        Comma-separated list of default fields to remove from the JSON access
+        log, e.g. "api_consumer,user_agent". Also applies to the OpenTelemetry
+        access log attributes.
+        '''
+    )
+    parser.add_argument(
+        '--access_log_grpc_address',
+        help='''
+        Address of an Envoy gRPC Access Log Service to stream the access logs
+        to, e.g. "grpc://als:9000". Use the "grpcs" or "https" scheme for TLS.
+        '''
+    )
+    parser.add_argument(
+        '--access_log_otel_address',
+        help='''
+        Address of an OpenTelemetry logs collector (OTLP gRPC) to stream the
+        access logs to, e.g. "grpc://otel-collector:4317". Use the "grpcs" or
+        "https" scheme for TLS. The log attributes are the JSON access log
+        fields.
+        '''
+    )
+    parser.add_argument(
+        '--access_log_min_status_code',
+        default=None, type=int,
+        help='''
+        If set, only requests with a response code greater than or equal to it
+        are streamed to the gRPC and OpenTelemetry access log sinks.
+        '''
+    )
+    parser.add_argument(
+        '--access_log_sampling_rate',
+        help='''
+        The fraction of requests streamed to the gRPC and OpenTelemetry access
+        log sinks, from 0.0 to 1.0. By default, all requests are streamed.
+        '''
+    )
 
     parser.add_argument(
         '--disable_tracing',
@@ -778,6 +947,29 @@ environment variable or by passing "-k" flag to this script.
         the --tracing_sample_rate flag.
         '''
     )
+    parser.add_argument(
+        '--tracing_provider',
+        default=None,
+        choices=['opencensus', 'opentelemetry', 'zipkin'],
+        help='''
+        The tracer used to export spans. "opencensus" exports to Stackdriver,
+        "opentelemetry" exports to an OTLP gRPC collector and "zipkin" exports
+        to a Zipkin collector. The collector is set by
+        --tracing_collector_address. Default is "opencensus". The
+        "opentelemetry" tracer only propagates traceparent and the "zipkin"
+        tracer only propagates B3 headers, other trace contexts and span limits
+        set by the --tracing_* flags are rejected for them.
+        '''
+    )
+    parser.add_argument(
+        '--tracing_collector_address',
+        default=None,
+        help='''
+        The address of the trace collector for the "opentelemetry" and
+        "zipkin" tracing providers, e.g. "http://otel-collector:4317" or
+        "http://zipkin:9411/api/v2/spans".
+        '''
+    )
     parser.add_argument(
         '--tracing_project_id',
         default="",
@@ -791,6 +983,17 @@ environment variable or by passing "-k" flag to this script.
         trace context regardless this flag value.
         '''
     )
+    parser.add_argument(
+        '--tracing_operation_sample_rates',
+        default=None,
+        help='''
+        Comma separated list of "selector=rate" pairs that override
+        --tracing_sample_rate for individual operations, e.g.
+        "endpoints.examples.bookstore.Bookstore.ListBooks=0". Each rate must be
+        from 0.0 to 1.0. Selectors without an operation are ignored with a
+        warning.
+        '''
+    )
     parser.add_argument(
         '--disable_cloud_trace_auto_sampling',
         action='store_true',
@@ -908,11 +1111,31 @@ environment variable or by passing "-k" flag to this script.
         help='''If true, use new line delimiter to separate response streaming messages.
         If false, all response streaming messages will be transcoded into a JSON array.''')
 
+    parser.add_argument(
+        '--transcoding_stream_sse', action='store_true',
+        help='''If true, the responses of server-streaming methods are
+        server-sent events with the content type "text/event-stream", one
+        "data:" event per message, so browsers can consume them with
+        EventSource. Streams already use the stream idle timeout instead
+        of the request timeout. It can be overridden per API or operation
+        with "stream_sse" in --transcoding_options_path.''')
+
     parser.add_argument(
         '--transcoding_case_insensitive_enum_parsing', action='store_true',
         help='''Proto enum values are supposed to be in upper cases when used in JSON.
         Set this flag to true if your JSON request uses non uppercase enum values.''')
 
+    parser.add_argument(
+        '--transcoding_rich_error_details', action='store_true',
+        help='''If true, the google.rpc error detail types, such as BadRequest
+        and RetryInfo, are added to the proto descriptor, so the details of
+        the "grpc-status-details-bin" trailer of failed calls are converted
+        into the "details" of the JSON response body. The HTTP status code
+        follows the google.rpc mapping of the gRPC status code. The body is
+        the bare google.rpc.Status with the gRPC status code, so it cannot
+        be used together with the "google_rpc_status" local reply
+        preset.''')
+
     parser.add_argument(
         '--transcoding_preserve_proto_field_names', action='store_true',
         help='''Whether to preserve proto field names for grpc-json transcoding.
@@ -958,6 +1181,40 @@ environment variable or by passing "-k" flag to this script.
         [1](https://github.com/googleapis/googleapis/blob/master/google/api/http.proto#L226-L231)
         ''')
 
+    parser.add_argument(
+        '--transcoding_options_path', default=None,
+        help='''
+        Path to a JSON file that overrides the --transcoding_* flags for some
+        APIs or operations, e.g. {"options": [{"selectors":
+        ["bookstore.Bookstore"], "preserve_proto_field_names": true}]}. The
+        options of an operation take precedence over the options of its API.
+        ''')
+
+    parser.add_argument(
+        '--transcoding_descriptor_path', default=None,
+        help='''
+        Path to a binary FileDescriptorSet used for grpc-json transcoding,
+        instead of the descriptor in the service config.
+        ''')
+
+    parser.add_argument(
+        '--transcoding_grpc_reflection', action='store_true',
+        help='''
+        If set, fetch the proto descriptors of the APIs from the gRPC backend
+        via gRPC server reflection, instead of the descriptor in the service
+        config. The descriptors are fetched at startup and every
+        --transcoding_grpc_reflection_interval, and Envoy is updated when they
+        change.
+        ''')
+
+    parser.add_argument(
+        '--transcoding_grpc_reflection_interval', default=None,
+        help='''
+        The interval to fetch the proto descriptors from the backend via gRPC
+        server reflection, e.g. "30s". Default is 60 seconds. Requires
+        --transcoding_grpc_reflection.
+        ''')
+
     parser.add_argument(
         '--disallow_colon_in_wildcard_path_segment', action='store_true',
         help='''
@@ -990,6 +1247,97 @@ environment variable or by passing "-k" flag to this script.
         help='''Enable both gzip and brotli compression for response data with
         default envoy compression settings. Please see envoy document for detail.
         https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_filters/compressor_filter.''')
+    parser.add_argument('--response_compression_algorithms',
+        help='''Comma-separated list of the response compression algorithms,
+        from (gzip|br|zstd). The default is "gzip,br". Requires
+        --enable_response_compression.''')
+    parser.add_argument('--response_compression_levels',
+        help='''Comma-separated list of algorithm=level to set the compression
+        levels, e.g. "gzip=6,br=4,zstd=3". The level range is [1, 9] for gzip,
+        [0, 11] for br and [1, 22] for zstd. If unset, the Envoy default level
+        is used. Requires --enable_response_compression.''')
+    parser.add_argument('--response_compression_min_content_length',
+        default=None, type=int,
+        help='''The minimum response size in bytes to compress. If unset, the
+        Envoy default of 30 is used. Requires --enable_response_compression.''')
+    parser.add_argument('--response_compression_content_types',
+        help='''Comma-separated list of the response content types to compress.
+        If unset, the Envoy default content types are compressed. Requires
+        --enable_response_compression.''')
+    parser.add_argument('--response_compression_operations',
+        help='''Comma-separated list of the operation selectors to compress the
+        responses of. If unset, the responses of all operations are compressed.
+        Requires --enable_response_compression.''')
+    parser.add_argument('--enable_request_decompression', action='store_true',
+        help='''Enable gzip decompression for request data, so backends receive
+        uncompressed requests. Please see envoy document for detail.
+        https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_filters/decompressor_filter.''')
+    parser.add_argument('--max_request_body_bytes', default=None, type=int,
+        help='''The maximum request body size in bytes. Larger requests are
+        rejected with 413, before the body is forwarded to the backend. The
+        default is no limit.''')
+    parser.add_argument('--max_request_body_bytes_operations',
+        help='''Comma-separated list of "selector=bytes" pairs that override
+        --max_request_body_bytes for individual operations, e.g.
+        "endpoints.examples.bookstore.Bookstore.CreateBook=1048576". A limit
+        of 0 means no limit for the operation.''')
+    parser.add_argument('--enable_fault_injection', action='store_true',
+        help='''Enable the fault injection policies in
+        --fault_injection_policies_path. For resilience testing only, do not
+        use it in production.''')
+    parser.add_argument('--fault_injection_policies_path', default=None,
+        help='''Path to a JSON file with fault injection policies that inject
+        delays and aborts into the requests of APIs or operations, e.g.
+        {"policies": [{"selectors": ["bookstore.Bookstore.GetShelf"],
+        "header": "x-chaos-test", "delay": {"duration": "2s",
+        "percentage": 50}, "abort": {"grpc_status": 14, "percentage": 10}}]}.
+        Requires --enable_fault_injection.''')
+    parser.add_argument('--http_cache_operations',
+        help='''Comma-separated list of the selectors of GET operations to
+        cache the responses of in memory. The backend controls caching with
+        the Cache-Control and Vary response headers. Cached responses are only
+        served to the same consumer, identified by the JWT headers, the API
+        key headers from the service config system parameters and, with
+        --api_key_store_path, the API consumer header, unless the operation is
+        in --http_cache_public_operations. Operations that require JWT
+        authentication cannot be cached.''')
+    parser.add_argument('--http_cache_public_operations',
+        help='''Comma-separated list of the selectors in
+        --http_cache_operations whose cached responses are shared across
+        consumers.''')
+    parser.add_argument('--http_cache_max_body_bytes', default=None, type=int,
+        help='''The maximum response size in bytes to cache. If unset, the
+        Envoy default is used. Requires --http_cache_operations.''')
+    parser.add_argument('--local_reply_config_path', default=None,
+        help='''Path to a JSON file that customizes the bodies of the error
+        replies generated by ESPv2 and Envoy, with body formats per status
+        code range and per Accept content type, and mappers that rewrite
+        specific failures, e.g. {"body_format": {"preset":
+        "google_rpc_status"}, "mappers": [{"status_codes": {"min": 503,
+        "max": 503}, "response_flags": ["UH"], "body": "Service unavailable,
+        please retry later."}]}. If unset, the replies have the JSON body
+        {"code": <status code>, "message": <error message>}.''')
+    parser.add_argument('--enable_connect_grpc_bridge', action='store_true',
+        help='''Enable the Connect protocol for gRPC backends, so Connect
+        clients, e.g. Connect-Web, can call the gRPC methods at their
+        "/package.Service/Method" paths. Unary Connect requests with the
+        JSON encoding are transcoded, so their errors have the
+        google.rpc.Status body of the transcoder instead of the Connect error
+        body. Streaming Connect requests with the JSON encoding are rejected
+        with 415. The Connect request headers are also allowed by CORS.''')
+    parser.add_argument('--enable_grpc_json_reverse_transcoding',
+        action='store_true',
+        help='''Enable gRPC clients to call the unary methods served by
+        HTTP/JSON backends at their "/package.Service/Method" paths. The gRPC
+        requests are converted into HTTP/JSON requests following the http
+        rules of the methods, using the proto descriptor of the service
+        config. Methods whose http rule path has variables cannot use the
+        CONSTANT_ADDRESS path translation.''')
+    parser.add_argument('--enable_operation_stats', action='store_true',
+        help='''Enable per-operation stats on the admin endpoint, e.g.
+        /stats/prometheus. The requests are counted and timed per operation,
+        and per gRPC method with the gRPC status for gRPC backends. The stats
+        are tagged with the operation and gRPC method names.''')
 
     # Start Deprecated Flags Section
 
@@ -1117,13 +1465,69 @@ def enforce_conflict_args(args):
             return "If --non_gcp is specified, --service_account_key or --enable_application_default_credentials has to be specified, or GOOGLE_APPLICATION_CREDENTIALS has to set in os.environ."
         if args.service_account_key and args.enable_application_default_credentials:
             return "Only one of --service_account_key or --enable_application_default_credentials can be supplied for credentials at once."
-        if not args.tracing_project_id:
-            # for non gcp case, disable tracing if tracing project id is not provided.
+        if not args.tracing_project_id and args.tracing_provider in (None, 'opencensus'):
+            # for non gcp case, disable Stackdriver tracing if tracing project id is not provided.
             args.disable_tracing = True
 
+    if args.tracing_provider in ('opentelemetry', 'zipkin') and not args.tracing_collector_address:
+        return "Flag --tracing_collector_address is required if --tracing_provider=%s." % args.tracing_provider
+
+    if not args.enable_response_compression and (
+            args.response_compression_algorithms or
+            args.response_compression_levels or
+            args.response_compression_min_content_length is not None or
+            args.response_compression_content_types or
+            args.response_compression_operations):
+        return ("Flags --response_compression_* have to be used together "
+                "with --enable_response_compression.")
+
+    if args.max_request_body_bytes is not None and args.max_request_body_bytes < 0:
+        return "Flag --max_request_body_bytes must not be negative."
+
+    if args.fault_injection_policies_path and not args.enable_fault_injection:
+        return ("Flag --fault_injection_policies_path has to be used together "
+                "with --enable_fault_injection.")
+
+    if not args.http_cache_operations and (
+            args.http_cache_public_operations or
+            args.http_cache_max_body_bytes is not None):
+        return ("Flags --http_cache_* have to be used together "
+                "with --http_cache_operations.")
+
+    if args.transcoding_grpc_reflection and args.transcoding_descriptor_path:
+        return ("Flag --transcoding_grpc_reflection cannot be used together "
+                "with --transcoding_descriptor_path.")
+
+    if args.transcoding_grpc_reflection_interval and not args.transcoding_grpc_reflection:
+        return ("Flag --transcoding_grpc_reflection_interval has to be used "
+                "together with --transcoding_grpc_reflection.")
+
+    if args.cors_policies_path and not args.cors_preset:
+        return "Flag --cors_policies_path has to be used together with --cors_preset."
+
     if not args.access_log and args.access_log_format:
         return "Flag --access_log_format has to be used together with --access_log."
 
+    if not args.access_log and args.access_log_json:
+        return "Flag --access_log_json has to be used together with --access_log."
+
+    if (args.access_log_json_add_fields or
+            args.access_log_json_remove_fields) and not (
+                args.access_log_json or args.access_log_otel_address):
+        return ("Flags --access_log_json_add_fields and "
+                "--access_log_json_remove_fields have to be used together "
+                "with --access_log_json or --access_log_otel_address.")
+
+    if (args.access_log_min_status_code is not None or
+            args.access_log_sampling_rate) and not (
+                args.access_log_grpc_address or args.access_log_otel_address):
+        return ("Flags --access_log_min_status_code and "
+                "--access_log_sampling_rate have to be used together with "
+                "--access_log_grpc_address or --access_log_otel_address.")
+
+    if args.access_log_json and args.access_log_format:
+        return "Flag --access_log_json cannot be used together with --access_log_format."
+
     if args.ssl_port and args.ssl_server_cert_path:
         return "Flag --ssl_port is going to be deprecated, please use --ssl_server_cert_path only."
     if args.tls_mutual_auth and (args.ssl_backend_client_cert_path or args.ssl_client_cert_path):
@@ -1259,6 +1663,19 @@ def gen_proxy_config(args):
         proxy_conf.append("--jwt_pad_forward_payload_header")
     if args.disable_jwt_audience_service_name_check:
         proxy_conf.append("--disable_jwt_audience_service_name_check")
+    if args.jwks_local_files:
+        proxy_conf.extend(["--jwks_local_files", args.jwks_local_files])
+    if args.jwt_authn_audit_only:
+        proxy_conf.append("--jwt_authn_audit_only")
+    if args.jwt_authn_audit_only_selectors:
+        proxy_conf.extend(["--jwt_authn_audit_only_selectors", args.jwt_authn_audit_only_selectors])
+    if args.jwt_provider_options_path:
+        proxy_conf.extend(["--jwt_provider_options_path", args.jwt_provider_options_path])
+    if args.token_introspection_providers_path:
+        proxy_conf.extend(["--token_introspection_providers_path", args.token_introspection_providers_path])
+
+    if args.api_key_store_path:
+        proxy_conf.extend(["--api_key_store_path", args.api_key_store_path])
 
     if args.management:
         proxy_conf.extend(["--service_management_url", args.management])
@@ -1324,11 +1741,59 @@ def gen_proxy_config(args):
         proxy_conf.extend(["--add_response_headers", ";".join(args.add_response_header)])
     if args.append_response_header:
         proxy_conf.extend(["--append_response_headers", ";".join(args.append_response_header)])
+    if args.header_rules_path:
+        proxy_conf.extend(["--header_rules_path", args.header_rules_path])
 
     if args.enable_operation_name_header:
         proxy_conf.append("--enable_operation_name_header")
     if args.enable_response_compression:
         proxy_conf.append("--enable_response_compression")
+    if args.response_compression_algorithms:
+        proxy_conf.extend(["--response_compression_algorithms",
+                           args.response_compression_algorithms])
+    if args.response_compression_levels:
+        proxy_conf.extend(["--response_compression_levels",
+                           args.response_compression_levels])
+    if args.response_compression_min_content_length is not None:
+        proxy_conf.extend(["--response_compression_min_content_length",
+                           str(args.response_compression_min_content_length)])
+    if args.response_compression_content_types:
+        proxy_conf.extend(["--response_compression_content_types",
+                           args.response_compression_content_types])
+    if args.response_compression_operations:
+        proxy_conf.extend(["--response_compression_operations",
+                           args.response_compression_operations])
+    if args.enable_request_decompression:
+        proxy_conf.append("--enable_request_decompression")
+    if args.max_request_body_bytes is not None:
+        proxy_conf.extend(["--max_request_body_bytes",
+                           str(args.max_request_body_bytes)])
+    if args.max_request_body_bytes_operations:
+        proxy_conf.extend(["--max_request_body_bytes_operations",
+                           args.max_request_body_bytes_operations])
+    if args.enable_fault_injection:
+        proxy_conf.append("--enable_fault_injection")
+    if args.fault_injection_policies_path:
+        proxy_conf.extend(["--fault_injection_policies_path",
+                           args.fault_injection_policies_path])
+    if args.http_cache_operations:
+        proxy_conf.extend(["--http_cache_operations",
+                           args.http_cache_operations])
+    if args.http_cache_public_operations:
+        proxy_conf.extend(["--http_cache_public_operations",
+                           args.http_cache_public_operations])
+    if args.http_cache_max_body_bytes is not None:
+        proxy_conf.extend(["--http_cache_max_body_bytes",
+                           str(args.http_cache_max_body_bytes)])
+    if args.local_reply_config_path:
+        proxy_conf.extend(["--local_reply_config_path",
+                           args.local_reply_config_path])
+    if args.enable_connect_grpc_bridge:
+        proxy_conf.append("--enable_connect_grpc_bridge")
+    if args.enable_grpc_json_reverse_transcoding:
+        proxy_conf.append("--enable_grpc_json_reverse_transcoding")
+    if args.enable_operation_stats:
+        proxy_conf.append("--enable_operation_stats")
 
     # Generate self-signed cert if needed
     if args.generate_self_signed_cert:
@@ -1360,6 +1825,16 @@ def gen_proxy_config(args):
             "--service_control_url", args.service_control_url
         ])
 
+    if args.service_control_local_sink:
+        proxy_conf.extend([
+            "--service_control_local_sink", args.service_control_local_sink
+        ])
+    if args.service_control_local_sink_port:
+        proxy_conf.extend([
+            "--service_control_local_sink_port",
+            str(args.service_control_local_sink_port)
+        ])
+
     if args.service_control_check_retries:
         proxy_conf.extend([
             "--service_control_check_retries",
@@ -1434,10 +1909,35 @@ def gen_proxy_config(args):
     if args.access_log_format:
         proxy_conf.extend(["--access_log_format",
                            args.access_log_format])
+    if args.access_log_json:
+        proxy_conf.append("--access_log_json")
+    if args.access_log_json_add_fields:
+        proxy_conf.extend(["--access_log_json_add_fields",
+                           args.access_log_json_add_fields])
+    if args.access_log_json_remove_fields:
+        proxy_conf.extend(["--access_log_json_remove_fields",
+                           args.access_log_json_remove_fields])
+    if args.access_log_grpc_address:
+        proxy_conf.extend(["--access_log_grpc_address",
+                           args.access_log_grpc_address])
+    if args.access_log_otel_address:
+        proxy_conf.extend(["--access_log_otel_address",
+                           args.access_log_otel_address])
+    if args.access_log_min_status_code is not None:
+        proxy_conf.extend(["--access_log_min_status_code",
+                           str(args.access_log_min_status_code)])
+    if args.access_log_sampling_rate:
+        proxy_conf.extend(["--access_log_sampling_rate",
+                           args.access_log_sampling_rate])
 
     if args.disable_tracing:
         proxy_conf.append("--disable_tracing")
     else:
+        if args.tracing_provider:
+            proxy_conf.extend(["--tracing_provider", args.tracing_provider])
+        if args.tracing_collector_address:
+            proxy_conf.extend(
+                ["--tracing_collector_address", args.tracing_collector_address])
         if args.tracing_project_id:
             proxy_conf.extend(["--tracing_project_id", args.tracing_project_id])
         if args.tracing_incoming_context:
@@ -1455,6 +1955,9 @@ def gen_proxy_config(args):
         elif args.tracing_sample_rate:
             proxy_conf.extend(["--tracing_sample_rate",
                                str(args.tracing_sample_rate)])
+        if args.tracing_operation_sample_rates:
+            proxy_conf.extend(["--tracing_operation_sample_rates",
+                               args.tracing_operation_sample_rates])
         # TODO(nareddyt): Enable if we find it's helpful for gRPC streaming.
         # if args.enable_debug:
         #     proxy_conf.append("--tracing_enable_verbose_annotations")
@@ -1468,9 +1971,15 @@ def gen_proxy_config(args):
     if args.transcoding_stream_newline_delimited:
         proxy_conf.append("--transcoding_stream_newline_delimited")
 
+    if args.transcoding_stream_sse:
+        proxy_conf.append("--transcoding_stream_sse")
+
     if args.transcoding_case_insensitive_enum_parsing:
         proxy_conf.append("--transcoding_case_insensitive_enum_parsing")
 
+    if args.transcoding_rich_error_details:
+        proxy_conf.append("--transcoding_rich_error_details")
+
     if args.transcoding_preserve_proto_field_names:
         proxy_conf.append("--transcoding_preserve_proto_field_names")
 
@@ -1487,6 +1996,21 @@ def gen_proxy_config(args):
     if args.transcoding_match_unregistered_custom_verb:
         proxy_conf.append("--transcoding_match_unregistered_custom_verb")
 
+    if args.transcoding_options_path:
+        proxy_conf.extend(["--transcoding_options_path",
+                           args.transcoding_options_path])
+
+    if args.transcoding_descriptor_path:
+        proxy_conf.extend(["--transcoding_descriptor_path",
+                           args.transcoding_descriptor_path])
+
+    if args.transcoding_grpc_reflection:
+        proxy_conf.append("--transcoding_grpc_reflection")
+
+    if args.transcoding_grpc_reflection_interval:
+        proxy_conf.extend(["--transcoding_grpc_reflection_interval",
+                           args.transcoding_grpc_reflection_interval])
+
     if args.disallow_colon_in_wildcard_path_segment:
         proxy_conf.append("--disallow_colon_in_wildcard_path_segment")
 
@@ -1528,6 +2052,8 @@ def gen_proxy_config(args):
         ])
         if args.cors_allow_credentials:
             proxy_conf.append("--cors_allow_credentials")
+        if args.cors_policies_path:
+            proxy_conf.extend(["--cors_policies_path", args.cors_policies_path])
 
     if args.enable_application_default_credentials:
         proxy_conf.append("--enable_application_default_credentials")
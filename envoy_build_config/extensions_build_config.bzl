@@ -14,17 +14,30 @@ EXTENSIONS = {
     "envoy.clusters.strict_dns": "//source/extensions/clusters/strict_dns:strict_dns_cluster_lib",
     "envoy.clusters.logical_dns": "//source/extensions/clusters/logical_dns:logical_dns_cluster_lib",
     "envoy.access_loggers.file": "//source/extensions/access_loggers/file:config",
+    "envoy.access_loggers.stdout": "//source/extensions/access_loggers/stream:config",
+    "envoy.access_loggers.open_telemetry": "//source/extensions/access_loggers/open_telemetry:config",
     "envoy.compression.gzip.compressor": "//source/extensions/compression/gzip/compressor:config",
     "envoy.compression.brotli.compressor": "//source/extensions/compression/brotli/compressor:config",
+    "envoy.compression.zstd.compressor": "//source/extensions/compression/zstd/compressor:config",
+    "envoy.compression.gzip.decompressor": "//source/extensions/compression/gzip/decompressor:config",
+    "envoy.filters.http.cache": "//source/extensions/filters/http/cache:config",
     "envoy.filters.http.compressor": "//source/extensions/filters/http/compressor:config",
+    "envoy.filters.http.connect_grpc_bridge": "//source/extensions/filters/http/connect_grpc_bridge:config",
     "envoy.filters.http.cors": "//source/extensions/filters/http/cors:config",
+    "envoy.filters.http.decompressor": "//source/extensions/filters/http/decompressor:config",
+    "envoy.filters.http.fault": "//source/extensions/filters/http/fault:config",
     "envoy.filters.http.grpc_json_transcoder": "//source/extensions/filters/http/grpc_json_transcoder:config",
+    "envoy.filters.http.grpc_stats": "//source/extensions/filters/http/grpc_stats:config",
     "envoy.filters.http.grpc_web": "//source/extensions/filters/http/grpc_web:config",
     "envoy.filters.http.health_check": "//source/extensions/filters/http/health_check:config",
     "envoy.filters.http.jwt_authn": "//source/extensions/filters/http/jwt_authn:config",
+    "envoy.filters.http.rbac": "//source/extensions/filters/http/rbac:config",
     "envoy.filters.http.router": "//source/extensions/filters/http/router:config",
+    "envoy.extensions.http.cache.simple": "//source/extensions/http/cache/simple_http_cache:config",
     "envoy.filters.network.http_connection_manager": "//source/extensions/filters/network/http_connection_manager:config",
     "envoy.tracers.opencensus": "//source/extensions/tracers/opencensus:config",
+    "envoy.tracers.opentelemetry": "//source/extensions/tracers/opentelemetry:config",
+    "envoy.tracers.zipkin": "//source/extensions/tracers/zipkin:config",
 
     # Implicitly needed for TLS config.
     "envoy.transport_sockets.raw_buffer": "//source/extensions/transport_sockets/raw_buffer:config",
@@ -16,6 +16,7 @@ require (
 	github.com/gorilla/websocket v1.4.2
 	github.com/imdario/mergo v0.3.15
 	github.com/miekg/dns v1.1.45
+	go.opentelemetry.io/proto/otlp v0.19.0
 	golang.org/x/net v0.38.0
 	golang.org/x/oauth2 v0.27.0
 	google.golang.org/api v0.114.0
@@ -0,0 +1,187 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+#include "src/envoy/http/api_key/filter.h"
+
+#include "absl/strings/match.h"
+#include "source/common/buffer/buffer_impl.h"
+#include "source/common/common/hex.h"
+#include "source/common/crypto/utility.h"
+#include "source/common/http/headers.h"
+#include "source/common/http/utility.h"
+#include "src/envoy/http/service_control/handler_utils.h"
+#include "src/envoy/utils/rc_detail_utils.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace api_key {
+
+using Envoy::Http::FilterHeadersStatus;
+using Envoy::Http::RequestHeaderMap;
+
+namespace {
+
+// Removes the URL scheme, if any.
+absl::string_view stripScheme(absl::string_view url) {
+  const size_t pos = url.find("://");
+  if (pos != absl::string_view::npos) {
+    url.remove_prefix(pos + 3);
+  }
+  return url;
+}
+
+// Matches `text` against a glob `pattern` where `*` matches any sequence.
+bool globMatch(absl::string_view pattern, absl::string_view text) {
+  size_t p = 0, t = 0;
+  size_t star = absl::string_view::npos, star_t = 0;
+  while (t < text.size()) {
+    if (p < pattern.size() && pattern[p] == '*') {
+      star = p++;
+      star_t = t;
+    } else if (p < pattern.size() && pattern[p] == text[t]) {
+      p++;
+      t++;
+    } else if (star != absl::string_view::npos) {
+      p = star + 1;
+      t = ++star_t;
+    } else {
+      return false;
+    }
+  }
+  while (p < pattern.size() && pattern[p] == '*') {
+    p++;
+  }
+  return p == pattern.size();
+}
+
+std::string sha256Hex(const std::string& api_key) {
+  Envoy::Buffer::OwnedImpl buffer(api_key);
+  return Envoy::Hex::encode(
+      Envoy::Common::Crypto::UtilitySingleton::get().getSha256Digest(buffer));
+}
+
+}  // namespace
+
+bool matchReferrer(absl::string_view pattern, absl::string_view referrer) {
+  if (!absl::StrContains(pattern, "://")) {
+    referrer = stripScheme(referrer);
+  }
+  return globMatch(pattern, referrer);
+}
+
+FilterHeadersStatus Filter::decodeHeaders(RequestHeaderMap& headers, bool) {
+  // The consumer header is only set by this filter.
+  if (!config_->consumerHeader().get().empty()) {
+    headers.remove(config_->consumerHeader());
+  }
+
+  const auto* per_route =
+      ::Envoy::Http::Utility::resolveMostSpecificPerFilterConfig<
+          PerRouteFilterConfig>(decoder_callbacks_);
+  if (per_route == nullptr) {
+    ENVOY_LOG(debug, "no per-route config, API key is not validated");
+    return FilterHeadersStatus::Continue;
+  }
+
+  std::string api_key;
+  const auto& locations = per_route->locations().empty()
+                              ? config_->defaultLocations()
+                              : per_route->locations();
+  if (!service_control::extractAPIKey(headers, locations, api_key)) {
+    if (per_route->allow_without_api_key()) {
+      config_->stats().allowed_without_api_key_.inc();
+      return FilterHeadersStatus::Continue;
+    }
+    config_->stats().denied_by_missing_api_key_.inc();
+    rejectRequest(Envoy::Http::Code::Unauthorized,
+                  "Method doesn't allow unregistered callers (callers without "
+                  "established identity). Please use API Key or other form of "
+                  "API consumer identity to call this API.",
+                  utils::generateRcDetails(utils::kRcDetailFilterApiKey,
+                                           utils::kRcDetailErrorTypeBadRequest,
+                                           utils::kRcDetailErrorMissingApiKey));
+    return FilterHeadersStatus::StopIteration;
+  }
+
+  const ApiKeyInfo* info = config_->findApiKey(sha256Hex(api_key));
+  if (info == nullptr) {
+    config_->stats().denied_by_invalid_api_key_.inc();
+    rejectRequest(Envoy::Http::Code::BadRequest,
+                  "API key not valid. Please pass a valid API key.",
+                  utils::generateRcDetails(
+                      utils::kRcDetailFilterApiKey,
+                      utils::kRcDetailErrorTypeInvalidApiKey));
+    return FilterHeadersStatus::StopIteration;
+  }
+
+  if (!info->allowed_selectors.empty() &&
+      !info->allowed_selectors.contains(per_route->operation_name())) {
+    config_->stats().denied_by_operation_.inc();
+    rejectRequest(
+        Envoy::Http::Code::Forbidden,
+        "The API targeted by this request is invalid for the given API key.",
+        utils::generateRcDetails(utils::kRcDetailFilterApiKey,
+                                 utils::kRcDetailErrorTypeApiKeyBlocked,
+                                 "OPERATION"));
+    return FilterHeadersStatus::StopIteration;
+  }
+
+  if (!info->allowed_referrers.empty()) {
+    const auto referrer =
+        headers.get(Envoy::Http::CustomHeaders::get().Referer);
+    const absl::string_view referrer_value =
+        referrer.empty() ? "" : referrer[0]->value().getStringView();
+    bool allowed = false;
+    for (const auto& pattern : info->allowed_referrers) {
+      if (matchReferrer(pattern, referrer_value)) {
+        allowed = true;
+        break;
+      }
+    }
+    if (!allowed) {
+      config_->stats().denied_by_referrer_.inc();
+      rejectRequest(Envoy::Http::Code::Forbidden, "Referer blocked.",
+                    utils::generateRcDetails(
+                        utils::kRcDetailFilterApiKey,
+                        utils::kRcDetailErrorTypeApiKeyBlocked, "REFERER"));
+      return FilterHeadersStatus::StopIteration;
+    }
+  }
+
+  Envoy::ProtobufWkt::Struct metadata;
+  (*metadata.mutable_fields())[kConsumerMetadataKey].set_string_value(
+      info->consumer);
+  decoder_callbacks_->streamInfo().setDynamicMetadata(kFilterName, metadata);
+
+  if (!config_->consumerHeader().get().empty()) {
+    headers.setCopy(config_->consumerHeader(), info->consumer);
+  }
+
+  config_->stats().allowed_.inc();
+  return FilterHeadersStatus::Continue;
+}
+
+void Filter::rejectRequest(Envoy::Http::Code code, absl::string_view error_msg,
+                           absl::string_view details) {
+  ENVOY_LOG(debug, "{}", error_msg);
+  decoder_callbacks_->sendLocalReply(code, error_msg, nullptr, absl::nullopt,
+                                     details);
+}
+
+}  // namespace api_key
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
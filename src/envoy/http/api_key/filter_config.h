@@ -0,0 +1,148 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+#pragma once
+
+#include <memory>
+#include <string>
+#include <vector>
+
+#include "absl/container/flat_hash_map.h"
+#include "absl/container/flat_hash_set.h"
+#include "api/envoy/v12/http/api_key/config.pb.h"
+#include "envoy/http/header_map.h"
+#include "envoy/router/router.h"
+#include "envoy/stats/scope.h"
+#include "envoy/stats/stats_macros.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace api_key {
+
+// The filter name.
+constexpr const char kFilterName[] = "com.google.espv2.filters.http.api_key";
+
+// The dynamic metadata key of the consumer label.
+constexpr const char kConsumerMetadataKey[] = "consumer";
+
+/**
+ * All stats for the API key filter. @see stats_macros.h
+ */
+#define ALL_API_KEY_FILTER_STATS(COUNTER) \
+  COUNTER(allowed)                        \
+  COUNTER(allowed_without_api_key)        \
+  COUNTER(denied_by_missing_api_key)      \
+  COUNTER(denied_by_invalid_api_key)      \
+  COUNTER(denied_by_operation)            \
+  COUNTER(denied_by_referrer)
+
+/**
+ * Wrapper struct for API key filter stats. @see stats_macros.h
+ */
+struct FilterStats {
+  ALL_API_KEY_FILTER_STATS(GENERATE_COUNTER_STRUCT)
+};
+
+// The restrictions of an API key in the key store.
+struct ApiKeyInfo {
+  std::string consumer;
+  // Empty if all operations are allowed.
+  absl::flat_hash_set<std::string> allowed_selectors;
+  // Empty if any referrer is allowed.
+  std::vector<std::string> allowed_referrers;
+};
+
+class FilterConfig {
+ public:
+  FilterConfig(
+      const ::espv2::api::envoy::v12::http::api_key::FilterConfig& proto_config,
+      const std::string& stats_prefix, Envoy::Stats::Scope& scope)
+      : consumer_header_(proto_config.consumer_header()),
+        stats_(generateStats(stats_prefix, scope)) {
+    for (const auto& api_key : proto_config.api_keys()) {
+      ApiKeyInfo& info = api_keys_[api_key.key_sha256()];
+      info.consumer = api_key.consumer();
+      info.allowed_selectors.insert(api_key.allowed_selectors().begin(),
+                                    api_key.allowed_selectors().end());
+      info.allowed_referrers.assign(api_key.allowed_referrers().begin(),
+                                    api_key.allowed_referrers().end());
+    }
+
+    // The default places to extract the API key, the same as the Service
+    // Control filter.
+    default_locations_.Add()->set_query("key");
+    default_locations_.Add()->set_query("api_key");
+    default_locations_.Add()->set_header("x-api-key");
+  }
+
+  // Returns nullptr if the digest is not in the key store.
+  const ApiKeyInfo* findApiKey(const std::string& key_sha256) const {
+    auto it = api_keys_.find(key_sha256);
+    return it == api_keys_.end() ? nullptr : &it->second;
+  }
+
+  const ::google::protobuf::RepeatedPtrField<
+      ::espv2::api::envoy::v12::http::service_control::ApiKeyLocation>&
+  defaultLocations() const {
+    return default_locations_;
+  }
+
+  const Envoy::Http::LowerCaseString& consumerHeader() const {
+    return consumer_header_;
+  }
+
+  FilterStats& stats() { return stats_; }
+
+ private:
+  FilterStats generateStats(const std::string& prefix,
+                            Envoy::Stats::Scope& scope) {
+    const std::string final_prefix = prefix + "api_key.";
+    return {ALL_API_KEY_FILTER_STATS(POOL_COUNTER_PREFIX(scope, final_prefix))};
+  }
+
+  absl::flat_hash_map<std::string, ApiKeyInfo> api_keys_;
+  ::google::protobuf::RepeatedPtrField<
+      ::espv2::api::envoy::v12::http::service_control::ApiKeyLocation>
+      default_locations_;
+  const Envoy::Http::LowerCaseString consumer_header_;
+  FilterStats stats_;
+};
+
+using FilterConfigSharedPtr = std::shared_ptr<FilterConfig>;
+
+class PerRouteFilterConfig : public Envoy::Router::RouteSpecificFilterConfig {
+ public:
+  PerRouteFilterConfig(
+      const ::espv2::api::envoy::v12::http::api_key::PerRouteFilterConfig&
+          proto)
+      : proto_(proto) {}
+
+  const std::string& operation_name() const { return proto_.operation_name(); }
+  const ::google::protobuf::RepeatedPtrField<
+      ::espv2::api::envoy::v12::http::service_control::ApiKeyLocation>&
+  locations() const {
+    return proto_.locations();
+  }
+  bool allow_without_api_key() const { return proto_.allow_without_api_key(); }
+
+ private:
+  const ::espv2::api::envoy::v12::http::api_key::PerRouteFilterConfig proto_;
+};
+
+}  // namespace api_key
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,237 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+#include "src/envoy/http/api_key/filter.h"
+
+#include "gmock/gmock.h"
+#include "google/protobuf/text_format.h"
+#include "gtest/gtest.h"
+#include "source/common/buffer/buffer_impl.h"
+#include "source/common/common/hex.h"
+#include "source/common/crypto/utility.h"
+#include "source/common/stats/isolated_store_impl.h"
+#include "test/mocks/http/mocks.h"
+#include "test/test_common/utility.h"
+
+using ::testing::_;
+using ::testing::Return;
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace api_key {
+namespace {
+
+// The key digests are set in SetUp.
+constexpr char kFilterConfig[] = R"(
+api_keys {
+  consumer: "mobile-app"
+}
+api_keys {
+  consumer: "partner"
+  allowed_selectors: "api.Allowed"
+  allowed_referrers: "*.example.com/*"
+}
+consumer_header: "x-endpoint-api-consumer"
+)";
+
+const Envoy::Http::LowerCaseString kConsumerHeader{"x-endpoint-api-consumer"};
+
+std::string sha256Hex(const std::string& value) {
+  return Envoy::Hex::encode(
+      Envoy::Common::Crypto::UtilitySingleton::get().getSha256Digest(
+          Envoy::Buffer::OwnedImpl(value)));
+}
+
+class ApiKeyFilterTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    ::espv2::api::envoy::v12::http::api_key::FilterConfig proto;
+    ASSERT_TRUE(Envoy::Protobuf::TextFormat::ParseFromString(kFilterConfig,
+                                                             &proto));
+    proto.mutable_api_keys(0)->set_key_sha256(sha256Hex("valid-key"));
+    proto.mutable_api_keys(1)->set_key_sha256(sha256Hex("restricted-key"));
+    config_ = std::make_shared<FilterConfig>(proto, "", *store_.rootScope());
+
+    filter_ = std::make_unique<Filter>(config_);
+    filter_->setDecoderFilterCallbacks(mock_decoder_callbacks_);
+  }
+
+  void setPerRouteConfig(const std::string& per_route_config) {
+    ::espv2::api::envoy::v12::http::api_key::PerRouteFilterConfig proto;
+    ASSERT_TRUE(Envoy::Protobuf::TextFormat::ParseFromString(per_route_config,
+                                                             &proto));
+    per_route_ = std::make_shared<PerRouteFilterConfig>(proto);
+    EXPECT_CALL(mock_decoder_callbacks_, mostSpecificPerFilterConfig())
+        .WillRepeatedly(Return(per_route_.get()));
+  }
+
+  uint64_t counter(const std::string& name) {
+    const auto counter =
+        Envoy::TestUtility::findCounter(store_, absl::StrCat("api_key.", name));
+    return counter == nullptr ? 0 : counter->value();
+  }
+
+  Envoy::Stats::IsolatedStoreImpl store_;
+  testing::NiceMock<Envoy::Http::MockStreamDecoderFilterCallbacks>
+      mock_decoder_callbacks_;
+  FilterConfigSharedPtr config_;
+  std::shared_ptr<PerRouteFilterConfig> per_route_;
+  std::unique_ptr<Filter> filter_;
+};
+
+TEST_F(ApiKeyFilterTest, NoPerRouteConfigAllowed) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"x-endpoint-api-consumer", "spoofed"}};
+  EXPECT_CALL(mock_decoder_callbacks_, mostSpecificPerFilterConfig())
+      .WillRepeatedly(Return(nullptr));
+
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_FALSE(headers.has(kConsumerHeader));
+}
+
+TEST_F(ApiKeyFilterTest, MissingApiKeyRejected) {
+  setPerRouteConfig(R"(operation_name: "api.Allowed")");
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/books/1"}};
+
+  EXPECT_CALL(mock_decoder_callbacks_,
+              sendLocalReply(Envoy::Http::Code::Unauthorized, _, _, _,
+                             "api_key_bad_request{MISSING_API_KEY}"));
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::StopIteration);
+  EXPECT_EQ(counter("denied_by_missing_api_key"), 1);
+}
+
+TEST_F(ApiKeyFilterTest, MissingApiKeyAllowed) {
+  setPerRouteConfig(R"(
+operation_name: "api.Allowed"
+allow_without_api_key: true
+)");
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/books/1"}};
+
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_EQ(counter("allowed_without_api_key"), 1);
+}
+
+TEST_F(ApiKeyFilterTest, InvalidApiKeyRejected) {
+  setPerRouteConfig(R"(operation_name: "api.Allowed")");
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/books/1?key=bad"}};
+
+  EXPECT_CALL(mock_decoder_callbacks_,
+              sendLocalReply(Envoy::Http::Code::BadRequest,
+                             "API key not valid. Please pass a valid API key.",
+                             _, _, "api_key_invalid_api_key"));
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::StopIteration);
+  EXPECT_EQ(counter("denied_by_invalid_api_key"), 1);
+}
+
+TEST_F(ApiKeyFilterTest, ValidApiKeyAllowed) {
+  setPerRouteConfig(R"(operation_name: "api.Other")");
+  Envoy::Http::TestRequestHeaderMapImpl headers{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"x-api-key", "valid-key"},
+      {"x-endpoint-api-consumer", "spoofed"}};
+
+  EXPECT_CALL(mock_decoder_callbacks_.stream_info_,
+              setDynamicMetadata(kFilterName, _))
+      .WillOnce([](const std::string&, const Envoy::ProtobufWkt::Struct& obj) {
+        EXPECT_EQ(obj.fields().at(kConsumerMetadataKey).string_value(),
+                  "mobile-app");
+      });
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_EQ(headers.get_(kConsumerHeader), "mobile-app");
+  EXPECT_EQ(counter("allowed"), 1);
+}
+
+TEST_F(ApiKeyFilterTest, CustomLocation) {
+  setPerRouteConfig(R"(
+operation_name: "api.Other"
+locations { header: "x-custom-key" }
+)");
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/books/1"},
+                                                {"x-api-key", "bad"},
+                                                {"x-custom-key", "valid-key"}};
+
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_EQ(headers.get_(kConsumerHeader), "mobile-app");
+}
+
+TEST_F(ApiKeyFilterTest, OperationRestriction) {
+  setPerRouteConfig(R"(operation_name: "api.Other")");
+  Envoy::Http::TestRequestHeaderMapImpl headers{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"x-api-key", "restricted-key"},
+      {"referer", "https://www.example.com/index.html"}};
+
+  EXPECT_CALL(mock_decoder_callbacks_,
+              sendLocalReply(Envoy::Http::Code::Forbidden, _, _, _,
+                             "api_key_api_key_blocked{OPERATION}"));
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::StopIteration);
+  EXPECT_EQ(counter("denied_by_operation"), 1);
+}
+
+TEST_F(ApiKeyFilterTest, ReferrerRestriction) {
+  setPerRouteConfig(R"(operation_name: "api.Allowed")");
+  Envoy::Http::TestRequestHeaderMapImpl allowed_headers{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"x-api-key", "restricted-key"},
+      {"referer", "https://www.example.com/index.html"}};
+  EXPECT_EQ(filter_->decodeHeaders(allowed_headers, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_EQ(allowed_headers.get_(kConsumerHeader), "partner");
+
+  Envoy::Http::TestRequestHeaderMapImpl blocked_headers{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"x-api-key", "restricted-key"},
+      {"referer", "https://www.example.org/index.html"}};
+  EXPECT_CALL(mock_decoder_callbacks_,
+              sendLocalReply(Envoy::Http::Code::Forbidden, "Referer blocked.",
+                             _, _, "api_key_api_key_blocked{REFERER}"));
+  EXPECT_EQ(filter_->decodeHeaders(blocked_headers, false),
+            Envoy::Http::FilterHeadersStatus::StopIteration);
+  EXPECT_EQ(counter("denied_by_referrer"), 1);
+}
+
+TEST(MatchReferrerTest, Patterns) {
+  EXPECT_TRUE(matchReferrer("*.example.com/*", "https://www.example.com/a"));
+  EXPECT_TRUE(matchReferrer("*.example.com/*", "www.example.com/"));
+  EXPECT_FALSE(matchReferrer("*.example.com/*", "https://example.org/a"));
+  EXPECT_FALSE(matchReferrer("*.example.com/*", ""));
+  EXPECT_TRUE(matchReferrer("https://example.com/*", "https://example.com/a"));
+  EXPECT_FALSE(matchReferrer("https://example.com/*", "http://example.com/a"));
+  EXPECT_TRUE(matchReferrer("*", "anything"));
+}
+
+}  // namespace
+}  // namespace api_key
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
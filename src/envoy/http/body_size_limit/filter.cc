@@ -0,0 +1,92 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/body_size_limit/filter.h"
+
+#include "absl/strings/numbers.h"
+#include "absl/strings/str_cat.h"
+#include "source/common/http/utility.h"
+#include "src/envoy/utils/rc_detail_utils.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace body_size_limit {
+
+using Envoy::Http::FilterDataStatus;
+using Envoy::Http::FilterHeadersStatus;
+using Envoy::Http::RequestHeaderMap;
+
+FilterHeadersStatus Filter::decodeHeaders(RequestHeaderMap& headers,
+                                          bool end_stream) {
+  max_request_bytes_ = config_->default_max_request_bytes();
+  const auto* per_route =
+      ::Envoy::Http::Utility::resolveMostSpecificPerFilterConfig<
+          PerRouteFilterConfig>(decoder_callbacks_);
+  if (per_route != nullptr) {
+    max_request_bytes_ = per_route->max_request_bytes();
+  }
+
+  if (max_request_bytes_ == 0 || end_stream) {
+    return FilterHeadersStatus::Continue;
+  }
+
+  // Reject early if the declared body size is already over the limit.
+  uint64_t content_length;
+  if (headers.ContentLength() != nullptr &&
+      absl::SimpleAtoi(headers.getContentLengthValue(), &content_length) &&
+      content_length > max_request_bytes_) {
+    config_->stats().denied_by_content_length_.inc();
+    rejectRequest(utils::kRcDetailErrorContentLengthTooLarge);
+    return FilterHeadersStatus::StopIteration;
+  }
+
+  return FilterHeadersStatus::Continue;
+}
+
+FilterDataStatus Filter::decodeData(Envoy::Buffer::Instance& data, bool) {
+  if (rejected_) {
+    return FilterDataStatus::StopIterationNoBuffer;
+  }
+  if (max_request_bytes_ == 0) {
+    return FilterDataStatus::Continue;
+  }
+
+  received_bytes_ += data.length();
+  if (received_bytes_ > max_request_bytes_) {
+    config_->stats().denied_by_body_size_.inc();
+    rejectRequest(utils::kRcDetailErrorBodyTooLarge);
+    return FilterDataStatus::StopIterationNoBuffer;
+  }
+
+  return FilterDataStatus::Continue;
+}
+
+void Filter::rejectRequest(absl::string_view details) {
+  const std::string error_msg =
+      absl::StrCat("Request body is too large, max allowed size is ",
+                   max_request_bytes_, " bytes.");
+  ENVOY_LOG(debug, "{}", error_msg);
+  rejected_ = true;
+  decoder_callbacks_->sendLocalReply(
+      Envoy::Http::Code::PayloadTooLarge, error_msg, nullptr, absl::nullopt,
+      utils::generateRcDetails(utils::kRcDetailFilterBodySizeLimit,
+                               utils::kRcDetailErrorTypePayloadTooLarge,
+                               std::string(details)));
+}
+
+}  // namespace body_size_limit
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,56 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include "envoy/http/filter.h"
+#include "envoy/http/header_map.h"
+#include "source/common/common/logger.h"
+#include "source/extensions/filters/http/common/pass_through_filter.h"
+#include "src/envoy/http/body_size_limit/filter_config.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace body_size_limit {
+
+// Rejects requests whose body is larger than the limit of the route with 413.
+// Requests with a Content-Length over the limit are rejected before the body
+// is read, other requests are rejected once the received body exceeds it, so
+// streamed bodies are never buffered.
+class Filter : public Envoy::Http::PassThroughDecoderFilter,
+               public Envoy::Logger::Loggable<Envoy::Logger::Id::filter> {
+ public:
+  Filter(FilterConfigSharedPtr config) : config_(config) {}
+
+  // Envoy::Http::StreamDecoderFilter
+  Envoy::Http::FilterHeadersStatus decodeHeaders(Envoy::Http::RequestHeaderMap&,
+                                                 bool) override;
+  Envoy::Http::FilterDataStatus decodeData(Envoy::Buffer::Instance&,
+                                           bool) override;
+
+ private:
+  void rejectRequest(absl::string_view details);
+
+  const FilterConfigSharedPtr config_;
+  // The limit of the route, zero means no limit.
+  uint64_t max_request_bytes_{};
+  uint64_t received_bytes_{};
+  bool rejected_{};
+};
+
+}  // namespace body_size_limit
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <memory>
+#include <string>
+
+#include "api/envoy/v12/http/body_size_limit/config.pb.h"
+#include "envoy/router/router.h"
+#include "envoy/stats/scope.h"
+#include "envoy/stats/stats_macros.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace body_size_limit {
+
+// The filter name.
+constexpr const char kFilterName[] =
+    "com.google.espv2.filters.http.body_size_limit";
+
+/**
+ * All stats for the body size limit filter. @see stats_macros.h
+ */
+#define ALL_BODY_SIZE_LIMIT_FILTER_STATS(COUNTER) \
+  COUNTER(denied_by_content_length)               \
+  COUNTER(denied_by_body_size)
+
+/**
+ * Wrapper struct for body size limit filter stats. @see stats_macros.h
+ */
+struct FilterStats {
+  ALL_BODY_SIZE_LIMIT_FILTER_STATS(GENERATE_COUNTER_STRUCT)
+};
+
+class FilterConfig {
+ public:
+  FilterConfig(const ::espv2::api::envoy::v12::http::body_size_limit::
+                   FilterConfig& proto_config,
+               const std::string& stats_prefix, Envoy::Stats::Scope& scope)
+      : default_max_request_bytes_(proto_config.default_max_request_bytes()),
+        stats_(generateStats(stats_prefix, scope)) {}
+
+  uint64_t default_max_request_bytes() const {
+    return default_max_request_bytes_;
+  }
+
+  FilterStats& stats() { return stats_; }
+
+ private:
+  FilterStats generateStats(const std::string& prefix,
+                            Envoy::Stats::Scope& scope) {
+    const std::string final_prefix = prefix + "body_size_limit.";
+    return {ALL_BODY_SIZE_LIMIT_FILTER_STATS(
+        POOL_COUNTER_PREFIX(scope, final_prefix))};
+  }
+
+  const uint64_t default_max_request_bytes_;
+  FilterStats stats_;
+};
+
+using FilterConfigSharedPtr = std::shared_ptr<FilterConfig>;
+
+class PerRouteFilterConfig : public Envoy::Router::RouteSpecificFilterConfig {
+ public:
+  PerRouteFilterConfig(const ::espv2::api::envoy::v12::http::body_size_limit::
+                           PerRouteFilterConfig& proto)
+      : max_request_bytes_(proto.max_request_bytes()) {}
+
+  uint64_t max_request_bytes() const { return max_request_bytes_; }
+
+ private:
+  const uint64_t max_request_bytes_;
+};
+
+}  // namespace body_size_limit
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
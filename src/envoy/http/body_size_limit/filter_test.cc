@@ -0,0 +1,148 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/body_size_limit/filter.h"
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "source/common/buffer/buffer_impl.h"
+#include "source/common/stats/isolated_store_impl.h"
+#include "test/mocks/http/mocks.h"
+#include "test/test_common/utility.h"
+
+using ::testing::_;
+using ::testing::NiceMock;
+using ::testing::Return;
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace body_size_limit {
+namespace {
+
+constexpr char kErrorMessage[] =
+    "Request body is too large, max allowed size is 10 bytes.";
+
+class BodySizeLimitFilterTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    ::espv2::api::envoy::v12::http::body_size_limit::FilterConfig proto;
+    proto.set_default_max_request_bytes(10);
+    config_ = std::make_shared<FilterConfig>(proto, "", *store_.rootScope());
+
+    filter_ = std::make_unique<Filter>(config_);
+    filter_->setDecoderFilterCallbacks(mock_decoder_callbacks_);
+  }
+
+  void setPerRouteLimit(uint64_t max_request_bytes) {
+    ::espv2::api::envoy::v12::http::body_size_limit::PerRouteFilterConfig
+        proto;
+    proto.set_max_request_bytes(max_request_bytes);
+    per_route_config_ = std::make_shared<PerRouteFilterConfig>(proto);
+    EXPECT_CALL(mock_decoder_callbacks_, mostSpecificPerFilterConfig())
+        .WillRepeatedly(Return(per_route_config_.get()));
+  }
+
+  Envoy::Stats::IsolatedStoreImpl store_;
+  FilterConfigSharedPtr config_;
+  std::shared_ptr<PerRouteFilterConfig> per_route_config_;
+  NiceMock<Envoy::Http::MockStreamDecoderFilterCallbacks>
+      mock_decoder_callbacks_;
+  std::unique_ptr<Filter> filter_;
+};
+
+TEST_F(BodySizeLimitFilterTest, AllowRequestWithinDefaultLimit) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "POST"},
+                                                {":path", "/books"},
+                                                {"content-length", "10"}};
+  EXPECT_CALL(mock_decoder_callbacks_, sendLocalReply(_, _, _, _, _)).Times(0);
+
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, false));
+  Envoy::Buffer::OwnedImpl data("0123456789");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->decodeData(data, true));
+}
+
+TEST_F(BodySizeLimitFilterTest, RejectByContentLength) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "POST"},
+                                                {":path", "/books"},
+                                                {"content-length", "11"}};
+  EXPECT_CALL(
+      mock_decoder_callbacks_,
+      sendLocalReply(
+          Envoy::Http::Code::PayloadTooLarge, kErrorMessage, _, _,
+          "body_size_limit_payload_too_large{CONTENT_LENGTH_TOO_LARGE}"));
+
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::StopIteration,
+            filter_->decodeHeaders(headers, false));
+  EXPECT_EQ(1UL, config_->stats().denied_by_content_length_.value());
+}
+
+TEST_F(BodySizeLimitFilterTest, RejectStreamedBody) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{
+      {":method", "POST"}, {":path", "/books"}, {"transfer-encoding", "chunked"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, false));
+
+  Envoy::Buffer::OwnedImpl first("012345");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->decodeData(first, false));
+
+  EXPECT_CALL(
+      mock_decoder_callbacks_,
+      sendLocalReply(Envoy::Http::Code::PayloadTooLarge, kErrorMessage, _, _,
+                     "body_size_limit_payload_too_large{BODY_TOO_LARGE}"))
+      .Times(1);
+  Envoy::Buffer::OwnedImpl second("67890");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::StopIterationNoBuffer,
+            filter_->decodeData(second, false));
+
+  // Data after the rejection is dropped without another local reply.
+  Envoy::Buffer::OwnedImpl third("1");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::StopIterationNoBuffer,
+            filter_->decodeData(third, true));
+  EXPECT_EQ(1UL, config_->stats().denied_by_body_size_.value());
+}
+
+TEST_F(BodySizeLimitFilterTest, PerRouteLimitOverridesDefault) {
+  setPerRouteLimit(20);
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "POST"},
+                                                {":path", "/books"},
+                                                {"content-length", "20"}};
+  EXPECT_CALL(mock_decoder_callbacks_, sendLocalReply(_, _, _, _, _)).Times(0);
+
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, false));
+}
+
+TEST_F(BodySizeLimitFilterTest, PerRouteZeroLimitDisablesCheck) {
+  setPerRouteLimit(0);
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "POST"},
+                                                {":path", "/books"},
+                                                {"content-length", "1000"}};
+  EXPECT_CALL(mock_decoder_callbacks_, sendLocalReply(_, _, _, _, _)).Times(0);
+
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, false));
+  Envoy::Buffer::OwnedImpl data(std::string(1000, 'a'));
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->decodeData(data, true));
+}
+
+}  // namespace
+}  // namespace body_size_limit
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,283 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/grpc_json_reverse_transcoder/filter.h"
+
+#include <vector>
+
+#include "absl/strings/str_cat.h"
+#include "google/api/annotations.pb.h"
+#include "source/common/common/enum_to_int.h"
+#include "source/common/grpc/codec.h"
+#include "source/common/grpc/common.h"
+#include "source/common/grpc/status.h"
+#include "source/common/http/headers.h"
+#include "source/common/http/utility.h"
+#include "src/envoy/http/grpc_json_reverse_transcoder/request_builder.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace grpc_json_reverse_transcoder {
+
+using Envoy::Grpc::Status;
+using Envoy::Http::FilterDataStatus;
+using Envoy::Http::FilterHeadersStatus;
+using Envoy::Http::FilterTrailersStatus;
+using Envoy::Http::RequestHeaderMap;
+using Envoy::Http::RequestTrailerMap;
+using Envoy::Http::ResponseHeaderMap;
+using Envoy::Http::ResponseTrailerMap;
+
+FilterHeadersStatus Filter::decodeHeaders(RequestHeaderMap& headers,
+                                          bool end_stream) {
+  const auto* per_route =
+      ::Envoy::Http::Utility::resolveMostSpecificPerFilterConfig<
+          PerRouteFilterConfig>(decoder_callbacks_);
+  if (per_route == nullptr ||
+      !Envoy::Grpc::Common::isGrpcRequestHeaders(headers)) {
+    return FilterHeadersStatus::Continue;
+  }
+
+  const auto* method = config_->findMethod(per_route->methodName());
+  if (method == nullptr ||
+      !method->options().HasExtension(google::api::http)) {
+    ENVOY_LOG(warn, "no http rule for reverse transcoded method {}",
+              per_route->methodName());
+    rejectRequest(Status::WellKnownGrpcStatus::Unimplemented,
+                  absl::StrCat("method ", per_route->methodName(),
+                               " cannot be transcoded"));
+    return FilterHeadersStatus::StopIteration;
+  }
+
+  method_ = method;
+  http_rule_ = method->options().GetExtension(google::api::http);
+  request_headers_ = &headers;
+  if (end_stream) {
+    rejectRequest(Status::WellKnownGrpcStatus::InvalidArgument,
+                  "missing request message");
+  }
+  return FilterHeadersStatus::StopIteration;
+}
+
+FilterDataStatus Filter::decodeData(Envoy::Buffer::Instance& data,
+                                    bool end_stream) {
+  if (method_ == nullptr) {
+    return FilterDataStatus::Continue;
+  }
+
+  request_buffer_.move(data);
+  if (config_->maxRequestBodyBytes() > 0 &&
+      request_buffer_.length() > config_->maxRequestBodyBytes()) {
+    rejectRequest(Status::WellKnownGrpcStatus::ResourceExhausted,
+                  "request message too large");
+    return FilterDataStatus::StopIterationNoBuffer;
+  }
+  if (!end_stream) {
+    return FilterDataStatus::StopIterationNoBuffer;
+  }
+
+  if (!transcodeRequest(data)) {
+    return FilterDataStatus::StopIterationNoBuffer;
+  }
+  return FilterDataStatus::Continue;
+}
+
+FilterTrailersStatus Filter::decodeTrailers(RequestTrailerMap&) {
+  if (method_ == nullptr) {
+    return FilterTrailersStatus::Continue;
+  }
+
+  Envoy::Buffer::OwnedImpl data;
+  if (!transcodeRequest(data)) {
+    return FilterTrailersStatus::StopIteration;
+  }
+  decoder_callbacks_->addDecodedData(data, false);
+  return FilterTrailersStatus::Continue;
+}
+
+bool Filter::transcodeRequest(Envoy::Buffer::Instance& data) {
+  std::vector<Envoy::Grpc::Frame> frames;
+  Envoy::Grpc::Decoder decoder;
+  const absl::Status decode_status = decoder.decode(request_buffer_, frames);
+  if (!decode_status.ok() || frames.size() != 1 ||
+      frames[0].flags_ != Envoy::Grpc::GRPC_FH_DEFAULT) {
+    rejectRequest(Status::WellKnownGrpcStatus::InvalidArgument,
+                  "expected one uncompressed request message");
+    return false;
+  }
+
+  auto message = config_->newMessage(method_->input_type());
+  // An empty message has no frame data.
+  if (frames[0].data_ != nullptr &&
+      !message->ParseFromString(frames[0].data_->toString())) {
+    rejectRequest(Status::WellKnownGrpcStatus::InvalidArgument,
+                  "unable to parse the request message");
+    return false;
+  }
+
+  const auto request = buildHttpRequest(http_rule_, *message);
+  if (!request.ok()) {
+    rejectRequest(Status::WellKnownGrpcStatus::Internal,
+                  request.status().message());
+    return false;
+  }
+
+  ENVOY_LOG(debug, "reverse transcoding {} to {} {}", method_->full_name(),
+            request->method, request->path);
+  config_->stats().requests_transcoded_.inc();
+  request_headers_->setMethod(request->method);
+  request_headers_->setPath(request->path);
+  request_headers_->removeTE();
+  if (request->body.empty()) {
+    request_headers_->removeContentType();
+  } else {
+    request_headers_->setContentType(
+        Envoy::Http::Headers::get().ContentTypeValues.Json);
+  }
+  request_headers_->setContentLength(request->body.size());
+
+  data.drain(data.length());
+  data.add(request->body);
+  return true;
+}
+
+void Filter::rejectRequest(Status::GrpcStatus status,
+                           absl::string_view message) {
+  config_->stats().request_transcoding_errors_.inc();
+  method_ = nullptr;
+  decoder_callbacks_->sendLocalReply(
+      Envoy::Http::Code::BadRequest, message, nullptr, status,
+      "grpc_json_reverse_transcoder_bad_request");
+}
+
+FilterHeadersStatus Filter::encodeHeaders(ResponseHeaderMap& headers,
+                                          bool end_stream) {
+  if (method_ == nullptr) {
+    return FilterHeadersStatus::Continue;
+  }
+
+  response_status_ = Envoy::Http::Utility::getResponseStatus(headers);
+  headers.setStatus(Envoy::enumToInt(Envoy::Http::Code::OK));
+  headers.setReferenceContentType(
+      Envoy::Http::Headers::get().ContentTypeValues.Grpc);
+  headers.removeContentLength();
+
+  if (!end_stream) {
+    return FilterHeadersStatus::StopIteration;
+  }
+
+  // A response without body becomes a trailers-only gRPC response.
+  if (response_status_ < 200 || response_status_ >= 300) {
+    headers.setGrpcStatus(
+        Envoy::Grpc::Utility::httpToGrpcStatus(response_status_));
+    return FilterHeadersStatus::Continue;
+  }
+
+  // A successful response without body, e.g. 204, is OK if the empty output
+  // message can be parsed from it, unless the http rule requires JSON
+  // content. Trailers cannot be added in encodeHeaders, so the status is sent
+  // in the headers.
+  Envoy::Buffer::OwnedImpl data;
+  const absl::Status status = transcodeResponseMessage("", data);
+  if (!status.ok()) {
+    headers.setGrpcStatus(Status::WellKnownGrpcStatus::Internal);
+    headers.setGrpcMessage(percentEncode(status.message(), false));
+    return FilterHeadersStatus::Continue;
+  }
+  headers.setGrpcStatus(Status::WellKnownGrpcStatus::Ok);
+  return FilterHeadersStatus::Continue;
+}
+
+FilterDataStatus Filter::encodeData(Envoy::Buffer::Instance& data,
+                                    bool end_stream) {
+  if (method_ == nullptr) {
+    return FilterDataStatus::Continue;
+  }
+
+  if (!response_too_large_) {
+    response_buffer_.move(data);
+  }
+  data.drain(data.length());
+  if (config_->maxResponseBodyBytes() > 0 &&
+      response_buffer_.length() > config_->maxResponseBodyBytes()) {
+    response_too_large_ = true;
+    response_buffer_.drain(response_buffer_.length());
+  }
+  if (!end_stream) {
+    return FilterDataStatus::StopIterationNoBuffer;
+  }
+
+  transcodeResponse(data, encoder_callbacks_->addEncodedTrailers());
+  return FilterDataStatus::Continue;
+}
+
+FilterTrailersStatus Filter::encodeTrailers(ResponseTrailerMap& trailers) {
+  if (method_ == nullptr) {
+    return FilterTrailersStatus::Continue;
+  }
+
+  Envoy::Buffer::OwnedImpl data;
+  transcodeResponse(data, trailers);
+  encoder_callbacks_->addEncodedData(data, false);
+  return FilterTrailersStatus::Continue;
+}
+
+void Filter::transcodeResponse(Envoy::Buffer::Instance& data,
+                               ResponseTrailerMap& trailers) {
+  if (response_too_large_) {
+    config_->stats().response_transcoding_errors_.inc();
+    trailers.setGrpcStatus(Status::WellKnownGrpcStatus::ResourceExhausted);
+    trailers.setGrpcMessage(percentEncode("response too large", false));
+    return;
+  }
+
+  const std::string body = response_buffer_.toString();
+  response_buffer_.drain(response_buffer_.length());
+  if (response_status_ < 200 || response_status_ >= 300) {
+    // The error body is passed to the client as the gRPC status message.
+    trailers.setGrpcStatus(
+        Envoy::Grpc::Utility::httpToGrpcStatus(response_status_));
+    trailers.setGrpcMessage(percentEncode(body, false));
+    return;
+  }
+
+  const absl::Status status = transcodeResponseMessage(body, data);
+  if (!status.ok()) {
+    trailers.setGrpcStatus(Status::WellKnownGrpcStatus::Internal);
+    trailers.setGrpcMessage(percentEncode(status.message(), false));
+    return;
+  }
+  trailers.setGrpcStatus(Status::WellKnownGrpcStatus::Ok);
+}
+
+absl::Status Filter::transcodeResponseMessage(absl::string_view body,
+                                              Envoy::Buffer::Instance& data) {
+  auto message = config_->newMessage(method_->output_type());
+  const absl::Status status = parseHttpResponse(http_rule_, body, *message);
+  if (!status.ok()) {
+    ENVOY_LOG(debug, "failed to transcode the response of {}: {}",
+              method_->full_name(), status.message());
+    config_->stats().response_transcoding_errors_.inc();
+    return status;
+  }
+
+  data.move(*Envoy::Grpc::Common::serializeToGrpcFrame(*message));
+  return absl::OkStatus();
+}
+
+}  // namespace grpc_json_reverse_transcoder
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,93 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <memory>
+#include <string>
+
+#include "absl/status/status.h"
+#include "absl/strings/string_view.h"
+#include "envoy/grpc/status.h"
+#include "envoy/http/filter.h"
+#include "envoy/http/header_map.h"
+#include "google/api/http.pb.h"
+#include "source/common/buffer/buffer_impl.h"
+#include "source/common/common/logger.h"
+#include "source/extensions/filters/http/common/pass_through_filter.h"
+#include "src/envoy/http/grpc_json_reverse_transcoder/filter_config.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace grpc_json_reverse_transcoder {
+
+// Transcodes the unary gRPC requests of methods served by HTTP/JSON backends
+// into HTTP/JSON requests following the http rules of the methods, and the
+// JSON responses back into gRPC responses. Only gRPC requests on routes with
+// a PerRouteFilterConfig are transcoded.
+class Filter : public Envoy::Http::PassThroughFilter,
+               public Envoy::Logger::Loggable<Envoy::Logger::Id::filter> {
+ public:
+  Filter(FilterConfigSharedPtr config) : config_(config) {}
+
+  // Envoy::Http::StreamDecoderFilter
+  Envoy::Http::FilterHeadersStatus decodeHeaders(
+      Envoy::Http::RequestHeaderMap&, bool) override;
+  Envoy::Http::FilterDataStatus decodeData(Envoy::Buffer::Instance&,
+                                           bool) override;
+  Envoy::Http::FilterTrailersStatus decodeTrailers(
+      Envoy::Http::RequestTrailerMap&) override;
+
+  // Envoy::Http::StreamEncoderFilter
+  Envoy::Http::FilterHeadersStatus encodeHeaders(
+      Envoy::Http::ResponseHeaderMap&, bool) override;
+  Envoy::Http::FilterDataStatus encodeData(Envoy::Buffer::Instance&,
+                                           bool) override;
+  Envoy::Http::FilterTrailersStatus encodeTrailers(
+      Envoy::Http::ResponseTrailerMap&) override;
+
+ private:
+  // Converts the buffered gRPC request into the HTTP/JSON request, replacing
+  // data. Returns false after sending an error reply.
+  bool transcodeRequest(Envoy::Buffer::Instance& data);
+
+  // Converts the buffered JSON response into the gRPC response, replacing
+  // data and setting the gRPC status in trailers.
+  void transcodeResponse(Envoy::Buffer::Instance& data,
+                         Envoy::Http::ResponseTrailerMap& trailers);
+
+  // Parses the JSON body into the output message and appends its gRPC frame
+  // to data.
+  absl::Status transcodeResponseMessage(absl::string_view body,
+                                        Envoy::Buffer::Instance& data);
+
+  void rejectRequest(Envoy::Grpc::Status::GrpcStatus status,
+                     absl::string_view message);
+
+  const FilterConfigSharedPtr config_;
+  const google::protobuf::MethodDescriptor* method_{};
+  google::api::HttpRule http_rule_;
+  Envoy::Http::RequestHeaderMap* request_headers_{};
+  // The HTTP status of the backend response.
+  uint64_t response_status_{};
+  bool response_too_large_{};
+  Envoy::Buffer::OwnedImpl request_buffer_;
+  Envoy::Buffer::OwnedImpl response_buffer_;
+};
+
+}  // namespace grpc_json_reverse_transcoder
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,53 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/grpc_json_reverse_transcoder/filter_config.h"
+
+#include "absl/strings/str_cat.h"
+#include "envoy/common/exception.h"
+#include "google/protobuf/descriptor.pb.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace grpc_json_reverse_transcoder {
+
+FilterConfig::FilterConfig(
+    const ::espv2::api::envoy::v12::http::grpc_json_reverse_transcoder::
+        FilterConfig& proto_config,
+    const std::string& stats_prefix, Envoy::Stats::Scope& scope)
+    : message_factory_(&descriptor_pool_),
+      max_request_body_bytes_(proto_config.max_request_body_bytes()),
+      max_response_body_bytes_(proto_config.max_response_body_bytes()),
+      stats_(generateStats(stats_prefix, scope)) {
+  google::protobuf::FileDescriptorSet descriptor_set;
+  if (!descriptor_set.ParseFromString(proto_config.descriptor_bin())) {
+    throw Envoy::EnvoyException(
+        "grpc_json_reverse_transcoder: unable to parse the proto descriptor");
+  }
+  // The files are ordered with their dependencies first.
+  for (const auto& file : descriptor_set.file()) {
+    if (descriptor_pool_.BuildFile(file) == nullptr) {
+      throw Envoy::EnvoyException(
+          absl::StrCat("grpc_json_reverse_transcoder: unable to build the "
+                       "proto descriptor of ",
+                       file.name()));
+    }
+  }
+}
+
+}  // namespace grpc_json_reverse_transcoder
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
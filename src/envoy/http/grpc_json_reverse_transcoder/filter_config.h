@@ -0,0 +1,112 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <memory>
+#include <string>
+
+#include "api/envoy/v12/http/grpc_json_reverse_transcoder/config.pb.h"
+#include "envoy/router/router.h"
+#include "envoy/stats/scope.h"
+#include "envoy/stats/stats_macros.h"
+#include "google/protobuf/descriptor.h"
+#include "google/protobuf/dynamic_message.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace grpc_json_reverse_transcoder {
+
+// The filter name.
+constexpr const char kFilterName[] =
+    "com.google.espv2.filters.http.grpc_json_reverse_transcoder";
+
+/**
+ * All stats for the gRPC JSON reverse transcoder filter. @see stats_macros.h
+ */
+#define ALL_GRPC_JSON_REVERSE_TRANSCODER_FILTER_STATS(COUNTER) \
+  COUNTER(requests_transcoded)                                  \
+  COUNTER(request_transcoding_errors)                           \
+  COUNTER(response_transcoding_errors)
+
+/**
+ * Wrapper struct for gRPC JSON reverse transcoder filter stats.
+ * @see stats_macros.h
+ */
+struct FilterStats {
+  ALL_GRPC_JSON_REVERSE_TRANSCODER_FILTER_STATS(GENERATE_COUNTER_STRUCT)
+};
+
+class FilterConfig {
+ public:
+  // Throws EnvoyException if the descriptor set cannot be loaded.
+  FilterConfig(const ::espv2::api::envoy::v12::http::
+                   grpc_json_reverse_transcoder::FilterConfig& proto_config,
+               const std::string& stats_prefix, Envoy::Stats::Scope& scope);
+
+  // Returns the method of the fully qualified name, or nullptr.
+  const google::protobuf::MethodDescriptor* findMethod(
+      const std::string& method_name) const {
+    return descriptor_pool_.FindMethodByName(method_name);
+  }
+
+  // Creates an empty message of the type.
+  std::unique_ptr<google::protobuf::Message> newMessage(
+      const google::protobuf::Descriptor* type) const {
+    return std::unique_ptr<google::protobuf::Message>(
+        message_factory_.GetPrototype(type)->New());
+  }
+
+  // The max size of the buffered request or response body, 0 for no limit.
+  uint32_t maxRequestBodyBytes() const { return max_request_body_bytes_; }
+  uint32_t maxResponseBodyBytes() const { return max_response_body_bytes_; }
+
+  FilterStats& stats() { return stats_; }
+
+ private:
+  FilterStats generateStats(const std::string& prefix,
+                            Envoy::Stats::Scope& scope) {
+    const std::string final_prefix = prefix + "grpc_json_reverse_transcoder.";
+    return {ALL_GRPC_JSON_REVERSE_TRANSCODER_FILTER_STATS(
+        POOL_COUNTER_PREFIX(scope, final_prefix))};
+  }
+
+  google::protobuf::DescriptorPool descriptor_pool_;
+  mutable google::protobuf::DynamicMessageFactory message_factory_;
+  const uint32_t max_request_body_bytes_;
+  const uint32_t max_response_body_bytes_;
+  FilterStats stats_;
+};
+
+using FilterConfigSharedPtr = std::shared_ptr<FilterConfig>;
+
+class PerRouteFilterConfig : public Envoy::Router::RouteSpecificFilterConfig {
+ public:
+  PerRouteFilterConfig(const ::espv2::api::envoy::v12::http::
+                           grpc_json_reverse_transcoder::PerRouteFilterConfig&
+                               per_route)
+      : method_name_(per_route.method_name()) {}
+
+  // The fully qualified name of the reverse transcoded method.
+  const std::string& methodName() const { return method_name_; }
+
+ private:
+  const std::string method_name_;
+};
+
+}  // namespace grpc_json_reverse_transcoder
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
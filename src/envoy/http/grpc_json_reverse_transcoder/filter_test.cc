@@ -0,0 +1,341 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/grpc_json_reverse_transcoder/filter.h"
+
+#include "gmock/gmock.h"
+#include "google/api/annotations.pb.h"
+#include "google/protobuf/descriptor.pb.h"
+#include "google/protobuf/text_format.h"
+#include "gtest/gtest.h"
+#include "source/common/buffer/buffer_impl.h"
+#include "source/common/grpc/codec.h"
+#include "source/common/grpc/common.h"
+#include "source/common/stats/isolated_store_impl.h"
+#include "test/mocks/http/mocks.h"
+#include "test/test_common/utility.h"
+
+using ::testing::_;
+using ::testing::Eq;
+using ::testing::NiceMock;
+using ::testing::Optional;
+using ::testing::Return;
+using ::testing::ReturnRef;
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace grpc_json_reverse_transcoder {
+namespace {
+
+constexpr char kBookstoreProto[] = R"(
+name: "bookstore.proto"
+package: "bookstore"
+syntax: "proto3"
+message_type {
+  name: "Shelf"
+  field { name: "id" number: 1 type: TYPE_INT64 label: LABEL_OPTIONAL }
+  field { name: "theme" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL }
+}
+message_type {
+  name: "GetShelfRequest"
+  field { name: "shelf" number: 1 type: TYPE_INT64 label: LABEL_OPTIONAL }
+}
+service {
+  name: "Bookstore"
+  method {
+    name: "GetShelf"
+    input_type: ".bookstore.GetShelfRequest"
+    output_type: ".bookstore.Shelf"
+    options {
+      [google.api.http] { get: "/v1/shelves/{shelf}" }
+    }
+  }
+  method {
+    name: "GetShelfTheme"
+    input_type: ".bookstore.GetShelfRequest"
+    output_type: ".bookstore.Shelf"
+    options {
+      [google.api.http] {
+        get: "/v1/shelves/{shelf}/theme"
+        response_body: "theme"
+      }
+    }
+  }
+  method {
+    name: "DeleteShelf"
+    input_type: ".bookstore.GetShelfRequest"
+    output_type: ".bookstore.Shelf"
+  }
+}
+)";
+
+class GrpcJsonReverseTranscoderFilterTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    google::protobuf::FileDescriptorSet descriptor_set;
+    ASSERT_TRUE(google::protobuf::TextFormat::ParseFromString(
+        kBookstoreProto, descriptor_set.add_file()));
+
+    ::espv2::api::envoy::v12::http::grpc_json_reverse_transcoder::FilterConfig
+        proto;
+    proto.set_descriptor_bin(descriptor_set.SerializeAsString());
+    proto.set_max_response_body_bytes(64);
+    config_ = std::make_shared<FilterConfig>(proto, "", *store_.rootScope());
+
+    filter_ = std::make_unique<Filter>(config_);
+    filter_->setDecoderFilterCallbacks(mock_decoder_callbacks_);
+    filter_->setEncoderFilterCallbacks(mock_encoder_callbacks_);
+  }
+
+  void enableForRoute(const std::string& method_name) {
+    ::espv2::api::envoy::v12::http::grpc_json_reverse_transcoder::
+        PerRouteFilterConfig proto;
+    proto.set_method_name(method_name);
+    per_route_config_ = std::make_shared<PerRouteFilterConfig>(proto);
+    EXPECT_CALL(mock_decoder_callbacks_, mostSpecificPerFilterConfig())
+        .WillRepeatedly(Return(per_route_config_.get()));
+  }
+
+  // Returns the gRPC frame of the message of the type.
+  Envoy::Buffer::InstancePtr grpcFrame(const std::string& type,
+                                       const std::string& text) {
+    auto message = config_->newMessage(
+        config_->findMethod("bookstore.Bookstore.GetShelf")
+            ->file()
+            ->pool()
+            ->FindMessageTypeByName(type));
+    EXPECT_TRUE(
+        google::protobuf::TextFormat::ParseFromString(text, message.get()));
+    return Envoy::Grpc::Common::serializeToGrpcFrame(*message);
+  }
+
+  Envoy::Http::TestRequestHeaderMapImpl grpcRequestHeaders() {
+    return Envoy::Http::TestRequestHeaderMapImpl{
+        {":method", "POST"},
+        {":path", "/bookstore.Bookstore/GetShelf"},
+        {"content-type", "application/grpc"},
+        {"te", "trailers"}};
+  }
+
+  Envoy::Stats::IsolatedStoreImpl store_;
+  FilterConfigSharedPtr config_;
+  std::shared_ptr<PerRouteFilterConfig> per_route_config_;
+  NiceMock<Envoy::Http::MockStreamDecoderFilterCallbacks>
+      mock_decoder_callbacks_;
+  NiceMock<Envoy::Http::MockStreamEncoderFilterCallbacks>
+      mock_encoder_callbacks_;
+  std::unique_ptr<Filter> filter_;
+};
+
+TEST_F(GrpcJsonReverseTranscoderFilterTest, RouteWithoutConfigPassesThrough) {
+  auto headers = grpcRequestHeaders();
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, false));
+  EXPECT_EQ("/bookstore.Bookstore/GetShelf", headers.getPathValue());
+
+  Envoy::Http::TestResponseHeaderMapImpl response_headers{
+      {":status", "200"}, {"content-type", "application/json"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->encodeHeaders(response_headers, false));
+  EXPECT_EQ("application/json", response_headers.getContentTypeValue());
+}
+
+TEST_F(GrpcJsonReverseTranscoderFilterTest, NonGrpcRequestPassesThrough) {
+  enableForRoute("bookstore.Bookstore.GetShelf");
+  Envoy::Http::TestRequestHeaderMapImpl headers{
+      {":method", "POST"},
+      {":path", "/bookstore.Bookstore/GetShelf"},
+      {"content-type", "application/json"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(headers, false));
+  EXPECT_EQ("/bookstore.Bookstore/GetShelf", headers.getPathValue());
+}
+
+TEST_F(GrpcJsonReverseTranscoderFilterTest, TranscodesRequestAndResponse) {
+  enableForRoute("bookstore.Bookstore.GetShelf");
+  auto headers = grpcRequestHeaders();
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::StopIteration,
+            filter_->decodeHeaders(headers, false));
+
+  auto data = grpcFrame("bookstore.GetShelfRequest", "shelf: 1");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->decodeData(*data, true));
+  EXPECT_EQ("GET", headers.getMethodValue());
+  EXPECT_EQ("/v1/shelves/1", headers.getPathValue());
+  EXPECT_EQ("", headers.getContentTypeValue());
+  EXPECT_EQ("0", headers.getContentLengthValue());
+  EXPECT_EQ(nullptr, headers.TE());
+  EXPECT_EQ(0, data->length());
+  EXPECT_EQ(1, config_->stats().requests_transcoded_.value());
+
+  Envoy::Http::TestResponseHeaderMapImpl response_headers{
+      {":status", "200"},
+      {"content-type", "application/json"},
+      {"content-length", "27"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::StopIteration,
+            filter_->encodeHeaders(response_headers, false));
+  EXPECT_EQ("200", response_headers.getStatusValue());
+  EXPECT_EQ("application/grpc", response_headers.getContentTypeValue());
+  EXPECT_EQ(nullptr, response_headers.ContentLength());
+
+  Envoy::Http::TestResponseTrailerMapImpl trailers;
+  EXPECT_CALL(mock_encoder_callbacks_, addEncodedTrailers())
+      .WillOnce(ReturnRef(trailers));
+  Envoy::Buffer::OwnedImpl response_data(R"({"id":"1",)");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::StopIterationNoBuffer,
+            filter_->encodeData(response_data, false));
+  response_data.add(R"("theme":"art"})");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->encodeData(response_data, true));
+  EXPECT_EQ(grpcFrame("bookstore.Shelf", R"(id: 1 theme: "art")")->toString(),
+            response_data.toString());
+  EXPECT_EQ("0", trailers.getGrpcStatusValue());
+}
+
+TEST_F(GrpcJsonReverseTranscoderFilterTest, ErrorResponse) {
+  enableForRoute("bookstore.Bookstore.GetShelf");
+  auto headers = grpcRequestHeaders();
+  filter_->decodeHeaders(headers, false);
+  auto data = grpcFrame("bookstore.GetShelfRequest", "shelf: 1");
+  filter_->decodeData(*data, true);
+
+  Envoy::Http::TestResponseHeaderMapImpl response_headers{
+      {":status", "404"}, {"content-type", "text/plain"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::StopIteration,
+            filter_->encodeHeaders(response_headers, false));
+  EXPECT_EQ("200", response_headers.getStatusValue());
+
+  Envoy::Http::TestResponseTrailerMapImpl trailers;
+  EXPECT_CALL(mock_encoder_callbacks_, addEncodedTrailers())
+      .WillOnce(ReturnRef(trailers));
+  Envoy::Buffer::OwnedImpl response_data("shelf not found");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->encodeData(response_data, true));
+  EXPECT_EQ(0, response_data.length());
+  EXPECT_EQ("12", trailers.getGrpcStatusValue());
+  EXPECT_EQ("shelf%20not%20found", trailers.getGrpcMessageValue());
+}
+
+TEST_F(GrpcJsonReverseTranscoderFilterTest, HeadersOnlyErrorResponse) {
+  enableForRoute("bookstore.Bookstore.GetShelf");
+  auto headers = grpcRequestHeaders();
+  filter_->decodeHeaders(headers, false);
+  auto data = grpcFrame("bookstore.GetShelfRequest", "shelf: 1");
+  filter_->decodeData(*data, true);
+
+  Envoy::Http::TestResponseHeaderMapImpl response_headers{{":status", "503"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->encodeHeaders(response_headers, true));
+  EXPECT_EQ("200", response_headers.getStatusValue());
+  EXPECT_EQ("14", response_headers.getGrpcStatusValue());
+}
+
+TEST_F(GrpcJsonReverseTranscoderFilterTest, HeadersOnlySuccessfulResponse) {
+  enableForRoute("bookstore.Bookstore.GetShelf");
+  auto headers = grpcRequestHeaders();
+  filter_->decodeHeaders(headers, false);
+  auto data = grpcFrame("bookstore.GetShelfRequest", "shelf: 1");
+  filter_->decodeData(*data, true);
+
+  // The response is trailers-only, no data or trailers are added.
+  EXPECT_CALL(mock_encoder_callbacks_, addEncodedData(_, _)).Times(0);
+  EXPECT_CALL(mock_encoder_callbacks_, addEncodedTrailers()).Times(0);
+  Envoy::Http::TestResponseHeaderMapImpl response_headers{{":status", "204"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->encodeHeaders(response_headers, true));
+  EXPECT_EQ("200", response_headers.getStatusValue());
+  EXPECT_EQ("0", response_headers.getGrpcStatusValue());
+  EXPECT_EQ(0, config_->stats().response_transcoding_errors_.value());
+}
+
+TEST_F(GrpcJsonReverseTranscoderFilterTest,
+       HeadersOnlyResponseMissingResponseBody) {
+  enableForRoute("bookstore.Bookstore.GetShelfTheme");
+  auto headers = grpcRequestHeaders();
+  filter_->decodeHeaders(headers, false);
+  auto data = grpcFrame("bookstore.GetShelfRequest", "shelf: 1");
+  filter_->decodeData(*data, true);
+
+  // The string response_body field cannot be parsed from an empty body.
+  EXPECT_CALL(mock_encoder_callbacks_, addEncodedData(_, _)).Times(0);
+  Envoy::Http::TestResponseHeaderMapImpl response_headers{{":status", "200"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->encodeHeaders(response_headers, true));
+  EXPECT_EQ("13", response_headers.getGrpcStatusValue());
+  EXPECT_EQ(1, config_->stats().response_transcoding_errors_.value());
+}
+
+TEST_F(GrpcJsonReverseTranscoderFilterTest, ResponseTooLarge) {
+  enableForRoute("bookstore.Bookstore.GetShelf");
+  auto headers = grpcRequestHeaders();
+  filter_->decodeHeaders(headers, false);
+  auto data = grpcFrame("bookstore.GetShelfRequest", "shelf: 1");
+  filter_->decodeData(*data, true);
+
+  Envoy::Http::TestResponseHeaderMapImpl response_headers{{":status", "200"}};
+  filter_->encodeHeaders(response_headers, false);
+
+  Envoy::Http::TestResponseTrailerMapImpl trailers;
+  EXPECT_CALL(mock_encoder_callbacks_, addEncodedTrailers())
+      .WillOnce(ReturnRef(trailers));
+  Envoy::Buffer::OwnedImpl response_data(std::string(100, ' '));
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->encodeData(response_data, true));
+  EXPECT_EQ("8", trailers.getGrpcStatusValue());
+  EXPECT_EQ(1, config_->stats().response_transcoding_errors_.value());
+}
+
+TEST_F(GrpcJsonReverseTranscoderFilterTest, InvalidRequestMessage) {
+  enableForRoute("bookstore.Bookstore.GetShelf");
+  auto headers = grpcRequestHeaders();
+  filter_->decodeHeaders(headers, false);
+
+  EXPECT_CALL(
+      mock_decoder_callbacks_,
+      sendLocalReply(Envoy::Http::Code::BadRequest, _, _,
+                     Optional(Eq(Envoy::Grpc::Status::InvalidArgument)), _));
+  Envoy::Buffer::OwnedImpl data("not a grpc frame");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::StopIterationNoBuffer,
+            filter_->decodeData(data, true));
+  EXPECT_EQ("/bookstore.Bookstore/GetShelf", headers.getPathValue());
+  EXPECT_EQ(1, config_->stats().request_transcoding_errors_.value());
+}
+
+TEST_F(GrpcJsonReverseTranscoderFilterTest, MethodWithoutHttpRule) {
+  enableForRoute("bookstore.Bookstore.DeleteShelf");
+  auto headers = grpcRequestHeaders();
+
+  EXPECT_CALL(
+      mock_decoder_callbacks_,
+      sendLocalReply(Envoy::Http::Code::BadRequest, _, _,
+                     Optional(Eq(Envoy::Grpc::Status::Unimplemented)), _));
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::StopIteration,
+            filter_->decodeHeaders(headers, false));
+}
+
+TEST(FilterConfigTest, InvalidDescriptor) {
+  Envoy::Stats::IsolatedStoreImpl store;
+  ::espv2::api::envoy::v12::http::grpc_json_reverse_transcoder::FilterConfig
+      proto;
+  proto.set_descriptor_bin("not a descriptor");
+  EXPECT_THROW(FilterConfig(proto, "", *store.rootScope()),
+               Envoy::EnvoyException);
+}
+
+}  // namespace
+}  // namespace grpc_json_reverse_transcoder
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,389 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/grpc_json_reverse_transcoder/request_builder.h"
+
+#include <vector>
+
+#include "absl/strings/ascii.h"
+#include "absl/strings/match.h"
+#include "absl/strings/str_cat.h"
+#include "absl/strings/str_format.h"
+#include "absl/strings/str_join.h"
+#include "absl/strings/str_split.h"
+#include "google/protobuf/util/json_util.h"
+#include "source/common/common/base64.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace grpc_json_reverse_transcoder {
+
+using google::protobuf::FieldDescriptor;
+using google::protobuf::Message;
+using google::protobuf::Reflection;
+
+namespace {
+
+absl::StatusOr<std::string> httpMethod(const google::api::HttpRule& rule) {
+  switch (rule.pattern_case()) {
+    case google::api::HttpRule::kGet:
+      return std::string("GET");
+    case google::api::HttpRule::kPut:
+      return std::string("PUT");
+    case google::api::HttpRule::kPost:
+      return std::string("POST");
+    case google::api::HttpRule::kDelete:
+      return std::string("DELETE");
+    case google::api::HttpRule::kPatch:
+      return std::string("PATCH");
+    case google::api::HttpRule::kCustom:
+      return rule.custom().kind();
+    default:
+      return absl::InvalidArgumentError(
+          absl::StrCat("http rule of ", rule.selector(), " has no pattern"));
+  }
+}
+
+absl::string_view pathTemplate(const google::api::HttpRule& rule) {
+  switch (rule.pattern_case()) {
+    case google::api::HttpRule::kGet:
+      return rule.get();
+    case google::api::HttpRule::kPut:
+      return rule.put();
+    case google::api::HttpRule::kPost:
+      return rule.post();
+    case google::api::HttpRule::kDelete:
+      return rule.delete_();
+    case google::api::HttpRule::kPatch:
+      return rule.patch();
+    case google::api::HttpRule::kCustom:
+      return rule.custom().path();
+    default:
+      return "";
+  }
+}
+
+// Formats the scalar field value for a path or a query parameter. index is
+// the element of a repeated field, or -1.
+absl::StatusOr<std::string> scalarToString(const Message& message,
+                                           const FieldDescriptor* field,
+                                           int index) {
+  const Reflection* reflection = message.GetReflection();
+  const bool repeated = index >= 0;
+  switch (field->cpp_type()) {
+    case FieldDescriptor::CPPTYPE_INT32:
+      return absl::StrCat(
+          repeated ? reflection->GetRepeatedInt32(message, field, index)
+                   : reflection->GetInt32(message, field));
+    case FieldDescriptor::CPPTYPE_INT64:
+      return absl::StrCat(
+          repeated ? reflection->GetRepeatedInt64(message, field, index)
+                   : reflection->GetInt64(message, field));
+    case FieldDescriptor::CPPTYPE_UINT32:
+      return absl::StrCat(
+          repeated ? reflection->GetRepeatedUInt32(message, field, index)
+                   : reflection->GetUInt32(message, field));
+    case FieldDescriptor::CPPTYPE_UINT64:
+      return absl::StrCat(
+          repeated ? reflection->GetRepeatedUInt64(message, field, index)
+                   : reflection->GetUInt64(message, field));
+    case FieldDescriptor::CPPTYPE_DOUBLE:
+      return absl::StrFormat(
+          "%.17g",
+          repeated ? reflection->GetRepeatedDouble(message, field, index)
+                   : reflection->GetDouble(message, field));
+    case FieldDescriptor::CPPTYPE_FLOAT:
+      return absl::StrFormat(
+          "%.9g", repeated ? reflection->GetRepeatedFloat(message, field, index)
+                           : reflection->GetFloat(message, field));
+    case FieldDescriptor::CPPTYPE_BOOL:
+      return std::string(
+          (repeated ? reflection->GetRepeatedBool(message, field, index)
+                    : reflection->GetBool(message, field))
+              ? "true"
+              : "false");
+    case FieldDescriptor::CPPTYPE_ENUM:
+      return (repeated ? reflection->GetRepeatedEnum(message, field, index)
+                       : reflection->GetEnum(message, field))
+          ->name();
+    case FieldDescriptor::CPPTYPE_STRING: {
+      const std::string value =
+          repeated ? reflection->GetRepeatedString(message, field, index)
+                   : reflection->GetString(message, field);
+      if (field->type() == FieldDescriptor::TYPE_BYTES) {
+        return Envoy::Base64::encode(value.data(), value.size());
+      }
+      return value;
+    }
+    default:
+      return absl::UnimplementedError(absl::StrCat(
+          "field ", field->full_name(), " cannot be bound to a URL"));
+  }
+}
+
+// Finds the field of the dot-separated field path in message. Returns the
+// field and the message containing it, the default instance if an enclosing
+// message field is not set.
+absl::StatusOr<std::pair<const Message*, const FieldDescriptor*>> findField(
+    const Message& message, absl::string_view field_path) {
+  const Message* parent = &message;
+  const std::vector<absl::string_view> names = absl::StrSplit(field_path, '.');
+  for (size_t i = 0; i < names.size(); ++i) {
+    const FieldDescriptor* field =
+        parent->GetDescriptor()->FindFieldByName(std::string(names[i]));
+    if (field == nullptr) {
+      return absl::InvalidArgumentError(absl::StrCat(
+          "field ", field_path, " not found in ",
+          message.GetDescriptor()->full_name()));
+    }
+    if (i + 1 == names.size()) {
+      return std::make_pair(parent, field);
+    }
+    if (field->is_repeated() ||
+        field->cpp_type() != FieldDescriptor::CPPTYPE_MESSAGE) {
+      return absl::InvalidArgumentError(
+          absl::StrCat("field ", field_path, " is not a singular message"));
+    }
+    parent = &parent->GetReflection()->GetMessage(*parent, field);
+  }
+  return absl::InvalidArgumentError("empty field path");
+}
+
+// Clears the field of the dot-separated field path, which was bound to the
+// path, so it is not sent again in the body or the query parameters.
+void clearField(Message& message, absl::string_view field_path) {
+  Message* parent = &message;
+  const std::vector<absl::string_view> names = absl::StrSplit(field_path, '.');
+  for (size_t i = 0; i < names.size(); ++i) {
+    const FieldDescriptor* field =
+        parent->GetDescriptor()->FindFieldByName(std::string(names[i]));
+    const Reflection* reflection = parent->GetReflection();
+    if (i + 1 == names.size()) {
+      reflection->ClearField(parent, field);
+      return;
+    }
+    if (!reflection->HasField(*parent, field)) {
+      return;
+    }
+    parent = reflection->MutableMessage(parent, field);
+  }
+}
+
+// Substitutes the variables of the path template, e.g. "/v1/{name=shelves/*}",
+// with the values of their fields.
+absl::StatusOr<std::string> buildPath(absl::string_view path_template,
+                                      Message& message) {
+  std::string path;
+  std::vector<std::string> bound_fields;
+  size_t pos = 0;
+  while (pos < path_template.size()) {
+    if (path_template[pos] != '{') {
+      path.push_back(path_template[pos++]);
+      continue;
+    }
+
+    const size_t end = path_template.find('}', pos);
+    if (end == absl::string_view::npos) {
+      return absl::InvalidArgumentError(
+          absl::StrCat("invalid path template ", path_template));
+    }
+    const std::vector<absl::string_view> variable = absl::StrSplit(
+        path_template.substr(pos + 1, end - pos - 1), absl::MaxSplits('=', 1));
+    // Multi-segment variables keep their slashes.
+    const bool keep_slash =
+        variable.size() > 1 && (absl::StrContains(variable[1], '/') ||
+                                absl::StrContains(variable[1], "**"));
+
+    const auto field = findField(message, variable[0]);
+    if (!field.ok()) {
+      return field.status();
+    }
+    if (field->second->is_repeated()) {
+      return absl::InvalidArgumentError(absl::StrCat(
+          "repeated field ", variable[0], " cannot be bound to the path"));
+    }
+    const auto value = scalarToString(*field->first, field->second, -1);
+    if (!value.ok()) {
+      return value.status();
+    }
+    absl::StrAppend(&path, percentEncode(*value, keep_slash));
+    bound_fields.emplace_back(variable[0]);
+    pos = end + 1;
+  }
+
+  for (const std::string& field_path : bound_fields) {
+    clearField(message, field_path);
+  }
+  return path;
+}
+
+// Appends the fields set in message, except skip_field, as query parameters
+// named by their JSON names. Nested message fields use dotted names.
+absl::Status appendQueryParams(const Message& message,
+                               const std::string& prefix,
+                               const FieldDescriptor* skip_field,
+                               std::vector<std::string>& params) {
+  const Reflection* reflection = message.GetReflection();
+  std::vector<const FieldDescriptor*> fields;
+  reflection->ListFields(message, &fields);
+  for (const FieldDescriptor* field : fields) {
+    if (field == skip_field) {
+      continue;
+    }
+    const std::string name = absl::StrCat(prefix, field->json_name());
+    if (field->is_map() || (field->is_repeated() &&
+                            field->cpp_type() ==
+                                FieldDescriptor::CPPTYPE_MESSAGE)) {
+      return absl::UnimplementedError(absl::StrCat(
+          "field ", field->full_name(), " cannot be a query parameter"));
+    }
+    if (field->cpp_type() == FieldDescriptor::CPPTYPE_MESSAGE) {
+      const absl::Status status =
+          appendQueryParams(reflection->GetMessage(message, field),
+                            absl::StrCat(name, "."), nullptr, params);
+      if (!status.ok()) {
+        return status;
+      }
+      continue;
+    }
+
+    const int count =
+        field->is_repeated() ? reflection->FieldSize(message, field) : 1;
+    for (int i = 0; i < count; ++i) {
+      const auto value =
+          scalarToString(message, field, field->is_repeated() ? i : -1);
+      if (!value.ok()) {
+        return value.status();
+      }
+      params.push_back(absl::StrCat(percentEncode(name, false), "=",
+                                    percentEncode(*value, false)));
+    }
+  }
+  return absl::OkStatus();
+}
+
+absl::StatusOr<std::string> messageToJson(const Message& message) {
+  std::string json;
+  const auto status =
+      google::protobuf::util::MessageToJsonString(message, &json);
+  if (!status.ok()) {
+    return absl::InternalError(absl::StrCat(
+        "failed to convert the request message to JSON: ", status.message()));
+  }
+  return json;
+}
+
+}  // namespace
+
+absl::StatusOr<HttpRequest> buildHttpRequest(const google::api::HttpRule& rule,
+                                             Message& message) {
+  HttpRequest request;
+  const auto method = httpMethod(rule);
+  if (!method.ok()) {
+    return method.status();
+  }
+  request.method = *method;
+
+  const auto path = buildPath(pathTemplate(rule), message);
+  if (!path.ok()) {
+    return path.status();
+  }
+  request.path = *path;
+
+  const FieldDescriptor* body_field = nullptr;
+  if (rule.body() == "*") {
+    const auto json = messageToJson(message);
+    if (!json.ok()) {
+      return json.status();
+    }
+    request.body = *json;
+    return request;
+  }
+  if (!rule.body().empty()) {
+    body_field = message.GetDescriptor()->FindFieldByName(rule.body());
+    if (body_field == nullptr || body_field->is_repeated() ||
+        body_field->cpp_type() != FieldDescriptor::CPPTYPE_MESSAGE) {
+      return absl::UnimplementedError(absl::StrCat(
+          "body field ", rule.body(), " must be a singular message field"));
+    }
+    const auto json = messageToJson(
+        message.GetReflection()->GetMessage(message, body_field));
+    if (!json.ok()) {
+      return json.status();
+    }
+    request.body = *json;
+  }
+
+  std::vector<std::string> params;
+  const absl::Status status =
+      appendQueryParams(message, "", body_field, params);
+  if (!status.ok()) {
+    return status;
+  }
+  if (!params.empty()) {
+    absl::StrAppend(&request.path,
+                    absl::StrContains(request.path, '?') ? "&" : "?",
+                    absl::StrJoin(params, "&"));
+  }
+  return request;
+}
+
+absl::Status parseHttpResponse(const google::api::HttpRule& rule,
+                               absl::string_view body, Message& message) {
+  if (absl::StripAsciiWhitespace(body).empty()) {
+    body = "{}";
+  }
+
+  std::string json(body);
+  if (!rule.response_body().empty()) {
+    const FieldDescriptor* field =
+        message.GetDescriptor()->FindFieldByName(rule.response_body());
+    if (field == nullptr) {
+      return absl::InvalidArgumentError(
+          absl::StrCat("response body field ", rule.response_body(),
+                       " not found in ", message.GetDescriptor()->full_name()));
+    }
+    json = absl::StrCat("{\"", field->json_name(), "\":", body, "}");
+  }
+
+  google::protobuf::util::JsonParseOptions options;
+  // Backends may return fields not in the gRPC API.
+  options.ignore_unknown_fields = true;
+  const auto status =
+      google::protobuf::util::JsonStringToMessage(json, &message, options);
+  if (!status.ok()) {
+    return absl::InternalError(absl::StrCat(
+        "failed to parse the response body: ", status.message()));
+  }
+  return absl::OkStatus();
+}
+
+std::string percentEncode(absl::string_view value, bool keep_slash) {
+  std::string encoded;
+  for (const char c : value) {
+    if (absl::ascii_isalnum(c) || c == '-' || c == '.' || c == '_' ||
+        c == '~' || (keep_slash && c == '/')) {
+      encoded.push_back(c);
+    } else {
+      absl::StrAppendFormat(&encoded, "%%%02X", static_cast<unsigned char>(c));
+    }
+  }
+  return encoded;
+}
+
+}  // namespace grpc_json_reverse_transcoder
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,60 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <string>
+
+#include "absl/status/statusor.h"
+#include "absl/strings/string_view.h"
+#include "google/api/http.pb.h"
+#include "google/protobuf/message.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace grpc_json_reverse_transcoder {
+
+// The HTTP/JSON request transcoded from a gRPC request message.
+struct HttpRequest {
+  std::string method;
+  std::string path;
+  // The JSON body, empty if the http rule has no body.
+  std::string body;
+};
+
+// Builds the HTTP/JSON request of the gRPC request message following the
+// http rule:
+//   - the fields bound to the variables of the path template are substituted
+//     into the path;
+//   - the body field, or all remaining fields for "*", becomes the JSON body;
+//   - without body, the remaining fields become query parameters.
+// The message is modified: the fields bound to the path are cleared.
+absl::StatusOr<HttpRequest> buildHttpRequest(
+    const google::api::HttpRule& rule, google::protobuf::Message& message);
+
+// Parses the JSON body of an HTTP response into the gRPC response message,
+// following the response_body of the http rule.
+absl::Status parseHttpResponse(const google::api::HttpRule& rule,
+                               absl::string_view body,
+                               google::protobuf::Message& message);
+
+// Percent-encodes value for a URL. All characters except the unreserved
+// ones are escaped, and '/' too unless keep_slash is true.
+std::string percentEncode(absl::string_view value, bool keep_slash);
+
+}  // namespace grpc_json_reverse_transcoder
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,229 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/grpc_json_reverse_transcoder/request_builder.h"
+
+#include "google/protobuf/descriptor.h"
+#include "google/protobuf/descriptor.pb.h"
+#include "google/protobuf/dynamic_message.h"
+#include "google/protobuf/text_format.h"
+#include "google/protobuf/util/message_differencer.h"
+#include "gtest/gtest.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace grpc_json_reverse_transcoder {
+namespace {
+
+constexpr char kBookstoreProto[] = R"(
+name: "bookstore.proto"
+package: "bookstore"
+syntax: "proto3"
+message_type {
+  name: "Shelf"
+  field { name: "id" number: 1 type: TYPE_INT64 label: LABEL_OPTIONAL }
+  field { name: "theme" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL }
+}
+message_type {
+  name: "GetShelfRequest"
+  field { name: "shelf" number: 1 type: TYPE_INT64 label: LABEL_OPTIONAL }
+  field { name: "view_mode" number: 2 type: TYPE_STRING label: LABEL_OPTIONAL }
+  field { name: "tags" number: 3 type: TYPE_STRING label: LABEL_REPEATED }
+  field {
+    name: "filter"
+    number: 4
+    type: TYPE_MESSAGE
+    type_name: ".bookstore.Shelf"
+    label: LABEL_OPTIONAL
+  }
+}
+message_type {
+  name: "UpdateShelfRequest"
+  field { name: "name" number: 1 type: TYPE_STRING label: LABEL_OPTIONAL }
+  field {
+    name: "shelf"
+    number: 2
+    type: TYPE_MESSAGE
+    type_name: ".bookstore.Shelf"
+    label: LABEL_OPTIONAL
+  }
+}
+)";
+
+class RequestBuilderTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    google::protobuf::FileDescriptorProto file;
+    ASSERT_TRUE(
+        google::protobuf::TextFormat::ParseFromString(kBookstoreProto, &file));
+    ASSERT_NE(pool_.BuildFile(file), nullptr);
+  }
+
+  std::unique_ptr<google::protobuf::Message> parseMessage(
+      const std::string& type, const std::string& text) {
+    const auto* descriptor = pool_.FindMessageTypeByName(type);
+    EXPECT_NE(descriptor, nullptr);
+    std::unique_ptr<google::protobuf::Message> message(
+        factory_.GetPrototype(descriptor)->New());
+    EXPECT_TRUE(
+        google::protobuf::TextFormat::ParseFromString(text, message.get()));
+    return message;
+  }
+
+  google::api::HttpRule parseRule(const std::string& text) {
+    google::api::HttpRule rule;
+    EXPECT_TRUE(google::protobuf::TextFormat::ParseFromString(text, &rule));
+    return rule;
+  }
+
+  google::protobuf::DescriptorPool pool_;
+  google::protobuf::DynamicMessageFactory factory_{&pool_};
+};
+
+TEST_F(RequestBuilderTest, PathVariablesAndQueryParams) {
+  auto message = parseMessage("bookstore.GetShelfRequest", R"(
+    shelf: 1
+    view_mode: "full view"
+    tags: "a"
+    tags: "b&c"
+    filter { theme: "art" }
+  )");
+  const auto request =
+      buildHttpRequest(parseRule(R"(get: "/v1/shelves/{shelf}")"), *message);
+  ASSERT_TRUE(request.ok()) << request.status();
+  EXPECT_EQ("GET", request->method);
+  EXPECT_EQ(
+      "/v1/shelves/1?viewMode=full%20view&tags=a&tags=b%26c&filter.theme=art",
+      request->path);
+  EXPECT_EQ("", request->body);
+}
+
+TEST_F(RequestBuilderTest, MultiSegmentPathVariable) {
+  auto message = parseMessage("bookstore.UpdateShelfRequest",
+                              R"(name: "shelves/1 2")");
+  const auto request = buildHttpRequest(
+      parseRule(R"(delete: "/v1/{name=shelves/*}:archive")"), *message);
+  ASSERT_TRUE(request.ok()) << request.status();
+  EXPECT_EQ("DELETE", request->method);
+  EXPECT_EQ("/v1/shelves/1%202:archive", request->path);
+}
+
+TEST_F(RequestBuilderTest, SingleSegmentPathVariableEscapesSlash) {
+  auto message =
+      parseMessage("bookstore.UpdateShelfRequest", R"(name: "shelves/1")");
+  const auto request =
+      buildHttpRequest(parseRule(R"(get: "/v1/{name}")"), *message);
+  ASSERT_TRUE(request.ok()) << request.status();
+  EXPECT_EQ("/v1/shelves%2F1", request->path);
+}
+
+TEST_F(RequestBuilderTest, BodyWithAllFields) {
+  auto message = parseMessage("bookstore.UpdateShelfRequest", R"(
+    name: "shelves"
+    shelf { id: 1 theme: "art" }
+  )");
+  const auto request = buildHttpRequest(
+      parseRule(R"(post: "/v1/shelves/{shelf.id}" body: "*")"), *message);
+  ASSERT_TRUE(request.ok()) << request.status();
+  EXPECT_EQ("POST", request->method);
+  EXPECT_EQ("/v1/shelves/1", request->path);
+  EXPECT_EQ(R"({"name":"shelves","shelf":{"theme":"art"}})", request->body);
+}
+
+TEST_F(RequestBuilderTest, BodyField) {
+  auto message = parseMessage("bookstore.UpdateShelfRequest", R"(
+    name: "shelves/1"
+    shelf { theme: "art" }
+  )");
+  const auto request = buildHttpRequest(
+      parseRule(R"(patch: "/v1/{name=shelves/*}" body: "shelf")"), *message);
+  ASSERT_TRUE(request.ok()) << request.status();
+  EXPECT_EQ("PATCH", request->method);
+  EXPECT_EQ("/v1/shelves/1", request->path);
+  EXPECT_EQ(R"({"theme":"art"})", request->body);
+}
+
+TEST_F(RequestBuilderTest, CustomMethod) {
+  auto message = parseMessage("bookstore.GetShelfRequest", "shelf: 1");
+  const auto request = buildHttpRequest(
+      parseRule(R"(custom { kind: "HEAD" path: "/v1/shelves/{shelf}" })"),
+      *message);
+  ASSERT_TRUE(request.ok()) << request.status();
+  EXPECT_EQ("HEAD", request->method);
+  EXPECT_EQ("/v1/shelves/1", request->path);
+}
+
+TEST_F(RequestBuilderTest, Errors) {
+  auto message = parseMessage("bookstore.UpdateShelfRequest", "");
+  EXPECT_EQ(absl::StatusCode::kInvalidArgument,
+            buildHttpRequest(parseRule(R"(get: "/v1/{unknown}")"), *message)
+                .status()
+                .code());
+  EXPECT_EQ(absl::StatusCode::kUnimplemented,
+            buildHttpRequest(parseRule(R"(get: "/v1/{shelf}")"), *message)
+                .status()
+                .code());
+  EXPECT_EQ(absl::StatusCode::kUnimplemented,
+            buildHttpRequest(parseRule(R"(post: "/v1" body: "name")"), *message)
+                .status()
+                .code());
+  EXPECT_EQ(absl::StatusCode::kInvalidArgument,
+            buildHttpRequest(parseRule(""), *message).status().code());
+}
+
+TEST_F(RequestBuilderTest, ParseHttpResponse) {
+  auto message = parseMessage("bookstore.Shelf", "");
+  ASSERT_TRUE(parseHttpResponse(parseRule(R"(get: "/v1/shelves/{shelf}")"),
+                                R"({"id": "3", "theme": "art", "extra": 1})",
+                                *message)
+                  .ok());
+  EXPECT_TRUE(google::protobuf::util::MessageDifferencer::Equals(
+      *parseMessage("bookstore.Shelf", R"(id: 3 theme: "art")"), *message));
+}
+
+TEST_F(RequestBuilderTest, ParseHttpResponseBodyField) {
+  auto message = parseMessage("bookstore.UpdateShelfRequest", "");
+  ASSERT_TRUE(parseHttpResponse(parseRule(R"(response_body: "shelf")"),
+                                R"({"theme": "art"})", *message)
+                  .ok());
+  EXPECT_TRUE(google::protobuf::util::MessageDifferencer::Equals(
+      *parseMessage("bookstore.UpdateShelfRequest",
+                    R"(shelf { theme: "art" })"),
+      *message));
+}
+
+TEST_F(RequestBuilderTest, ParseHttpResponseErrors) {
+  auto message = parseMessage("bookstore.Shelf", "");
+  EXPECT_TRUE(parseHttpResponse(google::api::HttpRule(), "", *message).ok());
+  EXPECT_EQ(absl::StatusCode::kInternal,
+            parseHttpResponse(google::api::HttpRule(), "not json", *message)
+                .code());
+  EXPECT_EQ(absl::StatusCode::kInvalidArgument,
+            parseHttpResponse(parseRule(R"(response_body: "unknown")"), "{}",
+                              *message)
+                .code());
+}
+
+TEST(PercentEncodeTest, EscapesReservedCharacters) {
+  EXPECT_EQ("a-b_c.d~e", percentEncode("a-b_c.d~e", false));
+  EXPECT_EQ("a%2Fb%3F%20c%25", percentEncode("a/b? c%", false));
+  EXPECT_EQ("a/b%3F", percentEncode("a/b?", true));
+}
+
+}  // namespace
+}  // namespace grpc_json_reverse_transcoder
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -15,6 +15,11 @@
 #include "src/envoy/http/header_sanitizer/filter.h"
 
 #include <string>
+#include <vector>
+
+#include "absl/strings/ascii.h"
+#include "absl/strings/str_join.h"
+#include "absl/strings/str_split.h"
 
 #include "envoy/http/header_map.h"
 #include "source/common/http/headers.h"
@@ -31,6 +36,15 @@ using Envoy::Http::FilterDataStatus;
 using Envoy::Http::FilterHeadersStatus;
 using Envoy::Http::FilterTrailersStatus;
 using Envoy::Http::RequestHeaderMap;
+using Envoy::Http::ResponseHeaderMap;
+
+FilterConfig::FilterConfig(
+    const ::espv2::api::envoy::v12::http::header_sanitizer::FilterConfig&
+        proto_config) {
+  for (const auto& header : proto_config.internal_vary_headers()) {
+    internal_vary_headers_.insert(absl::AsciiStrToLower(header));
+  }
+}
 
 FilterHeadersStatus Filter::decodeHeaders(RequestHeaderMap& headers, bool) {
   if (utils::handleHttpMethodOverride(headers)) {
@@ -43,6 +57,45 @@ FilterHeadersStatus Filter::decodeHeaders(RequestHeaderMap& headers, bool) {
   return FilterHeadersStatus::Continue;
 }
 
+FilterHeadersStatus Filter::encodeHeaders(ResponseHeaderMap& headers, bool) {
+  if (config_->internalVaryHeaders().empty()) {
+    return FilterHeadersStatus::Continue;
+  }
+  const auto vary = headers.get(Envoy::Http::CustomHeaders::get().Vary);
+  if (vary.empty()) {
+    return FilterHeadersStatus::Continue;
+  }
+
+  std::vector<std::string> kept;
+  bool removed = false;
+  for (size_t i = 0; i < vary.size(); ++i) {
+    for (absl::string_view value :
+         absl::StrSplit(vary[i]->value().getStringView(), ',')) {
+      value = absl::StripAsciiWhitespace(value);
+      if (value.empty()) {
+        continue;
+      }
+      if (config_->internalVaryHeaders().contains(
+              absl::AsciiStrToLower(value))) {
+        removed = true;
+        continue;
+      }
+      kept.emplace_back(value);
+    }
+  }
+  if (!removed) {
+    return FilterHeadersStatus::Continue;
+  }
+
+  ENVOY_LOG(debug, "Removing internal headers from the Vary response header");
+  headers.remove(Envoy::Http::CustomHeaders::get().Vary);
+  if (!kept.empty()) {
+    headers.setCopy(Envoy::Http::CustomHeaders::get().Vary,
+                    absl::StrJoin(kept, ", "));
+  }
+  return FilterHeadersStatus::Continue;
+}
+
 }  // namespace header_sanitizer
 }  // namespace http_filters
 }  // namespace envoy
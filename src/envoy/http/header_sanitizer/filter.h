@@ -14,6 +14,11 @@
 
 #pragma once
 
+#include <memory>
+#include <string>
+
+#include "absl/container/flat_hash_set.h"
+#include "api/envoy/v12/http/header_sanitizer/config.pb.h"
 #include "envoy/http/filter.h"
 #include "envoy/http/header_map.h"
 #include "source/common/common/logger.h"
@@ -24,14 +29,39 @@ namespace envoy {
 namespace http_filters {
 namespace header_sanitizer {
 
-class Filter : public Envoy::Http::PassThroughDecoderFilter,
+class FilterConfig {
+ public:
+  explicit FilterConfig(
+      const ::espv2::api::envoy::v12::http::header_sanitizer::FilterConfig&
+          proto_config);
+
+  // The lower case names of the headers removed from the Vary response
+  // header.
+  const absl::flat_hash_set<std::string>& internalVaryHeaders() const {
+    return internal_vary_headers_;
+  }
+
+ private:
+  absl::flat_hash_set<std::string> internal_vary_headers_;
+};
+
+using FilterConfigSharedPtr = std::shared_ptr<const FilterConfig>;
+
+class Filter : public Envoy::Http::PassThroughFilter,
                public Envoy::Logger::Loggable<Envoy::Logger::Id::filter> {
  public:
-  Filter() = default;
+  explicit Filter(FilterConfigSharedPtr config) : config_(std::move(config)) {}
 
   // Envoy::Http::StreamDecoderFilter
   Envoy::Http::FilterHeadersStatus decodeHeaders(Envoy::Http::RequestHeaderMap&,
                                                  bool) override;
+
+  // Envoy::Http::StreamEncoderFilter
+  Envoy::Http::FilterHeadersStatus encodeHeaders(
+      Envoy::Http::ResponseHeaderMap&, bool) override;
+
+ private:
+  const FilterConfigSharedPtr config_;
 };
 
 }  // namespace header_sanitizer
@@ -37,12 +37,15 @@ class FilterFactory
 
  private:
   Envoy::Http::FilterFactoryCb createFilterFactoryFromProtoTyped(
-      const ::espv2::api::envoy::v12::http::header_sanitizer::FilterConfig&,
+      const ::espv2::api::envoy::v12::http::header_sanitizer::FilterConfig&
+          proto_config,
       const std::string&,
       Envoy::Server::Configuration::FactoryContext&) override {
-    return [](Envoy::Http::FilterChainFactoryCallbacks& callbacks) -> void {
-      auto filter = std::make_shared<Filter>();
-      callbacks.addStreamDecoderFilter(filter);
+    auto filter_config = std::make_shared<const FilterConfig>(proto_config);
+    return [filter_config](
+               Envoy::Http::FilterChainFactoryCallbacks& callbacks) -> void {
+      auto filter = std::make_shared<Filter>(filter_config);
+      callbacks.addStreamFilter(filter);
     };
   }
 };
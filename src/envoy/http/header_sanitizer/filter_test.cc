@@ -0,0 +1,94 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/header_sanitizer/filter.h"
+
+#include <string>
+#include <vector>
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "test/mocks/http/mocks.h"
+#include "test/test_common/utility.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace header_sanitizer {
+namespace {
+
+class HeaderSanitizerFilterTest : public ::testing::Test {
+ protected:
+  void createFilter(const std::vector<std::string>& internal_vary_headers) {
+    ::espv2::api::envoy::v12::http::header_sanitizer::FilterConfig proto;
+    for (const auto& header : internal_vary_headers) {
+      proto.add_internal_vary_headers(header);
+    }
+    filter_ =
+        std::make_unique<Filter>(std::make_shared<const FilterConfig>(proto));
+    filter_->setEncoderFilterCallbacks(mock_encoder_callbacks_);
+  }
+
+  testing::NiceMock<Envoy::Http::MockStreamEncoderFilterCallbacks>
+      mock_encoder_callbacks_;
+  std::unique_ptr<Filter> filter_;
+};
+
+TEST_F(HeaderSanitizerFilterTest, VaryUnchangedWithoutInternalHeaders) {
+  createFilter({});
+
+  Envoy::Http::TestResponseHeaderMapImpl headers{
+      {":status", "200"}, {"vary", "Accept-Encoding, x-api-key"}};
+  EXPECT_EQ(filter_->encodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_EQ(headers.get_("vary"), "Accept-Encoding, x-api-key");
+}
+
+TEST_F(HeaderSanitizerFilterTest, InternalVaryHeadersRemoved) {
+  createFilter({"Authorization", "X-Api-Key"});
+
+  // The backend Vary and the Vary appended by the route are separate entries.
+  Envoy::Http::TestResponseHeaderMapImpl headers{
+      {":status", "200"},
+      {"vary", "Accept-Encoding"},
+      {"vary", "authorization, x-api-key"}};
+  EXPECT_EQ(filter_->encodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_EQ(headers.get_("vary"), "Accept-Encoding");
+}
+
+TEST_F(HeaderSanitizerFilterTest, VaryRemovedWhenOnlyInternalHeaders) {
+  createFilter({"Authorization", "X-Api-Key"});
+
+  Envoy::Http::TestResponseHeaderMapImpl headers{
+      {":status", "200"}, {"vary", "Authorization, X-Api-Key"}};
+  EXPECT_EQ(filter_->encodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_FALSE(headers.has("vary"));
+}
+
+TEST_F(HeaderSanitizerFilterTest, NoVaryHeader) {
+  createFilter({"Authorization"});
+
+  Envoy::Http::TestResponseHeaderMapImpl headers{{":status", "200"}};
+  EXPECT_EQ(filter_->encodeHeaders(headers, true),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_FALSE(headers.has("vary"));
+}
+
+}  // namespace
+}  // namespace header_sanitizer
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
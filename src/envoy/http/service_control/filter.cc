@@ -17,7 +17,9 @@
 #include <chrono>
 
 #include "envoy/http/header_map.h"
+#include "source/common/config/metadata.h"
 #include "source/common/grpc/status.h"
+#include "source/extensions/filters/http/well_known_names.h"
 #include "src/envoy/http/service_control/handler.h"
 #include "src/envoy/utils/http_header_utils.h"
 #include "src/envoy/utils/rc_detail_utils.h"
@@ -27,6 +29,14 @@ namespace envoy {
 namespace http_filters {
 namespace service_control {
 
+namespace {
+
+// The dynamic metadata key of the jwt_authn filter that holds the failed
+// status of JWT requirements in audit only mode.
+constexpr char kJwtFailedStatusMetadataName[] = "jwt_failed_status";
+
+}  // namespace
+
 void ServiceControlFilter::onDestroy() {
   ENVOY_LOG(debug, "Called ServiceControl Filter : {}", __func__);
   if (handler_) {
@@ -62,6 +72,17 @@ Envoy::Http::FilterHeadersStatus ServiceControlFilter::decodeHeaders(
     return Envoy::Http::FilterHeadersStatus::Continue;
   }
 
+  // The jwt_authn filter only sets the failed status when the JWT requirement
+  // is audited, the request is allowed through.
+  const Envoy::ProtobufWkt::Value& jwt_failed_status =
+      Envoy::Config::Metadata::metadataValue(
+          &decoder_callbacks_->streamInfo().dynamicMetadata(),
+          Envoy::Extensions::HttpFilters::HttpFilterNames::get().JwtAuthn,
+          kJwtFailedStatusMetadataName);
+  if (&jwt_failed_status != &Envoy::ProtobufWkt::Value::default_instance()) {
+    stats_.filter_.jwt_audit_failed_.inc();
+  }
+
   handler_ = factory_.createHandler(headers, decoder_callbacks_, stats_);
   handler_->fillFilterState(*decoder_callbacks_->streamInfo().filterState());
   state_ = Calling;
@@ -36,6 +36,7 @@ namespace service_control {
   COUNTER(denied_consumer_error)         \
   COUNTER(denied_consumer_quota)         \
   COUNTER(denied_producer_error)         \
+  COUNTER(jwt_audit_failed)              \
   HISTOGRAM(request_time, Milliseconds)  \
   HISTOGRAM(backend_time, Milliseconds)  \
   HISTOGRAM(overhead_time, Milliseconds)
@@ -128,6 +128,40 @@ TEST_F(ServiceControlFilterTest, DecodeHeadersSyncOKStatus) {
   filter_->onDestroy();
 }
 
+TEST_F(ServiceControlFilterTest, DecodeHeadersCountsJwtAuditFailure) {
+  // Test: A request allowed with a failed JWT in audit only mode is counted.
+  Envoy::ProtobufWkt::Value failed_status;
+  auto& failed_status_fields =
+      *failed_status.mutable_struct_value()->mutable_fields();
+  failed_status_fields["code"].set_number_value(401);
+  failed_status_fields["message"].set_string_value("Jwt is missing");
+  (*(*mock_decoder_callbacks_.stream_info_.metadata_
+          .mutable_filter_metadata())["envoy.filters.http.jwt_authn"]
+        .mutable_fields())["jwt_failed_status"] = failed_status;
+
+  EXPECT_CALL(*mock_handler_, callCheck(_, _, _))
+      .WillOnce(Invoke([](Envoy::Http::RequestHeaderMap&, Envoy::Tracing::Span&,
+                          ServiceControlHandler::CheckDoneCallback& callback) {
+        callback.onCheckDone(OkStatus(), "");
+      }));
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(req_headers_, true));
+  EXPECT_EQ(1, stats_.filter_.jwt_audit_failed_.value());
+  EXPECT_EQ(1, stats_.filter_.allowed_.value());
+}
+
+TEST_F(ServiceControlFilterTest, DecodeHeadersWithoutJwtAuditFailure) {
+  // Test: A request without a failed JWT status is not counted.
+  EXPECT_CALL(*mock_handler_, callCheck(_, _, _))
+      .WillOnce(Invoke([](Envoy::Http::RequestHeaderMap&, Envoy::Tracing::Span&,
+                          ServiceControlHandler::CheckDoneCallback& callback) {
+        callback.onCheckDone(OkStatus(), "");
+      }));
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->decodeHeaders(req_headers_, true));
+  EXPECT_EQ(0, stats_.filter_.jwt_audit_failed_.value());
+}
+
 TEST_F(ServiceControlFilterTest, OnDestoryWithoutHandler) {
   // Test: calling filter::onDestroy() without handler
   EXPECT_CALL(mock_handler_factory_, createHandler(_, _, _)).Times(0);
@@ -0,0 +1,117 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/sse_framing/filter.h"
+
+#include "absl/strings/ascii.h"
+#include "absl/strings/match.h"
+#include "absl/strings/str_cat.h"
+#include "absl/strings/str_split.h"
+#include "source/common/buffer/buffer_impl.h"
+#include "source/common/http/headers.h"
+#include "source/common/http/utility.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace sse_framing {
+
+using Envoy::Http::FilterDataStatus;
+using Envoy::Http::FilterHeadersStatus;
+using Envoy::Http::FilterTrailersStatus;
+using Envoy::Http::ResponseHeaderMap;
+using Envoy::Http::ResponseTrailerMap;
+
+namespace {
+
+constexpr absl::string_view kEventStreamContentType = "text/event-stream";
+
+}  // namespace
+
+FilterHeadersStatus Filter::encodeHeaders(ResponseHeaderMap& headers,
+                                          bool end_stream) {
+  const auto* per_route =
+      ::Envoy::Http::Utility::resolveMostSpecificPerFilterConfig<
+          PerRouteFilterConfig>(encoder_callbacks_);
+  if (per_route == nullptr || end_stream) {
+    return FilterHeadersStatus::Continue;
+  }
+
+  // Only the JSON messages transcoded from the gRPC stream are framed; local
+  // replies and errors keep their format.
+  if (Envoy::Http::Utility::getResponseStatus(headers) != 200 ||
+      !absl::StartsWith(headers.getContentTypeValue(),
+                        Envoy::Http::Headers::get().ContentTypeValues.Json)) {
+    return FilterHeadersStatus::Continue;
+  }
+
+  ENVOY_LOG(debug, "framing the response as server-sent events");
+  config_->stats().framed_streams_.inc();
+  framing_ = true;
+  headers.setContentType(kEventStreamContentType);
+  headers.setReference(
+      Envoy::Http::CustomHeaders::get().CacheControl,
+      Envoy::Http::CustomHeaders::get().CacheControlValues.NoCache);
+  headers.removeContentLength();
+  return FilterHeadersStatus::Continue;
+}
+
+FilterDataStatus Filter::encodeData(Envoy::Buffer::Instance& data,
+                                    bool end_stream) {
+  if (framing_) {
+    frameEvents(data, end_stream);
+  }
+  return FilterDataStatus::Continue;
+}
+
+FilterTrailersStatus Filter::encodeTrailers(ResponseTrailerMap&) {
+  if (framing_ && !pending_line_.empty()) {
+    Envoy::Buffer::OwnedImpl data;
+    frameEvents(data, true);
+    encoder_callbacks_->addEncodedData(data, false);
+  }
+  return FilterTrailersStatus::Continue;
+}
+
+void Filter::frameEvents(Envoy::Buffer::Instance& data, bool flush) {
+  absl::StrAppend(&pending_line_, data.toString());
+  data.drain(data.length());
+
+  std::string events;
+  std::vector<absl::string_view> lines = absl::StrSplit(pending_line_, '\n');
+  // The last element is the incomplete line after the last newline.
+  std::string incomplete_line(lines.back());
+  lines.pop_back();
+  if (flush) {
+    lines.push_back(incomplete_line);
+    incomplete_line.clear();
+  }
+
+  for (absl::string_view line : lines) {
+    line = absl::StripTrailingAsciiWhitespace(line);
+    if (line.empty()) {
+      continue;
+    }
+    absl::StrAppend(&events, "data: ", line, "\n\n");
+    config_->stats().framed_events_.inc();
+  }
+
+  pending_line_ = std::move(incomplete_line);
+  data.add(events);
+}
+
+}  // namespace sse_framing
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,61 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <string>
+
+#include "envoy/http/filter.h"
+#include "envoy/http/header_map.h"
+#include "source/common/common/logger.h"
+#include "source/extensions/filters/http/common/pass_through_filter.h"
+#include "src/envoy/http/sse_framing/filter_config.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace sse_framing {
+
+// Frames the newline-delimited JSON responses of transcoded server-streaming
+// methods as server-sent events, so browsers can consume them with
+// EventSource. Each line, i.e. each gRPC message, becomes one "data:" event.
+// Only routes with a PerRouteFilterConfig are framed.
+class Filter : public Envoy::Http::PassThroughEncoderFilter,
+               public Envoy::Logger::Loggable<Envoy::Logger::Id::filter> {
+ public:
+  Filter(FilterConfigSharedPtr config) : config_(config) {}
+
+  // Envoy::Http::StreamEncoderFilter
+  Envoy::Http::FilterHeadersStatus encodeHeaders(
+      Envoy::Http::ResponseHeaderMap&, bool) override;
+  Envoy::Http::FilterDataStatus encodeData(Envoy::Buffer::Instance&,
+                                           bool) override;
+  Envoy::Http::FilterTrailersStatus encodeTrailers(
+      Envoy::Http::ResponseTrailerMap&) override;
+
+ private:
+  // Moves the complete lines in data to the output as events. The incomplete
+  // last line is kept until more data arrives, or flushed if flush is true.
+  void frameEvents(Envoy::Buffer::Instance& data, bool flush);
+
+  const FilterConfigSharedPtr config_;
+  bool framing_{};
+  // The incomplete line received so far.
+  std::string pending_line_;
+};
+
+}  // namespace sse_framing
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
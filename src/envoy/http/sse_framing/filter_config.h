@@ -0,0 +1,80 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <memory>
+#include <string>
+
+#include "api/envoy/v12/http/sse_framing/config.pb.h"
+#include "envoy/router/router.h"
+#include "envoy/stats/scope.h"
+#include "envoy/stats/stats_macros.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace sse_framing {
+
+// The filter name.
+constexpr const char kFilterName[] =
+    "com.google.espv2.filters.http.sse_framing";
+
+/**
+ * All stats for the SSE framing filter. @see stats_macros.h
+ */
+#define ALL_SSE_FRAMING_FILTER_STATS(COUNTER) \
+  COUNTER(framed_streams)                     \
+  COUNTER(framed_events)
+
+/**
+ * Wrapper struct for SSE framing filter stats. @see stats_macros.h
+ */
+struct FilterStats {
+  ALL_SSE_FRAMING_FILTER_STATS(GENERATE_COUNTER_STRUCT)
+};
+
+class FilterConfig {
+ public:
+  FilterConfig(
+      const ::espv2::api::envoy::v12::http::sse_framing::FilterConfig&,
+      const std::string& stats_prefix, Envoy::Stats::Scope& scope)
+      : stats_(generateStats(stats_prefix, scope)) {}
+
+  FilterStats& stats() { return stats_; }
+
+ private:
+  FilterStats generateStats(const std::string& prefix,
+                            Envoy::Stats::Scope& scope) {
+    const std::string final_prefix = prefix + "sse_framing.";
+    return {ALL_SSE_FRAMING_FILTER_STATS(
+        POOL_COUNTER_PREFIX(scope, final_prefix))};
+  }
+
+  FilterStats stats_;
+};
+
+using FilterConfigSharedPtr = std::shared_ptr<FilterConfig>;
+
+class PerRouteFilterConfig : public Envoy::Router::RouteSpecificFilterConfig {
+ public:
+  PerRouteFilterConfig(
+      const ::espv2::api::envoy::v12::http::sse_framing::PerRouteFilterConfig&) {
+  }
+};
+
+}  // namespace sse_framing
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,147 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/sse_framing/filter.h"
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "source/common/buffer/buffer_impl.h"
+#include "source/common/stats/isolated_store_impl.h"
+#include "test/mocks/http/mocks.h"
+#include "test/test_common/utility.h"
+
+using ::testing::_;
+using ::testing::Invoke;
+using ::testing::NiceMock;
+using ::testing::Return;
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace sse_framing {
+namespace {
+
+class SseFramingFilterTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    ::espv2::api::envoy::v12::http::sse_framing::FilterConfig proto;
+    config_ = std::make_shared<FilterConfig>(proto, "", *store_.rootScope());
+
+    filter_ = std::make_unique<Filter>(config_);
+    filter_->setEncoderFilterCallbacks(mock_encoder_callbacks_);
+  }
+
+  void enableForRoute() {
+    ::espv2::api::envoy::v12::http::sse_framing::PerRouteFilterConfig proto;
+    per_route_config_ = std::make_shared<PerRouteFilterConfig>(proto);
+    EXPECT_CALL(mock_encoder_callbacks_, mostSpecificPerFilterConfig())
+        .WillRepeatedly(Return(per_route_config_.get()));
+  }
+
+  Envoy::Stats::IsolatedStoreImpl store_;
+  FilterConfigSharedPtr config_;
+  std::shared_ptr<PerRouteFilterConfig> per_route_config_;
+  NiceMock<Envoy::Http::MockStreamEncoderFilterCallbacks>
+      mock_encoder_callbacks_;
+  std::unique_ptr<Filter> filter_;
+};
+
+TEST_F(SseFramingFilterTest, RouteWithoutConfigIsNotFramed) {
+  Envoy::Http::TestResponseHeaderMapImpl headers{
+      {":status", "200"}, {"content-type", "application/json"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->encodeHeaders(headers, false));
+  EXPECT_EQ("application/json", headers.getContentTypeValue());
+
+  Envoy::Buffer::OwnedImpl data("{\"id\":1}\n");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->encodeData(data, true));
+  EXPECT_EQ("{\"id\":1}\n", data.toString());
+}
+
+TEST_F(SseFramingFilterTest, ErrorResponseIsNotFramed) {
+  enableForRoute();
+  Envoy::Http::TestResponseHeaderMapImpl headers{
+      {":status", "503"}, {"content-type", "application/json"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->encodeHeaders(headers, false));
+  EXPECT_EQ("application/json", headers.getContentTypeValue());
+
+  Envoy::Buffer::OwnedImpl data("{\"code\":503}");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->encodeData(data, true));
+  EXPECT_EQ("{\"code\":503}", data.toString());
+  EXPECT_EQ(0UL, config_->stats().framed_streams_.value());
+}
+
+TEST_F(SseFramingFilterTest, FrameEachLineAsEvent) {
+  enableForRoute();
+  Envoy::Http::TestResponseHeaderMapImpl headers{
+      {":status", "200"},
+      {"content-type", "application/json"},
+      {"content-length", "20"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->encodeHeaders(headers, false));
+  EXPECT_EQ("text/event-stream", headers.getContentTypeValue());
+  EXPECT_EQ("no-cache", headers.get_("cache-control"));
+  EXPECT_FALSE(headers.has("content-length"));
+
+  // A message split across data frames is sent once it is complete.
+  Envoy::Buffer::OwnedImpl first("{\"id\":1}\n{\"id\"");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->encodeData(first, false));
+  EXPECT_EQ("data: {\"id\":1}\n\n", first.toString());
+
+  Envoy::Buffer::OwnedImpl second(":2}\n");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->encodeData(second, false));
+  EXPECT_EQ("data: {\"id\":2}\n\n", second.toString());
+
+  // The last message is flushed at the end of the stream even without a
+  // trailing newline.
+  Envoy::Buffer::OwnedImpl third("{\"id\":3}");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->encodeData(third, true));
+  EXPECT_EQ("data: {\"id\":3}\n\n", third.toString());
+
+  EXPECT_EQ(1UL, config_->stats().framed_streams_.value());
+  EXPECT_EQ(3UL, config_->stats().framed_events_.value());
+}
+
+TEST_F(SseFramingFilterTest, FlushPendingLineBeforeTrailers) {
+  enableForRoute();
+  Envoy::Http::TestResponseHeaderMapImpl headers{
+      {":status", "200"}, {"content-type", "application/json"}};
+  EXPECT_EQ(Envoy::Http::FilterHeadersStatus::Continue,
+            filter_->encodeHeaders(headers, false));
+
+  Envoy::Buffer::OwnedImpl data("{\"id\":1}");
+  EXPECT_EQ(Envoy::Http::FilterDataStatus::Continue,
+            filter_->encodeData(data, false));
+  EXPECT_EQ("", data.toString());
+
+  EXPECT_CALL(mock_encoder_callbacks_, addEncodedData(_, false))
+      .WillOnce(Invoke([](Envoy::Buffer::Instance& added, bool) {
+        EXPECT_EQ("data: {\"id\":1}\n\n", added.toString());
+      }));
+  Envoy::Http::TestResponseTrailerMapImpl trailers{{"grpc-status", "0"}};
+  EXPECT_EQ(Envoy::Http::FilterTrailersStatus::Continue,
+            filter_->encodeTrailers(trailers));
+}
+
+}  // namespace
+}  // namespace sse_framing
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
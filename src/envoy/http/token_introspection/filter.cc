@@ -0,0 +1,299 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/token_introspection/filter.h"
+
+#include <algorithm>
+#include <chrono>
+
+#include "absl/strings/match.h"
+#include "absl/strings/str_cat.h"
+#include "absl/strings/strip.h"
+#include "source/common/common/base64.h"
+#include "source/common/common/enum_to_int.h"
+#include "source/common/http/headers.h"
+#include "source/common/http/message_impl.h"
+#include "source/common/http/utility.h"
+#include "source/common/protobuf/utility.h"
+#include "src/envoy/utils/rc_detail_utils.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace token_introspection {
+
+using Envoy::Http::FilterHeadersStatus;
+using Envoy::Http::RequestHeaderMap;
+
+namespace {
+
+constexpr absl::string_view kBearerPrefix = "Bearer ";
+constexpr absl::string_view kFormUrlEncoded =
+    "application/x-www-form-urlencoded";
+
+// The members of the introspection response that are exposed.
+constexpr absl::string_view kActiveField = "active";
+constexpr absl::string_view kExpField = "exp";
+const char* const kExposedFields[] = {"active", "sub", "scope"};
+
+const Envoy::Http::LowerCaseString& wwwAuthenticateHeader() {
+  CONSTRUCT_ON_FIRST_USE(Envoy::Http::LowerCaseString, "www-authenticate");
+}
+
+// Returns the bearer token in the Authorization header, or empty.
+absl::string_view extractBearerToken(const RequestHeaderMap& headers) {
+  const auto result =
+      headers.get(Envoy::Http::CustomHeaders::get().Authorization);
+  if (result.empty()) {
+    return "";
+  }
+  absl::string_view value = result[0]->value().getStringView();
+  if (!absl::StartsWithIgnoreCase(value, kBearerPrefix)) {
+    return "";
+  }
+  value.remove_prefix(kBearerPrefix.size());
+  return absl::StripAsciiWhitespace(value);
+}
+
+}  // namespace
+
+void Filter::onDestroy() {
+  if (state_ == State::Calling && request_ != nullptr) {
+    request_->cancel();
+    request_ = nullptr;
+  }
+  state_ = State::Complete;
+}
+
+FilterHeadersStatus Filter::decodeHeaders(RequestHeaderMap& headers, bool) {
+  const auto* per_route =
+      ::Envoy::Http::Utility::resolveMostSpecificPerFilterConfig<
+          PerRouteFilterConfig>(decoder_callbacks_);
+  if (per_route == nullptr) {
+    ENVOY_LOG(debug, "no per-route config, no token introspection required");
+    return FilterHeadersStatus::Continue;
+  }
+
+  // The payload header is only set by this filter.
+  if (!config_->forwardPayloadHeader().get().empty()) {
+    headers.remove(config_->forwardPayloadHeader());
+  }
+
+  provider_ = config_->findProvider(per_route->provider_id());
+  if (provider_ == nullptr) {
+    rejectRequest(Envoy::Http::Code::InternalServerError,
+                  absl::StrCat("Unknown token introspection provider ",
+                               per_route->provider_id()),
+                  utils::generateRcDetails(
+                      utils::kRcDetailFilterTokenIntrospection,
+                      utils::kRcDetailErrorTypeWrongRouteConfig),
+                  "");
+    return FilterHeadersStatus::StopIteration;
+  }
+
+  token_ = std::string(extractBearerToken(headers));
+  if (token_.empty()) {
+    if (per_route->allow_missing()) {
+      config_->stats().allowed_without_token_.inc();
+      return FilterHeadersStatus::Continue;
+    }
+    config_->stats().denied_by_missing_token_.inc();
+    rejectRequest(Envoy::Http::Code::Unauthorized, "Access token is missing",
+                  utils::generateRcDetails(
+                      utils::kRcDetailFilterTokenIntrospection,
+                      utils::kRcDetailErrorTypeMissingAccessToken),
+                  "Bearer");
+    return FilterHeadersStatus::StopIteration;
+  }
+
+  headers_ = &headers;
+
+  Envoy::ProtobufWkt::Struct payload;
+  if (provider_->cache().lookup(token_, payload)) {
+    config_->stats().allowed_by_cache_.inc();
+    onActiveToken(payload);
+    return FilterHeadersStatus::Continue;
+  }
+
+  state_ = State::Calling;
+  initiating_call_ = true;
+  introspect();
+  initiating_call_ = false;
+
+  if (state_ == State::Complete) {
+    return rejected_ ? FilterHeadersStatus::StopIteration
+                     : FilterHeadersStatus::Continue;
+  }
+  return FilterHeadersStatus::StopAllIterationAndWatermark;
+}
+
+void Filter::introspect() {
+  const auto& uri = provider_->proto().introspection_uri();
+  const auto thread_local_cluster =
+      config_->clusterManager().getThreadLocalCluster(uri.cluster());
+  if (thread_local_cluster == nullptr) {
+    ENVOY_LOG(debug, "token introspection cluster {} is not found",
+              uri.cluster());
+    onIntrospectionFailure();
+    return;
+  }
+
+  absl::string_view host, path;
+  Envoy::Http::Utility::extractHostPathFromUri(uri.uri(), host, path);
+
+  Envoy::Http::RequestMessagePtr message =
+      std::make_unique<Envoy::Http::RequestMessageImpl>();
+  message->headers().setPath(path);
+  message->headers().setHost(host);
+  message->headers().setReferenceMethod(
+      Envoy::Http::Headers::get().MethodValues.Post);
+  message->headers().setContentType(kFormUrlEncoded);
+  message->headers().setCopy(Envoy::Http::CustomHeaders::get().Authorization,
+                             provider_->basicAuth());
+
+  const std::string body = absl::StrCat(
+      "token=",
+      Envoy::Http::Utility::PercentEncoding::urlEncodeQueryParameter(token_),
+      "&token_type_hint=access_token");
+  message->body().add(body);
+  message->headers().setContentLength(body.size());
+
+  request_ = thread_local_cluster->httpAsyncClient().send(
+      std::move(message), *this,
+      Envoy::Http::AsyncClient::RequestOptions().setTimeout(
+          std::chrono::milliseconds(
+              Envoy::DurationUtil::durationToMilliseconds(uri.timeout()))));
+}
+
+void Filter::onSuccess(const Envoy::Http::AsyncClient::Request&,
+                       Envoy::Http::ResponseMessagePtr&& response) {
+  request_ = nullptr;
+  const uint64_t status_code =
+      Envoy::Http::Utility::getResponseStatus(response->headers());
+  if (status_code != Envoy::enumToInt(Envoy::Http::Code::OK)) {
+    ENVOY_LOG(debug, "token introspection call failed with status {}",
+              status_code);
+    onIntrospectionFailure();
+    return;
+  }
+  onIntrospectionResponse(response->bodyAsString());
+}
+
+void Filter::onFailure(const Envoy::Http::AsyncClient::Request&,
+                       Envoy::Http::AsyncClient::FailureReason) {
+  request_ = nullptr;
+  onIntrospectionFailure();
+}
+
+void Filter::onIntrospectionFailure() {
+  config_->stats().denied_by_introspection_failure_.inc();
+  rejectRequest(Envoy::Http::Code::ServiceUnavailable,
+                "Failed to validate the access token",
+                utils::generateRcDetails(
+                    utils::kRcDetailFilterTokenIntrospection,
+                    utils::kRcDetailErrorTypeIntrospectionFailure),
+                "");
+}
+
+void Filter::onIntrospectionResponse(const std::string& body) {
+  Envoy::ProtobufWkt::Struct response;
+  TRY_NEEDS_AUDIT { Envoy::MessageUtil::loadFromJson(body, response); }
+  END_TRY catch (const Envoy::EnvoyException& e) {
+    ENVOY_LOG(debug, "invalid token introspection response: {}", e.what());
+    onIntrospectionFailure();
+    return;
+  }
+
+  const auto& fields = response.fields();
+  const auto active = fields.find(std::string(kActiveField));
+  if (active == fields.end() || !active->second.bool_value()) {
+    config_->stats().denied_by_inactive_token_.inc();
+    rejectRequest(Envoy::Http::Code::Unauthorized,
+                  "Access token is not active",
+                  utils::generateRcDetails(
+                      utils::kRcDetailFilterTokenIntrospection,
+                      utils::kRcDetailErrorTypeInactiveAccessToken),
+                  "Bearer error=\"invalid_token\"");
+    return;
+  }
+
+  Envoy::ProtobufWkt::Struct payload;
+  for (const char* field : kExposedFields) {
+    const auto it = fields.find(field);
+    if (it != fields.end()) {
+      (*payload.mutable_fields())[field] = it->second;
+    }
+  }
+
+  auto cache_duration = provider_->cacheDuration();
+  const auto exp = fields.find(std::string(kExpField));
+  if (exp != fields.end() && exp->second.has_number_value()) {
+    // Never cache a token beyond its expiration.
+    const auto now = std::chrono::duration_cast<std::chrono::milliseconds>(
+        config_->timeSource().systemTime().time_since_epoch());
+    const auto expires_in =
+        std::chrono::milliseconds(
+            static_cast<int64_t>(exp->second.number_value() * 1000)) -
+        now;
+    cache_duration = std::min(cache_duration, expires_in);
+  }
+  if (cache_duration.count() > 0) {
+    provider_->cache().insert(
+        token_, payload,
+        config_->timeSource().monotonicTime() + cache_duration);
+  }
+
+  config_->stats().allowed_.inc();
+  onActiveToken(payload);
+}
+
+void Filter::onActiveToken(const Envoy::ProtobufWkt::Struct& payload) {
+  decoder_callbacks_->streamInfo().setDynamicMetadata(kFilterName, payload);
+
+  if (!config_->forwardPayloadHeader().get().empty()) {
+    const std::string json =
+        Envoy::MessageUtil::getJsonStringFromMessageOrError(payload);
+    headers_->setCopy(config_->forwardPayloadHeader(),
+                      Envoy::Base64Url::encode(json.data(), json.size()));
+  }
+
+  const bool was_calling = state_ == State::Calling;
+  state_ = State::Complete;
+  if (was_calling && !initiating_call_) {
+    decoder_callbacks_->continueDecoding();
+  }
+}
+
+void Filter::rejectRequest(Envoy::Http::Code code, absl::string_view error_msg,
+                           absl::string_view details,
+                           absl::string_view www_authenticate) {
+  ENVOY_LOG(debug, "{}", error_msg);
+  state_ = State::Complete;
+  rejected_ = true;
+
+  const std::string www_authenticate_value(www_authenticate);
+  decoder_callbacks_->sendLocalReply(
+      code, error_msg,
+      [www_authenticate_value](Envoy::Http::ResponseHeaderMap& headers) {
+        if (!www_authenticate_value.empty()) {
+          headers.setCopy(wwwAuthenticateHeader(), www_authenticate_value);
+        }
+      },
+      absl::nullopt, details);
+}
+
+}  // namespace token_introspection
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,86 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <string>
+
+#include "envoy/http/async_client.h"
+#include "envoy/http/filter.h"
+#include "envoy/http/header_map.h"
+#include "source/common/common/logger.h"
+#include "source/extensions/filters/http/common/pass_through_filter.h"
+#include "src/envoy/http/token_introspection/filter_config.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace token_introspection {
+
+class Filter : public Envoy::Http::PassThroughDecoderFilter,
+               public Envoy::Http::AsyncClient::Callbacks,
+               public Envoy::Logger::Loggable<Envoy::Logger::Id::filter> {
+ public:
+  Filter(FilterConfigSharedPtr config) : config_(config) {}
+
+  // Envoy::Http::StreamFilterBase
+  void onDestroy() override;
+
+  // Envoy::Http::StreamDecoderFilter
+  Envoy::Http::FilterHeadersStatus decodeHeaders(Envoy::Http::RequestHeaderMap&,
+                                                 bool) override;
+
+  // Envoy::Http::AsyncClient::Callbacks
+  void onSuccess(const Envoy::Http::AsyncClient::Request&,
+                 Envoy::Http::ResponseMessagePtr&& response) override;
+  void onFailure(const Envoy::Http::AsyncClient::Request&,
+                 Envoy::Http::AsyncClient::FailureReason reason) override;
+  void onBeforeFinalizeUpstreamSpan(
+      Envoy::Tracing::Span&, const Envoy::Http::ResponseHeaderMap*) override {}
+
+ private:
+  enum class State { Init, Calling, Complete };
+
+  // Sends the token to the introspection endpoint of the provider.
+  void introspect();
+
+  // Rejects the request when the provider could not validate the token.
+  void onIntrospectionFailure();
+
+  // Handles the introspection response body.
+  void onIntrospectionResponse(const std::string& body);
+
+  // Exposes the payload of an active token and continues the request.
+  void onActiveToken(const Envoy::ProtobufWkt::Struct& payload);
+
+  void rejectRequest(Envoy::Http::Code code, absl::string_view error_msg,
+                     absl::string_view details,
+                     absl::string_view www_authenticate);
+
+  const FilterConfigSharedPtr config_;
+  const Provider* provider_{};
+  std::string token_;
+
+  Envoy::Http::RequestHeaderMap* headers_{};
+  Envoy::Http::AsyncClient::Request* request_{};
+  State state_{State::Init};
+  // Whether the introspection call is being sent, it may complete inline.
+  bool initiating_call_{};
+  bool rejected_{};
+};
+
+}  // namespace token_introspection
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -0,0 +1,198 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <memory>
+#include <string>
+
+#include "absl/container/flat_hash_map.h"
+#include "absl/strings/ascii.h"
+#include "absl/strings/str_cat.h"
+#include "api/envoy/v12/http/token_introspection/config.pb.h"
+#include "envoy/api/api.h"
+#include "envoy/common/exception.h"
+#include "envoy/common/time.h"
+#include "envoy/http/header_map.h"
+#include "envoy/router/router.h"
+#include "envoy/stats/scope.h"
+#include "envoy/stats/stats_macros.h"
+#include "envoy/upstream/cluster_manager.h"
+#include "source/common/common/base64.h"
+#include "source/common/protobuf/utility.h"
+#include "src/envoy/http/token_introspection/token_cache.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace token_introspection {
+
+// The filter name.
+constexpr const char kFilterName[] =
+    "com.google.espv2.filters.http.token_introspection";
+
+// The number of cached tokens per provider if the cache size is not set.
+constexpr uint32_t kDefaultCacheSize = 1000;
+
+/**
+ * All stats for the token introspection filter. @see stats_macros.h
+ */
+#define ALL_TOKEN_INTROSPECTION_FILTER_STATS(COUNTER) \
+  COUNTER(allowed)                                    \
+  COUNTER(allowed_by_cache)                           \
+  COUNTER(allowed_without_token)                      \
+  COUNTER(denied_by_missing_token)                    \
+  COUNTER(denied_by_inactive_token)                   \
+  COUNTER(denied_by_introspection_failure)
+
+/**
+ * Wrapper struct for token introspection filter stats. @see stats_macros.h
+ */
+struct FilterStats {
+  ALL_TOKEN_INTROSPECTION_FILTER_STATS(GENERATE_COUNTER_STRUCT)
+};
+
+// An introspection provider with its token cache.
+class Provider {
+ public:
+  Provider(const ::espv2::api::envoy::v12::http::token_introspection::
+               IntrospectionProvider& proto,
+           const std::string& client_secret, Envoy::TimeSource& time_source)
+      : proto_(proto),
+        basic_auth_(makeBasicAuth(proto.client_id(), client_secret)),
+        cache_duration_(std::chrono::milliseconds(
+            PROTOBUF_GET_MS_OR_DEFAULT(proto, cache_duration, 0))),
+        cache_(proto.cache_size() > 0 ? proto.cache_size() : kDefaultCacheSize,
+               time_source) {}
+
+  const ::espv2::api::envoy::v12::http::token_introspection::
+      IntrospectionProvider&
+      proto() const {
+    return proto_;
+  }
+
+  // The value of the Authorization header for the introspection call.
+  const std::string& basicAuth() const { return basic_auth_; }
+
+  // How long active tokens are cached, zero if caching is disabled.
+  std::chrono::milliseconds cacheDuration() const { return cache_duration_; }
+
+  TokenCache& cache() const { return cache_; }
+
+ private:
+  static std::string makeBasicAuth(const std::string& client_id,
+                                   const std::string& client_secret) {
+    const std::string credentials = absl::StrCat(client_id, ":", client_secret);
+    return absl::StrCat("Basic ", Envoy::Base64::encode(credentials.data(),
+                                                        credentials.size()));
+  }
+
+  const ::espv2::api::envoy::v12::http::token_introspection::
+      IntrospectionProvider proto_;
+  const std::string basic_auth_;
+  const std::chrono::milliseconds cache_duration_;
+  mutable TokenCache cache_;
+};
+
+class FilterConfig {
+ public:
+  FilterConfig(const ::espv2::api::envoy::v12::http::token_introspection::
+                   FilterConfig& proto_config,
+               const std::string& stats_prefix, Envoy::Stats::Scope& scope,
+               Envoy::Upstream::ClusterManager& cm, Envoy::Api::Api& api,
+               Envoy::TimeSource& time_source)
+      : forward_payload_header_(proto_config.forward_payload_header()),
+        stats_(generateStats(stats_prefix, scope)),
+        cm_(cm),
+        time_source_(time_source) {
+    for (const auto& provider : proto_config.providers()) {
+      providers_.emplace(
+          provider.id(),
+          std::make_unique<Provider>(
+              provider, readClientSecret(provider, api), time_source));
+    }
+  }
+
+  // Returns nullptr if there is no provider with the ID.
+  const Provider* findProvider(const std::string& id) const {
+    auto it = providers_.find(id);
+    return it == providers_.end() ? nullptr : it->second.get();
+  }
+
+  const Envoy::Http::LowerCaseString& forwardPayloadHeader() const {
+    return forward_payload_header_;
+  }
+
+  FilterStats& stats() { return stats_; }
+  Envoy::Upstream::ClusterManager& clusterManager() { return cm_; }
+  Envoy::TimeSource& timeSource() { return time_source_; }
+
+ private:
+  FilterStats generateStats(const std::string& prefix,
+                            Envoy::Stats::Scope& scope) {
+    const std::string final_prefix = prefix + "token_introspection.";
+    return {ALL_TOKEN_INTROSPECTION_FILTER_STATS(
+        POOL_COUNTER_PREFIX(scope, final_prefix))};
+  }
+
+  // Reads the client secret from `client_secret_path`, without surrounding
+  // whitespace.
+  static std::string readClientSecret(
+      const ::espv2::api::envoy::v12::http::token_introspection::
+          IntrospectionProvider& provider,
+      Envoy::Api::Api& api) {
+    const auto secret =
+        api.fileSystem().fileReadToEnd(provider.client_secret_path());
+    if (!secret.ok()) {
+      throw Envoy::EnvoyException(absl::StrCat(
+          "Failed to read the client secret of token introspection provider ",
+          provider.id(), ": ", secret.status().message()));
+    }
+    const std::string stripped(absl::StripAsciiWhitespace(*secret));
+    if (stripped.empty()) {
+      throw Envoy::EnvoyException(absl::StrCat(
+          "The client secret file of token introspection provider ",
+          provider.id(), " is empty"));
+    }
+    return stripped;
+  }
+
+  absl::flat_hash_map<std::string, std::unique_ptr<Provider>> providers_;
+  const Envoy::Http::LowerCaseString forward_payload_header_;
+  FilterStats stats_;
+  Envoy::Upstream::ClusterManager& cm_;
+  Envoy::TimeSource& time_source_;
+};
+
+using FilterConfigSharedPtr = std::shared_ptr<FilterConfig>;
+
+class PerRouteFilterConfig : public Envoy::Router::RouteSpecificFilterConfig {
+ public:
+  PerRouteFilterConfig(const ::espv2::api::envoy::v12::http::
+                           token_introspection::PerRouteFilterConfig& proto)
+      : provider_id_(proto.provider_id()),
+        allow_missing_(proto.allow_missing()) {}
+
+  const std::string& provider_id() const { return provider_id_; }
+  bool allow_missing() const { return allow_missing_; }
+
+ private:
+  const std::string provider_id_;
+  const bool allow_missing_;
+};
+
+}  // namespace token_introspection
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
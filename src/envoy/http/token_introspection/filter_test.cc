@@ -0,0 +1,327 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+#include "src/envoy/http/token_introspection/filter.h"
+
+#include "gmock/gmock.h"
+#include "gtest/gtest.h"
+#include "source/common/common/base64.h"
+#include "source/common/http/message_impl.h"
+#include "source/common/stats/isolated_store_impl.h"
+#include "test/mocks/http/mocks.h"
+#include "test/mocks/router/mocks.h"
+#include "test/mocks/upstream/mocks.h"
+#include "test/test_common/environment.h"
+#include "test/test_common/simulated_time_system.h"
+#include "test/test_common/utility.h"
+
+using ::testing::_;
+using ::testing::Invoke;
+using ::testing::NiceMock;
+using ::testing::Return;
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace token_introspection {
+namespace {
+
+constexpr char kFilterConfig[] = R"(
+providers {
+  id: "introspection"
+  introspection_uri {
+    uri: "https://auth.example.com/oauth2/introspect"
+    cluster: "token-introspection-cluster-auth.example.com:443"
+    timeout { seconds: 5 }
+  }
+  client_id: "client"
+  client_secret_path: "{{ test_tmpdir }}/introspection_secret"
+  cache_duration { seconds: 60 }
+}
+forward_payload_header: "x-endpoint-api-userinfo"
+)";
+
+const Envoy::Http::LowerCaseString kPayloadHeader{"x-endpoint-api-userinfo"};
+
+class TokenIntrospectionFilterTest : public ::testing::Test {
+ protected:
+  void SetUp() override {
+    Envoy::TestEnvironment::writeStringToFileForTest("introspection_secret",
+                                                     "secret\n");
+    ::espv2::api::envoy::v12::http::token_introspection::FilterConfig proto;
+    ASSERT_TRUE(Envoy::Protobuf::TextFormat::ParseFromString(
+        Envoy::TestEnvironment::substitute(kFilterConfig), &proto));
+    config_ = std::make_shared<FilterConfig>(proto, "", *store_.rootScope(),
+                                             cm_, *api_, time_system_);
+
+    EXPECT_CALL(cm_, getThreadLocalCluster(_))
+        .WillRepeatedly(Return(&thread_local_cluster_));
+    EXPECT_CALL(thread_local_cluster_.async_client_, send_(_, _, _))
+        .WillRepeatedly(
+            Invoke([this](Envoy::Http::RequestMessagePtr& message,
+                          Envoy::Http::AsyncClient::Callbacks& callbacks,
+                          const Envoy::Http::AsyncClient::RequestOptions&) {
+              call_count_++;
+              message_.swap(message);
+              client_callbacks_ = &callbacks;
+              return &client_request_;
+            }));
+
+    filter_ = std::make_unique<Filter>(config_);
+    filter_->setDecoderFilterCallbacks(mock_decoder_callbacks_);
+  }
+
+  void setPerRouteConfig(const std::string& provider_id, bool allow_missing) {
+    ::espv2::api::envoy::v12::http::token_introspection::PerRouteFilterConfig
+        proto;
+    proto.set_provider_id(provider_id);
+    proto.set_allow_missing(allow_missing);
+    per_route_ = std::make_shared<PerRouteFilterConfig>(proto);
+    EXPECT_CALL(mock_decoder_callbacks_, mostSpecificPerFilterConfig())
+        .WillRepeatedly(Return(per_route_.get()));
+  }
+
+  void respond(const std::string& status, const std::string& body) {
+    auto response = std::make_unique<Envoy::Http::ResponseMessageImpl>(
+        Envoy::Http::ResponseHeaderMapPtr{
+            new Envoy::Http::TestResponseHeaderMapImpl{{":status", status}}});
+    response->body().add(body);
+    client_callbacks_->onSuccess(client_request_, std::move(response));
+  }
+
+  uint64_t counter(const std::string& name) {
+    const auto counter = Envoy::TestUtility::findCounter(
+        store_, absl::StrCat("token_introspection.", name));
+    return counter == nullptr ? 0 : counter->value();
+  }
+
+  Envoy::Stats::IsolatedStoreImpl store_;
+  Envoy::Api::ApiPtr api_ = Envoy::Api::createApiForTest();
+  Envoy::Event::SimulatedTimeSystem time_system_;
+  NiceMock<Envoy::Upstream::MockClusterManager> cm_;
+  NiceMock<Envoy::Upstream::MockThreadLocalCluster> thread_local_cluster_;
+  NiceMock<Envoy::Http::MockAsyncClientRequest> client_request_{
+      &thread_local_cluster_.async_client_};
+  NiceMock<Envoy::Http::MockStreamDecoderFilterCallbacks>
+      mock_decoder_callbacks_;
+
+  FilterConfigSharedPtr config_;
+  std::shared_ptr<PerRouteFilterConfig> per_route_;
+  std::unique_ptr<Filter> filter_;
+
+  Envoy::Http::RequestMessagePtr message_;
+  Envoy::Http::AsyncClient::Callbacks* client_callbacks_{};
+  int call_count_ = 0;
+};
+
+TEST_F(TokenIntrospectionFilterTest, NoPerRouteConfigAllowed) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/books/1"}};
+  EXPECT_CALL(mock_decoder_callbacks_, mostSpecificPerFilterConfig())
+      .WillRepeatedly(Return(nullptr));
+
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_EQ(call_count_, 0);
+}
+
+TEST_F(TokenIntrospectionFilterTest, UnknownProviderRejected) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/books/1"}};
+  setPerRouteConfig("unknown", false);
+
+  EXPECT_CALL(mock_decoder_callbacks_,
+              sendLocalReply(Envoy::Http::Code::InternalServerError, _, _, _,
+                             "token_introspection_wrong_route_config"));
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::StopIteration);
+}
+
+TEST_F(TokenIntrospectionFilterTest, MissingTokenRejected) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/books/1"}};
+  setPerRouteConfig("introspection", false);
+
+  EXPECT_CALL(mock_decoder_callbacks_,
+              sendLocalReply(Envoy::Http::Code::Unauthorized,
+                             "Access token is missing", _, _,
+                             "token_introspection_missing_access_token"));
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::StopIteration);
+  EXPECT_EQ(counter("denied_by_missing_token"), 1);
+}
+
+TEST_F(TokenIntrospectionFilterTest, MissingTokenAllowed) {
+  Envoy::Http::TestRequestHeaderMapImpl headers{{":method", "GET"},
+                                                {":path", "/books/1"}};
+  setPerRouteConfig("introspection", true);
+
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_EQ(call_count_, 0);
+  EXPECT_EQ(counter("allowed_without_token"), 1);
+}
+
+TEST_F(TokenIntrospectionFilterTest, ActiveTokenAllowedAndCached) {
+  setPerRouteConfig("introspection", false);
+
+  Envoy::Http::TestRequestHeaderMapImpl headers{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"authorization", "Bearer opaque-token"},
+      {"x-endpoint-api-userinfo", "spoofed"}};
+  EXPECT_EQ(filter_->decodeHeaders(headers, false),
+            Envoy::Http::FilterHeadersStatus::StopAllIterationAndWatermark);
+  ASSERT_EQ(call_count_, 1);
+  EXPECT_FALSE(headers.has(kPayloadHeader));
+
+  // The introspection request.
+  EXPECT_EQ(message_->headers().getMethodValue(), "POST");
+  EXPECT_EQ(message_->headers().getHostValue(), "auth.example.com");
+  EXPECT_EQ(message_->headers().getPathValue(), "/oauth2/introspect");
+  EXPECT_EQ(message_->headers().getContentTypeValue(),
+            "application/x-www-form-urlencoded");
+  // The secret is read from the file, without the trailing newline.
+  EXPECT_EQ(message_->headers()
+                .get(Envoy::Http::CustomHeaders::get().Authorization)[0]
+                ->value()
+                .getStringView(),
+            absl::StrCat("Basic ", Envoy::Base64::encode("client:secret", 13)));
+  EXPECT_EQ(message_->bodyAsString(),
+            "token=opaque-token&token_type_hint=access_token");
+
+  EXPECT_CALL(mock_decoder_callbacks_, continueDecoding());
+  respond("200",
+          R"({"active": true, "sub": "alice", "scope": "read", "aud": "x"})");
+
+  const std::string payload = Envoy::Base64Url::decode(
+      std::string(headers.get(kPayloadHeader)[0]->value().getStringView()));
+  EXPECT_EQ(payload, R"({"active":true,"scope":"read","sub":"alice"})");
+  EXPECT_EQ(counter("allowed"), 1);
+
+  // The second request with the same token is served from the cache.
+  Filter filter(config_);
+  filter.setDecoderFilterCallbacks(mock_decoder_callbacks_);
+  Envoy::Http::TestRequestHeaderMapImpl headers2{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"authorization", "Bearer opaque-token"}};
+  EXPECT_EQ(filter.decodeHeaders(headers2, false),
+            Envoy::Http::FilterHeadersStatus::Continue);
+  EXPECT_EQ(call_count_, 1);
+  EXPECT_TRUE(headers2.has(kPayloadHeader));
+  EXPECT_EQ(counter("allowed_by_cache"), 1);
+}
+
+TEST_F(TokenIntrospectionFilterTest, ExpiredTokenNotCached) {
+  setPerRouteConfig("introspection", false);
+
+  Envoy::Http::TestRequestHeaderMapImpl headers{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"authorization", "Bearer opaque-token"}};
+  filter_->decodeHeaders(headers, false);
+  respond("200", absl::StrCat(R"({"active": true, "exp": )",
+                              std::chrono::duration_cast<std::chrono::seconds>(
+                                  time_system_.systemTime().time_since_epoch())
+                                  .count(),
+                              "}"));
+
+  Filter filter(config_);
+  filter.setDecoderFilterCallbacks(mock_decoder_callbacks_);
+  Envoy::Http::TestRequestHeaderMapImpl headers2{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"authorization", "Bearer opaque-token"}};
+  filter.decodeHeaders(headers2, false);
+  EXPECT_EQ(call_count_, 2);
+}
+
+TEST_F(TokenIntrospectionFilterTest, InactiveTokenRejected) {
+  setPerRouteConfig("introspection", false);
+
+  Envoy::Http::TestRequestHeaderMapImpl headers{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"authorization", "Bearer opaque-token"}};
+  filter_->decodeHeaders(headers, false);
+
+  EXPECT_CALL(mock_decoder_callbacks_,
+              sendLocalReply(Envoy::Http::Code::Unauthorized,
+                             "Access token is not active", _, _,
+                             "token_introspection_inactive_access_token"))
+      .WillOnce(Invoke([](Envoy::Http::Code, absl::string_view,
+                          std::function<void(
+                              Envoy::Http::ResponseHeaderMap & headers)>
+                              modify_headers,
+                          const absl::optional<Envoy::Grpc::Status::GrpcStatus>,
+                          absl::string_view) {
+        Envoy::Http::TestResponseHeaderMapImpl response_headers;
+        modify_headers(response_headers);
+        EXPECT_EQ(response_headers.get_("www-authenticate"),
+                  "Bearer error=\"invalid_token\"");
+      }));
+  EXPECT_CALL(mock_decoder_callbacks_, continueDecoding()).Times(0);
+  respond("200", R"({"active": false})");
+  EXPECT_EQ(counter("denied_by_inactive_token"), 1);
+}
+
+TEST_F(TokenIntrospectionFilterTest, IntrospectionFailureRejected) {
+  setPerRouteConfig("introspection", false);
+
+  Envoy::Http::TestRequestHeaderMapImpl headers{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"authorization", "Bearer opaque-token"}};
+  filter_->decodeHeaders(headers, false);
+
+  EXPECT_CALL(mock_decoder_callbacks_,
+              sendLocalReply(Envoy::Http::Code::ServiceUnavailable, _, _, _,
+                             "token_introspection_introspection_failure"));
+  respond("500", "internal error");
+  EXPECT_EQ(counter("denied_by_introspection_failure"), 1);
+}
+
+TEST_F(TokenIntrospectionFilterTest, DestroyCancelsCall) {
+  setPerRouteConfig("introspection", false);
+
+  Envoy::Http::TestRequestHeaderMapImpl headers{
+      {":method", "GET"},
+      {":path", "/books/1"},
+      {"authorization", "Bearer opaque-token"}};
+  filter_->decodeHeaders(headers, false);
+
+  EXPECT_CALL(client_request_, cancel());
+  filter_->onDestroy();
+}
+
+TEST_F(TokenIntrospectionFilterTest, MissingClientSecretFileRejected) {
+  ::espv2::api::envoy::v12::http::token_introspection::FilterConfig proto;
+  ASSERT_TRUE(Envoy::Protobuf::TextFormat::ParseFromString(
+      Envoy::TestEnvironment::substitute(kFilterConfig), &proto));
+  proto.mutable_providers(0)->set_client_secret_path(
+      Envoy::TestEnvironment::temporaryPath("missing_secret"));
+  EXPECT_THROW_WITH_REGEX(
+      FilterConfig(proto, "", *store_.rootScope(), cm_, *api_, time_system_),
+      Envoy::EnvoyException,
+      "Failed to read the client secret of token introspection provider "
+      "introspection");
+}
+
+}  // namespace
+}  // namespace token_introspection
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
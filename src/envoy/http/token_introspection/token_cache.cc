@@ -0,0 +1,72 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#include "src/envoy/http/token_introspection/token_cache.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace token_introspection {
+
+bool TokenCache::lookup(const std::string& token,
+                        Envoy::ProtobufWkt::Struct& payload) {
+  absl::MutexLock lock(&mu_);
+  auto it = index_.find(token);
+  if (it == index_.end()) {
+    return false;
+  }
+
+  if (it->second->expiry <= time_source_.monotonicTime()) {
+    entries_.erase(it->second);
+    index_.erase(it);
+    return false;
+  }
+
+  entries_.splice(entries_.begin(), entries_, it->second);
+  payload = it->second->payload;
+  return true;
+}
+
+void TokenCache::insert(const std::string& token,
+                        const Envoy::ProtobufWkt::Struct& payload,
+                        Envoy::MonotonicTime expiry) {
+  if (max_size_ == 0) {
+    return;
+  }
+
+  absl::MutexLock lock(&mu_);
+  auto it = index_.find(token);
+  if (it != index_.end()) {
+    entries_.erase(it->second);
+    index_.erase(it);
+  }
+
+  entries_.push_front(Entry{token, payload, expiry});
+  index_[token] = entries_.begin();
+
+  while (entries_.size() > max_size_) {
+    index_.erase(entries_.back().token);
+    entries_.pop_back();
+  }
+}
+
+size_t TokenCache::size() {
+  absl::MutexLock lock(&mu_);
+  return entries_.size();
+}
+
+}  // namespace token_introspection
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
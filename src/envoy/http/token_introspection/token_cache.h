@@ -0,0 +1,68 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+#pragma once
+
+#include <list>
+#include <string>
+
+#include "absl/container/flat_hash_map.h"
+#include "absl/synchronization/mutex.h"
+#include "envoy/common/time.h"
+#include "source/common/protobuf/protobuf.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace token_introspection {
+
+// A size bounded LRU cache of active tokens and their introspection payload.
+// It is shared by all worker threads.
+class TokenCache {
+ public:
+  TokenCache(uint32_t max_size, Envoy::TimeSource& time_source)
+      : max_size_(max_size), time_source_(time_source) {}
+
+  // Returns true and sets `payload` if the token is cached and not expired.
+  bool lookup(const std::string& token, Envoy::ProtobufWkt::Struct& payload);
+
+  // Caches the payload of an active token until `expiry`.
+  void insert(const std::string& token,
+              const Envoy::ProtobufWkt::Struct& payload,
+              Envoy::MonotonicTime expiry);
+
+  size_t size();
+
+ private:
+  struct Entry {
+    std::string token;
+    Envoy::ProtobufWkt::Struct payload;
+    Envoy::MonotonicTime expiry;
+  };
+  using EntryList = std::list<Entry>;
+
+  const uint32_t max_size_;
+  Envoy::TimeSource& time_source_;
+
+  absl::Mutex mu_;
+  // Most recently used first.
+  EntryList entries_ ABSL_GUARDED_BY(mu_);
+  absl::flat_hash_map<std::string, EntryList::iterator> index_
+      ABSL_GUARDED_BY(mu_);
+};
+
+}  // namespace token_introspection
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
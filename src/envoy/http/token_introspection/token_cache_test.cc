@@ -0,0 +1,100 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+#include "src/envoy/http/token_introspection/token_cache.h"
+
+#include "gtest/gtest.h"
+#include "test/test_common/simulated_time_system.h"
+
+namespace espv2 {
+namespace envoy {
+namespace http_filters {
+namespace token_introspection {
+namespace {
+
+Envoy::ProtobufWkt::Struct makePayload(const std::string& sub) {
+  Envoy::ProtobufWkt::Struct payload;
+  (*payload.mutable_fields())["sub"].set_string_value(sub);
+  return payload;
+}
+
+class TokenCacheTest : public ::testing::Test {
+ protected:
+  Envoy::MonotonicTime expiryIn(std::chrono::seconds seconds) {
+    return time_system_.monotonicTime() + seconds;
+  }
+
+  Envoy::Event::SimulatedTimeSystem time_system_;
+};
+
+TEST_F(TokenCacheTest, LookupMissing) {
+  TokenCache cache(10, time_system_);
+  Envoy::ProtobufWkt::Struct payload;
+  EXPECT_FALSE(cache.lookup("token", payload));
+}
+
+TEST_F(TokenCacheTest, LookupHit) {
+  TokenCache cache(10, time_system_);
+  cache.insert("token", makePayload("alice"),
+               expiryIn(std::chrono::seconds(60)));
+
+  Envoy::ProtobufWkt::Struct payload;
+  ASSERT_TRUE(cache.lookup("token", payload));
+  EXPECT_EQ(payload.fields().at("sub").string_value(), "alice");
+}
+
+TEST_F(TokenCacheTest, ExpiredEntryIsEvicted) {
+  TokenCache cache(10, time_system_);
+  cache.insert("token", makePayload("alice"),
+               expiryIn(std::chrono::seconds(60)));
+
+  time_system_.advanceTimeWait(std::chrono::seconds(61));
+
+  Envoy::ProtobufWkt::Struct payload;
+  EXPECT_FALSE(cache.lookup("token", payload));
+  EXPECT_EQ(cache.size(), 0);
+}
+
+TEST_F(TokenCacheTest, LeastRecentlyUsedIsEvicted) {
+  TokenCache cache(2, time_system_);
+  cache.insert("token-1", makePayload("1"), expiryIn(std::chrono::seconds(60)));
+  cache.insert("token-2", makePayload("2"), expiryIn(std::chrono::seconds(60)));
+
+  // Makes token-2 the least recently used.
+  Envoy::ProtobufWkt::Struct payload;
+  ASSERT_TRUE(cache.lookup("token-1", payload));
+
+  cache.insert("token-3", makePayload("3"), expiryIn(std::chrono::seconds(60)));
+
+  EXPECT_EQ(cache.size(), 2);
+  EXPECT_TRUE(cache.lookup("token-1", payload));
+  EXPECT_FALSE(cache.lookup("token-2", payload));
+  EXPECT_TRUE(cache.lookup("token-3", payload));
+}
+
+TEST_F(TokenCacheTest, ZeroSizeDisablesCache) {
+  TokenCache cache(0, time_system_);
+  cache.insert("token", makePayload("alice"),
+               expiryIn(std::chrono::seconds(60)));
+
+  Envoy::ProtobufWkt::Struct payload;
+  EXPECT_FALSE(cache.lookup("token", payload));
+}
+
+}  // namespace
+}  // namespace token_introspection
+}  // namespace http_filters
+}  // namespace envoy
+}  // namespace espv2
@@ -25,6 +25,9 @@ namespace utils {
 const char kRcDetailFilterServiceControl[] = "service_control";
 const char kRcDetailFilterBackendAuth[] = "backend_auth";
 const char kRcDetailFilterPathRewrite[] = "path_rewrite";
+const char kRcDetailFilterTokenIntrospection[] = "token_introspection";
+const char kRcDetailFilterApiKey[] = "api_key";
+const char kRcDetailFilterBodySizeLimit[] = "body_size_limit";
 
 // The error types
 //
@@ -41,12 +44,26 @@ const char kRcDetailErrorTypeMissingBackendToken[] = "missing_backend_token";
 // The ones specific to the path rewrite filter
 const char kRcDetailErrorTypeWrongRouteConfig[] = "wrong_route_config";
 
+// Token introspection error types.
+const char kRcDetailErrorTypeMissingAccessToken[] = "missing_access_token";
+const char kRcDetailErrorTypeInactiveAccessToken[] = "inactive_access_token";
+const char kRcDetailErrorTypeIntrospectionFailure[] = "introspection_failure";
+
+// API key error types.
+const char kRcDetailErrorTypeInvalidApiKey[] = "invalid_api_key";
+const char kRcDetailErrorTypeApiKeyBlocked[] = "api_key_blocked";
+
+// Body size limit error types.
+const char kRcDetailErrorTypePayloadTooLarge[] = "payload_too_large";
+
 // The detailed errors.
 const char kRcDetailErrorMissingApiKey[] = "MISSING_API_KEY";
 const char kRcDetailErrorMissingMethod[] = "MISSING_METHOD";
 const char kRcDetailErrorMissingPath[] = "MISSING_PATH";
 const char kRcDetailErrorOversizePath[] = "OVERSIZE_PATH";
 const char kRcDetailErrorFragmentIdentifier[] = "PATH_WITH_FRAGMENT_IDENTIFIER";
+const char kRcDetailErrorContentLengthTooLarge[] = "CONTENT_LENGTH_TOO_LARGE";
+const char kRcDetailErrorBodyTooLarge[] = "BODY_TOO_LARGE";
 
 // Generate a string for response code details in format of
 // `filter_name`_`error_type`_{`error_detail`}.
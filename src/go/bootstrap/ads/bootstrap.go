@@ -43,6 +43,9 @@ func CreateBootstrapConfig(opts options.AdsBootstrapperOptions) (string, error)
 		// layer runtime
 		LayeredRuntime: bt.CreateLayeredRuntime(),
 
+		// stats tags
+		StatsConfig: bt.CreateStatsConfig(opts.CommonOptions),
+
 		// Dynamic resource
 		DynamicResources: &bootstrappb.Bootstrap_DynamicResources{
 			LdsConfig: &corepb.ConfigSource{
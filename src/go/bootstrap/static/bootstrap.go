@@ -18,12 +18,14 @@ import (
 	"fmt"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/bootstrap"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 
 	gen "github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator"
 	sc "github.com/GoogleCloudPlatform/esp-v2/src/go/configinfo"
 	bootstrappb "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	"github.com/golang/glog"
 	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
 )
 
@@ -35,6 +37,7 @@ func ServiceToBootstrapConfig(serviceConfig *confpb.Service, opts options.Config
 		Node:           bootstrap.CreateNode(opts.CommonOptions),
 		Admin:          bootstrap.CreateAdmin(opts.CommonOptions),
 		LayeredRuntime: bootstrap.CreateLayeredRuntime(),
+		StatsConfig:    bootstrap.CreateStatsConfig(opts.CommonOptions),
 	}
 
 	serviceInfo, err := sc.NewServiceInfoFromServiceConfig(serviceConfig, opts)
@@ -42,6 +45,14 @@ func ServiceToBootstrapConfig(serviceConfig *confpb.Service, opts options.Config
 		return nil, fmt.Errorf("fail to initialize ServiceInfo, %s", err)
 	}
 
+	localJwksFiles, err := clustergen.GetLocalJwksFilesFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(localJwksFiles) != 0 {
+		glog.Warningf("Local JWKS files are inlined into the static bootstrap config and are not watched, keys are only rotated when the bootstrap config is regenerated and Envoy restarts.")
+	}
+
 	clusterGensFactories := gen.GetESPv2ClusterGenFactories()
 	clusterGens, err := gen.NewClusterGeneratorsFromOPConfig(serviceConfig, opts, clusterGensFactories)
 	if err != nil {
@@ -0,0 +1,68 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	metricspb "github.com/envoyproxy/go-control-plane/envoy/config/metrics/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// OperationStatsTagName tags the route stats, e.g.
+	// "vhost.backend.route.<operation>.upstream_rq_200", with the operation.
+	OperationStatsTagName = "envoy.route"
+	// GRPCServiceStatsTagName and GRPCMethodStatsTagName tag the gRPC stats,
+	// e.g. "cluster.<cluster>.grpc.<service>.<method>.success", with the gRPC
+	// service and method. They override the default tags of the same names.
+	GRPCServiceStatsTagName = "envoy.grpc_bridge_service"
+	GRPCMethodStatsTagName  = "envoy.grpc_bridge_method"
+)
+
+// CreateStatsConfig outputs StatsConfig struct for bootstrap config. It is nil
+// unless operation stats are enabled.
+func CreateStatsConfig(opts options.CommonOptions) *metricspb.StatsConfig {
+	if !opts.EnableOperationStats {
+		return nil
+	}
+
+	return &metricspb.StatsConfig{
+		UseAllDefaultTags: wrapperspb.Bool(true),
+		StatsTags: []*metricspb.TagSpecifier{
+			{
+				// Operation names contain dots, so the operation ends right before
+				// the route stat name instead of at the next dot.
+				TagName: OperationStatsTagName,
+				TagValue: &metricspb.TagSpecifier_Regex{
+					Regex: `^vhost\.[^.]+\.route\.((.+?)\.)upstream_rq`,
+				},
+			},
+			{
+				// Cluster names may contain dots, the gRPC service names do not as
+				// the gRPC stats filter replaces them.
+				TagName: GRPCServiceStatsTagName,
+				TagValue: &metricspb.TagSpecifier_Regex{
+					Regex: `^cluster\..+?\.grpc\.(([^.]+)\.)`,
+				},
+			},
+			{
+				TagName: GRPCMethodStatsTagName,
+				TagValue: &metricspb.TagSpecifier_Regex{
+					Regex: `^cluster\..+?\.grpc\.[^.]+\.(([^.]+)\.)`,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+func TestCreateStatsConfig(t *testing.T) {
+	opts := options.DefaultCommonOptions()
+	if got := CreateStatsConfig(opts); got != nil {
+		t.Errorf("CreateStatsConfig() got %v, want nil when operation stats are disabled", got)
+	}
+
+	opts.EnableOperationStats = true
+	got := CreateStatsConfig(opts)
+	if !got.GetUseAllDefaultTags().GetValue() {
+		t.Errorf("CreateStatsConfig() got use_all_default_tags false, want true")
+	}
+
+	testData := []struct {
+		tagName  string
+		statName string
+		// The tag value and the stat name without it, empty if the tag does not
+		// match.
+		wantValue    string
+		wantStatName string
+	}{
+		{
+			tagName:      OperationStatsTagName,
+			statName:     "vhost.backend.route.bookstore.Bookstore.GetShelf.upstream_rq_200",
+			wantValue:    "bookstore.Bookstore.GetShelf",
+			wantStatName: "vhost.backend.route.upstream_rq_200",
+		},
+		{
+			tagName:  OperationStatsTagName,
+			statName: "vhost.backend.vcluster.other.upstream_rq_200",
+		},
+		{
+			tagName:      GRPCServiceStatsTagName,
+			statName:     "cluster.backend-cluster-bookstore.endpoints.cloud.goog_local.grpc.bookstore_Bookstore.GetShelf.success",
+			wantValue:    "bookstore_Bookstore",
+			wantStatName: "cluster.backend-cluster-bookstore.endpoints.cloud.goog_local.grpc.GetShelf.success",
+		},
+		{
+			tagName:      GRPCMethodStatsTagName,
+			statName:     "cluster.backend-cluster-bookstore.endpoints.cloud.goog_local.grpc.bookstore_Bookstore.GetShelf.14",
+			wantValue:    "GetShelf",
+			wantStatName: "cluster.backend-cluster-bookstore.endpoints.cloud.goog_local.grpc.bookstore_Bookstore.14",
+		},
+	}
+
+	regexByTagName := make(map[string]string)
+	for _, tag := range got.GetStatsTags() {
+		regexByTagName[tag.GetTagName()] = tag.GetRegex()
+	}
+	for _, tc := range testData {
+		t.Run(tc.statName, func(t *testing.T) {
+			re := regexp.MustCompile(regexByTagName[tc.tagName])
+			match := re.FindStringSubmatchIndex(tc.statName)
+			if match == nil {
+				if tc.wantValue != "" {
+					t.Fatalf("tag %q does not match stat %q", tc.tagName, tc.statName)
+				}
+				return
+			}
+			if tc.wantValue == "" {
+				t.Fatalf("tag %q unexpectedly matches stat %q", tc.tagName, tc.statName)
+			}
+
+			// Envoy removes the first group from the stat name, the second group is
+			// the tag value.
+			gotValue := tc.statName[match[4]:match[5]]
+			gotStatName := tc.statName[:match[2]] + tc.statName[match[3]:]
+			if gotValue != tc.wantValue || gotStatName != tc.wantStatName {
+				t.Errorf("tag %q got value %q and stat %q, want %q and %q", tc.tagName, gotValue, gotStatName, tc.wantValue, tc.wantStatName)
+			}
+		})
+	}
+}
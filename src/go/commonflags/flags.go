@@ -35,9 +35,12 @@ var (
 	Node                            = flag.String("node", defaults.Node, "envoy node id")
 	NonGCP                          = flag.Bool("non_gcp", defaults.NonGCP, `By default, the proxy tries to talk to GCP metadata server to get VM location in the first few requests. Setting this flag to true to skip this step`)
 	GeneratedHeaderPrefix           = flag.String("generated_header_prefix", defaults.GeneratedHeaderPrefix, "Set the header prefix for the generated headers. By default, it is `X-Endpoint-`")
+	TracingProvider                 = flag.String("tracing_provider", defaults.TracingOptions.Provider, `The tracer used to export spans: "opencensus" exports to Stackdriver, "opentelemetry" exports to an OTLP gRPC collector, "zipkin" exports to a Zipkin collector. The collector is set by --tracing_collector_address. The "opentelemetry" tracer only propagates traceparent and the "zipkin" tracer only propagates B3 headers, other trace contexts and span limits set by the --tracing_* flags are rejected for them.`)
+	TracingCollectorAddress         = flag.String("tracing_collector_address", defaults.TracingOptions.CollectorAddress, `The address of the trace collector for the "opentelemetry" and "zipkin" tracing providers, e.g. "http://otel-collector:4317" or "http://zipkin:9411/api/v2/spans". If the scheme is missing, https is used. For "zipkin", the path defaults to /api/v2/spans.`)
 	TracingProjectId                = flag.String("tracing_project_id", defaults.TracingOptions.ProjectId, "The Google project id required for Stack driver tracing. If not set, will automatically use fetch it from GCP Metadata server")
 	TracingStackdriverAddress       = flag.String("tracing_stackdriver_address", defaults.TracingOptions.StackdriverAddress, "By default, the Stackdriver exporter will connect to production Stackdriver. If this is non-empty, it will connect to this address. It must be in the gRPC format and implement the cloud trace v2 RPCs.")
 	TracingSamplingRate             = flag.Float64("tracing_sample_rate", defaults.TracingOptions.SamplingRate, "tracing sampling rate from 0.0 to 1.0")
+	TracingOperationSampleRates     = flag.String("tracing_operation_sample_rates", defaults.TracingOptions.OperationSamplingRates, `Comma separated list of "selector=rate" pairs that override --tracing_sample_rate for individual operations, e.g. "endpoints.examples.bookstore.Bookstore.ListBooks=0,endpoints.examples.bookstore.Bookstore.CreateBook=1". Each rate must be from 0.0 to 1.0. Selectors without an operation are ignored with a warning.`)
 	TracingIncomingContext          = flag.String("tracing_incoming_context", defaults.TracingOptions.IncomingContext, "comma separated incoming trace contexts (traceparent|grpc-trace-bin|x-cloud-trace-context)")
 	TracingOutgoingContext          = flag.String("tracing_outgoing_context", defaults.TracingOptions.OutgoingContext, "comma separated outgoing trace contexts (traceparent|grpc-trace-bin|x-cloud-trace-context)")
 	TracingMaxNumAttributes         = flag.Int64("tracing_max_num_attributes", defaults.TracingOptions.MaxNumAttributes, "Sets the maximum number of attributes that each span can contain. Defaults to the maximum allowed by Stackdriver. In practice, the number of attributes published will be much less.")
@@ -63,6 +66,7 @@ var (
 	BackendAuthIamDelegates            = flag.String("backend_auth_iam_delegates", "", "The sequence of service accounts in a delegation chain used to fetch identity token for the Backend Auth from Google Cloud IAM. The multiple delegates should be separated by \",\" and the flag only applies when BackendAuthIamServiceAccount is not empty.")
 	DisallowColonInWildcardPathSegment = flag.Bool("disallow_colon_in_wildcard_path_segment", false, `Whether disallow colon in the url wildcard path segment for route match. According to Google http url template spec[1], the literal colon cannot be used in url wildcard path segment. This flag isn't enabled for backward compatibility. 
 		[1]https://github.com/googleapis/googleapis/blob/165280d3deea4d225a079eb5c34717b214a5b732/google/api/http.proto#L226-L252`)
+	EnableOperationStats = flag.Bool("enable_operation_stats", false, `Enable per-operation stats. The routes emit request counts and latencies per operation, the gRPC backends emit them per gRPC method with the gRPC status, and the stats are tagged with the operation and gRPC method names.`)
 )
 
 func DefaultCommonOptionsFromFlags() options.CommonOptions {
@@ -76,9 +80,12 @@ func DefaultCommonOptionsFromFlags() options.CommonOptions {
 		GeneratedHeaderPrefix: *GeneratedHeaderPrefix,
 		TracingOptions: &options.TracingOptions{
 			DisableTracing:           *DisableTracing,
+			Provider:                 *TracingProvider,
+			CollectorAddress:         *TracingCollectorAddress,
 			ProjectId:                *TracingProjectId,
 			StackdriverAddress:       *TracingStackdriverAddress,
 			SamplingRate:             *TracingSamplingRate,
+			OperationSamplingRates:   *TracingOperationSampleRates,
 			IncomingContext:          *TracingIncomingContext,
 			OutgoingContext:          *TracingOutgoingContext,
 			MaxNumAttributes:         *TracingMaxNumAttributes,
@@ -90,6 +97,7 @@ func DefaultCommonOptionsFromFlags() options.CommonOptions {
 		MetadataURL:                        *MetadataURL,
 		IamURL:                             *IamURL,
 		DisallowColonInWildcardPathSegment: *DisallowColonInWildcardPathSegment,
+		EnableOperationStats:               *EnableOperationStats,
 	}
 	if *BackendAuthIamServiceAccount != "" {
 		opts.BackendAuthCredentials = &options.IAMCredentialsOptions{
@@ -35,6 +35,9 @@ func GetESPv2ClusterGenFactories() []clustergen.ClusterGeneratorOPFactory {
 		clustergen.NewServiceControlClustersFromOPConfig,
 		clustergen.NewRemoteBackendClustersFromOPConfig,
 		clustergen.NewJWTProviderClustersFromOPConfig,
+		clustergen.NewTokenIntrospectionClustersFromOPConfig,
+		clustergen.NewTracingCollectorClustersFromOPConfig,
+		clustergen.NewAccessLogCollectorClustersFromOPConfig,
 	}
 }
 
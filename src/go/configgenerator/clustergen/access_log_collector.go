@@ -0,0 +1,125 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// accessLogCollectorTLSBySchemes lists the allowed schemes of the access log
+// collector addresses, and whether they use TLS.
+var accessLogCollectorTLSBySchemes = map[string]bool{
+	"http":  false,
+	"https": true,
+	"grpc":  false,
+	"grpcs": true,
+}
+
+// AccessLogCollectorCluster is an Envoy cluster to stream access logs to the
+// gRPC Access Log Service or the OpenTelemetry logs collector.
+type AccessLogCollectorCluster struct {
+	ClusterName           string
+	Address               string
+	ClusterConnectTimeout time.Duration
+
+	DNS *helpers.ClusterDNSConfiger
+	TLS *helpers.ClusterTLSConfiger
+}
+
+// NewAccessLogCollectorClustersFromOPConfig creates AccessLogCollectorClusters from
+// OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+func NewAccessLogCollectorClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	var gens []ClusterGenerator
+	for _, collector := range []struct {
+		clusterName string
+		address     string
+	}{
+		{
+			clusterName: util.AccessLogGrpcClusterName,
+			address:     opts.AccessLogGrpcAddress,
+		},
+		{
+			clusterName: util.AccessLogOpenTelemetryClusterName,
+			address:     opts.AccessLogOpenTelemetryAddress,
+		},
+	} {
+		if collector.address == "" {
+			continue
+		}
+
+		scheme, _, _, _, err := util.ParseURI(collector.address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse access log collector address %q: %v", collector.address, err)
+		}
+		if _, ok := accessLogCollectorTLSBySchemes[scheme]; !ok {
+			return nil, fmt.Errorf("access log collector address %q has invalid scheme %q, it must be one of (http|https|grpc|grpcs)", collector.address, scheme)
+		}
+
+		gens = append(gens, &AccessLogCollectorCluster{
+			ClusterName:           collector.clusterName,
+			Address:               collector.address,
+			ClusterConnectTimeout: opts.ClusterConnectTimeout,
+			DNS:                   helpers.NewClusterDNSConfigerFromOPConfig(opts),
+			TLS:                   helpers.NewClusterTLSConfigerFromOPConfig(opts, false),
+		})
+	}
+	return gens, nil
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *AccessLogCollectorCluster) GetName() string {
+	return c.ClusterName
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *AccessLogCollectorCluster) GenConfig() (*clusterpb.Cluster, error) {
+	scheme, hostname, port, _, err := util.ParseURI(c.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both collectors are gRPC services.
+	config := &clusterpb.Cluster{
+		Name:                          c.GetName(),
+		LbPolicy:                      clusterpb.Cluster_ROUND_ROBIN,
+		ConnectTimeout:                durationpb.New(c.ClusterConnectTimeout),
+		DnsLookupFamily:               clusterpb.Cluster_V4_ONLY,
+		ClusterDiscoveryType:          &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+		LoadAssignment:                util.CreateLoadAssignment(hostname, port),
+		TypedExtensionProtocolOptions: util.CreateUpstreamProtocolOptions(),
+	}
+
+	if accessLogCollectorTLSBySchemes[scheme] {
+		transportSocket, err := c.TLS.MakeTLSConfig(hostname, []string{"h2"})
+		if err != nil {
+			return nil, err
+		}
+		config.TransportSocket = transportSocket
+	}
+
+	if err := helpers.MaybeAddDNSResolver(c.DNS, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewAccessLogCollectorClustersFromOPConfig_GenConfig(t *testing.T) {
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc: "gRPC Access Log Service and OpenTelemetry collector",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLogGrpcAddress:          "grpc://als:9000",
+				AccessLogOpenTelemetryAddress: "grpcs://otel-collector:4317",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                          "access-log-grpc-cluster",
+					LbPolicy:                      clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:                durationpb.New(20 * time.Second),
+					DnsLookupFamily:               clusterpb.Cluster_V4_ONLY,
+					ClusterDiscoveryType:          &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:                util.CreateLoadAssignment("als", 9000),
+					TypedExtensionProtocolOptions: util.CreateUpstreamProtocolOptions(),
+				},
+				{
+					Name:                          "access-log-otel-cluster",
+					LbPolicy:                      clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:                durationpb.New(20 * time.Second),
+					DnsLookupFamily:               clusterpb.Cluster_V4_ONLY,
+					ClusterDiscoveryType:          &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:                util.CreateLoadAssignment("otel-collector", 4317),
+					TypedExtensionProtocolOptions: util.CreateUpstreamProtocolOptions(),
+					TransportSocket:               clustergentest.CreateDefaultTLS(t, "otel-collector", true),
+				},
+			},
+		},
+		{
+			Desc: "OpenTelemetry collector without scheme uses TLS",
+			OptsIn: options.ConfigGeneratorOptions{
+				ClusterConnectTimeout:         5 * time.Second,
+				AccessLogOpenTelemetryAddress: "otel.example.com",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                          "access-log-otel-cluster",
+					LbPolicy:                      clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:                durationpb.New(5 * time.Second),
+					DnsLookupFamily:               clusterpb.Cluster_V4_ONLY,
+					ClusterDiscoveryType:          &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:                util.CreateLoadAssignment("otel.example.com", 443),
+					TypedExtensionProtocolOptions: util.CreateUpstreamProtocolOptions(),
+					TransportSocket:               clustergentest.CreateDefaultTLS(t, "otel.example.com", true),
+				},
+			},
+		},
+		{
+			Desc:   "No cluster without remote access log sinks",
+			OptsIn: options.ConfigGeneratorOptions{},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewAccessLogCollectorClustersFromOPConfig)
+	}
+}
+
+func TestNewAccessLogCollectorClustersFromOPConfig_BadInputFactory(t *testing.T) {
+	testData := []clustergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Invalid scheme",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLogGrpcAddress: "tcp://als:9000",
+			},
+			WantFactoryError: `access log collector address "tcp://als:9000" has invalid scheme "tcp"`,
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewAccessLogCollectorClustersFromOPConfig)
+	}
+}
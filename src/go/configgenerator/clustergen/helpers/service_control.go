@@ -47,6 +47,11 @@ func ParseServiceControlURLFromOPConfig(serviceConfig *servicepb.Service, opts o
 }
 
 func getServiceControlURI(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) string {
+	// The local sink replaces the Service Control server entirely.
+	if opts.ServiceControlLocalSink != "" {
+		return util.ServiceControlLocalSinkURL(opts.ServiceControlLocalSinkPort)
+	}
+
 	// Ignore value from ServiceConfig if flag is set
 	if uri := opts.ServiceControlURL; uri != "" {
 		return uri
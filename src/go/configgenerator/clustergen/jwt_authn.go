@@ -16,6 +16,7 @@ package clustergen
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
@@ -44,10 +45,29 @@ type JWTProviderCluster struct {
 // Generates multiple clusters, one per each JWT provider address.
 // Automatically de-duplicates multiple clusters with the same remote socket address.
 func NewJWTProviderClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	localJwksFiles, err := GetLocalJwksFilesFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	introspectionProviders, err := GetTokenIntrospectionProvidersFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	var gens []ClusterGenerator
 	dedupClusterNames := make(map[string]bool)
 
 	for _, provider := range serviceConfig.GetAuthentication().GetProviders() {
+		if path, ok := localJwksFiles[provider.GetId()]; ok {
+			glog.Infof("Not adding JWKS cluster for authn provider with ID %q because its JWKS is read from local file %q.", provider.GetId(), path)
+			continue
+		}
+		if _, ok := introspectionProviders[provider.GetId()]; ok {
+			glog.Infof("Not adding JWKS cluster for authn provider with ID %q because its tokens are validated by token introspection.", provider.GetId())
+			continue
+		}
+
 		jwksURI, err := maybeGetJWKSURIByOpenID(provider, opts)
 		if err != nil {
 			return nil, err
@@ -77,6 +97,51 @@ func NewJWTProviderClustersFromOPConfig(serviceConfig *servicepb.Service, opts o
 	return gens, nil
 }
 
+// GetLocalJwksFilesFromOPConfig returns the local JWKS file path for each
+// authentication provider that does not fetch its JWKS remotely, keyed by
+// provider ID.
+//
+// A provider uses a local file if it is listed in the `--jwks_local_files`
+// option, or if its jwks_uri uses the `file://` scheme. The option takes
+// precedence.
+func GetLocalJwksFilesFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (map[string]string, error) {
+	localFiles := make(map[string]string)
+
+	providerIDs := make(map[string]bool)
+	for _, provider := range serviceConfig.GetAuthentication().GetProviders() {
+		providerIDs[provider.GetId()] = true
+		if path, ok := util.LocalFilePathFromURI(provider.GetJwksUri()); ok {
+			if path == "" {
+				return nil, fmt.Errorf("error processing authentication provider %q: jwks_uri %q has an empty file path", provider.GetId(), provider.GetJwksUri())
+			}
+			localFiles[provider.GetId()] = path
+		}
+	}
+
+	if opts.JwksLocalFiles == "" {
+		return localFiles, nil
+	}
+
+	for _, pair := range strings.Split(opts.JwksLocalFiles, ";") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("invalid local JWKS file %q, should be in the format of provider_id=path", pair)
+		}
+
+		id, path := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if !providerIDs[id] {
+			glog.Warningf("Ignoring local JWKS file %q because there is no authentication provider with ID %q.", path, id)
+			continue
+		}
+		localFiles[id] = path
+	}
+
+	return localFiles, nil
+}
+
 // maybeGetJWKSURIByOpenID will return the best option for JWKS URI, or an error
 // if it can't find one.
 func maybeGetJWKSURIByOpenID(provider *servicepb.AuthProvider, opts options.ConfigGeneratorOptions) (string, error) {
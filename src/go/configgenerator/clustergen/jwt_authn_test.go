@@ -133,6 +133,42 @@ func TestNewJWTProviderClustersFromOPConfig_GenConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Desc: "Skip providers with local JWKS files",
+			ServiceConfigIn: &confpb.Service{
+				Authentication: &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id:      "auth_provider_0",
+							Issuer:  "issuer_0",
+							JwksUri: "file:///etc/espv2/jwks.json",
+						},
+						{
+							Id:     "auth_provider_1",
+							Issuer: "issuer_1",
+						},
+						{
+							Id:      "auth_provider_2",
+							Issuer:  "issuer_2",
+							JwksUri: "http://metadata.com/pkey",
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				DisableOidcDiscovery: true,
+				JwksLocalFiles:       "auth_provider_1=/etc/espv2/jwks_1.json",
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "jwt-provider-cluster-metadata.com:80",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					DnsLookupFamily:      clusterpb.Cluster_V4_ONLY,
+					LoadAssignment:       util.CreateLoadAssignment("metadata.com", 80),
+				},
+			},
+		},
 	}
 
 	for _, tc := range testData {
@@ -174,6 +210,39 @@ func TestNewJWTProviderClustersFromOPConfig_BadInputFactory(t *testing.T) {
 			},
 			WantFactoryError: "error processing authentication provider",
 		},
+		{
+			Desc: "Malformed local JWKS files option",
+			ServiceConfigIn: &confpb.Service{
+				Authentication: &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id:      "auth_provider_0",
+							Issuer:  "issuer_0",
+							JwksUri: "https://metadata.com/pkey",
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				JwksLocalFiles: "auth_provider_0=",
+			},
+			WantFactoryError: "invalid local JWKS file",
+		},
+		{
+			Desc: "File JWKS URI without a path",
+			ServiceConfigIn: &confpb.Service{
+				Authentication: &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id:      "auth_provider_0",
+							Issuer:  "issuer_0",
+							JwksUri: "file://",
+						},
+					},
+				},
+			},
+			WantFactoryError: "has an empty file path",
+		},
 	}
 
 	for _, tc := range testData {
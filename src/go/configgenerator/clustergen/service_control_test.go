@@ -44,6 +44,23 @@ func TestNewServiceControlClusterFromOPConfig_GenConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Desc: "Local sink overrides the Service Control URL",
+			OptsIn: options.ConfigGeneratorOptions{
+				ServiceControlURL:           "https://servicecontrol.googleapis.com",
+				ServiceControlLocalSink:     "stdout",
+				ServiceControlLocalSinkPort: 8792,
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "service-control-cluster",
+					ConnectTimeout:       durationpb.New(5 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					DnsLookupFamily:      clusterpb.Cluster_V4_ONLY,
+					LoadAssignment:       util.CreateLoadAssignment("127.0.0.1", 8792),
+				},
+			},
+		},
 		{
 			Desc: "Success with https address",
 			OptsIn: options.ConfigGeneratorOptions{
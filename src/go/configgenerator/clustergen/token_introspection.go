@@ -0,0 +1,232 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// TokenIntrospectionProvider is an authentication provider whose opaque access
+// tokens are validated by an OAuth 2.0 token introspection endpoint (RFC 7662)
+// instead of being verified as JWTs.
+type TokenIntrospectionProvider struct {
+	// Endpoint is the URL of the introspection endpoint.
+	Endpoint string `json:"introspection_endpoint"`
+
+	// ClientID and ClientSecretPath are the client credentials of ESPv2. Only
+	// the path is passed to Envoy, which reads the secret from the file.
+	ClientID         string `json:"client_id"`
+	ClientSecretPath string `json:"client_secret_path"`
+
+	// CacheDurationSeconds is how long active tokens are cached. 0 disables
+	// caching.
+	CacheDurationSeconds int64 `json:"cache_duration_seconds"`
+
+	// CacheSize is the maximum number of cached tokens.
+	CacheSize uint32 `json:"cache_size"`
+}
+
+type tokenIntrospectionProvidersFile struct {
+	Providers map[string]*TokenIntrospectionProvider `json:"providers"`
+}
+
+// TokenIntrospectionCluster is an Envoy cluster to communicate with a token
+// introspection endpoint. Each cluster talks to one remote server.
+type TokenIntrospectionCluster struct {
+	ID                    string
+	Endpoint              string
+	ClusterConnectTimeout time.Duration
+
+	DNS *helpers.ClusterDNSConfiger
+	TLS *helpers.ClusterTLSConfiger
+}
+
+// NewTokenIntrospectionClustersFromOPConfig creates all TokenIntrospectionCluster
+// from OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+//
+// Generates one cluster per introspection endpoint address.
+func NewTokenIntrospectionClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	providers, err := GetTokenIntrospectionProvidersFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for id := range providers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var gens []ClusterGenerator
+	dedupClusterNames := make(map[string]bool)
+	for _, id := range ids {
+		endpoint := providers[id].Endpoint
+		addr, err := util.ExtractAddressFromURI(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract address from token introspection endpoint: %v", err)
+		}
+
+		if dedupClusterNames[addr] {
+			glog.Infof("Ignoring token introspection provider with ID %q and endpoint %q because it already has a config.", id, endpoint)
+			continue
+		}
+		dedupClusterNames[addr] = true
+
+		gens = append(gens, &TokenIntrospectionCluster{
+			ID:                    id,
+			Endpoint:              endpoint,
+			ClusterConnectTimeout: opts.ClusterConnectTimeout,
+			DNS:                   helpers.NewClusterDNSConfigerFromOPConfig(opts),
+			TLS:                   helpers.NewClusterTLSConfigerFromOPConfig(opts, false),
+		})
+	}
+	return gens, nil
+}
+
+// GetTokenIntrospectionProvidersFromOPConfig reads the file at
+// `--token_introspection_providers_path`, keyed by the ID of an authentication
+// provider in the service config.
+//
+// The file is JSON in the format of:
+//
+//	{
+//	  "providers": {
+//	    "<provider_id>": {
+//	      "introspection_endpoint": "https://idp.example.com/oauth2/introspect",
+//	      "client_id": "espv2",
+//	      "client_secret_path": "/etc/espv2/introspection_secret",
+//	      "cache_duration_seconds": 60,
+//	      "cache_size": 1000
+//	    }
+//	  }
+//	}
+func GetTokenIntrospectionProvidersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (map[string]*TokenIntrospectionProvider, error) {
+	if opts.TokenIntrospectionProvidersPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(opts.TokenIntrospectionProvidersPath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read token introspection providers file: %v", err)
+	}
+
+	var file tokenIntrospectionProvidersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("fail to parse token introspection providers file %q: %v", opts.TokenIntrospectionProvidersPath, err)
+	}
+
+	providerIDs := make(map[string]bool)
+	for _, provider := range serviceConfig.GetAuthentication().GetProviders() {
+		providerIDs[provider.GetId()] = true
+	}
+
+	providers := make(map[string]*TokenIntrospectionProvider)
+	for id, provider := range file.Providers {
+		if !providerIDs[id] {
+			glog.Warningf("Ignoring token introspection provider %q because there is no authentication provider with that ID.", id)
+			continue
+		}
+		if provider == nil {
+			return nil, fmt.Errorf("token introspection provider %q is empty", id)
+		}
+		scheme, hostname, _, _, err := util.ParseURI(provider.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid introspection_endpoint for token introspection provider %q: %v", id, err)
+		}
+		// The client secret is sent to the endpoint, only loopback endpoints may
+		// use plain text.
+		if scheme != "https" && !isLoopbackHost(hostname) {
+			return nil, fmt.Errorf("introspection_endpoint %q of token introspection provider %q must use https unless it is a loopback address", provider.Endpoint, id)
+		}
+		if provider.ClientID == "" || provider.ClientSecretPath == "" {
+			return nil, fmt.Errorf("token introspection provider %q must set client_id and client_secret_path", id)
+		}
+		if provider.CacheDurationSeconds < 0 {
+			return nil, fmt.Errorf("cache_duration_seconds of token introspection provider %q must not be negative, got %d", id, provider.CacheDurationSeconds)
+		}
+
+		secret, err := os.ReadFile(provider.ClientSecretPath)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read client secret of token introspection provider %q: %v", id, err)
+		}
+		if strings.TrimSpace(string(secret)) == "" {
+			return nil, fmt.Errorf("client secret file %q of token introspection provider %q is empty", provider.ClientSecretPath, id)
+		}
+		providers[id] = provider
+	}
+	return providers, nil
+}
+
+func isLoopbackHost(hostname string) bool {
+	if hostname == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(hostname)
+	return ip != nil && ip.IsLoopback()
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *TokenIntrospectionCluster) GetName() string {
+	return c.ID
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *TokenIntrospectionCluster) GenConfig() (*clusterpb.Cluster, error) {
+	addr, err := util.ExtractAddressFromURI(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract address from token introspection endpoint: %v", err)
+	}
+
+	scheme, hostname, port, _, err := util.ParseURI(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token introspection endpoint: %v", err)
+	}
+
+	config := &clusterpb.Cluster{
+		Name:                 util.TokenIntrospectionClusterName(addr),
+		LbPolicy:             clusterpb.Cluster_ROUND_ROBIN,
+		ConnectTimeout:       durationpb.New(c.ClusterConnectTimeout),
+		DnsLookupFamily:      clusterpb.Cluster_V4_ONLY,
+		ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+		LoadAssignment:       util.CreateLoadAssignment(hostname, port),
+	}
+	if scheme == "https" {
+		transportSocket, err := c.TLS.MakeTLSConfig(hostname, nil)
+		if err != nil {
+			return nil, err
+		}
+		config.TransportSocket = transportSocket
+	}
+
+	if err := helpers.MaybeAddDNSResolver(c.DNS, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
@@ -0,0 +1,251 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// writeTokenIntrospectionProviders writes the providers file and a client
+// secret file, `%s` in the content is replaced by the secret path.
+func writeTokenIntrospectionProviders(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secretPath, []byte("client-secret\n"), 0600); err != nil {
+		t.Fatalf("fail to write client secret file: %v", err)
+	}
+	path := filepath.Join(dir, "providers.json")
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(content, secretPath)), 0644); err != nil {
+		t.Fatalf("fail to write token introspection providers file: %v", err)
+	}
+	return path
+}
+
+func tokenIntrospectionTestServiceConfig() *confpb.Service {
+	return &confpb.Service{
+		Authentication: &confpb.Authentication{
+			Providers: []*confpb.AuthProvider{
+				{
+					Id:      "jwt_provider",
+					Issuer:  "issuer_0",
+					JwksUri: "https://metadata.com/pkey",
+				},
+				{
+					Id:     "introspection_provider_0",
+					Issuer: "https://idp.example.com",
+				},
+				{
+					Id:     "introspection_provider_1",
+					Issuer: "https://idp.example.com",
+				},
+			},
+		},
+	}
+}
+
+func TestNewTokenIntrospectionClustersFromOPConfig_GenConfig(t *testing.T) {
+	providersPath := writeTokenIntrospectionProviders(t, `{
+  "providers": {
+    "introspection_provider_0": {
+      "introspection_endpoint": "https://idp.example.com/oauth2/introspect",
+      "client_id": "espv2",
+      "client_secret_path": %[1]q
+    },
+    "introspection_provider_1": {
+      "introspection_endpoint": "https://idp.example.com/v2/introspect",
+      "client_id": "espv2",
+      "client_secret_path": %[1]q
+    },
+    "unknown_provider": {
+      "introspection_endpoint": "http://unknown.example.com/introspect"
+    }
+  }
+}`)
+
+	loopbackProvidersPath := writeTokenIntrospectionProviders(t, `{
+  "providers": {
+    "introspection_provider_0": {
+      "introspection_endpoint": "http://127.0.0.1:8090/oauth2/introspect",
+      "client_id": "espv2",
+      "client_secret_path": %q
+    }
+  }
+}`)
+
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc:            "No providers file",
+			ServiceConfigIn: tokenIntrospectionTestServiceConfig(),
+		},
+		{
+			Desc:            "De-duplicate introspection endpoints with the same address",
+			ServiceConfigIn: tokenIntrospectionTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				TokenIntrospectionProvidersPath: providersPath,
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "token-introspection-cluster-idp.example.com:443",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					DnsLookupFamily:      clusterpb.Cluster_V4_ONLY,
+					LoadAssignment:       util.CreateLoadAssignment("idp.example.com", 443),
+					TransportSocket:      clustergentest.CreateDefaultTLS(t, "idp.example.com", false),
+				},
+			},
+		},
+		{
+			Desc:            "Plain text loopback introspection endpoint",
+			ServiceConfigIn: tokenIntrospectionTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				TokenIntrospectionProvidersPath: loopbackProvidersPath,
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "token-introspection-cluster-127.0.0.1:8090",
+					ConnectTimeout:       durationpb.New(20 * time.Second),
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					DnsLookupFamily:      clusterpb.Cluster_V4_ONLY,
+					LoadAssignment:       util.CreateLoadAssignment("127.0.0.1", 8090),
+				},
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewTokenIntrospectionClustersFromOPConfig)
+	}
+}
+
+func TestNewTokenIntrospectionClustersFromOPConfig_BadInputFactory(t *testing.T) {
+	testData := []struct {
+		desc             string
+		content          string
+		wantFactoryError string
+	}{
+		{
+			desc:             "Malformed providers file",
+			content:          `{"providers": [%q]}`,
+			wantFactoryError: "fail to parse token introspection providers file",
+		},
+		{
+			desc: "Invalid introspection endpoint",
+			content: `{"providers": {"introspection_provider_0": {
+  "introspection_endpoint": "https://invalid^url:googleapis:com/test",
+  "client_id": "espv2",
+  "client_secret_path": %q
+}}}`,
+			wantFactoryError: "invalid introspection_endpoint",
+		},
+		{
+			desc: "Plain text introspection endpoint",
+			content: `{"providers": {"introspection_provider_0": {
+  "introspection_endpoint": "http://idp.example.com/oauth2/introspect",
+  "client_id": "espv2",
+  "client_secret_path": %q
+}}}`,
+			wantFactoryError: "must use https unless it is a loopback address",
+		},
+		{
+			desc: "Missing client credentials",
+			content: `{"providers": {"introspection_provider_0": {
+  "introspection_endpoint": "https://idp.example.com/oauth2/introspect",
+  "client_secret_path": %q
+}}}`,
+			wantFactoryError: "must set client_id and client_secret_path",
+		},
+		{
+			desc: "Negative cache duration",
+			content: `{"providers": {"introspection_provider_0": {
+  "introspection_endpoint": "https://idp.example.com/oauth2/introspect",
+  "client_id": "espv2",
+  "client_secret_path": %q,
+  "cache_duration_seconds": -1
+}}}`,
+			wantFactoryError: "must not be negative",
+		},
+		{
+			desc: "Missing client secret file",
+			content: `{"providers": {"introspection_provider_0": {
+  "introspection_endpoint": "https://idp.example.com/oauth2/introspect",
+  "client_id": "espv2",
+  "client_secret_path": "%s.missing"
+}}}`,
+			wantFactoryError: "fail to read client secret",
+		},
+	}
+
+	for _, tc := range testData {
+		errorTC := clustergentest.FactoryErrorOPTestCase{
+			Desc:            tc.desc,
+			ServiceConfigIn: tokenIntrospectionTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				TokenIntrospectionProvidersPath: writeTokenIntrospectionProviders(t, tc.content),
+			},
+			WantFactoryError: tc.wantFactoryError,
+		}
+		errorTC.RunTest(t, clustergen.NewTokenIntrospectionClustersFromOPConfig)
+	}
+}
+
+func TestNewJWTProviderClustersFromOPConfig_SkipTokenIntrospection(t *testing.T) {
+	providersPath := writeTokenIntrospectionProviders(t, `{
+  "providers": {
+    "introspection_provider_0": {
+      "introspection_endpoint": "https://idp.example.com/oauth2/introspect",
+      "client_id": "espv2",
+      "client_secret_path": %[1]q
+    },
+    "introspection_provider_1": {
+      "introspection_endpoint": "https://idp.example.com/oauth2/introspect",
+      "client_id": "espv2",
+      "client_secret_path": %[1]q
+    }
+  }
+}`)
+
+	tc := clustergentest.SuccessOPTestCase{
+		Desc:            "Introspection providers do not need a JWKS cluster",
+		ServiceConfigIn: tokenIntrospectionTestServiceConfig(),
+		OptsIn: options.ConfigGeneratorOptions{
+			DisableOidcDiscovery:            true,
+			TokenIntrospectionProvidersPath: providersPath,
+		},
+		WantClusters: []*clusterpb.Cluster{
+			{
+				Name:                 "jwt-provider-cluster-metadata.com:443",
+				ConnectTimeout:       durationpb.New(20 * time.Second),
+				ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+				DnsLookupFamily:      clusterpb.Cluster_V4_ONLY,
+				LoadAssignment:       util.CreateLoadAssignment("metadata.com", 443),
+				TransportSocket:      clustergentest.CreateDefaultTLS(t, "metadata.com", false),
+			},
+		},
+	}
+	tc.RunTest(t, clustergen.NewJWTProviderClustersFromOPConfig)
+}
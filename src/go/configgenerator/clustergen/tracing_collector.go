@@ -0,0 +1,101 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen
+
+import (
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/tracing"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// TracingCollectorCluster is an Envoy cluster to export spans to the
+// OpenTelemetry (OTLP gRPC) or Zipkin collector.
+type TracingCollectorCluster struct {
+	TracingOptions        options.TracingOptions
+	ClusterConnectTimeout time.Duration
+
+	DNS *helpers.ClusterDNSConfiger
+	TLS *helpers.ClusterTLSConfiger
+}
+
+// NewTracingCollectorClustersFromOPConfig creates a TracingCollectorCluster from
+// OP service config + descriptor + ESPv2 options. It is a ClusterGeneratorOPFactory.
+func NewTracingCollectorClustersFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]ClusterGenerator, error) {
+	if opts.TracingOptions == nil || !tracing.IsCollectorRequired(*opts.TracingOptions) {
+		return nil, nil
+	}
+
+	if _, _, _, _, err := tracing.ParseCollectorAddress(*opts.TracingOptions); err != nil {
+		return nil, err
+	}
+
+	return []ClusterGenerator{
+		&TracingCollectorCluster{
+			TracingOptions:        *opts.TracingOptions,
+			ClusterConnectTimeout: opts.ClusterConnectTimeout,
+			DNS:                   helpers.NewClusterDNSConfigerFromOPConfig(opts),
+			TLS:                   helpers.NewClusterTLSConfigerFromOPConfig(opts, false),
+		},
+	}, nil
+}
+
+// GetName implements the ClusterGenerator interface.
+func (c *TracingCollectorCluster) GetName() string {
+	return util.TracingCollectorClusterName
+}
+
+// GenConfig implements the ClusterGenerator interface.
+func (c *TracingCollectorCluster) GenConfig() (*clusterpb.Cluster, error) {
+	scheme, hostname, port, _, err := tracing.ParseCollectorAddress(c.TracingOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &clusterpb.Cluster{
+		Name:                 c.GetName(),
+		LbPolicy:             clusterpb.Cluster_ROUND_ROBIN,
+		ConnectTimeout:       durationpb.New(c.ClusterConnectTimeout),
+		DnsLookupFamily:      clusterpb.Cluster_V4_ONLY,
+		ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+		LoadAssignment:       util.CreateLoadAssignment(hostname, port),
+	}
+
+	// OTLP is exported over gRPC, Zipkin over HTTP/1.1.
+	var alpn []string
+	if c.TracingOptions.Provider == tracing.OpenTelemetryProvider {
+		config.TypedExtensionProtocolOptions = util.CreateUpstreamProtocolOptions()
+		alpn = []string{"h2"}
+	}
+
+	if scheme == "https" {
+		transportSocket, err := c.TLS.MakeTLSConfig(hostname, alpn)
+		if err != nil {
+			return nil, err
+		}
+		config.TransportSocket = transportSocket
+	}
+
+	if err := helpers.MaybeAddDNSResolver(c.DNS, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
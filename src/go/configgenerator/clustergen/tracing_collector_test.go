@@ -0,0 +1,146 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clustergen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen/clustergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	clusterpb "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewTracingCollectorClustersFromOPConfig_GenConfig(t *testing.T) {
+	testData := []clustergentest.SuccessOPTestCase{
+		{
+			Desc: "OpenTelemetry collector uses http2",
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						Provider:         "opentelemetry",
+						CollectorAddress: "http://otel-collector:4317",
+					},
+				},
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                          "tracing-collector-cluster",
+					LbPolicy:                      clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:                durationpb.New(20 * time.Second),
+					DnsLookupFamily:               clusterpb.Cluster_V4_ONLY,
+					ClusterDiscoveryType:          &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:                util.CreateLoadAssignment("otel-collector", 4317),
+					TypedExtensionProtocolOptions: util.CreateUpstreamProtocolOptions(),
+				},
+			},
+		},
+		{
+			Desc: "OpenTelemetry collector with TLS",
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						Provider:         "opentelemetry",
+						CollectorAddress: "otel.example.com:4317",
+					},
+				},
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                          "tracing-collector-cluster",
+					LbPolicy:                      clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:                durationpb.New(20 * time.Second),
+					DnsLookupFamily:               clusterpb.Cluster_V4_ONLY,
+					ClusterDiscoveryType:          &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:                util.CreateLoadAssignment("otel.example.com", 4317),
+					TypedExtensionProtocolOptions: util.CreateUpstreamProtocolOptions(),
+					TransportSocket:               clustergentest.CreateDefaultTLS(t, "otel.example.com", true),
+				},
+			},
+		},
+		{
+			Desc: "Zipkin collector",
+			OptsIn: options.ConfigGeneratorOptions{
+				ClusterConnectTimeout: 5 * time.Second,
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						Provider:         "zipkin",
+						CollectorAddress: "http://zipkin:9411/api/v2/spans",
+					},
+				},
+			},
+			WantClusters: []*clusterpb.Cluster{
+				{
+					Name:                 "tracing-collector-cluster",
+					LbPolicy:             clusterpb.Cluster_ROUND_ROBIN,
+					ConnectTimeout:       durationpb.New(5 * time.Second),
+					DnsLookupFamily:      clusterpb.Cluster_V4_ONLY,
+					ClusterDiscoveryType: &clusterpb.Cluster_Type{Type: clusterpb.Cluster_LOGICAL_DNS},
+					LoadAssignment:       util.CreateLoadAssignment("zipkin", 9411),
+				},
+			},
+		},
+		{
+			Desc: "No cluster for OpenCensus",
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						Provider:         "opencensus",
+						CollectorAddress: "http://otel-collector:4317",
+					},
+				},
+			},
+		},
+		{
+			Desc: "No cluster when tracing is disabled",
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing:   true,
+						Provider:         "zipkin",
+						CollectorAddress: "http://zipkin:9411",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewTracingCollectorClustersFromOPConfig)
+	}
+}
+
+func TestNewTracingCollectorClustersFromOPConfig_BadInputFactory(t *testing.T) {
+	testData := []clustergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Missing collector address",
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						Provider: "zipkin",
+					},
+				},
+			},
+			WantFactoryError: `tracing provider "zipkin" requires a collector address`,
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, clustergen.NewTracingCollectorClustersFromOPConfig)
+	}
+}
@@ -34,10 +34,25 @@ func MakeHTTPFilterGenFactories(scParams filtergen.ServiceControlOPFactoryParams
 		// filter needs to get the corresponding rule for health check in order to skip Report
 		filtergen.NewHealthCheckFilterGensFromOPConfig,
 		filtergen.NewCompressorFilterGensFromOPConfig,
+		// Decompressor filter is before all filters that read the request body.
+		filtergen.NewDecompressorFilterGensFromOPConfig,
+		// Body size limit filter is after the decompressor filter, so the
+		// decompressed body is limited.
+		filtergen.NewBodySizeLimitFilterGensFromOPConfig,
 		filtergen.NewJwtAuthnFilterGensFromOPConfig,
+		filtergen.NewTokenIntrospectionFilterGensFromOPConfig,
+		// JWT claims filter checks the payloads verified by the JWT authn filter.
+		filtergen.NewJwtClaimsFilterGensFromOPConfig,
+		filtergen.NewApiKeyFilterGensFromOPConfig,
 		func(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]filtergen.FilterGenerator, error) {
 			return filtergen.NewServiceControlFilterGensFromOPConfig(serviceConfig, opts, scParams)
 		},
+		// HTTP cache filter is after all authentication filters, so cached
+		// responses are only served to authenticated consumers.
+		filtergen.NewHTTPCacheFilterGensFromOPConfig,
+		// SSE framing filter is before the grpc transcoder filter, so it encodes
+		// the newline-delimited JSON messages written by the transcoder.
+		filtergen.NewSSEFramingFilterGensFromOPConfig,
 
 		// grpc-web filter should be before grpc transcoder filter.
 		// It converts content-type application/grpc-web to application/grpc and
@@ -45,10 +60,23 @@ func MakeHTTPFilterGenFactories(scParams filtergen.ServiceControlOPFactoryParams
 		// Otherwise grpc transcoder will try to transcode a grpc-web request which
 		// will fail.
 		filtergen.NewGRPCWebFilterGensFromOPConfig,
+		// Connect gRPC bridge filter should be before grpc transcoder filter for
+		// the same reason, it converts Connect requests to application/grpc.
+		filtergen.NewConnectGRPCBridgeFilterGensFromOPConfig,
 		filtergen.NewGRPCTranscoderFilterGensFromOPConfig,
+		// gRPC stats filter is after the grpc transcoder filter, so it also counts
+		// the transcoded, grpc-web and Connect requests.
+		filtergen.NewGRPCStatsFilterGensFromOPConfig,
+		// Reverse transcoder filter is before the backend auth and path rewrite
+		// filters, so they apply to the HTTP requests it creates.
+		filtergen.NewGRPCJSONReverseTranscoderFilterGensFromOPConfig,
 		filtergen.NewBackendAuthFilterGensFromOPConfig,
 		filtergen.NewPathRewriteFilterGensFromOPConfig,
 		filtergen.NewGRPCMetadataScrubberFilterGensFromOPConfig,
+		// Fault filter is right before the router filter, so injected faults look
+		// like backend failures to the other filters, e.g. gRPC aborts are
+		// transcoded and reported to Service Control.
+		filtergen.NewFaultFilterGensFromOPConfig,
 
 		// Add Envoy Router filter so requests are routed upstream.
 		// Router filter should be the last.
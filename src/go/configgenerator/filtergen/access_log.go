@@ -0,0 +1,305 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	acpb "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	facpb "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
+	gacpb "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	oacpb "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/open_telemetry/v3"
+	sacpb "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/stream/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	otelcommonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// AccessLogStdout is the access log path that writes to standard output
+	// instead of a file.
+	AccessLogStdout = "stdout"
+
+	// remoteAccessLogName is the log name of the gRPC and OpenTelemetry
+	// access logs.
+	remoteAccessLogName = "espv2"
+
+	// Runtime keys to override the remote access log filters.
+	accessLogMinStatusCodeRuntimeKey = "espv2.access_log.min_status_code"
+	accessLogSamplingRuntimeKey      = "espv2.access_log.sampling"
+
+	// Filter state set by the Service Control filter, see
+	// src/envoy/utils/filter_state_utils.h.
+	serviceControlApiMethodFilterState = "com.google.espv2.filters.http.service_control.api_method"
+)
+
+// DefaultAccessLogJSONFields are the fields of the JSON access log, keyed by
+// the JSON field name.
+//
+// api_consumer is set by the API key filter, so it is empty unless the local
+// API key store is enabled.
+var DefaultAccessLogJSONFields = map[string]string{
+	"start_time":             "%START_TIME%",
+	"method":                 "%REQ(:METHOD)%",
+	"path":                   "%REQ(X-ENVOY-ORIGINAL-PATH?:PATH)%",
+	"protocol":               "%PROTOCOL%",
+	"response_code":          "%RESPONSE_CODE%",
+	"response_code_details":  "%RESPONSE_CODE_DETAILS%",
+	"response_flags":         "%RESPONSE_FLAGS%",
+	"bytes_received":         "%BYTES_RECEIVED%",
+	"bytes_sent":             "%BYTES_SENT%",
+	"duration":               "%DURATION%",
+	"operation_name":         fmt.Sprintf("%%FILTER_STATE(%s:PLAIN)%%", serviceControlApiMethodFilterState),
+	"api_consumer":           fmt.Sprintf("%%DYNAMIC_METADATA(%s:consumer)%%", ApiKeyFilterName),
+	"jwt_subject":            fmt.Sprintf("%%DYNAMIC_METADATA(%s:%s:sub)%%", JWTAuthnFilterName, util.JwtPayloadMetadataName),
+	"jwt_failed_status":      fmt.Sprintf("%%DYNAMIC_METADATA(%s:%s)%%", JWTAuthnFilterName, util.JwtFailedStatusMetadataName),
+	"backend_cluster":        "%UPSTREAM_CLUSTER%",
+	"upstream_host":          "%UPSTREAM_HOST%",
+	"upstream_latency":       "%RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)%",
+	"upstream_attempt_count": "%UPSTREAM_REQUEST_ATTEMPT_COUNT%",
+	"grpc_status":            "%GRPC_STATUS%",
+	"trace_id":               "%TRACE_ID%",
+	"request_id":             "%REQ(X-REQUEST-ID)%",
+	"user_agent":             "%REQ(USER-AGENT)%",
+	"x_forwarded_for":        "%REQ(X-FORWARDED-FOR)%",
+}
+
+// MakeAccessLogJSONFormat creates the JSON access log format from the default
+// fields. addFields is a comma-separated list of `name=format` that adds or
+// overrides fields, removeFields is a comma-separated list of field names to
+// drop.
+func MakeAccessLogJSONFormat(addFields, removeFields string) (*structpb.Struct, error) {
+	fields := make(map[string]string, len(DefaultAccessLogJSONFields))
+	for name, format := range DefaultAccessLogJSONFields {
+		fields[name] = format
+	}
+
+	for _, name := range strings.Split(removeFields, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("invalid access log JSON field to remove %q, it is not a default field", name)
+		}
+		delete(fields, name)
+	}
+
+	for _, field := range strings.Split(addFields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, format, ok := strings.Cut(field, "=")
+		name = strings.TrimSpace(name)
+		format = strings.TrimSpace(format)
+		if !ok || name == "" || format == "" {
+			return nil, fmt.Errorf("invalid access log JSON field %q, it must be in the format name=format", field)
+		}
+		fields[name] = format
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("access log JSON format has no fields after removing %q", removeFields)
+	}
+
+	jsonFormat := &structpb.Struct{
+		Fields: make(map[string]*structpb.Value, len(fields)),
+	}
+	for name, format := range fields {
+		jsonFormat.Fields[name] = structpb.NewStringValue(format)
+	}
+	return jsonFormat, nil
+}
+
+// makeAccessLog creates the access log writing to the given path, or to
+// standard output if the path is "stdout".
+func makeAccessLog(path string, logFormat *corepb.SubstitutionFormatString) (*acpb.AccessLog, error) {
+	var name string
+	var accessLog proto.Message
+	if path == AccessLogStdout {
+		name = util.AccessStdoutLogger
+		stdoutAccessLog := &sacpb.StdoutAccessLog{}
+		if logFormat != nil {
+			stdoutAccessLog.AccessLogFormat = &sacpb.StdoutAccessLog_LogFormat{
+				LogFormat: logFormat,
+			}
+		}
+		accessLog = stdoutAccessLog
+	} else {
+		name = util.AccessFileLogger
+		fileAccessLog := &facpb.FileAccessLog{
+			Path: path,
+		}
+		if logFormat != nil {
+			fileAccessLog.AccessLogFormat = &facpb.FileAccessLog_LogFormat{
+				LogFormat: logFormat,
+			}
+		}
+		accessLog = fileAccessLog
+	}
+
+	serialized, err := anypb.New(accessLog)
+	if err != nil {
+		return nil, err
+	}
+
+	return &acpb.AccessLog{
+		Name: name,
+		ConfigType: &acpb.AccessLog_TypedConfig{
+			TypedConfig: serialized,
+		},
+	}, nil
+}
+
+// makeRemoteAccessLogFilter creates the filter of the remote access log sinks.
+// Only requests with a response code of at least minStatusCode are logged, and
+// of those only the samplingRate fraction. It returns nil if all requests are
+// logged.
+func makeRemoteAccessLogFilter(minStatusCode uint, samplingRate float64) (*acpb.AccessLogFilter, error) {
+	if samplingRate < 0.0 || samplingRate > 1.0 {
+		return nil, fmt.Errorf("invalid access log sampling rate: %v. It must be >= 0.0 and <= 1.0", samplingRate)
+	}
+
+	var filters []*acpb.AccessLogFilter
+	if minStatusCode > 0 {
+		filters = append(filters, &acpb.AccessLogFilter{
+			FilterSpecifier: &acpb.AccessLogFilter_StatusCodeFilter{
+				StatusCodeFilter: &acpb.StatusCodeFilter{
+					Comparison: &acpb.ComparisonFilter{
+						Op: acpb.ComparisonFilter_GE,
+						Value: &corepb.RuntimeUInt32{
+							DefaultValue: uint32(minStatusCode),
+							RuntimeKey:   accessLogMinStatusCodeRuntimeKey,
+						},
+					},
+				},
+			},
+		})
+	}
+	if samplingRate < 1.0 {
+		filters = append(filters, &acpb.AccessLogFilter{
+			FilterSpecifier: &acpb.AccessLogFilter_RuntimeFilter{
+				RuntimeFilter: &acpb.RuntimeFilter{
+					RuntimeKey: accessLogSamplingRuntimeKey,
+					PercentSampled: &typepb.FractionalPercent{
+						Numerator:   uint32(math.Round(samplingRate * 1000000)),
+						Denominator: typepb.FractionalPercent_MILLION,
+					},
+				},
+			},
+		})
+	}
+
+	switch len(filters) {
+	case 0:
+		return nil, nil
+	case 1:
+		return filters[0], nil
+	default:
+		return &acpb.AccessLogFilter{
+			FilterSpecifier: &acpb.AccessLogFilter_AndFilter{
+				AndFilter: &acpb.AndFilter{
+					Filters: filters,
+				},
+			},
+		}, nil
+	}
+}
+
+func makeGrpcAccessLogCommonConfig(clusterName string) *gacpb.CommonGrpcAccessLogConfig {
+	return &gacpb.CommonGrpcAccessLogConfig{
+		LogName: remoteAccessLogName,
+		GrpcService: &corepb.GrpcService{
+			TargetSpecifier: &corepb.GrpcService_EnvoyGrpc_{
+				EnvoyGrpc: &corepb.GrpcService_EnvoyGrpc{
+					ClusterName: clusterName,
+				},
+			},
+		},
+		TransportApiVersion: corepb.ApiVersion_V3,
+	}
+}
+
+// makeGrpcAccessLog creates the access log streaming to the gRPC Access Log
+// Service. The operation name is sent as a filter state object. The API key
+// filter state is not logged, it holds the raw API key.
+func makeGrpcAccessLog(filter *acpb.AccessLogFilter) (*acpb.AccessLog, error) {
+	commonConfig := makeGrpcAccessLogCommonConfig(util.AccessLogGrpcClusterName)
+	commonConfig.FilterStateObjectsToLog = []string{
+		serviceControlApiMethodFilterState,
+	}
+
+	serialized, err := anypb.New(&gacpb.HttpGrpcAccessLogConfig{
+		CommonConfig: commonConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &acpb.AccessLog{
+		Name:   util.AccessGrpcLogger,
+		Filter: filter,
+		ConfigType: &acpb.AccessLog_TypedConfig{
+			TypedConfig: serialized,
+		},
+	}, nil
+}
+
+// makeOpenTelemetryAccessLog creates the access log streaming to the
+// OpenTelemetry logs collector. The log record attributes are the given
+// fields, in the same format as the JSON access log.
+func makeOpenTelemetryAccessLog(fields *structpb.Struct, filter *acpb.AccessLogFilter) (*acpb.AccessLog, error) {
+	names := make([]string, 0, len(fields.GetFields()))
+	for name := range fields.GetFields() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attributes := &otelcommonpb.KeyValueList{}
+	for _, name := range names {
+		attributes.Values = append(attributes.Values, &otelcommonpb.KeyValue{
+			Key: name,
+			Value: &otelcommonpb.AnyValue{
+				Value: &otelcommonpb.AnyValue_StringValue{
+					StringValue: fields.GetFields()[name].GetStringValue(),
+				},
+			},
+		})
+	}
+
+	serialized, err := anypb.New(&oacpb.OpenTelemetryAccessLogConfig{
+		CommonConfig: makeGrpcAccessLogCommonConfig(util.AccessLogOpenTelemetryClusterName),
+		Attributes:   attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &acpb.AccessLog{
+		Name:   util.AccessOpenTelemetryLogger,
+		Filter: filter,
+		ConfigType: &acpb.AccessLog_TypedConfig{
+			TypedConfig: serialized,
+		},
+	}, nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+)
+
+func TestMakeAccessLogJSONFormat(t *testing.T) {
+	testdata := []struct {
+		desc         string
+		addFields    string
+		removeFields string
+		wantFields   map[string]string
+		wantErr      string
+	}{
+		{
+			desc:         "Add, override and remove fields",
+			addFields:    "region=%REQ(X-REGION)%, duration=%DURATION%ms",
+			removeFields: "api_consumer, user_agent",
+			wantFields: map[string]string{
+				"region":   "%REQ(X-REGION)%",
+				"duration": "%DURATION%ms",
+			},
+		},
+		{
+			desc:         "The raw API key is not a default field",
+			removeFields: "api_key",
+			wantErr:      `invalid access log JSON field to remove "api_key", it is not a default field`,
+		},
+		{
+			desc:      "Add field without format",
+			addFields: "region=",
+			wantErr:   `invalid access log JSON field "region=", it must be in the format name=format`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := filtergen.MakeAccessLogJSONFormat(tc.addFields, tc.removeFields)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("MakeAccessLogJSONFormat() got error %v, want error to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MakeAccessLogJSONFormat() got error: %v", err)
+			}
+
+			wantLen := len(filtergen.DefaultAccessLogJSONFields) + 1 - 2
+			if len(got.GetFields()) != wantLen {
+				t.Errorf("MakeAccessLogJSONFormat() got %d fields, want %d", len(got.GetFields()), wantLen)
+			}
+			for _, name := range strings.Split(tc.removeFields, ",") {
+				if _, ok := got.GetFields()[strings.TrimSpace(name)]; ok {
+					t.Errorf("MakeAccessLogJSONFormat() got removed field %q", name)
+				}
+			}
+			for name, want := range tc.wantFields {
+				if got.GetFields()[name].GetStringValue() != want {
+					t.Errorf("MakeAccessLogJSONFormat() got field %q = %q, want %q", name, got.GetFields()[name].GetStringValue(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	akpb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/api_key"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// ApiKeyFilterName is the Envoy filter name for debug logging.
+	ApiKeyFilterName = "com.google.espv2.filters.http.api_key"
+
+	// ApiKeyConsumerHeaderSuffix is the suffix of the header with the consumer
+	// label of a locally validated API key.
+	ApiKeyConsumerHeaderSuffix = "API-Consumer"
+)
+
+var apiKeySha256Regex = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// ApiKeyStoreEntry is an API key in the file at `--api_key_store_path`.
+type ApiKeyStoreEntry struct {
+	KeySha256        string   `json:"key_sha256"`
+	Consumer         string   `json:"consumer"`
+	AllowedSelectors []string `json:"allowed_selectors"`
+	AllowedReferrers []string `json:"allowed_referrers"`
+}
+
+type apiKeyStoreFile struct {
+	ApiKeys []*ApiKeyStoreEntry `json:"api_keys"`
+}
+
+type ApiKeyGenerator struct {
+	ApiKeys []*akpb.ApiKey
+
+	// PerRouteConfigBySelector is the API key requirement of each method that
+	// is not skipped by its usage rule.
+	PerRouteConfigBySelector map[string]*akpb.PerRouteFilterConfig
+
+	ConsumerHeader string
+
+	NoopFilterGenerator
+}
+
+// NewApiKeyFilterGensFromOPConfig creates a ApiKeyGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewApiKeyFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.ApiKeyStorePath == "" {
+		glog.Infof("Not adding API key filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	apiKeys, err := GetApiKeyStoreFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	usageRulesBySelector := GetUsageRulesBySelectorFromOPConfig(serviceConfig, opts)
+	apiKeySystemParamsBySelector := GetAPIKeySystemParametersBySelectorFromOPConfig(serviceConfig, opts)
+
+	perRouteConfigBySelector := make(map[string]*akpb.PerRouteFilterConfig)
+	for _, api := range serviceConfig.GetApis() {
+		for _, method := range api.GetMethods() {
+			selector := MethodToSelector(api, method)
+			if util.ShouldSkipOPDiscoveryAPI(selector, opts.AllowDiscoveryAPIs) {
+				continue
+			}
+
+			usageRule := usageRulesBySelector[selector]
+			if usageRule.GetSkipServiceControl() {
+				continue
+			}
+
+			perRoute := &akpb.PerRouteFilterConfig{
+				OperationName:      selector,
+				AllowWithoutApiKey: usageRule.GetAllowUnregisteredCalls(),
+			}
+			if apiKeySystemParams, ok := apiKeySystemParamsBySelector[selector]; ok {
+				perRoute.Locations = ExtractAPIKeyLocations(apiKeySystemParams)
+			}
+			perRouteConfigBySelector[selector] = perRoute
+		}
+	}
+
+	return []FilterGenerator{
+		&ApiKeyGenerator{
+			ApiKeys:                  apiKeys,
+			PerRouteConfigBySelector: perRouteConfigBySelector,
+			ConsumerHeader:           opts.GeneratedHeaderPrefix + ApiKeyConsumerHeaderSuffix,
+		},
+	}, nil
+}
+
+// GetApiKeyStoreFromOPConfig reads the API keys from the file at
+// `--api_key_store_path`. Only the SHA-256 digest of each key is stored.
+//
+// The file is JSON in the format of:
+//
+//	{
+//	  "api_keys": [
+//	    {
+//	      "key_sha256": "<lowercase hex SHA-256 of the key>",
+//	      "consumer": "mobile-app",
+//	      "allowed_selectors": ["endpoints.examples.bookstore.Bookstore.ListShelves"],
+//	      "allowed_referrers": ["*.example.com/*"]
+//	    }
+//	  ]
+//	}
+func GetApiKeyStoreFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]*akpb.ApiKey, error) {
+	data, err := os.ReadFile(opts.ApiKeyStorePath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read API key store file: %v", err)
+	}
+
+	var file apiKeyStoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("fail to parse API key store file %q: %v", opts.ApiKeyStorePath, err)
+	}
+
+	selectors := make(map[string]bool)
+	for _, api := range serviceConfig.GetApis() {
+		for _, method := range api.GetMethods() {
+			selectors[MethodToSelector(api, method)] = true
+		}
+	}
+
+	var apiKeys []*akpb.ApiKey
+	seen := make(map[string]bool)
+	for i, entry := range file.ApiKeys {
+		if entry == nil {
+			return nil, fmt.Errorf("API key %d in the API key store is empty", i)
+		}
+		if !apiKeySha256Regex.MatchString(entry.KeySha256) {
+			return nil, fmt.Errorf("API key %d in the API key store has an invalid key_sha256, it must be a lowercase hex encoded SHA-256 digest", i)
+		}
+		if seen[entry.KeySha256] {
+			return nil, fmt.Errorf("API key %d in the API key store is duplicated", i)
+		}
+		seen[entry.KeySha256] = true
+		if entry.Consumer == "" {
+			return nil, fmt.Errorf("API key %d in the API key store must set consumer", i)
+		}
+		for _, selector := range entry.AllowedSelectors {
+			if !selectors[selector] {
+				glog.Warningf("API key of consumer %q allows selector %q, which is not a method in the service config.", entry.Consumer, selector)
+			}
+		}
+
+		apiKeys = append(apiKeys, &akpb.ApiKey{
+			KeySha256:        entry.KeySha256,
+			Consumer:         entry.Consumer,
+			AllowedSelectors: entry.AllowedSelectors,
+			AllowedReferrers: entry.AllowedReferrers,
+		})
+	}
+	return apiKeys, nil
+}
+
+func (g *ApiKeyGenerator) FilterName() string {
+	return ApiKeyFilterName
+}
+
+func (g *ApiKeyGenerator) GenFilterConfig() (proto.Message, error) {
+	return &akpb.FilterConfig{
+		ApiKeys:        g.ApiKeys,
+		ConsumerHeader: g.ConsumerHeader,
+	}, nil
+}
+
+func (g *ApiKeyGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	perRoute, ok := g.PerRouteConfigBySelector[selector]
+	if !ok {
+		return nil, nil
+	}
+	return perRoute, nil
+}
@@ -0,0 +1,268 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+var (
+	testApiKeySha256   = strings.Repeat("a", 64)
+	testApiKeySha256_1 = strings.Repeat("b", 64)
+)
+
+func writeApiKeyStore(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "api_keys.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("fail to write API key store file: %v", err)
+	}
+	return path
+}
+
+func apiKeyTestServiceConfig() *confpb.Service {
+	return &confpb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "ListShelves",
+					},
+					{
+						Name: "GetShelf",
+					},
+					{
+						Name: "Healthz",
+					},
+				},
+			},
+		},
+		Usage: &confpb.Usage{
+			Rules: []*confpb.UsageRule{
+				{
+					Selector:               "endpoints.examples.bookstore.Bookstore.GetShelf",
+					AllowUnregisteredCalls: true,
+				},
+				{
+					Selector:           "endpoints.examples.bookstore.Bookstore.Healthz",
+					SkipServiceControl: true,
+				},
+			},
+		},
+		SystemParameters: &confpb.SystemParameters{
+			Rules: []*confpb.SystemParameterRule{
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+					Parameters: []*confpb.SystemParameter{
+						{
+							Name:       "api_key",
+							HttpHeader: "x-bookstore-key",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestNewApiKeyFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	storePath := writeApiKeyStore(t, `{
+  "api_keys": [
+    {
+      "key_sha256": "`+testApiKeySha256+`",
+      "consumer": "mobile-app",
+      "allowed_selectors": ["endpoints.examples.bookstore.Bookstore.ListShelves"],
+      "allowed_referrers": ["*.example.com/*"]
+    },
+    {
+      "key_sha256": "`+testApiKeySha256_1+`",
+      "consumer": "partner"
+    }
+  ]
+}`)
+
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Not generated without an API key store",
+			ServiceConfigIn: apiKeyTestServiceConfig(),
+		},
+		{
+			Desc:            "API keys from the store",
+			ServiceConfigIn: apiKeyTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				ApiKeyStorePath: storePath,
+			},
+			WantFilterConfigs: []string{`
+{
+  "name": "com.google.espv2.filters.http.api_key",
+  "typedConfig": {
+    "@type": "type.googleapis.com/espv2.api.envoy.v12.http.api_key.FilterConfig",
+    "apiKeys": [
+      {
+        "keySha256": "` + testApiKeySha256 + `",
+        "consumer": "mobile-app",
+        "allowedSelectors": ["endpoints.examples.bookstore.Bookstore.ListShelves"],
+        "allowedReferrers": ["*.example.com/*"]
+      },
+      {
+        "keySha256": "` + testApiKeySha256_1 + `",
+        "consumer": "partner"
+      }
+    ],
+    "consumerHeader": "X-Endpoint-API-Consumer"
+  }
+}`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewApiKeyFilterGensFromOPConfig)
+	}
+}
+
+func TestNewApiKeyFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []struct {
+		desc             string
+		content          string
+		wantFactoryError string
+	}{
+		{
+			desc:             "Malformed store",
+			content:          `{"api_keys": {}}`,
+			wantFactoryError: "fail to parse API key store file",
+		},
+		{
+			desc:             "Key is not hashed",
+			content:          `{"api_keys": [{"key_sha256": "AIzaSyD-plain-key", "consumer": "mobile-app"}]}`,
+			wantFactoryError: "invalid key_sha256",
+		},
+		{
+			desc:             "Missing consumer",
+			content:          `{"api_keys": [{"key_sha256": "` + testApiKeySha256 + `"}]}`,
+			wantFactoryError: "must set consumer",
+		},
+		{
+			desc:             "Duplicated key",
+			content:          `{"api_keys": [{"key_sha256": "` + testApiKeySha256 + `", "consumer": "a"}, {"key_sha256": "` + testApiKeySha256 + `", "consumer": "b"}]}`,
+			wantFactoryError: "is duplicated",
+		},
+	}
+
+	for _, tc := range testdata {
+		errorTC := filtergentest.FactoryErrorOPTestCase{
+			Desc:            tc.desc,
+			ServiceConfigIn: apiKeyTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				ApiKeyStorePath: writeApiKeyStore(t, tc.content),
+			},
+			WantFactoryError: tc.wantFactoryError,
+		}
+		errorTC.RunTest(t, filtergen.NewApiKeyFilterGensFromOPConfig)
+	}
+
+	missingFileTC := filtergentest.FactoryErrorOPTestCase{
+		Desc:            "Missing store file",
+		ServiceConfigIn: apiKeyTestServiceConfig(),
+		OptsIn: options.ConfigGeneratorOptions{
+			ApiKeyStorePath: filepath.Join(t.TempDir(), "missing.json"),
+		},
+		WantFactoryError: "fail to read API key store file",
+	}
+	missingFileTC.RunTest(t, filtergen.NewApiKeyFilterGensFromOPConfig)
+}
+
+func TestApiKeyGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.ApiKeyStorePath = writeApiKeyStore(t, `{"api_keys": []}`)
+
+	gens, err := filtergen.NewApiKeyFilterGensFromOPConfig(apiKeyTestServiceConfig(), opts)
+	if err != nil {
+		t.Fatalf("NewApiKeyFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewApiKeyFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	testdata := []struct {
+		desc       string
+		selector   string
+		wantConfig string
+	}{
+		{
+			desc:     "Custom API key locations",
+			selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+			wantConfig: `
+{
+  "operationName": "endpoints.examples.bookstore.Bookstore.ListShelves",
+  "locations": [
+    {"header": "x-bookstore-key"}
+  ]
+}`,
+		},
+		{
+			desc:     "Unregistered calls are allowed",
+			selector: "endpoints.examples.bookstore.Bookstore.GetShelf",
+			wantConfig: `
+{
+  "operationName": "endpoints.examples.bookstore.Bookstore.GetShelf",
+  "allowWithoutApiKey": true
+}`,
+		},
+		{
+			desc:     "Skipped by usage rule",
+			selector: "endpoints.examples.bookstore.Bookstore.Healthz",
+		},
+		{
+			desc:     "Autogenerated operation",
+			selector: "espv2_deployment.ESPv2_Autogenerated_HealthCheck",
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := gens[0].GenPerRouteConfig(tc.selector, nil)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantConfig == "" {
+				if got != nil {
+					t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	bslpb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/body_size_limit"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// BodySizeLimitFilterName is the Envoy filter name for debug logging.
+	BodySizeLimitFilterName = "com.google.espv2.filters.http.body_size_limit"
+)
+
+// BodySizeLimitGenerator rejects requests with a body larger than the limit
+// of their operation.
+type BodySizeLimitGenerator struct {
+	// DefaultMaxRequestBytes is the limit of operations without an override.
+	// Zero means no limit.
+	DefaultMaxRequestBytes uint64
+
+	// MaxRequestBytesBySelector are the per-operation overrides.
+	MaxRequestBytesBySelector map[string]uint64
+
+	CORSOperationDelimiter string
+
+	NoopFilterGenerator
+}
+
+// NewBodySizeLimitFilterGensFromOPConfig creates a BodySizeLimitGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewBodySizeLimitFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	limits, err := ParseMaxRequestBodyBytesOperations(opts.MaxRequestBodyBytesOperations)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxRequestBodyBytes == 0 && len(limits) == 0 {
+		glog.Info("Not adding body size limit filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	return []FilterGenerator{
+		&BodySizeLimitGenerator{
+			DefaultMaxRequestBytes:    opts.MaxRequestBodyBytes,
+			MaxRequestBytesBySelector: limits,
+			CORSOperationDelimiter:    opts.CorsOperationDelimiter,
+		},
+	}, nil
+}
+
+func (g *BodySizeLimitGenerator) FilterName() string {
+	return BodySizeLimitFilterName
+}
+
+func (g *BodySizeLimitGenerator) GenFilterConfig() (proto.Message, error) {
+	return &bslpb.FilterConfig{
+		DefaultMaxRequestBytes: g.DefaultMaxRequestBytes,
+	}, nil
+}
+
+// GenPerRouteConfig overrides the default limit for the operations in
+// `--max_request_body_bytes_operations`. Autogenerated CORS operations use the
+// limit of their original operation.
+func (g *BodySizeLimitGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	maxRequestBytes, ok := g.MaxRequestBytesBySelector[selector]
+	if !ok {
+		originalSelector, err := CORSSelectorToSelector(selector, g.CORSOperationDelimiter)
+		if err != nil {
+			return nil, err
+		}
+		if maxRequestBytes, ok = g.MaxRequestBytesBySelector[originalSelector]; !ok {
+			return nil, nil
+		}
+	}
+
+	return &bslpb.PerRouteFilterConfig{
+		MaxRequestBytes: maxRequestBytes,
+	}, nil
+}
+
+// ParseMaxRequestBodyBytesOperations parses the per-operation body size
+// limits, a comma separated list of "selector=bytes" pairs.
+func ParseMaxRequestBodyBytesOperations(limitsStr string) (map[string]uint64, error) {
+	limits := make(map[string]uint64)
+	for _, pair := range strings.Split(limitsStr, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		selector, bytesStr, ok := strings.Cut(pair, "=")
+		selector = strings.TrimSpace(selector)
+		if !ok || selector == "" {
+			return nil, fmt.Errorf("invalid max request body bytes %q, it must be in the format selector=bytes", pair)
+		}
+		if _, ok := limits[selector]; ok {
+			return nil, fmt.Errorf("duplicate max request body bytes for selector %q", selector)
+		}
+
+		maxRequestBytes, err := strconv.ParseUint(strings.TrimSpace(bytesStr), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max request body bytes %q for selector %q, it must be a non-negative integer", bytesStr, selector)
+		}
+		limits[selector] = maxRequestBytes
+	}
+	return limits, nil
+}
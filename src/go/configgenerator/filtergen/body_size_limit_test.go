@@ -0,0 +1,164 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+)
+
+func TestNewBodySizeLimitFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc: "Generate with the default limit",
+			OptsIn: options.ConfigGeneratorOptions{
+				MaxRequestBodyBytes: 1048576,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.body_size_limit",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.body_size_limit.FilterConfig",
+      "defaultMaxRequestBytes":"1048576"
+   }
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate with only per-operation limits",
+			OptsIn: options.ConfigGeneratorOptions{
+				MaxRequestBodyBytesOperations: "bookstore.Bookstore.CreateBook=1024",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.body_size_limit",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.body_size_limit.FilterConfig"
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:              "No-op when no limit is set",
+			OptsIn:            options.ConfigGeneratorOptions{},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewBodySizeLimitFilterGensFromOPConfig)
+	}
+}
+
+func TestBodySizeLimitGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.MaxRequestBodyBytes = 1048576
+	opts.MaxRequestBodyBytesOperations = "bookstore.Bookstore.CreateBook=10485760, bookstore.Bookstore.UploadCover=0"
+
+	gens, err := filtergen.NewBodySizeLimitFilterGensFromOPConfig(nil, opts)
+	if err != nil {
+		t.Fatalf("NewBodySizeLimitFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewBodySizeLimitFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	testdata := []struct {
+		desc       string
+		selector   string
+		wantConfig string
+	}{
+		{
+			desc:     "Operation without override uses the default limit",
+			selector: "bookstore.Bookstore.ListShelves",
+		},
+		{
+			desc:       "Operation with override",
+			selector:   "bookstore.Bookstore.CreateBook",
+			wantConfig: `{"maxRequestBytes": "10485760"}`,
+		},
+		{
+			desc:       "Operation without limit",
+			selector:   "bookstore.Bookstore.UploadCover",
+			wantConfig: `{}`,
+		},
+		{
+			desc:       "Autogenerated CORS operation uses the limit of its original operation",
+			selector:   "bookstore.Bookstore.ESPv2_Autogenerated_CORS_CreateBook",
+			wantConfig: `{"maxRequestBytes": "10485760"}`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := gens[0].GenPerRouteConfig(tc.selector, nil)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantConfig == "" {
+				if got != nil {
+					t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewBodySizeLimitFilterGensFromOPConfig_FactoryError(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Missing bytes",
+			OptsIn: options.ConfigGeneratorOptions{
+				MaxRequestBodyBytesOperations: "bookstore.Bookstore.CreateBook",
+			},
+			WantFactoryError: `invalid max request body bytes "bookstore.Bookstore.CreateBook", it must be in the format selector=bytes`,
+		},
+		{
+			Desc: "Negative bytes",
+			OptsIn: options.ConfigGeneratorOptions{
+				MaxRequestBodyBytesOperations: "bookstore.Bookstore.CreateBook=-1",
+			},
+			WantFactoryError: `invalid max request body bytes "-1" for selector "bookstore.Bookstore.CreateBook"`,
+		},
+		{
+			Desc: "Duplicate selector",
+			OptsIn: options.ConfigGeneratorOptions{
+				MaxRequestBodyBytesOperations: "bookstore.Bookstore.CreateBook=1,bookstore.Bookstore.CreateBook=2",
+			},
+			WantFactoryError: `duplicate max request body bytes for selector "bookstore.Bookstore.CreateBook"`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewBodySizeLimitFilterGensFromOPConfig)
+	}
+}
@@ -16,16 +16,21 @@ package filtergen
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	brpb "github.com/envoyproxy/go-control-plane/envoy/extensions/compression/brotli/compressor/v3"
 	gzippb "github.com/envoyproxy/go-control-plane/envoy/extensions/compression/gzip/compressor/v3"
+	zstdpb "github.com/envoyproxy/go-control-plane/envoy/extensions/compression/zstd/compressor/v3"
 	comppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/compressor/v3"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 type CompressorType int
@@ -33,6 +38,7 @@ type CompressorType int
 const (
 	GzipCompressor CompressorType = iota
 	BrotliCompressor
+	ZstdCompressor
 )
 
 const (
@@ -44,11 +50,46 @@ const (
 
 	// EnvoyGzipCompressorName is a compressor extension name.
 	EnvoyGzipCompressorName = "envoy.compression.gzip.compressor"
+
+	// EnvoyZstdCompressorName is a compressor extension name.
+	EnvoyZstdCompressorName = "envoy.compression.zstd.compressor"
 )
 
+// compressorAlgorithm is a compression algorithm that can be enabled by the
+// `--response_compression_algorithms` option.
+type compressorAlgorithm struct {
+	compressorType CompressorType
+	minLevel       uint32
+	maxLevel       uint32
+}
+
+// compressorAlgorithms are the supported algorithms, keyed by the name used in
+// the options, which is also the content encoding.
+var compressorAlgorithms = map[string]compressorAlgorithm{
+	"gzip": {compressorType: GzipCompressor, minLevel: 1, maxLevel: 9},
+	"br":   {compressorType: BrotliCompressor, minLevel: 0, maxLevel: 11},
+	"zstd": {compressorType: ZstdCompressor, minLevel: 1, maxLevel: 22},
+}
+
 type CompressorGenerator struct {
 	compressorType CompressorType
 
+	// Level is the compression level, or quality for brotli. Unset uses the
+	// default of the compressor library.
+	Level *uint32
+
+	// MinContentLength is the minimum response size to compress. Zero uses the
+	// Envoy default.
+	MinContentLength uint32
+
+	// ContentTypes are the response content types to compress. Empty uses the
+	// Envoy default.
+	ContentTypes []string
+
+	// Operations are the selectors of the operations to compress. If nil, all
+	// operations are compressed.
+	Operations map[string]bool
+
 	NoopFilterGenerator
 }
 
@@ -60,14 +101,93 @@ func NewCompressorFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts
 		return nil, nil
 	}
 
-	return []FilterGenerator{
-		&CompressorGenerator{
-			compressorType: GzipCompressor,
-		},
-		&CompressorGenerator{
-			compressorType: BrotliCompressor,
-		},
-	}, nil
+	levels, err := parseCompressionLevels(opts.ResponseCompressionLevels)
+	if err != nil {
+		return nil, err
+	}
+
+	var contentTypes []string
+	for _, contentType := range strings.Split(opts.ResponseCompressionContentTypes, ",") {
+		if contentType = strings.TrimSpace(contentType); contentType != "" {
+			contentTypes = append(contentTypes, contentType)
+		}
+	}
+
+	var operations map[string]bool
+	for _, operation := range strings.Split(opts.ResponseCompressionOperations, ",") {
+		if operation = strings.TrimSpace(operation); operation == "" {
+			continue
+		}
+		if operations == nil {
+			operations = make(map[string]bool)
+		}
+		operations[operation] = true
+	}
+
+	var gens []FilterGenerator
+	enabled := make(map[string]bool)
+	for _, name := range strings.Split(opts.ResponseCompressionAlgorithms, ",") {
+		name = strings.TrimSpace(name)
+		algorithm, ok := compressorAlgorithms[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid response compression algorithm %q, it must be one of (gzip|br|zstd)", name)
+		}
+		if enabled[name] {
+			return nil, fmt.Errorf("duplicate response compression algorithm %q", name)
+		}
+		enabled[name] = true
+
+		gen := &CompressorGenerator{
+			compressorType:   algorithm.compressorType,
+			MinContentLength: uint32(opts.ResponseCompressionMinContentLength),
+			ContentTypes:     contentTypes,
+			Operations:       operations,
+		}
+		if level, ok := levels[name]; ok {
+			gen.Level = &level
+		}
+		gens = append(gens, gen)
+	}
+
+	for name := range levels {
+		if !enabled[name] {
+			return nil, fmt.Errorf("response compression level is set for algorithm %q, which is not enabled", name)
+		}
+	}
+
+	return gens, nil
+}
+
+// parseCompressionLevels parses the compression levels, a comma separated list
+// of `algorithm=level`.
+func parseCompressionLevels(levelsStr string) (map[string]uint32, error) {
+	levels := make(map[string]uint32)
+	for _, levelStr := range strings.Split(levelsStr, ",") {
+		levelStr = strings.TrimSpace(levelStr)
+		if levelStr == "" {
+			continue
+		}
+
+		name, valueStr, ok := strings.Cut(levelStr, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid response compression level %q, it must be in the format algorithm=level", levelStr)
+		}
+		algorithm, ok := compressorAlgorithms[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid response compression level %q, algorithm must be one of (gzip|br|zstd)", levelStr)
+		}
+		if _, ok := levels[name]; ok {
+			return nil, fmt.Errorf("duplicate response compression level for algorithm %q", name)
+		}
+
+		level, err := strconv.ParseUint(strings.TrimSpace(valueStr), 10, 32)
+		if err != nil || uint32(level) < algorithm.minLevel || uint32(level) > algorithm.maxLevel {
+			return nil, fmt.Errorf("invalid response compression level %q, the level of %q must be in the range [%d, %d]", levelStr, name, algorithm.minLevel, algorithm.maxLevel)
+		}
+		levels[name] = uint32(level)
+	}
+	return levels, nil
 }
 
 func (g *CompressorGenerator) FilterName() string {
@@ -83,20 +203,62 @@ func (g *CompressorGenerator) GenFilterConfig() (proto.Message, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling %s Compressor config to Any: %v", name, err)
 	}
-	return &comppb.Compressor{
+
+	compressor := &comppb.Compressor{
 		CompressorLibrary: &corepb.TypedExtensionConfig{
 			Name:        name,
 			TypedConfig: ca,
 		},
+	}
+
+	if g.MinContentLength > 0 || len(g.ContentTypes) > 0 {
+		commonConfig := &comppb.Compressor_CommonDirectionConfig{
+			ContentType: g.ContentTypes,
+		}
+		if g.MinContentLength > 0 {
+			commonConfig.MinContentLength = wrapperspb.UInt32(g.MinContentLength)
+		}
+		compressor.ResponseDirectionConfig = &comppb.Compressor_ResponseDirectionConfig{
+			CommonConfig: commonConfig,
+		}
+	}
+	return compressor, nil
+}
+
+// GenPerRouteConfig disables the compression of the operations that are not
+// selected.
+func (g *CompressorGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	if g.Operations == nil || g.Operations[selector] {
+		return nil, nil
+	}
+
+	return &comppb.CompressorPerRoute{
+		Override: &comppb.CompressorPerRoute_Disabled{
+			Disabled: true,
+		},
 	}, nil
 }
 
 func (g *CompressorGenerator) getCompressorConfig() (proto.Message, string, error) {
 	switch g.compressorType {
 	case GzipCompressor:
-		return &gzippb.Gzip{}, EnvoyGzipCompressorName, nil
+		cfg := &gzippb.Gzip{}
+		if g.Level != nil {
+			cfg.CompressionLevel = gzippb.Gzip_CompressionLevel(*g.Level)
+		}
+		return cfg, EnvoyGzipCompressorName, nil
 	case BrotliCompressor:
-		return &brpb.Brotli{}, EnvoyBrotliCompressorName, nil
+		cfg := &brpb.Brotli{}
+		if g.Level != nil {
+			cfg.Quality = wrapperspb.UInt32(*g.Level)
+		}
+		return cfg, EnvoyBrotliCompressorName, nil
+	case ZstdCompressor:
+		cfg := &zstdpb.Zstd{}
+		if g.Level != nil {
+			cfg.CompressionLevel = wrapperspb.UInt32(*g.Level)
+		}
+		return cfg, EnvoyZstdCompressorName, nil
 	}
 	return nil, "", fmt.Errorf("unknown compressor type: %v", g.compressorType)
 }
@@ -20,6 +20,7 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 )
 
 func TestNewCompressorFilterGensFromOPConfig_GenConfig(t *testing.T) {
@@ -57,6 +58,85 @@ func TestNewCompressorFilterGensFromOPConfig_GenConfig(t *testing.T) {
       }
    }
 }
+`,
+			},
+		},
+		{
+			Desc: "Generate with zstd, levels, min content length and content types",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCompression:           true,
+				ResponseCompressionAlgorithms:       "zstd, gzip",
+				ResponseCompressionLevels:           "gzip=6,zstd=3",
+				ResponseCompressionMinContentLength: 1024,
+				ResponseCompressionContentTypes:     "application/json, text/plain",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.compressor",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.compressor.v3.Compressor",
+      "compressorLibrary":{
+         "name":"envoy.compression.zstd.compressor",
+         "typedConfig":{
+            "@type":"type.googleapis.com/envoy.extensions.compression.zstd.compressor.v3.Zstd",
+            "compressionLevel":3
+         }
+      },
+      "responseDirectionConfig":{
+         "commonConfig":{
+            "minContentLength":1024,
+            "contentType":["application/json", "text/plain"]
+         }
+      }
+   }
+}
+`,
+				`
+{
+   "name":"envoy.filters.http.compressor",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.compressor.v3.Compressor",
+      "compressorLibrary":{
+         "name":"envoy.compression.gzip.compressor",
+         "typedConfig":{
+            "@type":"type.googleapis.com/envoy.extensions.compression.gzip.compressor.v3.Gzip",
+            "compressionLevel":"COMPRESSION_LEVEL_6"
+         }
+      },
+      "responseDirectionConfig":{
+         "commonConfig":{
+            "minContentLength":1024,
+            "contentType":["application/json", "text/plain"]
+         }
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate brotli with quality",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCompression:     true,
+				ResponseCompressionAlgorithms: "br",
+				ResponseCompressionLevels:     "br=0",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.compressor",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.compressor.v3.Compressor",
+      "compressorLibrary":{
+         "name":"envoy.compression.brotli.compressor",
+         "typedConfig":{
+            "@type":"type.googleapis.com/envoy.extensions.compression.brotli.compressor.v3.Brotli",
+            "quality":0
+         }
+      }
+   }
+}
 `,
 			},
 		},
@@ -73,3 +153,106 @@ func TestNewCompressorFilterGensFromOPConfig_GenConfig(t *testing.T) {
 		tc.RunTest(t, filtergen.NewCompressorFilterGensFromOPConfig)
 	}
 }
+
+func TestNewCompressorFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Unknown algorithm",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCompression:     true,
+				ResponseCompressionAlgorithms: "gzip,deflate",
+			},
+			WantFactoryError: `invalid response compression algorithm "deflate"`,
+		},
+		{
+			Desc: "Duplicate algorithm",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCompression:     true,
+				ResponseCompressionAlgorithms: "gzip,gzip",
+			},
+			WantFactoryError: `duplicate response compression algorithm "gzip"`,
+		},
+		{
+			Desc: "Level out of range",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCompression: true,
+				ResponseCompressionLevels: "gzip=10",
+			},
+			WantFactoryError: `the level of "gzip" must be in the range [1, 9]`,
+		},
+		{
+			Desc: "Level in a wrong format",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCompression: true,
+				ResponseCompressionLevels: "gzip",
+			},
+			WantFactoryError: `invalid response compression level "gzip", it must be in the format algorithm=level`,
+		},
+		{
+			Desc: "Level for an algorithm not enabled",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableResponseCompression: true,
+				ResponseCompressionLevels: "zstd=3",
+			},
+			WantFactoryError: `response compression level is set for algorithm "zstd", which is not enabled`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewCompressorFilterGensFromOPConfig)
+	}
+}
+
+func TestCompressorGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.EnableResponseCompression = true
+	opts.ResponseCompressionOperations = "testapi.foo, testapi.bar"
+	gens, err := filtergen.NewCompressorFilterGensFromOPConfig(nil, opts)
+	if err != nil {
+		t.Fatalf("NewCompressorFilterGensFromOPConfig() got error: %v", err)
+	}
+
+	testdata := []struct {
+		desc       string
+		selector   string
+		wantConfig string
+	}{
+		{
+			desc:     "Selected operation is compressed",
+			selector: "testapi.foo",
+		},
+		{
+			desc:     "Other operation is not compressed",
+			selector: "testapi.baz",
+			wantConfig: `
+{
+  "disabled": true
+}`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			for _, gen := range gens {
+				got, err := gen.GenPerRouteConfig(tc.selector, nil)
+				if err != nil {
+					t.Fatalf("GenPerRouteConfig() got error: %v", err)
+				}
+				if tc.wantConfig == "" {
+					if got != nil {
+						t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+					}
+					continue
+				}
+
+				gotJson, err := util.ProtoToJson(got)
+				if err != nil {
+					t.Fatalf("ProtoToJson() got error: %v", err)
+				}
+				if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+					t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+				}
+			}
+		})
+	}
+}
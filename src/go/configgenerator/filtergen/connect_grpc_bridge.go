@@ -0,0 +1,154 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	connectpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/connect_grpc_bridge/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// ConnectGRPCBridgeFilterName is the Envoy filter name for debug logging.
+	ConnectGRPCBridgeFilterName = "envoy.filters.http.connect_grpc_bridge"
+)
+
+// ConnectGRPCBridgeAllowHeaders are the request headers of the Connect
+// protocol, allowed by CORS when the bridge is enabled.
+var ConnectGRPCBridgeAllowHeaders = []string{
+	"Connect-Protocol-Version",
+	"Connect-Timeout-Ms",
+	"Connect-Accept-Encoding",
+	"Connect-Content-Encoding",
+}
+
+// ConnectGRPCBridgeGenerator converts Connect protocol requests into gRPC
+// requests, and the gRPC responses back.
+type ConnectGRPCBridgeGenerator struct {
+	// GRPCSelectors are the selectors of the methods served by gRPC backends.
+	GRPCSelectors map[string]bool
+
+	NoopFilterGenerator
+}
+
+// NewConnectGRPCBridgeFilterGensFromOPConfig creates a ConnectGRPCBridgeGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewConnectGRPCBridgeFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if !opts.EnableConnectGrpcBridge {
+		glog.Infof("Not adding Connect gRPC bridge filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	isGRPCSupportRequired, err := IsGRPCSupportRequiredForOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !isGRPCSupportRequired {
+		glog.Infof("gRPC support is NOT required, skip Connect gRPC bridge filter completely.")
+		return nil, nil
+	}
+
+	grpcSelectors, err := GetGRPCSelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return []FilterGenerator{
+		&ConnectGRPCBridgeGenerator{
+			GRPCSelectors: grpcSelectors,
+		},
+	}, nil
+}
+
+func (g *ConnectGRPCBridgeGenerator) FilterName() string {
+	return ConnectGRPCBridgeFilterName
+}
+
+func (g *ConnectGRPCBridgeGenerator) GenFilterConfig() (proto.Message, error) {
+	return &connectpb.FilterConfig{}, nil
+}
+
+// GenPerRouteConfig disables the filter on all routes except the gRPC paths
+// of the methods served by gRPC backends. Connect requests use the same
+// "/package.Service/Method" paths as gRPC, but their JSON bodies would also
+// match the transcoded HTTP routes.
+//
+// The bridge forwards JSON Connect requests as "application/grpc+json", which
+// gRPC backends reject. They never reach these routes: unary ones match the
+// routes of routegen.ProxyConnectJSONGenerator, which disable the bridge so the
+// transcoder handles them, and streaming ones are denied by
+// routegen.DenyConnectJSONGenerator.
+func (g *ConnectGRPCBridgeGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	if g.GRPCSelectors[selector] && httpRule != nil && httpRule.UriTemplate != nil {
+		isGRPCPath, err := httpRule.IsGRPCPathForOperation(selector)
+		if err != nil {
+			return nil, fmt.Errorf("fail to check the gRPC path of operation %q: %v", selector, err)
+		}
+		if isGRPCPath {
+			return nil, nil
+		}
+	}
+	return &routepb.FilterConfig{
+		Disabled: true,
+	}, nil
+}
+
+// GetGRPCSelectorsFromOPConfig returns the selectors of the methods whose
+// backend, the remote backend of their backend rule or else the local backend,
+// is a gRPC backend.
+func GetGRPCSelectorsFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (map[string]bool, error) {
+	isLocalBackendGRPC, err := util.IsBackendGRPC(opts.BackendAddress)
+	if err != nil {
+		return nil, fmt.Errorf("fail to check local backend address: %v", err)
+	}
+
+	remoteAddressBySelector := make(map[string]string)
+	if !opts.EnableBackendAddressOverride {
+		for _, rule := range serviceConfig.GetBackend().GetRules() {
+			if rule.GetAddress() != "" {
+				remoteAddressBySelector[rule.GetSelector()] = rule.GetAddress()
+			}
+		}
+	}
+
+	grpcSelectors := make(map[string]bool)
+	for _, api := range serviceConfig.GetApis() {
+		if util.ShouldSkipOPDiscoveryAPI(api.GetName(), opts.AllowDiscoveryAPIs) {
+			glog.Warningf("Skip API %q because discovery API is not supported.", api.GetName())
+			continue
+		}
+
+		for _, method := range api.GetMethods() {
+			selector := MethodToSelector(api, method)
+			isGRPC := isLocalBackendGRPC
+			if address, ok := remoteAddressBySelector[selector]; ok {
+				if isGRPC, err = util.IsBackendGRPC(address); err != nil {
+					return nil, fmt.Errorf("fail to check remote backend address for selector %q: %v", selector, err)
+				}
+			}
+			if isGRPC {
+				grpcSelectors[selector] = true
+			}
+		}
+	}
+	return grpcSelectors, nil
+}
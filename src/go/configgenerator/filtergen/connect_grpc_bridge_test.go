@@ -0,0 +1,227 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	"github.com/google/go-cmp/cmp"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func connectGRPCBridgeTestServiceConfig() *servicepb.Service {
+	return &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "testapi",
+				Methods: []*apipb.Method{
+					{Name: "Get"},
+					{Name: "List"},
+				},
+			},
+		},
+		Backend: &servicepb.Backend{
+			Rules: []*servicepb.BackendRule{
+				{
+					Selector: "testapi.List",
+					Address:  "https://remote.example.com",
+				},
+			},
+		},
+	}
+}
+
+func TestNewConnectGRPCBridgeFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate with the Connect gRPC bridge enabled",
+			ServiceConfigIn: connectGRPCBridgeTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:          "grpc://127.0.0.1:80",
+				EnableConnectGrpcBridge: true,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.connect_grpc_bridge",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.connect_grpc_bridge.v3.FilterConfig"
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "No-op when the Connect gRPC bridge is not enabled",
+			ServiceConfigIn: connectGRPCBridgeTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "grpc://127.0.0.1:80",
+			},
+			WantFilterConfigs: nil,
+		},
+		{
+			Desc: "No-op when gRPC support is not required",
+			ServiceConfigIn: &servicepb.Service{
+				Apis: []*apipb.Api{
+					{
+						Name: "testapi",
+						Methods: []*apipb.Method{
+							{Name: "Get"},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:          "http://127.0.0.1:80",
+				EnableConnectGrpcBridge: true,
+			},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewConnectGRPCBridgeFilterGensFromOPConfig)
+	}
+}
+
+func TestConnectGRPCBridgeGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.BackendAddress = "grpc://127.0.0.1:80"
+	opts.EnableConnectGrpcBridge = true
+
+	gens, err := filtergen.NewConnectGRPCBridgeFilterGensFromOPConfig(connectGRPCBridgeTestServiceConfig(), opts)
+	if err != nil {
+		t.Fatalf("NewConnectGRPCBridgeFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewConnectGRPCBridgeFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	disabledConfig := `{"disabled": true}`
+	testdata := []struct {
+		desc       string
+		selector   string
+		path       string
+		wantConfig string
+	}{
+		{
+			desc:     "gRPC path of a gRPC method uses the filter",
+			selector: "testapi.Get",
+			path:     "/testapi/Get",
+		},
+		{
+			desc:       "Transcoded path of a gRPC method disables the filter",
+			selector:   "testapi.Get",
+			path:       "/v1/items/{id}",
+			wantConfig: disabledConfig,
+		},
+		{
+			desc:       "Method of an HTTP backend disables the filter",
+			selector:   "testapi.List",
+			path:       "/testapi/List",
+			wantConfig: disabledConfig,
+		},
+		{
+			desc:       "Unknown operation disables the filter",
+			selector:   "testapi.ESPv2_Autogenerated_CORS_Get",
+			path:       "/testapi/Get",
+			wantConfig: disabledConfig,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			uriTemplate, err := httppattern.ParseUriTemplate(tc.path)
+			if err != nil {
+				t.Fatalf("ParseUriTemplate() got error: %v", err)
+			}
+			got, err := gens[0].GenPerRouteConfig(tc.selector, &httppattern.Pattern{
+				UriTemplate: uriTemplate,
+				HttpMethod:  util.POST,
+			})
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantConfig == "" {
+				if got != nil {
+					t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetGRPCSelectorsFromOPConfig(t *testing.T) {
+	testdata := []struct {
+		desc          string
+		opts          options.ConfigGeneratorOptions
+		wantSelectors map[string]bool
+	}{
+		{
+			desc: "Local gRPC backend and remote HTTP backend",
+			opts: options.ConfigGeneratorOptions{
+				BackendAddress: "grpc://127.0.0.1:80",
+			},
+			wantSelectors: map[string]bool{
+				"testapi.Get": true,
+			},
+		},
+		{
+			desc: "Local HTTP backend and remote HTTP backend",
+			opts: options.ConfigGeneratorOptions{
+				BackendAddress: "http://127.0.0.1:80",
+			},
+			wantSelectors: map[string]bool{},
+		},
+		{
+			desc: "Backend address override sends all methods to the local gRPC backend",
+			opts: options.ConfigGeneratorOptions{
+				BackendAddress:               "grpc://127.0.0.1:80",
+				EnableBackendAddressOverride: true,
+			},
+			wantSelectors: map[string]bool{
+				"testapi.Get":  true,
+				"testapi.List": true,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := filtergen.GetGRPCSelectorsFromOPConfig(connectGRPCBridgeTestServiceConfig(), tc.opts)
+			if err != nil {
+				t.Fatalf("GetGRPCSelectorsFromOPConfig() got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantSelectors, got); diff != "" {
+				t.Errorf("GetGRPCSelectorsFromOPConfig() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
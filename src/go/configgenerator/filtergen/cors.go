@@ -17,11 +17,16 @@ package filtergen
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/helpers"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	corspb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cors/v3"
 	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/proto"
@@ -36,7 +41,8 @@ const (
 // CORSGenerator is a FilterGenerator to configure CORS config.
 type CORSGenerator struct {
 	Preset string
-	// AllowOrigin should only be set if preset=basic
+	// AllowOrigin should only be set if preset=basic. It is a comma-separated
+	// list of origins.
 	AllowOrigin string
 	// AllowOriginRegex should only be set if preset=cors_with_regex
 	AllowOriginRegex string
@@ -46,6 +52,14 @@ type CORSGenerator struct {
 	ExposeHeaders    string
 	AllowCredentials bool
 
+	// PolicyBySelector overrides the global policy for some operations.
+	PolicyBySelector       map[string]*helpers.CORSPolicy
+	CORSOperationDelimiter string
+
+	// ExtraAllowHeaders are always appended to the allowed headers, e.g. the
+	// headers of the Connect protocol.
+	ExtraAllowHeaders []string
+
 	NoopFilterGenerator
 }
 
@@ -53,24 +67,51 @@ type CORSGenerator struct {
 // OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
 func NewCORSFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
 	if opts.CorsPreset == "" {
+		if opts.CorsPoliciesPath != "" {
+			return nil, fmt.Errorf("cors_policies_path can only be used together with cors_preset")
+		}
 		glog.Infof("Not adding CORS filter gen because the feature is disabled by option, option is currently %q", opts.CorsPreset)
 		return nil, nil
 	}
 
+	policyBySelector, err := GetCORSPoliciesBySelectorFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var extraAllowHeaders []string
+	if opts.EnableConnectGrpcBridge {
+		extraAllowHeaders = ConnectGRPCBridgeAllowHeaders
+	}
+
 	return []FilterGenerator{
 		&CORSGenerator{
-			Preset:           opts.CorsPreset,
-			AllowOrigin:      opts.CorsAllowOrigin,
-			AllowOriginRegex: opts.CorsAllowOriginRegex,
-			MaxAge:           opts.CorsMaxAge,
-			AllowMethods:     opts.CorsAllowMethods,
-			AllowHeaders:     opts.CorsAllowHeaders,
-			ExposeHeaders:    opts.CorsExposeHeaders,
-			AllowCredentials: opts.CorsAllowCredentials,
+			Preset:                 opts.CorsPreset,
+			AllowOrigin:            opts.CorsAllowOrigin,
+			AllowOriginRegex:       opts.CorsAllowOriginRegex,
+			MaxAge:                 opts.CorsMaxAge,
+			AllowMethods:           opts.CorsAllowMethods,
+			AllowHeaders:           opts.CorsAllowHeaders,
+			ExposeHeaders:          opts.CorsExposeHeaders,
+			AllowCredentials:       opts.CorsAllowCredentials,
+			PolicyBySelector:       policyBySelector,
+			CORSOperationDelimiter: opts.CorsOperationDelimiter,
+			ExtraAllowHeaders:      extraAllowHeaders,
 		},
 	}, nil
 }
 
+// SplitCORSAllowOrigins splits the comma-separated `--cors_allow_origin`.
+func SplitCORSAllowOrigins(allowOrigin string) []string {
+	var origins []string
+	for _, origin := range strings.Split(allowOrigin, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
 func (g *CORSGenerator) FilterName() string {
 	return CORSFilterName
 }
@@ -80,6 +121,47 @@ func (g *CORSGenerator) GenFilterConfig() (proto.Message, error) {
 }
 
 func (g *CORSGenerator) GenPerHostConfig(vHostName string) (proto.Message, error) {
+	return g.makeCorsPolicy(nil)
+}
+
+// GenPerRouteConfig overrides the per-host policy for operations with a CORS
+// policy. Autogenerated CORS operations use the policy of their original
+// operation.
+func (g *CORSGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	policy, err := g.PolicyForSelector(selector)
+	if err != nil || policy == nil {
+		return nil, err
+	}
+
+	if policy.Disabled {
+		return &corspb.CorsPolicy{
+			FilterEnabled: &corepb.RuntimeFractionalPercent{
+				DefaultValue: &typepb.FractionalPercent{
+					Numerator: 0,
+				},
+			},
+		}, nil
+	}
+	return g.makeCorsPolicy(policy)
+}
+
+// PolicyForSelector returns the CORS policy of the operation, or nil if the
+// operation uses the global policy.
+func (g *CORSGenerator) PolicyForSelector(selector string) (*helpers.CORSPolicy, error) {
+	if policy, ok := g.PolicyBySelector[selector]; ok {
+		return policy, nil
+	}
+
+	originalSelector, err := CORSSelectorToSelector(selector, g.CORSOperationDelimiter)
+	if err != nil {
+		return nil, err
+	}
+	return g.PolicyBySelector[originalSelector], nil
+}
+
+// makeCorsPolicy makes the global policy, with the fields set in the policy
+// override taking precedence.
+func (g *CORSGenerator) makeCorsPolicy(override *helpers.CORSPolicy) (*corspb.CorsPolicy, error) {
 	policy := &corspb.CorsPolicy{
 		MaxAge:        strconv.Itoa(int(g.MaxAge.Seconds())),
 		AllowMethods:  g.AllowMethods,
@@ -92,28 +174,105 @@ func (g *CORSGenerator) GenPerHostConfig(vHostName string) (proto.Message, error
 
 	switch g.Preset {
 	case "basic":
-		policy.AllowOriginStringMatch = []*matcherpb.StringMatcher{
-			{
-				MatchPattern: &matcherpb.StringMatcher_Exact{
-					Exact: g.AllowOrigin,
-				},
-			},
-		}
+		policy.AllowOriginStringMatch = makeOriginStringMatchers(SplitCORSAllowOrigins(g.AllowOrigin), nil)
 
 	case "cors_with_regex":
-		policy.AllowOriginStringMatch = []*matcherpb.StringMatcher{
-			{
-				MatchPattern: &matcherpb.StringMatcher_SafeRegex{
-					SafeRegex: &matcherpb.RegexMatcher{
-						Regex: g.AllowOriginRegex,
-					},
-				},
-			},
-		}
+		policy.AllowOriginStringMatch = makeOriginStringMatchers(nil, []string{g.AllowOriginRegex})
 
 	default:
 		return nil, fmt.Errorf(`cors_preset must be either "basic" or "cors_with_regex"`)
 	}
 
+	if override != nil {
+		if len(override.AllowOrigins) > 0 || len(override.AllowOriginRegexes) > 0 {
+			policy.AllowOriginStringMatch = makeOriginStringMatchers(override.AllowOrigins, override.AllowOriginRegexes)
+		}
+		if override.AllowMethods != "" {
+			policy.AllowMethods = override.AllowMethods
+		}
+		if override.AllowHeaders != "" {
+			policy.AllowHeaders = override.AllowHeaders
+		}
+		if override.ExposeHeaders != "" {
+			policy.ExposeHeaders = override.ExposeHeaders
+		}
+		if override.MaxAgeSeconds != nil {
+			policy.MaxAge = strconv.FormatInt(*override.MaxAgeSeconds, 10)
+		}
+		if override.AllowCredentials != nil {
+			policy.AllowCredentials = &wrapperspb.BoolValue{
+				Value: *override.AllowCredentials,
+			}
+		}
+	}
+
+	policy.AllowHeaders = appendCORSHeaders(policy.AllowHeaders, g.ExtraAllowHeaders)
 	return policy, nil
 }
+
+// appendCORSHeaders appends the headers missing from the comma-separated
+// header list, ignoring case.
+func appendCORSHeaders(headerList string, headers []string) string {
+	existingHeaders := make(map[string]bool)
+	for _, header := range strings.Split(headerList, ",") {
+		existingHeaders[strings.ToLower(strings.TrimSpace(header))] = true
+	}
+
+	for _, header := range headers {
+		if existingHeaders[strings.ToLower(header)] {
+			continue
+		}
+		if headerList != "" {
+			headerList += ","
+		}
+		headerList += header
+	}
+	return headerList
+}
+
+func makeOriginStringMatchers(origins []string, regexes []string) []*matcherpb.StringMatcher {
+	var matchers []*matcherpb.StringMatcher
+	for _, origin := range origins {
+		matchers = append(matchers, &matcherpb.StringMatcher{
+			MatchPattern: &matcherpb.StringMatcher_Exact{
+				Exact: origin,
+			},
+		})
+	}
+	for _, regex := range regexes {
+		matchers = append(matchers, &matcherpb.StringMatcher{
+			MatchPattern: &matcherpb.StringMatcher_SafeRegex{
+				SafeRegex: &matcherpb.RegexMatcher{
+					Regex: regex,
+				},
+			},
+		})
+	}
+	return matchers
+}
+
+// GetCORSPoliciesBySelectorFromOPConfig reads the policies file at
+// `--cors_policies_path`, and resolves it to the policy of each operation.
+// The policy for an operation takes precedence over the policy for its API.
+func GetCORSPoliciesBySelectorFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (map[string]*helpers.CORSPolicy, error) {
+	if opts.CorsPoliciesPath == "" {
+		return nil, nil
+	}
+
+	policies, err := helpers.ReadCORSPoliciesFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	policiesBySelector, err := GetValuesBySelectorFromOPConfig(serviceConfig, opts, "CORS policy", true, policies, func(p *helpers.CORSPolicy) []string {
+		return p.Selectors
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	policyBySelector := make(map[string]*helpers.CORSPolicy)
+	for selector, selectorPolicies := range policiesBySelector {
+		policyBySelector[selector] = selectorPolicies.First()
+	}
+	return policyBySelector, nil
+}
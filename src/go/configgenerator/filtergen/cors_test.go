@@ -15,13 +15,69 @@
 package filtergen_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
 )
 
+func writeCORSPolicies(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cors_policies.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("fail to write CORS policies file: %v", err)
+	}
+	return path
+}
+
+func corsTestServiceConfig() *servicepb.Service {
+	return &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "testapi",
+				Methods: []*apipb.Method{
+					{Name: "foo"},
+					{Name: "bar"},
+					{Name: "internal"},
+				},
+			},
+			{
+				Name: "otherapi",
+				Methods: []*apipb.Method{
+					{Name: "baz"},
+				},
+			},
+		},
+	}
+}
+
+const corsTestPolicies = `{
+  "policies": [
+    {
+      "selectors": ["testapi", "unknownapi"],
+      "allow_origins": ["https://a.example.com", "https://b.example.com"],
+      "max_age_seconds": 600
+    },
+    {
+      "selectors": ["testapi.bar"],
+      "allow_origin_regexes": ["https://.*\\.example\\.org"],
+      "allow_methods": "GET,POST",
+      "allow_credentials": false
+    },
+    {
+      "selectors": ["testapi.internal"],
+      "disabled": true
+    }
+  ]
+}`
+
 func TestNewCORSFilterGensFromOPConfig_GenConfig(t *testing.T) {
 	testdata := []filtergentest.SuccessOPTestCase{
 		{
@@ -69,3 +125,252 @@ func TestNewCORSFilterGensFromOPConfig_GenConfig(t *testing.T) {
 		tc.RunTest(t, filtergen.NewCORSFilterGensFromOPConfig)
 	}
 }
+
+func TestCORSGenerator_GenPerHostConfig(t *testing.T) {
+	testdata := []struct {
+		desc       string
+		opts       options.ConfigGeneratorOptions
+		wantConfig string
+	}{
+		{
+			desc: "Basic preset with multiple origins",
+			opts: options.ConfigGeneratorOptions{
+				CorsPreset:       "basic",
+				CorsAllowOrigin:  "https://a.example.com, https://b.example.com",
+				CorsAllowMethods: "GET",
+				CorsMaxAge:       10 * time.Minute,
+			},
+			wantConfig: `
+{
+  "allowOriginStringMatch": [
+    {"exact": "https://a.example.com"},
+    {"exact": "https://b.example.com"}
+  ],
+  "allowMethods": "GET",
+  "maxAge": "600",
+  "allowCredentials": false
+}`,
+		},
+		{
+			desc: "Regex preset",
+			opts: options.ConfigGeneratorOptions{
+				CorsPreset:           "cors_with_regex",
+				CorsAllowOriginRegex: "https://.*",
+				CorsAllowCredentials: true,
+			},
+			wantConfig: `
+{
+  "allowOriginStringMatch": [
+    {"safeRegex": {"regex": "https://.*"}}
+  ],
+  "maxAge": "0",
+  "allowCredentials": true
+}`,
+		},
+		{
+			desc: "Connect protocol headers are allowed with the Connect gRPC bridge",
+			opts: options.ConfigGeneratorOptions{
+				CorsPreset:              "basic",
+				CorsAllowOrigin:         "*",
+				CorsAllowHeaders:        "Content-Type,connect-protocol-version",
+				EnableConnectGrpcBridge: true,
+			},
+			wantConfig: `
+{
+  "allowOriginStringMatch": [
+    {"exact": "*"}
+  ],
+  "allowHeaders": "Content-Type,connect-protocol-version,Connect-Timeout-Ms,Connect-Accept-Encoding,Connect-Content-Encoding",
+  "maxAge": "0",
+  "allowCredentials": false
+}`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			gens, err := filtergen.NewCORSFilterGensFromOPConfig(&servicepb.Service{}, tc.opts)
+			if err != nil {
+				t.Fatalf("NewCORSFilterGensFromOPConfig() got error: %v", err)
+			}
+
+			got, err := gens[0].GenPerHostConfig("vhost")
+			if err != nil {
+				t.Fatalf("GenPerHostConfig() got error: %v", err)
+			}
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerHostConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
+
+func TestCORSGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.CorsPreset = "basic"
+	opts.CorsAllowOrigin = "https://global.example.com"
+	opts.CorsAllowMethods = "GET"
+	opts.CorsAllowHeaders = "Authorization"
+	opts.CorsExposeHeaders = "Content-Length"
+	opts.CorsAllowCredentials = true
+	opts.CorsPoliciesPath = writeCORSPolicies(t, corsTestPolicies)
+
+	gens, err := filtergen.NewCORSFilterGensFromOPConfig(corsTestServiceConfig(), opts)
+	if err != nil {
+		t.Fatalf("NewCORSFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewCORSFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	apiPolicy := `
+{
+  "allowOriginStringMatch": [
+    {"exact": "https://a.example.com"},
+    {"exact": "https://b.example.com"}
+  ],
+  "allowMethods": "GET",
+  "allowHeaders": "Authorization",
+  "exposeHeaders": "Content-Length",
+  "maxAge": "600",
+  "allowCredentials": true
+}`
+
+	testdata := []struct {
+		desc       string
+		selector   string
+		wantConfig string
+	}{
+		{
+			desc:     "Operation without policy uses the per-host policy",
+			selector: "otherapi.baz",
+		},
+		{
+			desc:       "Operation uses the policy of its API",
+			selector:   "testapi.foo",
+			wantConfig: apiPolicy,
+		},
+		{
+			desc:       "Autogenerated CORS operation uses the policy of its original operation",
+			selector:   "testapi.ESPv2_Autogenerated_CORS_foo",
+			wantConfig: apiPolicy,
+		},
+		{
+			desc:     "Operation policy takes precedence over the API policy",
+			selector: "testapi.bar",
+			wantConfig: `
+{
+  "allowOriginStringMatch": [
+    {"safeRegex": {"regex": "https://.*\\.example\\.org"}}
+  ],
+  "allowMethods": "GET,POST",
+  "allowHeaders": "Authorization",
+  "exposeHeaders": "Content-Length",
+  "maxAge": "1728000",
+  "allowCredentials": false
+}`,
+		},
+		{
+			desc:     "Disabled operation",
+			selector: "testapi.internal",
+			wantConfig: `
+{
+  "filterEnabled": {
+    "defaultValue": {}
+  }
+}`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := gens[0].GenPerRouteConfig(tc.selector, nil)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantConfig == "" {
+				if got != nil {
+					t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewCORSFilterGensFromOPConfig_FactoryError(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "CORS policies without CORS preset",
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsPoliciesPath: writeCORSPolicies(t, corsTestPolicies),
+			},
+			WantFactoryError: "cors_policies_path can only be used together with cors_preset",
+		},
+		{
+			Desc: "Missing CORS policies file",
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsPreset:       "basic",
+				CorsPoliciesPath: filepath.Join(t.TempDir(), "missing.json"),
+			},
+			WantFactoryError: "fail to read CORS policies file",
+		},
+		{
+			Desc: "Malformed CORS policies file",
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsPreset:       "basic",
+				CorsPoliciesPath: writeCORSPolicies(t, `{"policies": {}}`),
+			},
+			WantFactoryError: "fail to parse CORS policies file",
+		},
+		{
+			Desc: "Policy without selectors",
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsPreset:       "basic",
+				CorsPoliciesPath: writeCORSPolicies(t, `{"policies": [{"allow_origins": ["https://a.example.com"]}]}`),
+			},
+			WantFactoryError: "invalid CORS policy at index 0: selectors must not be empty",
+		},
+		{
+			Desc: "Disabled policy sets other fields",
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsPreset:       "basic",
+				CorsPoliciesPath: writeCORSPolicies(t, `{"policies": [{"selectors": ["testapi"], "disabled": true, "allow_methods": "GET"}]}`),
+			},
+			WantFactoryError: "a disabled policy must not set any other field",
+		},
+		{
+			Desc: "Oversize origin regex",
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsPreset:       "basic",
+				CorsPoliciesPath: writeCORSPolicies(t, `{"policies": [{"selectors": ["testapi"], "allow_origin_regexes": ["^https?://.+.google.com$.{1000}"]}]}`),
+			},
+			WantFactoryError: "invalid allow_origin_regexes",
+		},
+		{
+			Desc: "Selector used by two policies",
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsPreset:       "basic",
+				CorsPoliciesPath: writeCORSPolicies(t, `{"policies": [{"selectors": ["testapi.foo"], "max_age_seconds": 1}, {"selectors": ["testapi.foo"], "disabled": true}]}`),
+			},
+			WantFactoryError: `selector "testapi.foo" is used by more than one CORS policy`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.ServiceConfigIn = corsTestServiceConfig()
+		tc.RunTest(t, filtergen.NewCORSFilterGensFromOPConfig)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	gzippb "github.com/envoyproxy/go-control-plane/envoy/extensions/compression/gzip/decompressor/v3"
+	decomppb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/decompressor/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// EnvoyDecompressorFilterName is the Envoy filter name for debug logging.
+	EnvoyDecompressorFilterName = "envoy.filters.http.decompressor"
+
+	// EnvoyGzipDecompressorName is a decompressor extension name.
+	EnvoyGzipDecompressorName = "envoy.compression.gzip.decompressor"
+
+	// decompressorResponseRuntimeKey is the runtime key to enable response
+	// decompression, which is off by default.
+	decompressorResponseRuntimeKey = "espv2.decompressor.response_enabled"
+)
+
+// DecompressorGenerator decompresses gzip request bodies, so backends do not
+// need to handle compressed requests.
+type DecompressorGenerator struct {
+	NoopFilterGenerator
+}
+
+// NewDecompressorFilterGensFromOPConfig creates a DecompressorGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewDecompressorFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if !opts.EnableRequestDecompression {
+		glog.Info("Not adding decompressor filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	return []FilterGenerator{
+		&DecompressorGenerator{},
+	}, nil
+}
+
+func (g *DecompressorGenerator) FilterName() string {
+	return EnvoyDecompressorFilterName
+}
+
+func (g *DecompressorGenerator) GenFilterConfig() (proto.Message, error) {
+	ga, err := anypb.New(&gzippb.Gzip{})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling gzip Decompressor config to Any: %v", err)
+	}
+
+	return &decomppb.Decompressor{
+		DecompressorLibrary: &corepb.TypedExtensionConfig{
+			Name:        EnvoyGzipDecompressorName,
+			TypedConfig: ga,
+		},
+		RequestDirectionConfig: &decomppb.Decompressor_RequestDirectionConfig{
+			// Backends may not handle compressed responses, so do not ask for them.
+			AdvertiseAcceptEncoding: wrapperspb.Bool(false),
+		},
+		// Responses are compressed by the backend for the client.
+		ResponseDirectionConfig: &decomppb.Decompressor_ResponseDirectionConfig{
+			CommonConfig: &decomppb.Decompressor_CommonDirectionConfig{
+				Enabled: &corepb.RuntimeFeatureFlag{
+					DefaultValue: wrapperspb.Bool(false),
+					RuntimeKey:   decompressorResponseRuntimeKey,
+				},
+			},
+		},
+	}, nil
+}
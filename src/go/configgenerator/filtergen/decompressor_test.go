@@ -0,0 +1,72 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+func TestNewDecompressorFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc: "Generate with request decompression enabled",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableRequestDecompression: true,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.decompressor",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.decompressor.v3.Decompressor",
+      "decompressorLibrary":{
+         "name":"envoy.compression.gzip.decompressor",
+         "typedConfig":{
+            "@type":"type.googleapis.com/envoy.extensions.compression.gzip.decompressor.v3.Gzip"
+         }
+      },
+      "requestDirectionConfig":{
+         "advertiseAcceptEncoding":false
+      },
+      "responseDirectionConfig":{
+         "commonConfig":{
+            "enabled":{
+               "defaultValue":false,
+               "runtimeKey":"espv2.decompressor.response_enabled"
+            }
+         }
+      }
+   }
+}
+`,
+			},
+		},
+		{
+			Desc: "No-op when opt is disabled",
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableRequestDecompression: false,
+			},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewDecompressorFilterGensFromOPConfig)
+	}
+}
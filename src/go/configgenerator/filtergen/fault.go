@@ -0,0 +1,179 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	commonfaultpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/common/fault/v3"
+	faultpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/fault/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// EnvoyFaultFilterName is the Envoy filter name for debug logging.
+	EnvoyFaultFilterName = "envoy.filters.http.fault"
+)
+
+// FaultGenerator injects delays and aborts into the requests of selected
+// operations, for resilience testing.
+type FaultGenerator struct {
+	// PolicyBySelector is the fault injection policy of each operation.
+	PolicyBySelector map[string]*helpers.FaultInjectionPolicy
+
+	NoopFilterGenerator
+}
+
+// NewFaultFilterGensFromOPConfig creates a FaultGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewFaultFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.FaultInjectionPoliciesPath == "" {
+		glog.Info("Not adding fault filter gen because there are no fault injection policies.")
+		return nil, nil
+	}
+	if !opts.EnableFaultInjection {
+		return nil, fmt.Errorf("fault_injection_policies_path can only be used together with enable_fault_injection")
+	}
+
+	policies, err := GetFaultInjectionPoliciesBySelectorFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	glog.Warningf("Fault injection is enabled for %d operations, do not use it in production.", len(policies))
+	return []FilterGenerator{
+		&FaultGenerator{
+			PolicyBySelector: policies,
+		},
+	}, nil
+}
+
+func (g *FaultGenerator) FilterName() string {
+	return EnvoyFaultFilterName
+}
+
+// GenFilterConfig injects no faults, they are only injected by the per-route
+// configs.
+func (g *FaultGenerator) GenFilterConfig() (proto.Message, error) {
+	return &faultpb.HTTPFault{}, nil
+}
+
+func (g *FaultGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	policy, ok := g.PolicyBySelector[selector]
+	if !ok {
+		return nil, nil
+	}
+
+	config := &faultpb.HTTPFault{}
+	if policy.Delay != nil {
+		duration, err := time.ParseDuration(policy.Delay.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fault delay duration %q for selector %q: %v", policy.Delay.Duration, selector, err)
+		}
+		config.Delay = &commonfaultpb.FaultDelay{
+			FaultDelaySecifier: &commonfaultpb.FaultDelay_FixedDelay{
+				FixedDelay: durationpb.New(duration),
+			},
+			Percentage: makeFaultPercentage(policy.Delay.Percentage),
+		}
+	}
+
+	if policy.Abort != nil {
+		config.Abort = &faultpb.FaultAbort{
+			Percentage: makeFaultPercentage(policy.Abort.Percentage),
+		}
+		if policy.Abort.GRPCStatus != nil {
+			config.Abort.ErrorType = &faultpb.FaultAbort_GrpcStatus{
+				GrpcStatus: *policy.Abort.GRPCStatus,
+			}
+		} else {
+			config.Abort.ErrorType = &faultpb.FaultAbort_HttpStatus{
+				HttpStatus: *policy.Abort.HTTPStatus,
+			}
+		}
+	}
+
+	if policy.Header != "" {
+		matcher := &routepb.HeaderMatcher{
+			Name: policy.Header,
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_PresentMatch{
+				PresentMatch: true,
+			},
+		}
+		if policy.HeaderValue != "" {
+			matcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_StringMatch{
+				StringMatch: &matcherpb.StringMatcher{
+					MatchPattern: &matcherpb.StringMatcher_Exact{
+						Exact: policy.HeaderValue,
+					},
+				},
+			}
+		}
+		config.Headers = []*routepb.HeaderMatcher{matcher}
+	}
+
+	return config, nil
+}
+
+// makeFaultPercentage converts a percentage in [0, 100] to a fraction of a
+// million. An unset percentage means all requests.
+func makeFaultPercentage(percentage *float64) *typepb.FractionalPercent {
+	numerator := uint32(1000000)
+	if percentage != nil {
+		numerator = uint32(math.Round(*percentage * 10000))
+	}
+	return &typepb.FractionalPercent{
+		Numerator:   numerator,
+		Denominator: typepb.FractionalPercent_MILLION,
+	}
+}
+
+// GetFaultInjectionPoliciesBySelectorFromOPConfig reads the policies file at
+// `--fault_injection_policies_path`, and resolves it to the policy of each
+// operation. The policy for an operation takes precedence over the policy for
+// its API.
+func GetFaultInjectionPoliciesBySelectorFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (map[string]*helpers.FaultInjectionPolicy, error) {
+	if opts.FaultInjectionPoliciesPath == "" {
+		return nil, nil
+	}
+
+	policies, err := helpers.ReadFaultInjectionPoliciesFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	policiesBySelector, err := GetValuesBySelectorFromOPConfig(serviceConfig, opts, "fault injection policy", true, policies, func(p *helpers.FaultInjectionPolicy) []string {
+		return p.Selectors
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	policyBySelector := make(map[string]*helpers.FaultInjectionPolicy)
+	for selector, selectorPolicies := range policiesBySelector {
+		policyBySelector[selector] = selectorPolicies.First()
+	}
+	return policyBySelector, nil
+}
@@ -0,0 +1,284 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func writeFaultInjectionPolicies(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fault_injection_policies.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("fail to write fault injection policies file: %v", err)
+	}
+	return path
+}
+
+func faultTestServiceConfig() *servicepb.Service {
+	return &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "testapi",
+				Methods: []*apipb.Method{
+					{Name: "foo"},
+					{Name: "bar"},
+				},
+			},
+			{
+				Name: "grpcapi",
+				Methods: []*apipb.Method{
+					{Name: "baz"},
+				},
+			},
+		},
+	}
+}
+
+const faultTestPolicies = `{
+  "policies": [
+    {
+      "selectors": ["testapi"],
+      "header": "x-chaos-test",
+      "delay": {"duration": "1.5s", "percentage": 12.5}
+    },
+    {
+      "selectors": ["testapi.bar"],
+      "header": "x-chaos-test",
+      "header_value": "abort",
+      "abort": {"http_status": 503}
+    },
+    {
+      "selectors": ["grpcapi.baz"],
+      "delay": {"duration": "100ms"},
+      "abort": {"grpc_status": 14, "percentage": 0.5}
+    }
+  ]
+}`
+
+func TestNewFaultFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate with fault injection enabled",
+			ServiceConfigIn: faultTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableFaultInjection:       true,
+				FaultInjectionPoliciesPath: writeFaultInjectionPolicies(t, faultTestPolicies),
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.fault",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.fault.v3.HTTPFault"
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "No-op without policies",
+			ServiceConfigIn: faultTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableFaultInjection: true,
+			},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewFaultFilterGensFromOPConfig)
+	}
+}
+
+func TestFaultGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.EnableFaultInjection = true
+	opts.FaultInjectionPoliciesPath = writeFaultInjectionPolicies(t, faultTestPolicies)
+
+	gens, err := filtergen.NewFaultFilterGensFromOPConfig(faultTestServiceConfig(), opts)
+	if err != nil {
+		t.Fatalf("NewFaultFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewFaultFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	testdata := []struct {
+		desc       string
+		selector   string
+		wantConfig string
+	}{
+		{
+			desc:     "Operation without policy",
+			selector: "otherapi.qux",
+		},
+		{
+			desc:     "Operation uses the policy of its API",
+			selector: "testapi.foo",
+			wantConfig: `
+{
+  "delay": {
+    "fixedDelay": "1.500s",
+    "percentage": {"numerator": 125000, "denominator": "MILLION"}
+  },
+  "headers": [
+    {"name": "x-chaos-test", "presentMatch": true}
+  ]
+}`,
+		},
+		{
+			desc:     "Operation policy takes precedence over the API policy",
+			selector: "testapi.bar",
+			wantConfig: `
+{
+  "abort": {
+    "httpStatus": 503,
+    "percentage": {"numerator": 1000000, "denominator": "MILLION"}
+  },
+  "headers": [
+    {"name": "x-chaos-test", "stringMatch": {"exact": "abort"}}
+  ]
+}`,
+		},
+		{
+			desc:     "gRPC abort and delay",
+			selector: "grpcapi.baz",
+			wantConfig: `
+{
+  "delay": {
+    "fixedDelay": "0.100s",
+    "percentage": {"numerator": 1000000, "denominator": "MILLION"}
+  },
+  "abort": {
+    "grpcStatus": 14,
+    "percentage": {"numerator": 5000, "denominator": "MILLION"}
+  }
+}`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := gens[0].GenPerRouteConfig(tc.selector, nil)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantConfig == "" {
+				if got != nil {
+					t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewFaultFilterGensFromOPConfig_FactoryError(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Policies without enable_fault_injection",
+			ServiceConfigIn: faultTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				FaultInjectionPoliciesPath: writeFaultInjectionPolicies(t, faultTestPolicies),
+			},
+			WantFactoryError: "fault_injection_policies_path can only be used together with enable_fault_injection",
+		},
+		{
+			Desc:            "Malformed policies file",
+			ServiceConfigIn: faultTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableFaultInjection:       true,
+				FaultInjectionPoliciesPath: writeFaultInjectionPolicies(t, `{"policies": {}}`),
+			},
+			WantFactoryError: "fail to parse fault injection policies file",
+		},
+		{
+			Desc:            "Policy without faults",
+			ServiceConfigIn: faultTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableFaultInjection:       true,
+				FaultInjectionPoliciesPath: writeFaultInjectionPolicies(t, `{"policies": [{"selectors": ["testapi"]}]}`),
+			},
+			WantFactoryError: "invalid fault injection policy at index 0: at least one of delay and abort must be set",
+		},
+		{
+			Desc:            "Invalid delay duration",
+			ServiceConfigIn: faultTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableFaultInjection:       true,
+				FaultInjectionPoliciesPath: writeFaultInjectionPolicies(t, `{"policies": [{"selectors": ["testapi"], "delay": {"duration": "2"}}]}`),
+			},
+			WantFactoryError: `invalid delay duration "2"`,
+		},
+		{
+			Desc:            "Both abort statuses",
+			ServiceConfigIn: faultTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableFaultInjection:       true,
+				FaultInjectionPoliciesPath: writeFaultInjectionPolicies(t, `{"policies": [{"selectors": ["testapi"], "abort": {"http_status": 503, "grpc_status": 14}}]}`),
+			},
+			WantFactoryError: "exactly one of abort http_status and grpc_status must be set",
+		},
+		{
+			Desc:            "OK gRPC status",
+			ServiceConfigIn: faultTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableFaultInjection:       true,
+				FaultInjectionPoliciesPath: writeFaultInjectionPolicies(t, `{"policies": [{"selectors": ["testapi"], "abort": {"grpc_status": 0}}]}`),
+			},
+			WantFactoryError: "invalid abort grpc_status 0",
+		},
+		{
+			Desc:            "Percentage out of range",
+			ServiceConfigIn: faultTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableFaultInjection:       true,
+				FaultInjectionPoliciesPath: writeFaultInjectionPolicies(t, `{"policies": [{"selectors": ["testapi"], "abort": {"http_status": 503, "percentage": 101}}]}`),
+			},
+			WantFactoryError: "invalid abort: percentage must be in the range [0, 100], got 101",
+		},
+		{
+			Desc:            "Selector used twice",
+			ServiceConfigIn: faultTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				EnableFaultInjection:       true,
+				FaultInjectionPoliciesPath: writeFaultInjectionPolicies(t, `{"policies": [{"selectors": ["testapi.foo"], "abort": {"http_status": 503}}, {"selectors": ["testapi.foo"], "delay": {"duration": "1s"}}]}`),
+			},
+			WantFactoryError: `selector "testapi.foo" is used by more than one fault injection policy`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewFaultFilterGensFromOPConfig)
+	}
+}
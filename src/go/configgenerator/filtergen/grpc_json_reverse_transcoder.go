@@ -0,0 +1,154 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	rtpb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/grpc_json_reverse_transcoder"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// GRPCJSONReverseTranscoderFilterName is the Envoy filter name for debug logging.
+	GRPCJSONReverseTranscoderFilterName = "com.google.espv2.filters.http.grpc_json_reverse_transcoder"
+)
+
+// GRPCJSONReverseTranscoderGenerator converts the gRPC requests of methods
+// served by HTTP/JSON backends into HTTP/JSON requests, following the http
+// rules of the methods.
+type GRPCJSONReverseTranscoderGenerator struct {
+	ProtoDescriptorBin []byte
+
+	// ReverseTranscodingSelectors are the selectors of the reverse transcoded
+	// methods.
+	ReverseTranscodingSelectors map[string]bool
+
+	NoopFilterGenerator
+}
+
+// NewGRPCJSONReverseTranscoderFilterGensFromOPConfig creates a GRPCJSONReverseTranscoderGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewGRPCJSONReverseTranscoderFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if !opts.EnableGrpcJsonReverseTranscoding {
+		glog.Infof("Not adding gRPC JSON reverse transcoder filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	selectors, err := GetReverseTranscodingSelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(selectors) == 0 {
+		glog.Infof("Not adding gRPC JSON reverse transcoder filter gen because no method is served by an HTTP backend.")
+		return nil, nil
+	}
+
+	descBin, err := GetDescriptorBinFromOPConfig(serviceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("gRPC JSON reverse transcoding requires a proto descriptor in the service config: %v", err)
+	}
+
+	// Copy the http rules of the service config into the descriptor, the filter
+	// builds the HTTP requests from them.
+	descBin, err = UpdateProtoDescriptorFromOPConfig(serviceConfig, opts, descBin)
+	if err != nil {
+		return nil, err
+	}
+
+	return []FilterGenerator{
+		&GRPCJSONReverseTranscoderGenerator{
+			ProtoDescriptorBin:          descBin,
+			ReverseTranscodingSelectors: selectors,
+		},
+	}, nil
+}
+
+func (g *GRPCJSONReverseTranscoderGenerator) FilterName() string {
+	return GRPCJSONReverseTranscoderFilterName
+}
+
+func (g *GRPCJSONReverseTranscoderGenerator) GenFilterConfig() (proto.Message, error) {
+	return &rtpb.FilterConfig{
+		DescriptorBin: g.ProtoDescriptorBin,
+	}, nil
+}
+
+// GenPerRouteConfig enables reverse transcoding on the gRPC paths of the
+// reverse transcoded methods.
+func (g *GRPCJSONReverseTranscoderGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	if !g.ReverseTranscodingSelectors[selector] || httpRule == nil || httpRule.UriTemplate == nil {
+		return nil, nil
+	}
+
+	isGRPCPath, err := httpRule.IsGRPCPathForOperation(selector)
+	if err != nil {
+		return nil, fmt.Errorf("fail to check the gRPC path of operation %q: %v", selector, err)
+	}
+	if !isGRPCPath {
+		return nil, nil
+	}
+	return &rtpb.PerRouteFilterConfig{
+		MethodName: selector,
+	}, nil
+}
+
+// GetReverseTranscodingSelectorsFromOPConfig returns the selectors of the unary
+// methods with http rules that are not served by gRPC backends. gRPC clients
+// call them at their gRPC paths when reverse transcoding is enabled.
+func GetReverseTranscodingSelectorsFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (map[string]bool, error) {
+	if !opts.EnableGrpcJsonReverseTranscoding {
+		return nil, nil
+	}
+
+	grpcSelectors, err := GetGRPCSelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRuleSelectors := make(map[string]bool)
+	for _, rule := range serviceConfig.GetHttp().GetRules() {
+		httpRuleSelectors[rule.GetSelector()] = true
+	}
+
+	selectors := make(map[string]bool)
+	for _, api := range serviceConfig.GetApis() {
+		if util.ShouldSkipOPDiscoveryAPI(api.GetName(), opts.AllowDiscoveryAPIs) {
+			continue
+		}
+
+		for _, method := range api.GetMethods() {
+			selector := MethodToSelector(api, method)
+			if grpcSelectors[selector] {
+				continue
+			}
+			if method.GetRequestStreaming() || method.GetResponseStreaming() {
+				glog.Warningf("Skip reverse transcoding for operation %q because streaming methods are not supported.", selector)
+				continue
+			}
+			if !httpRuleSelectors[selector] {
+				glog.Warningf("Skip reverse transcoding for operation %q because it has no http rule.", selector)
+				continue
+			}
+			selectors[selector] = true
+		}
+	}
+	return selectors, nil
+}
@@ -0,0 +1,264 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	"github.com/google/go-cmp/cmp"
+	ahpb "google.golang.org/genproto/googleapis/api/annotations"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	smpb "google.golang.org/genproto/googleapis/api/servicemanagement/v1"
+	apipb "google.golang.org/genproto/protobuf/api"
+	"google.golang.org/protobuf/proto"
+	descpb "google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func reverseTranscoderTestServiceConfig(t *testing.T, descriptor []byte) *servicepb.Service {
+	t.Helper()
+	serviceConfig := &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{Name: "GetShelf"},
+					{Name: "ListShelves"},
+					{Name: "WatchShelves", ResponseStreaming: true},
+					{Name: "DeleteShelf"},
+				},
+			},
+		},
+		Http: &ahpb.Http{
+			Rules: []*ahpb.HttpRule{
+				{
+					Selector: "bookstore.Bookstore.GetShelf",
+					Pattern:  &ahpb.HttpRule_Get{Get: "/v1/shelves/{shelf}"},
+				},
+				{
+					Selector: "bookstore.Bookstore.ListShelves",
+					Pattern:  &ahpb.HttpRule_Get{Get: "/v1/shelves"},
+				},
+				{
+					Selector: "bookstore.Bookstore.WatchShelves",
+					Pattern:  &ahpb.HttpRule_Get{Get: "/v1/shelves:watch"},
+				},
+			},
+		},
+		Backend: &servicepb.Backend{
+			Rules: []*servicepb.BackendRule{
+				{
+					Selector: "bookstore.Bookstore.ListShelves",
+					Address:  "grpcs://grpc.example.com",
+				},
+			},
+		},
+	}
+
+	if descriptor != nil {
+		content, err := anypb.New(&smpb.ConfigFile{
+			FilePath:     "api_descriptor.pb",
+			FileContents: descriptor,
+			FileType:     smpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO,
+		})
+		if err != nil {
+			t.Fatalf("Failed to marshal source file into any: %v", err)
+		}
+		serviceConfig.SourceInfo = &servicepb.SourceInfo{
+			SourceFiles: []*anypb.Any{content},
+		}
+	}
+	return serviceConfig
+}
+
+func TestNewGRPCJSONReverseTranscoderFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	rawDescriptor, err := proto.Marshal(&descpb.FileDescriptorSet{
+		File: []*descpb.FileDescriptorProto{
+			{
+				Name: proto.String("bookstore.proto"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal FileDescriptorSet: %v", err)
+	}
+
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate with reverse transcoding enabled",
+			ServiceConfigIn: reverseTranscoderTestServiceConfig(t, rawDescriptor),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:                   "http://127.0.0.1:8080",
+				EnableGrpcJsonReverseTranscoding: true,
+			},
+			WantFilterConfigs: []string{
+				fmt.Sprintf(`
+{
+   "name":"com.google.espv2.filters.http.grpc_json_reverse_transcoder",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.grpc_json_reverse_transcoder.FilterConfig",
+      "descriptorBin":"%s"
+   }
+}
+`, base64.StdEncoding.EncodeToString(rawDescriptor)),
+			},
+		},
+		{
+			Desc:            "No-op when reverse transcoding is not enabled",
+			ServiceConfigIn: reverseTranscoderTestServiceConfig(t, rawDescriptor),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "http://127.0.0.1:8080",
+			},
+			WantFilterConfigs: nil,
+		},
+		{
+			Desc:            "No-op when all methods are served by gRPC backends",
+			ServiceConfigIn: reverseTranscoderTestServiceConfig(t, rawDescriptor),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:                   "grpc://127.0.0.1:8080",
+				EnableGrpcJsonReverseTranscoding: true,
+			},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewGRPCJSONReverseTranscoderFilterGensFromOPConfig)
+	}
+}
+
+func TestNewGRPCJSONReverseTranscoderFilterGensFromOPConfig_FactoryError(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Service config without proto descriptor",
+			ServiceConfigIn: reverseTranscoderTestServiceConfig(t, nil),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:                   "http://127.0.0.1:8080",
+				EnableGrpcJsonReverseTranscoding: true,
+			},
+			WantFactoryError: "gRPC JSON reverse transcoding requires a proto descriptor in the service config",
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewGRPCJSONReverseTranscoderFilterGensFromOPConfig)
+	}
+}
+
+func TestGRPCJSONReverseTranscoderGenerator_GenPerRouteConfig(t *testing.T) {
+	gen := &filtergen.GRPCJSONReverseTranscoderGenerator{
+		ReverseTranscodingSelectors: map[string]bool{
+			"bookstore.Bookstore.GetShelf": true,
+		},
+	}
+
+	testdata := []struct {
+		desc       string
+		selector   string
+		path       string
+		wantConfig string
+	}{
+		{
+			desc:       "gRPC path of a reverse transcoded method",
+			selector:   "bookstore.Bookstore.GetShelf",
+			path:       "/bookstore.Bookstore/GetShelf",
+			wantConfig: `{"methodName": "bookstore.Bookstore.GetShelf"}`,
+		},
+		{
+			desc:     "HTTP path of a reverse transcoded method",
+			selector: "bookstore.Bookstore.GetShelf",
+			path:     "/v1/shelves/{shelf}",
+		},
+		{
+			desc:     "Method served by a gRPC backend",
+			selector: "bookstore.Bookstore.ListShelves",
+			path:     "/bookstore.Bookstore/ListShelves",
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			uriTemplate, err := httppattern.ParseUriTemplate(tc.path)
+			if err != nil {
+				t.Fatalf("ParseUriTemplate() got error: %v", err)
+			}
+			got, err := gen.GenPerRouteConfig(tc.selector, &httppattern.Pattern{
+				UriTemplate: uriTemplate,
+				HttpMethod:  util.POST,
+			})
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantConfig == "" {
+				if got != nil {
+					t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetReverseTranscodingSelectorsFromOPConfig(t *testing.T) {
+	testdata := []struct {
+		desc          string
+		opts          options.ConfigGeneratorOptions
+		wantSelectors map[string]bool
+	}{
+		{
+			desc: "Reverse transcoding is not enabled",
+			opts: options.ConfigGeneratorOptions{
+				BackendAddress: "http://127.0.0.1:8080",
+			},
+		},
+		{
+			desc: "Unary methods with http rules served by the HTTP backend",
+			opts: options.ConfigGeneratorOptions{
+				BackendAddress:                   "http://127.0.0.1:8080",
+				EnableGrpcJsonReverseTranscoding: true,
+			},
+			wantSelectors: map[string]bool{
+				"bookstore.Bookstore.GetShelf": true,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := filtergen.GetReverseTranscodingSelectorsFromOPConfig(reverseTranscoderTestServiceConfig(t, nil), tc.opts)
+			if err != nil {
+				t.Fatalf("GetReverseTranscodingSelectorsFromOPConfig() got error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantSelectors, got); diff != "" {
+				t.Errorf("GetReverseTranscodingSelectorsFromOPConfig() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
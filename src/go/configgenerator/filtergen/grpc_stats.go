@@ -0,0 +1,98 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"sort"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	grpcstatspb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/grpc_stats/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// GRPCStatsFilterName is the Envoy filter name for debug logging.
+	GRPCStatsFilterName = "envoy.filters.http.grpc_stats"
+)
+
+// GRPCStatsGenerator emits the request counts, latencies and gRPC statuses of
+// the methods served by gRPC backends.
+type GRPCStatsGenerator struct {
+	// GRPCMethods are the methods with individual stats. Stats of other
+	// methods are only emitted in aggregate, bounding the stats cardinality.
+	GRPCMethods *corepb.GrpcMethodList
+
+	NoopFilterGenerator
+}
+
+// NewGRPCStatsFilterGensFromOPConfig creates a GRPCStatsGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewGRPCStatsFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if !opts.EnableOperationStats {
+		glog.Infof("Not adding gRPC stats filter gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	grpcSelectors, err := GetGRPCSelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(grpcSelectors) == 0 {
+		glog.Infof("Not adding gRPC stats filter gen because no method is served by a gRPC backend.")
+		return nil, nil
+	}
+
+	methodList := &corepb.GrpcMethodList{}
+	for _, api := range serviceConfig.GetApis() {
+		service := &corepb.GrpcMethodList_Service{
+			Name: api.GetName(),
+		}
+		for _, method := range api.GetMethods() {
+			if grpcSelectors[MethodToSelector(api, method)] {
+				service.MethodNames = append(service.MethodNames, method.GetName())
+			}
+		}
+		if len(service.MethodNames) == 0 {
+			continue
+		}
+		sort.Strings(service.MethodNames)
+		methodList.Services = append(methodList.Services, service)
+	}
+
+	return []FilterGenerator{
+		&GRPCStatsGenerator{
+			GRPCMethods: methodList,
+		},
+	}, nil
+}
+
+func (g *GRPCStatsGenerator) FilterName() string {
+	return GRPCStatsFilterName
+}
+
+func (g *GRPCStatsGenerator) GenFilterConfig() (proto.Message, error) {
+	return &grpcstatspb.FilterConfig{
+		PerMethodStatSpecifier: &grpcstatspb.FilterConfig_IndividualMethodStatsAllowlist{
+			IndividualMethodStatsAllowlist: g.GRPCMethods,
+		},
+		EnableUpstreamStats: true,
+		// The gRPC service is one segment of the stat names, so it can be
+		// extracted as a stats tag.
+		ReplaceDotsInGrpcServiceName: true,
+	}, nil
+}
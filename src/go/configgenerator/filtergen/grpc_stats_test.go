@@ -0,0 +1,117 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func TestNewGRPCStatsFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	serviceConfig := &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{Name: "ListShelves"},
+					{Name: "GetShelf"},
+					{Name: "GetBook"},
+				},
+			},
+			{
+				Name: "bookstore.Admin",
+				Methods: []*apipb.Method{
+					{Name: "Reset"},
+				},
+			},
+		},
+		Backend: &servicepb.Backend{
+			Rules: []*servicepb.BackendRule{
+				{
+					Selector: "bookstore.Bookstore.GetBook",
+					Address:  "https://books.example.com",
+				},
+				{
+					Selector: "bookstore.Admin.Reset",
+					Address:  "https://admin.example.com",
+				},
+			},
+		},
+	}
+
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate with the methods served by gRPC backends",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "grpc://127.0.0.1:80",
+				CommonOptions: options.CommonOptions{
+					EnableOperationStats: true,
+				},
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.grpc_stats",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.grpc_stats.v3.FilterConfig",
+      "individualMethodStatsAllowlist":{
+         "services":[
+            {
+               "name":"bookstore.Bookstore",
+               "methodNames":[
+                  "GetShelf",
+                  "ListShelves"
+               ]
+            }
+         ]
+      },
+      "enableUpstreamStats":true,
+      "replaceDotsInGrpcServiceName":true
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "No-op when operation stats are not enabled",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "grpc://127.0.0.1:80",
+			},
+			WantFilterConfigs: nil,
+		},
+		{
+			Desc:            "No-op for HTTP backends",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "http://127.0.0.1:80",
+				CommonOptions: options.CommonOptions{
+					EnableOperationStats: true,
+				},
+			},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewGRPCStatsFilterGensFromOPConfig)
+	}
+}
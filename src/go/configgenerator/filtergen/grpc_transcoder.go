@@ -16,9 +16,11 @@ package filtergen
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/helpers"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
@@ -26,9 +28,15 @@ import (
 	"github.com/golang/glog"
 	ahpb "google.golang.org/genproto/googleapis/api/annotations"
 	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	errdetailspb "google.golang.org/genproto/googleapis/rpc/errdetails"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/protobuf/proto"
 	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	descpb "google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 const (
@@ -48,6 +56,10 @@ type GRPCTranscoderGenerator struct {
 	// for via per-route filter config.
 	DisabledSelectors map[string]bool
 
+	// OptionsBySelector contains the transcoding options that override the
+	// options below for some selectors, via per-route filter config.
+	OptionsBySelector map[string]*helpers.TranscodingOptions
+
 	// Below are all small behavior changes the API Producer can fine-tune via options.
 
 	IgnoreUnknownQueryParameters       bool
@@ -56,6 +68,7 @@ type GRPCTranscoderGenerator struct {
 	CaseInsensitiveEnumParsing         bool
 	StrictRequestValidation            bool
 	RejectCollision                    bool
+	StreamSSE                          bool
 	PrintOptions                       *transcoderpb.GrpcJsonTranscoder_PrintOptions
 
 	NoopFilterGenerator
@@ -96,11 +109,18 @@ func NewGRPCTranscoderFilterGenFromOPConfig(serviceConfig *confpb.Service, opts
 		return nil, nil
 	}
 
-	descBin, err := GetDescriptorBinFromOPConfig(serviceConfig)
-
-	if err != nil {
-		glog.Error("Unable to setup gRPC-JSON transcoding because no proto descriptor was found in the service config.")
-		return nil, nil
+	var descBin []byte
+	if opts.TranscodingDescriptorPath != "" {
+		descBin, err = os.ReadFile(opts.TranscodingDescriptorPath)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read transcoding descriptor file: %v", err)
+		}
+	} else {
+		descBin, err = GetDescriptorBinFromOPConfig(serviceConfig)
+		if err != nil {
+			glog.Error("Unable to setup gRPC-JSON transcoding because no proto descriptor was found in the service config.")
+			return nil, nil
+		}
 	}
 
 	descBin, err = UpdateProtoDescriptorFromOPConfig(serviceConfig, opts, descBin)
@@ -118,26 +138,47 @@ func NewGRPCTranscoderFilterGenFromOPConfig(serviceConfig *confpb.Service, opts
 		return nil, err
 	}
 
+	optionsBySelector, err := GetTranscodingOptionsBySelectorFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	serviceNames := GetAPINamesListFromOPConfig(serviceConfig, opts)
 
-	return &GRPCTranscoderGenerator{
+	gen := &GRPCTranscoderGenerator{
 		ProtoDescriptorBin:                 descBin,
 		ServiceNames:                       serviceNames,
 		IgnoredQueryParams:                 ignoredQueryParams,
 		DisabledSelectors:                  disabledSelectors,
+		OptionsBySelector:                  optionsBySelector,
 		IgnoreUnknownQueryParameters:       opts.TranscodingIgnoreUnknownQueryParameters,
 		QueryParametersDisableUnescapePlus: opts.TranscodingQueryParametersDisableUnescapePlus,
 		MatchUnregisteredCustomVerb:        opts.TranscodingMatchUnregisteredCustomVerb,
 		CaseInsensitiveEnumParsing:         opts.TranscodingCaseInsensitiveEnumParsing,
 		StrictRequestValidation:            opts.TranscodingStrictRequestValidation,
 		RejectCollision:                    opts.TranscodingRejectCollision,
+		StreamSSE:                          opts.TranscodingStreamSSE,
 		PrintOptions: &transcoderpb.GrpcJsonTranscoder_PrintOptions{
 			AlwaysPrintPrimitiveFields: opts.TranscodingAlwaysPrintPrimitiveFields,
 			AlwaysPrintEnumsAsInts:     opts.TranscodingAlwaysPrintEnumsAsInts,
 			PreserveProtoFieldNames:    opts.TranscodingPreserveProtoFieldNames,
 			StreamNewlineDelimited:     opts.TranscodingStreamNewLineDelimited,
 		},
-	}, nil
+	}
+
+	// Each per-route config carries a copy of the proto descriptor, so only
+	// keep the options that change the listener-level config.
+	filterConfig := gen.genTranscoderConfig(nil)
+	for selector, overrides := range gen.OptionsBySelector {
+		if disabledSelectors[selector] || proto.Equal(gen.genTranscoderConfig(overrides), filterConfig) {
+			delete(gen.OptionsBySelector, selector)
+		}
+	}
+	if len(gen.OptionsBySelector) > 0 {
+		glog.Warningf("Transcoding options override the config of %d operations, their routes add %d bytes of proto descriptors to the route config.", len(gen.OptionsBySelector), len(gen.OptionsBySelector)*len(descBin))
+	}
+
+	return gen, nil
 }
 
 func (g *GRPCTranscoderGenerator) FilterName() string {
@@ -145,6 +186,12 @@ func (g *GRPCTranscoderGenerator) FilterName() string {
 }
 
 func (g *GRPCTranscoderGenerator) GenFilterConfig() (proto.Message, error) {
+	return g.genTranscoderConfig(nil), nil
+}
+
+// genTranscoderConfig generates the transcoder config with the options of the
+// generator, overridden by the fields set in overrides.
+func (g *GRPCTranscoderGenerator) genTranscoderConfig(overrides *helpers.TranscodingOptions) *transcoderpb.GrpcJsonTranscoder {
 	var ignoredQueryParameterList []string
 	for IgnoredQueryParameter := range g.IgnoredQueryParams {
 		ignoredQueryParameterList = append(ignoredQueryParameterList, IgnoredQueryParameter)
@@ -173,12 +220,63 @@ func (g *GRPCTranscoderGenerator) GenFilterConfig() (proto.Message, error) {
 			RejectBindingBodyFieldCollisions: g.RejectCollision,
 		}
 	}
-	return transcodeConfig, nil
+
+	if overrides == nil && !g.StreamSSE {
+		return transcodeConfig
+	}
+	if overrides == nil {
+		overrides = &helpers.TranscodingOptions{}
+	}
+
+	printOptions := &transcoderpb.GrpcJsonTranscoder_PrintOptions{}
+	if g.PrintOptions != nil {
+		printOptions = proto.Clone(g.PrintOptions).(*transcoderpb.GrpcJsonTranscoder_PrintOptions)
+	}
+	transcodeConfig.PrintOptions = printOptions
+	if overrides.AlwaysPrintPrimitiveFields != nil {
+		printOptions.AlwaysPrintPrimitiveFields = *overrides.AlwaysPrintPrimitiveFields
+	}
+	if overrides.AlwaysPrintEnumsAsInts != nil {
+		printOptions.AlwaysPrintEnumsAsInts = *overrides.AlwaysPrintEnumsAsInts
+	}
+	if overrides.PreserveProtoFieldNames != nil {
+		printOptions.PreserveProtoFieldNames = *overrides.PreserveProtoFieldNames
+	}
+	if overrides.StreamNewlineDelimited != nil {
+		printOptions.StreamNewlineDelimited = *overrides.StreamNewlineDelimited
+	}
+	if overrides.IgnoreUnknownQueryParameters != nil {
+		transcodeConfig.IgnoreUnknownQueryParameters = *overrides.IgnoreUnknownQueryParameters
+	}
+	if overrides.QueryParametersDisableUnescapePlus != nil {
+		transcodeConfig.QueryParamUnescapePlus = !*overrides.QueryParametersDisableUnescapePlus
+	}
+	if overrides.MatchUnregisteredCustomVerb != nil {
+		transcodeConfig.MatchUnregisteredCustomVerb = *overrides.MatchUnregisteredCustomVerb
+	}
+	if overrides.CaseInsensitiveEnumParsing != nil {
+		transcodeConfig.CaseInsensitiveEnumParsing = *overrides.CaseInsensitiveEnumParsing
+	}
+
+	// The SSE framing filter turns the newline-delimited messages into events.
+	streamSSE := g.StreamSSE
+	if overrides.StreamSSE != nil {
+		streamSSE = *overrides.StreamSSE
+	}
+	if streamSSE {
+		printOptions.StreamNewlineDelimited = true
+	}
+	return transcodeConfig
 }
 
 func (g *GRPCTranscoderGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (protov2.Message, error) {
 	disabled := g.DisabledSelectors[selector]
 	if !disabled {
+		if overrides, ok := g.OptionsBySelector[selector]; ok {
+			// The per-route config replaces the listener-level filter config.
+			return g.genTranscoderConfig(overrides), nil
+		}
+
 		// Transcoding occurs for this selector because of listener-level filter config.
 		return nil, nil
 	}
@@ -257,6 +355,10 @@ func UpdateProtoDescriptorFromOPConfig(serviceConfig *confpb.Service, opts optio
 		}
 	}
 
+	if opts.TranscodingRichErrorDetails {
+		addErrorDetailsDescriptors(fds)
+	}
+
 	newData, err := protov2.Marshal(fds)
 	if err != nil {
 		glog.Error("failed to marshal proto descriptor, error: ", err)
@@ -265,6 +367,37 @@ func UpdateProtoDescriptorFromOPConfig(serviceConfig *confpb.Service, opts optio
 	return newData, nil
 }
 
+// errorDetailsFiles are the proto files the transcoder needs to print the
+// details of google.rpc.Status, in dependency order.
+var errorDetailsFiles = []protoreflect.FileDescriptor{
+	anypb.File_google_protobuf_any_proto,
+	durationpb.File_google_protobuf_duration_proto,
+	statuspb.File_google_rpc_status_proto,
+	errdetailspb.File_google_rpc_error_details_proto,
+}
+
+// addErrorDetailsDescriptors adds the standard error detail types to the proto
+// descriptor, so the transcoder can convert the details of the
+// "grpc-status-details-bin" trailer into the JSON response body. Only the
+// missing files are added, after all others because the transcoder builds the
+// files in order and they may depend on the existing ones.
+//
+// The transcoder prints the bare google.rpc.Status with the gRPC status code,
+// so the google_rpc_status local reply preset is rejected together with it.
+func addErrorDetailsDescriptors(fds *descpb.FileDescriptorSet) {
+	existingFiles := make(map[string]bool)
+	for _, file := range fds.GetFile() {
+		existingFiles[file.GetName()] = true
+	}
+
+	for _, file := range errorDetailsFiles {
+		if existingFiles[file.Path()] {
+			continue
+		}
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(file))
+	}
+}
+
 func PreserveDefaultHttpBinding(httpRule *ahpb.HttpRule, defaultPath string) {
 	defaultBinding := &ahpb.HttpRule{Pattern: &ahpb.HttpRule_Post{Post: defaultPath}, Body: "*"}
 
@@ -377,3 +510,33 @@ func GetHTTPBackendSelectorsFromOPConfig(serviceConfig *confpb.Service, opts opt
 
 	return disabledSelectors, nil
 }
+
+// GetTranscodingOptionsBySelectorFromOPConfig reads the options file at
+// `--transcoding_options_path`, and resolves it to the options of each
+// operation. The options of an operation are merged on top of the options of
+// its API.
+func GetTranscodingOptionsBySelectorFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) (map[string]*helpers.TranscodingOptions, error) {
+	if opts.TranscodingOptionsPath == "" {
+		return nil, nil
+	}
+
+	transcodingOptions, err := helpers.ReadTranscodingOptionsFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	optionsBySelector, err := GetValuesBySelectorFromOPConfig(serviceConfig, opts, "transcoding options", true, transcodingOptions, func(o *helpers.TranscodingOptions) []string {
+		return o.Selectors
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]*helpers.TranscodingOptions)
+	for selector, selectorOptions := range optionsBySelector {
+		var ordered []*helpers.TranscodingOptions
+		ordered = append(ordered, selectorOptions.API...)
+		ordered = append(ordered, selectorOptions.Operation...)
+		merged[selector] = helpers.MergeTranscodingOptions(ordered...)
+	}
+	return merged, nil
+}
@@ -17,6 +17,8 @@ package filtergen_test
 import (
 	"encoding/base64"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -24,6 +26,7 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	"github.com/GoogleCloudPlatform/esp-v2/tests/utils"
 	"github.com/google/go-cmp/cmp"
 	ahpb "google.golang.org/genproto/googleapis/api/annotations"
@@ -436,6 +439,94 @@ func TestNewGRPCTranscoderFilterGensFromOPConfig_GenConfig(t *testing.T) {
          "endpoints.examples.bookstore.Bookstore"
       ]
    }
+}
+      `, fakeProtoDescriptor),
+			},
+		},
+		{
+			Desc: "Success. Generate transcoder filter with the descriptor file instead of protofile",
+			ServiceConfigIn: &confpb.Service{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "foo",
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:            "grpc://127.0.0.0:80",
+				TranscodingDescriptorPath: writeTranscodingFile(t, "api_descriptor.pb", rawDescriptor),
+			},
+			WantFilterConfigs: []string{
+				fmt.Sprintf(`
+{
+   "name":"envoy.filters.http.grpc_json_transcoder",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.grpc_json_transcoder.v3.GrpcJsonTranscoder",
+      "autoMapping":true,
+      "convertGrpcStatus":true,
+      "queryParamUnescapePlus":true,
+      "ignoredQueryParameters":[
+         "api_key",
+         "key"
+      ],
+      "printOptions":{},
+      "protoDescriptorBin":"%s",
+      "services":[
+         "endpoints.examples.bookstore.Bookstore"
+      ]
+   }
+}
+      `, fakeProtoDescriptor),
+			},
+		},
+		{
+			Desc: "Success. Generate transcoder filter with newline-delimited streams for server-sent events",
+			ServiceConfigIn: &confpb.Service{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name:              "foo",
+								ResponseStreaming: true,
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:            "grpc://127.0.0.0:80",
+				TranscodingDescriptorPath: writeTranscodingFile(t, "api_descriptor.pb", rawDescriptor),
+				TranscodingStreamSSE:      true,
+			},
+			WantFilterConfigs: []string{
+				fmt.Sprintf(`
+{
+   "name":"envoy.filters.http.grpc_json_transcoder",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.grpc_json_transcoder.v3.GrpcJsonTranscoder",
+      "autoMapping":true,
+      "convertGrpcStatus":true,
+      "queryParamUnescapePlus":true,
+      "ignoredQueryParameters":[
+         "api_key",
+         "key"
+      ],
+      "printOptions":{
+         "streamNewlineDelimited":true
+      },
+      "protoDescriptorBin":"%s",
+      "services":[
+         "endpoints.examples.bookstore.Bookstore"
+      ]
+   }
 }
       `, fakeProtoDescriptor),
 			},
@@ -448,6 +539,274 @@ func TestNewGRPCTranscoderFilterGensFromOPConfig_GenConfig(t *testing.T) {
 	}
 }
 
+func writeTranscodingFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("fail to write transcoding file: %v", err)
+	}
+	return path
+}
+
+func TestGRPCTranscoderGenerator_GenPerRouteConfig(t *testing.T) {
+	serviceConfig := &confpb.Service{
+		Name: "endpoints.examples.bookstore.Bookstore",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{Name: "ListShelves"},
+					{Name: "GetShelf"},
+					{Name: "StreamShelves", ResponseStreaming: true},
+				},
+			},
+			{
+				Name: "endpoints.examples.bookstore.Library",
+				Methods: []*apipb.Method{
+					{Name: "ListBooks"},
+					{Name: "GetBook"},
+				},
+			},
+		},
+		Backend: &confpb.Backend{
+			Rules: []*confpb.BackendRule{
+				{
+					Selector: "endpoints.examples.bookstore.Library.ListBooks",
+					OverridesByRequestProtocol: map[string]*confpb.BackendRule{
+						"http": {},
+					},
+				},
+			},
+		},
+	}
+
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.BackendAddress = "grpc://127.0.0.0:80"
+	opts.TranscodingAlwaysPrintEnumsAsInts = true
+	opts.TranscodingDescriptorPath = writeTranscodingFile(t, "api_descriptor.pb", []byte{})
+	opts.TranscodingOptionsPath = writeTranscodingFile(t, "transcoding_options.json", []byte(`
+{
+  "options": [
+    {
+      "selectors": ["endpoints.examples.bookstore.Bookstore"],
+      "preserve_proto_field_names": true,
+      "always_print_primitive_fields": true
+    },
+    {
+      "selectors": ["endpoints.examples.bookstore.Bookstore.ListShelves"],
+      "always_print_primitive_fields": false,
+      "always_print_enums_as_ints": false,
+      "query_parameters_disable_unescape_plus": true,
+      "case_insensitive_enum_parsing": true
+    },
+    {
+      "selectors": ["endpoints.examples.bookstore.Bookstore.StreamShelves"],
+      "stream_sse": true
+    },
+    {
+      "selectors": ["endpoints.examples.bookstore.Library"],
+      "ignore_unknown_query_parameters": true
+    },
+    {
+      "selectors": ["endpoints.examples.bookstore.Library.GetBook"],
+      "ignore_unknown_query_parameters": false,
+      "always_print_enums_as_ints": true
+    }
+  ]
+}`))
+
+	gen, err := filtergen.NewGRPCTranscoderFilterGenFromOPConfig(serviceConfig, opts, true)
+	if err != nil {
+		t.Fatalf("NewGRPCTranscoderFilterGenFromOPConfig() got error: %v", err)
+	}
+	// Only the routes of the operations with different options carry a copy
+	// of the proto descriptor.
+	if got, want := len(gen.OptionsBySelector), 3; got != want {
+		t.Errorf("NewGRPCTranscoderFilterGenFromOPConfig() got %d operations with options, want %d", got, want)
+	}
+
+	testData := []struct {
+		desc       string
+		selector   string
+		wantConfig string
+	}{
+		{
+			desc:     "Operation uses the options of its API",
+			selector: "endpoints.examples.bookstore.Bookstore.GetShelf",
+			wantConfig: `
+{
+  "autoMapping": true,
+  "convertGrpcStatus": true,
+  "queryParamUnescapePlus": true,
+  "ignoredQueryParameters": ["api_key", "key"],
+  "printOptions": {
+    "alwaysPrintEnumsAsInts": true,
+    "alwaysPrintPrimitiveFields": true,
+    "preserveProtoFieldNames": true
+  },
+  "protoDescriptorBin": "",
+  "services": [
+    "endpoints.examples.bookstore.Bookstore",
+    "endpoints.examples.bookstore.Library"
+  ]
+}`,
+		},
+		{
+			desc:     "Operation options are merged on top of the API options",
+			selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+			wantConfig: `
+{
+  "autoMapping": true,
+  "caseInsensitiveEnumParsing": true,
+  "convertGrpcStatus": true,
+  "ignoredQueryParameters": ["api_key", "key"],
+  "printOptions": {
+    "preserveProtoFieldNames": true
+  },
+  "protoDescriptorBin": "",
+  "services": [
+    "endpoints.examples.bookstore.Bookstore",
+    "endpoints.examples.bookstore.Library"
+  ]
+}`,
+		},
+		{
+			desc:     "Server-sent events stream newline-delimited messages",
+			selector: "endpoints.examples.bookstore.Bookstore.StreamShelves",
+			wantConfig: `
+{
+  "autoMapping": true,
+  "convertGrpcStatus": true,
+  "queryParamUnescapePlus": true,
+  "ignoredQueryParameters": ["api_key", "key"],
+  "printOptions": {
+    "alwaysPrintEnumsAsInts": true,
+    "alwaysPrintPrimitiveFields": true,
+    "preserveProtoFieldNames": true,
+    "streamNewlineDelimited": true
+  },
+  "protoDescriptorBin": "",
+  "services": [
+    "endpoints.examples.bookstore.Bookstore",
+    "endpoints.examples.bookstore.Library"
+  ]
+}`,
+		},
+		{
+			desc:     "Transcoder is disabled for HTTP backends regardless of options",
+			selector: "endpoints.examples.bookstore.Library.ListBooks",
+			wantConfig: `
+{
+  "protoDescriptor": ""
+}`,
+		},
+		{
+			desc:     "Options equal to the listener-level config are not repeated per route",
+			selector: "endpoints.examples.bookstore.Library.GetBook",
+		},
+		{
+			desc:     "Operation without options uses the listener-level config",
+			selector: "endpoints.examples.bookstore.Other.Foo",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := gen.GenPerRouteConfig(tc.selector, nil)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantConfig == "" {
+				if got != nil {
+					t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewGRPCTranscoderFilterGensFromOPConfig_FactoryError(t *testing.T) {
+	serviceConfig := &confpb.Service{
+		Name: "endpoints.examples.bookstore.Bookstore",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{Name: "foo"},
+				},
+			},
+		},
+	}
+
+	testData := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Missing descriptor file",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:            "grpc://127.0.0.0:80",
+				TranscodingDescriptorPath: "/does/not/exist.pb",
+			},
+			WantFactoryError: "fail to read transcoding descriptor file",
+		},
+		{
+			Desc:            "Invalid descriptor file",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:            "grpc://127.0.0.0:80",
+				TranscodingDescriptorPath: writeTranscodingFile(t, "api_descriptor.pb", []byte("invalid proto descriptor")),
+			},
+			WantFactoryError: "failed to unmarshal proto descriptor",
+		},
+		{
+			Desc:            "Malformed options file",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:            "grpc://127.0.0.0:80",
+				TranscodingDescriptorPath: writeTranscodingFile(t, "api_descriptor.pb", []byte{}),
+				TranscodingOptionsPath:    writeTranscodingFile(t, "transcoding_options.json", []byte(`{"options": {}}`)),
+			},
+			WantFactoryError: "fail to parse transcoding options file",
+		},
+		{
+			Desc:            "Options without selectors",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:            "grpc://127.0.0.0:80",
+				TranscodingDescriptorPath: writeTranscodingFile(t, "api_descriptor.pb", []byte{}),
+				TranscodingOptionsPath:    writeTranscodingFile(t, "transcoding_options.json", []byte(`{"options": [{"preserve_proto_field_names": true}]}`)),
+			},
+			WantFactoryError: "invalid transcoding options at index 0: selectors must not be empty",
+		},
+		{
+			Desc:            "Selector in more than one options",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:            "grpc://127.0.0.0:80",
+				TranscodingDescriptorPath: writeTranscodingFile(t, "api_descriptor.pb", []byte{}),
+				TranscodingOptionsPath: writeTranscodingFile(t, "transcoding_options.json", []byte(`
+{"options": [
+  {"selectors": ["endpoints.examples.bookstore.Bookstore.foo"], "preserve_proto_field_names": true},
+  {"selectors": ["endpoints.examples.bookstore.Bookstore.foo"], "always_print_primitive_fields": true}
+]}`)),
+			},
+			WantFactoryError: `selector "endpoints.examples.bookstore.Bookstore.foo" is used by more than one transcoding options`,
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, filtergen.NewGRPCTranscoderFilterGensFromOPConfig)
+	}
+}
+
 func TestPreserveDefaultHttpBinding(t *testing.T) {
 	testData := []struct {
 		desc             string
@@ -865,6 +1224,81 @@ file: {
 	}
 }
 
+func TestUpdateProtoDescriptorFromOPConfig_RichErrorDetails(t *testing.T) {
+	testData := []struct {
+		desc          string
+		opts          options.ConfigGeneratorOptions
+		inFiles       []string
+		wantFileNames []string
+	}{
+		{
+			desc:          "Error detail types are not added by default",
+			inFiles:       []string{"proto_file_path"},
+			wantFileNames: []string{"proto_file_path"},
+		},
+		{
+			desc: "Error detail types are added after the API files",
+			opts: options.ConfigGeneratorOptions{
+				TranscodingRichErrorDetails: true,
+			},
+			inFiles: []string{"proto_file_path"},
+			wantFileNames: []string{
+				"proto_file_path",
+				"google/protobuf/any.proto",
+				"google/protobuf/duration.proto",
+				"google/rpc/status.proto",
+				"google/rpc/error_details.proto",
+			},
+		},
+		{
+			desc: "Files already in the descriptor are not added again",
+			opts: options.ConfigGeneratorOptions{
+				TranscodingRichErrorDetails: true,
+			},
+			inFiles: []string{"google/protobuf/any.proto", "google/rpc/status.proto", "proto_file_path"},
+			wantFileNames: []string{
+				"google/protobuf/any.proto",
+				"google/rpc/status.proto",
+				"proto_file_path",
+				"google/protobuf/duration.proto",
+				"google/rpc/error_details.proto",
+			},
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			fds := &descpb.FileDescriptorSet{}
+			for _, name := range tc.inFiles {
+				fds.File = append(fds.File, &descpb.FileDescriptorProto{
+					Name: proto.String(name),
+				})
+			}
+			byteDesc, err := proto.Marshal(fds)
+			if err != nil {
+				t.Fatalf("failed to marshal descriptor: %v", err)
+			}
+
+			gotByteDesc, err := filtergen.UpdateProtoDescriptorFromOPConfig(&confpb.Service{}, tc.opts, byteDesc)
+			if err != nil {
+				t.Fatalf("UpdateProtoDescriptorFromOPConfig() got error: %v", err)
+			}
+
+			got := &descpb.FileDescriptorSet{}
+			if err := proto.Unmarshal(gotByteDesc, got); err != nil {
+				t.Fatalf("failed to unmarshal descriptor: %v", err)
+			}
+			var gotFileNames []string
+			for _, file := range got.GetFile() {
+				gotFileNames = append(gotFileNames, file.GetName())
+			}
+			if diff := cmp.Diff(tc.wantFileNames, gotFileNames); diff != "" {
+				t.Errorf("UpdateProtoDescriptorFromOPConfig() got unexpected files, diff (-want +got):\n%v", diff)
+			}
+		})
+	}
+}
+
 func TestGetIgnoredQueryParamsFromOPConfig(t *testing.T) {
 	testData := []struct {
 		desc            string
@@ -27,14 +27,25 @@ const (
 )
 
 type HeaderSanitizerGenerator struct {
+	// InternalVaryHeaders are removed from the Vary response header, see
+	// HTTPCacheGenerator.
+	InternalVaryHeaders []string
+
 	NoopFilterGenerator
 }
 
 // NewHeaderSanitizerFilterGensFromOPConfig creates a HeaderSanitizerGenerator from
 // OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
 func NewHeaderSanitizerFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	internalVaryHeaders, err := HTTPCacheInternalVaryHeaders(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	return []FilterGenerator{
-		&HeaderSanitizerGenerator{},
+		&HeaderSanitizerGenerator{
+			InternalVaryHeaders: internalVaryHeaders,
+		},
 	}, nil
 }
 
@@ -43,5 +54,7 @@ func (g *HeaderSanitizerGenerator) FilterName() string {
 }
 
 func (g *HeaderSanitizerGenerator) GenFilterConfig() (proto.Message, error) {
-	return &hspb.FilterConfig{}, nil
+	return &hspb.FilterConfig{
+		InternalVaryHeaders: g.InternalVaryHeaders,
+	}, nil
 }
@@ -0,0 +1,88 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+func TestNewHeaderSanitizerFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Default config",
+			ServiceConfigIn: httpCacheTestServiceConfig(),
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.header_sanitizer",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.header_sanitizer.FilterConfig"
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "Identity headers of private cached operations are internal",
+			ServiceConfigIn: httpCacheTestServiceConfigWithAPIKeyHeader(),
+			OptsIn: options.ConfigGeneratorOptions{
+				HTTPCacheOperations:       "bookstore.Bookstore.GetShelf,bookstore.Bookstore.ListShelves",
+				HTTPCachePublicOperations: "bookstore.Bookstore.ListShelves",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.header_sanitizer",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.header_sanitizer.FilterConfig",
+      "internalVaryHeaders":[
+         "Authorization",
+         "X-Goog-Iap-Jwt-Assertion",
+         "X-Tenant-Key",
+         "X-Endpoint-API-UserInfo"
+      ]
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "No internal Vary headers when all cached operations are public",
+			ServiceConfigIn: httpCacheTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				HTTPCacheOperations:       "bookstore.Bookstore.GetShelf",
+				HTTPCachePublicOperations: "bookstore.Bookstore.GetShelf",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.header_sanitizer",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.header_sanitizer.FilterConfig"
+   }
+}
+`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewHeaderSanitizerFilterGensFromOPConfig)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+)
+
+// CORSPolicy overrides the global CORS flags for a set of APIs or operations.
+// Unset fields fall back to the global CORS flags.
+type CORSPolicy struct {
+	// Selectors are operation selectors or API names the policy applies to.
+	// A policy for an operation takes precedence over a policy for its API.
+	Selectors []string `json:"selectors"`
+
+	// Disabled turns off CORS for the operations. No other field may be set.
+	Disabled bool `json:"disabled"`
+
+	// AllowOrigins are the exact origins allowed, "*" allows all origins.
+	AllowOrigins []string `json:"allow_origins"`
+
+	// AllowOriginRegexes are the regular expressions of the origins allowed.
+	AllowOriginRegexes []string `json:"allow_origin_regexes"`
+
+	AllowMethods     string `json:"allow_methods"`
+	AllowHeaders     string `json:"allow_headers"`
+	ExposeHeaders    string `json:"expose_headers"`
+	MaxAgeSeconds    *int64 `json:"max_age_seconds"`
+	AllowCredentials *bool  `json:"allow_credentials"`
+}
+
+type corsPoliciesFile struct {
+	Policies []*CORSPolicy `json:"policies"`
+}
+
+// ReadCORSPoliciesFromOPConfig reads and validates the policies file at
+// `--cors_policies_path`.
+//
+// The file is JSON in the format of:
+//
+//	{
+//	  "policies": [
+//	    {
+//	      "selectors": ["bookstore.Bookstore"],
+//	      "allow_origins": ["https://a.example.com", "https://b.example.com"],
+//	      "allow_origin_regexes": ["https://.*\\.example\\.org"],
+//	      "allow_methods": "GET,POST",
+//	      "allow_headers": "Authorization,Content-Type",
+//	      "expose_headers": "Content-Length",
+//	      "max_age_seconds": 600,
+//	      "allow_credentials": true
+//	    },
+//	    {
+//	      "selectors": ["bookstore.Bookstore.DeleteShelf"],
+//	      "disabled": true
+//	    }
+//	  ]
+//	}
+func ReadCORSPoliciesFromOPConfig(opts options.ConfigGeneratorOptions) ([]*CORSPolicy, error) {
+	if opts.CorsPoliciesPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(opts.CorsPoliciesPath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read CORS policies file: %v", err)
+	}
+
+	var file corsPoliciesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("fail to parse CORS policies file %q: %v", opts.CorsPoliciesPath, err)
+	}
+
+	for i, policy := range file.Policies {
+		if policy == nil {
+			return nil, fmt.Errorf("invalid CORS policy at index %d: policy must not be empty", i)
+		}
+		if err := policy.validate(); err != nil {
+			return nil, fmt.Errorf("invalid CORS policy at index %d: %v", i, err)
+		}
+	}
+	return file.Policies, nil
+}
+
+func (p *CORSPolicy) validate() error {
+	if len(p.Selectors) == 0 {
+		return fmt.Errorf("selectors must not be empty")
+	}
+	if p.Disabled {
+		if len(p.AllowOrigins) > 0 || len(p.AllowOriginRegexes) > 0 || p.AllowMethods != "" || p.AllowHeaders != "" ||
+			p.ExposeHeaders != "" || p.MaxAgeSeconds != nil || p.AllowCredentials != nil {
+			return fmt.Errorf("a disabled policy must not set any other field")
+		}
+		return nil
+	}
+	for _, origin := range p.AllowOrigins {
+		if origin == "" {
+			return fmt.Errorf("allow_origins must not contain an empty origin")
+		}
+	}
+	for _, regex := range p.AllowOriginRegexes {
+		if regex == "" {
+			return fmt.Errorf("allow_origin_regexes must not contain an empty regex")
+		}
+		if err := util.ValidateRegexProgramSize(regex, util.GoogleRE2MaxProgramSize); err != nil {
+			return fmt.Errorf("invalid allow_origin_regexes %q: %v", regex, err)
+		}
+	}
+	if p.MaxAgeSeconds != nil && *p.MaxAgeSeconds < 0 {
+		return fmt.Errorf("max_age_seconds must not be negative, got %d", *p.MaxAgeSeconds)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"google.golang.org/grpc/codes"
+)
+
+// FaultInjectionPolicy injects delays and aborts into the requests of a set of
+// APIs or operations.
+type FaultInjectionPolicy struct {
+	// Selectors are operation selectors or API names the policy applies to.
+	// A policy for an operation takes precedence over a policy for its API.
+	Selectors []string `json:"selectors"`
+
+	// Header limits the faults to requests with this header. If unset, faults
+	// are injected into all requests.
+	Header string `json:"header"`
+
+	// HeaderValue limits the faults to requests where Header has this exact
+	// value. Requires Header.
+	HeaderValue string `json:"header_value"`
+
+	Delay *FaultDelay `json:"delay"`
+	Abort *FaultAbort `json:"abort"`
+}
+
+// FaultDelay delays a percentage of the requests by a fixed duration.
+type FaultDelay struct {
+	// Duration is the delay in Go duration format, e.g. "1.5s".
+	Duration string `json:"duration"`
+
+	// Percentage is the percentage of requests to delay, from 0 to 100.
+	// Defaults to 100.
+	Percentage *float64 `json:"percentage"`
+}
+
+// FaultAbort aborts a percentage of the requests with an HTTP status or, for
+// gRPC methods, a gRPC status. Exactly one of the statuses must be set.
+type FaultAbort struct {
+	HTTPStatus *uint32 `json:"http_status"`
+	GRPCStatus *uint32 `json:"grpc_status"`
+
+	// Percentage is the percentage of requests to abort, from 0 to 100.
+	// Defaults to 100.
+	Percentage *float64 `json:"percentage"`
+}
+
+type faultInjectionPoliciesFile struct {
+	Policies []*FaultInjectionPolicy `json:"policies"`
+}
+
+// ReadFaultInjectionPoliciesFromOPConfig reads and validates the policies
+// file at `--fault_injection_policies_path`.
+//
+// The file is JSON in the format of:
+//
+//	{
+//	  "policies": [
+//	    {
+//	      "selectors": ["bookstore.Bookstore"],
+//	      "header": "x-chaos-test",
+//	      "delay": {"duration": "2s", "percentage": 50}
+//	    },
+//	    {
+//	      "selectors": ["bookstore.Bookstore.GetShelf"],
+//	      "abort": {"grpc_status": 14, "percentage": 10}
+//	    }
+//	  ]
+//	}
+func ReadFaultInjectionPoliciesFromOPConfig(opts options.ConfigGeneratorOptions) ([]*FaultInjectionPolicy, error) {
+	if opts.FaultInjectionPoliciesPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(opts.FaultInjectionPoliciesPath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read fault injection policies file: %v", err)
+	}
+
+	var file faultInjectionPoliciesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("fail to parse fault injection policies file %q: %v", opts.FaultInjectionPoliciesPath, err)
+	}
+
+	for i, policy := range file.Policies {
+		if policy == nil {
+			return nil, fmt.Errorf("invalid fault injection policy at index %d: policy must not be empty", i)
+		}
+		if err := policy.validate(); err != nil {
+			return nil, fmt.Errorf("invalid fault injection policy at index %d: %v", i, err)
+		}
+	}
+	return file.Policies, nil
+}
+
+func (p *FaultInjectionPolicy) validate() error {
+	if len(p.Selectors) == 0 {
+		return fmt.Errorf("selectors must not be empty")
+	}
+	if p.Delay == nil && p.Abort == nil {
+		return fmt.Errorf("at least one of delay and abort must be set")
+	}
+	if p.HeaderValue != "" && p.Header == "" {
+		return fmt.Errorf("header_value requires header")
+	}
+
+	if p.Delay != nil {
+		duration, err := time.ParseDuration(p.Delay.Duration)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("invalid delay duration %q, it must be a positive duration such as \"1.5s\"", p.Delay.Duration)
+		}
+		if err := validateFaultPercentage(p.Delay.Percentage); err != nil {
+			return fmt.Errorf("invalid delay: %v", err)
+		}
+	}
+
+	if p.Abort != nil {
+		switch {
+		case (p.Abort.HTTPStatus == nil) == (p.Abort.GRPCStatus == nil):
+			return fmt.Errorf("exactly one of abort http_status and grpc_status must be set")
+		case p.Abort.HTTPStatus != nil && (*p.Abort.HTTPStatus < 200 || *p.Abort.HTTPStatus >= 600):
+			return fmt.Errorf("invalid abort http_status %d, it must be in the range [200, 600)", *p.Abort.HTTPStatus)
+		case p.Abort.GRPCStatus != nil && (*p.Abort.GRPCStatus == uint32(codes.OK) || *p.Abort.GRPCStatus > uint32(codes.Unauthenticated)):
+			return fmt.Errorf("invalid abort grpc_status %d, it must be a non-OK gRPC status code in the range [1, 16]", *p.Abort.GRPCStatus)
+		}
+		if err := validateFaultPercentage(p.Abort.Percentage); err != nil {
+			return fmt.Errorf("invalid abort: %v", err)
+		}
+	}
+	return nil
+}
+
+func validateFaultPercentage(percentage *float64) error {
+	if percentage != nil && (*percentage < 0 || *percentage > 100) {
+		return fmt.Errorf("percentage must be in the range [0, 100], got %v", *percentage)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// JwtProviderOptions extends the validation of a service config AuthProvider
+// beyond what the service config supports.
+type JwtProviderOptions struct {
+	// ClockSkewSeconds is the tolerance when checking `exp` and `nbf`.
+	ClockSkewSeconds uint32 `json:"clock_skew_seconds"`
+
+	// MaxLifetimeSeconds rejects tokens whose `exp` is more than this after
+	// their `iat`.
+	MaxLifetimeSeconds int64 `json:"max_lifetime_seconds"`
+
+	// IssuerRegex accepts any issuer matching the regex instead of the exact
+	// issuer in the service config.
+	IssuerRegex string `json:"issuer_regex"`
+
+	// RequiredClaims must be present in the token payload.
+	RequiredClaims []string `json:"required_claims"`
+
+	// ClaimConstraints maps top-level string claims to their constraint.
+	ClaimConstraints map[string]*ClaimConstraint `json:"claim_constraints"`
+}
+
+// ClaimConstraint is a constraint on the value of a string claim. Exactly one
+// of the fields must be set.
+type ClaimConstraint struct {
+	Exact string `json:"exact"`
+	Regex string `json:"regex"`
+}
+
+// NeedsClaimCheck returns true if the options cannot be enforced by the JWT
+// authn filter alone.
+func (o *JwtProviderOptions) NeedsClaimCheck() bool {
+	return o.MaxLifetimeSeconds > 0 || o.IssuerRegex != "" || len(o.RequiredClaims) > 0 || len(o.ClaimConstraints) > 0
+}
+
+type jwtProviderOptionsFile struct {
+	Providers map[string]*JwtProviderOptions `json:"providers"`
+}
+
+// GetJwtProviderOptionsFromOPConfig reads the options file at
+// `--jwt_provider_options_path`, keyed by provider ID.
+//
+// The file is JSON in the format of:
+//
+//	{
+//	  "providers": {
+//	    "<provider_id>": {
+//	      "clock_skew_seconds": 30,
+//	      "max_lifetime_seconds": 3600,
+//	      "issuer_regex": "https://login\\.example\\.com/.*",
+//	      "required_claims": ["email"],
+//	      "claim_constraints": {
+//	        "hd": {"exact": "example.com"}
+//	      }
+//	    }
+//	  }
+//	}
+func GetJwtProviderOptionsFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (map[string]*JwtProviderOptions, error) {
+	if opts.JwtProviderOptionsPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(opts.JwtProviderOptionsPath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read JWT provider options file: %v", err)
+	}
+
+	var file jwtProviderOptionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("fail to parse JWT provider options file %q: %v", opts.JwtProviderOptionsPath, err)
+	}
+
+	providerIDs := make(map[string]bool)
+	for _, provider := range serviceConfig.GetAuthentication().GetProviders() {
+		providerIDs[provider.GetId()] = true
+	}
+
+	providerOptions := make(map[string]*JwtProviderOptions)
+	for id, o := range file.Providers {
+		if !providerIDs[id] {
+			glog.Warningf("Ignoring JWT provider options for %q because there is no authentication provider with that ID.", id)
+			continue
+		}
+		if err := o.validate(); err != nil {
+			return nil, fmt.Errorf("invalid JWT provider options for %q: %v", id, err)
+		}
+		providerOptions[id] = o
+	}
+	return providerOptions, nil
+}
+
+func (o *JwtProviderOptions) validate() error {
+	if o.MaxLifetimeSeconds < 0 {
+		return fmt.Errorf("max_lifetime_seconds must not be negative, got %d", o.MaxLifetimeSeconds)
+	}
+	if o.IssuerRegex != "" {
+		if err := util.ValidateRegexProgramSize(o.IssuerRegex, util.GoogleRE2MaxProgramSize); err != nil {
+			return fmt.Errorf("invalid issuer_regex %q: %v", o.IssuerRegex, err)
+		}
+	}
+	for _, claim := range o.RequiredClaims {
+		if claim == "" {
+			return fmt.Errorf("required_claims must not contain an empty claim")
+		}
+	}
+	for claim, c := range o.ClaimConstraints {
+		if claim == "" || c == nil {
+			return fmt.Errorf("claim_constraints must not contain an empty claim or constraint")
+		}
+		if (c.Exact == "") == (c.Regex == "") {
+			return fmt.Errorf("claim constraint for %q must set exactly one of exact or regex", claim)
+		}
+		if c.Regex != "" {
+			if err := util.ValidateRegexProgramSize(c.Regex, util.GoogleRE2MaxProgramSize); err != nil {
+				return fmt.Errorf("invalid regex %q for claim %q: %v", c.Regex, claim, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+const (
+	// GoogleRPCStatusPreset formats local replies like the JSON errors of
+	// Google APIs, a google.rpc.Status with the request ID in its details.
+	// Errors of transcoded gRPC calls are not local replies, their body is the
+	// bare google.rpc.Status, so the preset cannot be used together with
+	// `--transcoding_rich_error_details`.
+	GoogleRPCStatusPreset = "google_rpc_status"
+)
+
+// LocalReplyOptions customize the bodies of the replies generated by ESPv2
+// and Envoy, e.g. for rejected requests and upstream failures.
+type LocalReplyOptions struct {
+	// BodyFormat replaces the default JSON body of all local replies.
+	BodyFormat *LocalReplyBodyFormat `json:"body_format"`
+
+	// Formats override BodyFormat for some status codes or clients. The first
+	// matching format is used.
+	Formats []*LocalReplyFormat `json:"formats"`
+
+	// Mappers rewrite some local replies. The first matching mapper is used.
+	Mappers []*LocalReplyMapper `json:"mappers"`
+}
+
+// LocalReplyBodyFormat is a body template with Envoy command operators, such
+// as %LOCAL_REPLY_BODY% and %RESPONSE_CODE%. Exactly one of TextFormat,
+// JSONFormat and Preset must be set.
+type LocalReplyBodyFormat struct {
+	TextFormat string                 `json:"text_format"`
+	JSONFormat map[string]interface{} `json:"json_format"`
+	Preset     string                 `json:"preset"`
+
+	// ContentType is the content type of replies in TextFormat, e.g.
+	// "text/html; charset=UTF-8". Defaults to "text/plain".
+	ContentType string `json:"content_type"`
+}
+
+// LocalReplyFormat is the body format of the local replies with some status
+// codes, or for clients that accept some content type.
+type LocalReplyFormat struct {
+	StatusCodes *StatusCodeRange `json:"status_codes"`
+
+	// Accept matches requests whose Accept header contains this content type.
+	Accept string `json:"accept"`
+
+	BodyFormat *LocalReplyBodyFormat `json:"body_format"`
+}
+
+// LocalReplyMapper rewrites the status code, body and headers of the local
+// replies with some status codes or Envoy response flags, e.g. "UH" for no
+// healthy upstream.
+type LocalReplyMapper struct {
+	StatusCodes   *StatusCodeRange `json:"status_codes"`
+	ResponseFlags []string         `json:"response_flags"`
+
+	NewStatusCode uint32            `json:"new_status_code"`
+	Body          string            `json:"body"`
+	HeadersToAdd  map[string]string `json:"headers_to_add"`
+}
+
+// StatusCodeRange is an inclusive range of HTTP status codes.
+type StatusCodeRange struct {
+	Min uint32 `json:"min"`
+	Max uint32 `json:"max"`
+}
+
+// NewLocalReplyOptionsFromOPConfig reads the local reply config file at
+// `--local_reply_config_path`.
+//
+// The file is JSON in the format of:
+//
+//	{
+//	  "body_format": {"preset": "google_rpc_status"},
+//	  "formats": [
+//	    {
+//	      "status_codes": {"min": 400, "max": 599},
+//	      "accept": "text/html",
+//	      "body_format": {
+//	        "text_format": "<html><body><h1>%RESPONSE_CODE%</h1><p>%LOCAL_REPLY_BODY%</p></body></html>",
+//	        "content_type": "text/html; charset=UTF-8"
+//	      }
+//	    }
+//	  ],
+//	  "mappers": [
+//	    {
+//	      "status_codes": {"min": 503, "max": 503},
+//	      "response_flags": ["UH"],
+//	      "body": "The service is temporarily unavailable, please retry later.",
+//	      "headers_to_add": {"retry-after": "5"}
+//	    }
+//	  ]
+//	}
+func NewLocalReplyOptionsFromOPConfig(opts options.ConfigGeneratorOptions) (*LocalReplyOptions, error) {
+	if opts.LocalReplyConfigPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(opts.LocalReplyConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read local reply config file: %v", err)
+	}
+
+	var localReplyOptions LocalReplyOptions
+	if err := json.Unmarshal(data, &localReplyOptions); err != nil {
+		return nil, fmt.Errorf("fail to parse local reply config file %q: %v", opts.LocalReplyConfigPath, err)
+	}
+
+	if localReplyOptions.BodyFormat != nil {
+		if err := localReplyOptions.BodyFormat.validate(); err != nil {
+			return nil, fmt.Errorf("invalid local reply body_format: %v", err)
+		}
+	}
+	for i, format := range localReplyOptions.Formats {
+		if format == nil {
+			return nil, fmt.Errorf("invalid local reply format at index %d: format must not be empty", i)
+		}
+		if err := format.validate(); err != nil {
+			return nil, fmt.Errorf("invalid local reply format at index %d: %v", i, err)
+		}
+	}
+	for i, mapper := range localReplyOptions.Mappers {
+		if mapper == nil {
+			return nil, fmt.Errorf("invalid local reply mapper at index %d: mapper must not be empty", i)
+		}
+		if err := mapper.validate(); err != nil {
+			return nil, fmt.Errorf("invalid local reply mapper at index %d: %v", i, err)
+		}
+	}
+	if opts.TranscodingRichErrorDetails && localReplyOptions.usesPreset(GoogleRPCStatusPreset) {
+		return nil, fmt.Errorf("local reply preset %q cannot be used together with --transcoding_rich_error_details, the errors of transcoded gRPC calls are not wrapped in its format", GoogleRPCStatusPreset)
+	}
+	return &localReplyOptions, nil
+}
+
+func (o *LocalReplyOptions) usesPreset(preset string) bool {
+	if o.BodyFormat != nil && o.BodyFormat.Preset == preset {
+		return true
+	}
+	for _, format := range o.Formats {
+		if format.BodyFormat.Preset == preset {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *LocalReplyBodyFormat) validate() error {
+	set := 0
+	for _, isSet := range []bool{f.TextFormat != "", f.JSONFormat != nil, f.Preset != ""} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("exactly one of text_format, json_format and preset must be set")
+	}
+	if f.Preset != "" && f.Preset != GoogleRPCStatusPreset {
+		return fmt.Errorf("unknown preset %q, it must be %q", f.Preset, GoogleRPCStatusPreset)
+	}
+	if f.ContentType != "" && f.TextFormat == "" {
+		return fmt.Errorf("content_type can only be used together with text_format")
+	}
+	return nil
+}
+
+func (f *LocalReplyFormat) validate() error {
+	if f.StatusCodes == nil && f.Accept == "" {
+		return fmt.Errorf("at least one of status_codes and accept must be set")
+	}
+	if f.StatusCodes != nil {
+		if err := f.StatusCodes.validate(); err != nil {
+			return err
+		}
+	}
+	if f.BodyFormat == nil {
+		return fmt.Errorf("body_format must be set")
+	}
+	return f.BodyFormat.validate()
+}
+
+func (m *LocalReplyMapper) validate() error {
+	if m.StatusCodes == nil && len(m.ResponseFlags) == 0 {
+		return fmt.Errorf("at least one of status_codes and response_flags must be set")
+	}
+	if m.StatusCodes != nil {
+		if err := m.StatusCodes.validate(); err != nil {
+			return err
+		}
+	}
+	if m.NewStatusCode == 0 && m.Body == "" && len(m.HeadersToAdd) == 0 {
+		return fmt.Errorf("at least one of new_status_code, body and headers_to_add must be set")
+	}
+	if m.NewStatusCode != 0 && (m.NewStatusCode < 200 || m.NewStatusCode >= 600) {
+		return fmt.Errorf("invalid new_status_code %d, it must be in the range [200, 600)", m.NewStatusCode)
+	}
+	for key := range m.HeadersToAdd {
+		if key == "" || strings.HasPrefix(key, ":") {
+			return fmt.Errorf("invalid header %q in headers_to_add", key)
+		}
+	}
+	return nil
+}
+
+func (r *StatusCodeRange) validate() error {
+	if r.Min < 100 || r.Max >= 600 || r.Min > r.Max {
+		return fmt.Errorf("invalid status_codes {min: %d, max: %d}, it must be a range within [100, 600)", r.Min, r.Max)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+)
+
+// TranscodingOptions override the global `--transcoding_*` flags for a set of
+// APIs or operations. Unset fields keep the value of the flag.
+type TranscodingOptions struct {
+	// Selectors are operation selectors or API names the options apply to.
+	// Options for an operation take precedence over options for its API.
+	Selectors []string `json:"selectors"`
+
+	AlwaysPrintPrimitiveFields         *bool `json:"always_print_primitive_fields"`
+	AlwaysPrintEnumsAsInts             *bool `json:"always_print_enums_as_ints"`
+	PreserveProtoFieldNames            *bool `json:"preserve_proto_field_names"`
+	StreamNewlineDelimited             *bool `json:"stream_newline_delimited"`
+	IgnoreUnknownQueryParameters       *bool `json:"ignore_unknown_query_parameters"`
+	QueryParametersDisableUnescapePlus *bool `json:"query_parameters_disable_unescape_plus"`
+	MatchUnregisteredCustomVerb        *bool `json:"match_unregistered_custom_verb"`
+	CaseInsensitiveEnumParsing         *bool `json:"case_insensitive_enum_parsing"`
+
+	// StreamSSE frames the responses of server-streaming methods as
+	// server-sent events.
+	StreamSSE *bool `json:"stream_sse"`
+}
+
+type transcodingOptionsFile struct {
+	Options []*TranscodingOptions `json:"options"`
+}
+
+// ReadTranscodingOptionsFromOPConfig reads and validates the options file at
+// `--transcoding_options_path`.
+//
+// The file is JSON in the format of:
+//
+//	{
+//	  "options": [
+//	    {
+//	      "selectors": ["bookstore.Bookstore"],
+//	      "preserve_proto_field_names": true,
+//	      "always_print_primitive_fields": true
+//	    },
+//	    {
+//	      "selectors": ["bookstore.Bookstore.ListShelves"],
+//	      "always_print_primitive_fields": false
+//	    }
+//	  ]
+//	}
+func ReadTranscodingOptionsFromOPConfig(opts options.ConfigGeneratorOptions) ([]*TranscodingOptions, error) {
+	if opts.TranscodingOptionsPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(opts.TranscodingOptionsPath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read transcoding options file: %v", err)
+	}
+
+	var file transcodingOptionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("fail to parse transcoding options file %q: %v", opts.TranscodingOptionsPath, err)
+	}
+
+	for i, o := range file.Options {
+		if o == nil || len(o.Selectors) == 0 {
+			return nil, fmt.Errorf("invalid transcoding options at index %d: selectors must not be empty", i)
+		}
+	}
+	return file.Options, nil
+}
+
+// MergeTranscodingOptions merges the options in order, a field set by later
+// options overwrites the same field of earlier options.
+func MergeTranscodingOptions(options ...*TranscodingOptions) *TranscodingOptions {
+	merged := &TranscodingOptions{}
+	for _, o := range options {
+		merged.merge(o)
+	}
+	return merged
+}
+
+// merge overwrites the fields of o with the fields set in other.
+func (o *TranscodingOptions) merge(other *TranscodingOptions) {
+	mergeBool := func(dst **bool, src *bool) {
+		if src != nil {
+			*dst = src
+		}
+	}
+	mergeBool(&o.AlwaysPrintPrimitiveFields, other.AlwaysPrintPrimitiveFields)
+	mergeBool(&o.AlwaysPrintEnumsAsInts, other.AlwaysPrintEnumsAsInts)
+	mergeBool(&o.PreserveProtoFieldNames, other.PreserveProtoFieldNames)
+	mergeBool(&o.StreamNewlineDelimited, other.StreamNewlineDelimited)
+	mergeBool(&o.IgnoreUnknownQueryParameters, other.IgnoreUnknownQueryParameters)
+	mergeBool(&o.QueryParametersDisableUnescapePlus, other.QueryParametersDisableUnescapePlus)
+	mergeBool(&o.MatchUnregisteredCustomVerb, other.MatchUnregisteredCustomVerb)
+	mergeBool(&o.CaseInsensitiveEnumParsing, other.CaseInsensitiveEnumParsing)
+	mergeBool(&o.StreamSSE, other.StreamSSE)
+}
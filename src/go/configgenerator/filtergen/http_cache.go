@@ -0,0 +1,260 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	scpb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/service_control"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	cachepb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/cache/v3"
+	simplecachepb "github.com/envoyproxy/go-control-plane/envoy/extensions/http/cache/simple_http_cache/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/glog"
+	annotationspb "google.golang.org/genproto/googleapis/api/annotations"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// EnvoyHTTPCacheFilterName is the Envoy filter name for debug logging.
+	EnvoyHTTPCacheFilterName = "envoy.filters.http.cache"
+)
+
+// httpCacheContentVaryHeaders are the request headers that backends commonly
+// vary responses on. Responses that vary on other headers are not cached.
+var httpCacheContentVaryHeaders = []string{
+	"Accept",
+	"Accept-Encoding",
+	"Accept-Language",
+	"Origin",
+}
+
+// HTTPCacheGenerator caches the responses of GET operations in memory. The
+// backend controls caching with the Cache-Control and Vary response headers.
+//
+// Responses of operations that are not public are only served to the same
+// consumer, the routes of those operations add the consumer identity headers
+// to the Vary response header. The header sanitizer filter removes them again
+// before the response leaves the proxy.
+//
+// Operations that require JWT authentication cannot be cached, Envoy never
+// caches requests with an Authorization header.
+type HTTPCacheGenerator struct {
+	// CachedOperations are the selectors of the operations to cache.
+	CachedOperations map[string]bool
+
+	// IdentityHeaders are the request headers that identify the consumer.
+	IdentityHeaders []string
+
+	MaxBodyBytes uint32
+
+	NoopFilterGenerator
+}
+
+// NewHTTPCacheFilterGensFromOPConfig creates a HTTPCacheGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewHTTPCacheFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	cached, public, err := ParseHTTPCacheOperations(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(cached) == 0 {
+		glog.Info("Not adding HTTP cache filter gen because there are no operations to cache.")
+		return nil, nil
+	}
+
+	getSelectors := make(map[string]bool)
+	for _, rule := range serviceConfig.GetHttp().GetRules() {
+		for _, binding := range append([]*annotationspb.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			if binding.GetGet() != "" {
+				getSelectors[rule.GetSelector()] = true
+			}
+		}
+	}
+	for selector := range cached {
+		if !getSelectors[selector] {
+			return nil, fmt.Errorf("operation %q in http_cache_operations cannot be cached because it has no GET HTTP rule", selector)
+		}
+	}
+	for _, rule := range serviceConfig.GetAuthentication().GetRules() {
+		// Envoy never caches requests with an Authorization header, so the
+		// responses of operations that require a JWT would never be cached.
+		if cached[rule.GetSelector()] && len(rule.GetRequirements()) > 0 && !rule.GetAllowWithoutCredential() {
+			return nil, fmt.Errorf("operation %q in http_cache_operations cannot be cached because it requires JWT authentication", rule.GetSelector())
+		}
+	}
+
+	identityHeaders, err := HTTPCacheIdentityHeaders(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(public) > 0 {
+		glog.Infof("Responses of %d public operations are shared across consumers by the HTTP cache.", len(public))
+	}
+
+	return []FilterGenerator{
+		&HTTPCacheGenerator{
+			CachedOperations: cached,
+			IdentityHeaders:  identityHeaders,
+			MaxBodyBytes:     uint32(opts.HTTPCacheMaxBodyBytes),
+		},
+	}, nil
+}
+
+func (g *HTTPCacheGenerator) FilterName() string {
+	return EnvoyHTTPCacheFilterName
+}
+
+func (g *HTTPCacheGenerator) GenFilterConfig() (proto.Message, error) {
+	cache, err := anypb.New(&simplecachepb.SimpleHttpCacheConfig{})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling simple HTTP cache config to Any: %v", err)
+	}
+
+	var allowedVaryHeaders []*matcherpb.StringMatcher
+	for _, header := range append(append([]string{}, httpCacheContentVaryHeaders...), g.IdentityHeaders...) {
+		allowedVaryHeaders = append(allowedVaryHeaders, &matcherpb.StringMatcher{
+			MatchPattern: &matcherpb.StringMatcher_Exact{
+				Exact: strings.ToLower(header),
+			},
+		})
+	}
+
+	return &cachepb.CacheConfig{
+		TypedConfig:        cache,
+		AllowedVaryHeaders: allowedVaryHeaders,
+		MaxBodyBytes:       g.MaxBodyBytes,
+	}, nil
+}
+
+// GenPerRouteConfig disables the filter on all routes except the GET routes
+// of the cached operations.
+func (g *HTTPCacheGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	if g.CachedOperations[selector] && httpRule != nil && httpRule.HttpMethod == http.MethodGet {
+		return nil, nil
+	}
+	return &routepb.FilterConfig{
+		Disabled: true,
+	}, nil
+}
+
+// ParseHTTPCacheOperations parses the operations to cache and the subset of
+// them whose responses are shared across consumers.
+func ParseHTTPCacheOperations(opts options.ConfigGeneratorOptions) (map[string]bool, map[string]bool, error) {
+	cached := parseHTTPCacheSelectors(opts.HTTPCacheOperations)
+	public := parseHTTPCacheSelectors(opts.HTTPCachePublicOperations)
+	for selector := range public {
+		if !cached[selector] {
+			return nil, nil, fmt.Errorf("operation %q in http_cache_public_operations must also be in http_cache_operations", selector)
+		}
+	}
+	return cached, public, nil
+}
+
+// HTTPCacheInternalVaryHeaders returns the headers that the routes of private
+// cached operations add to the Vary response header, nil if there are none.
+func HTTPCacheInternalVaryHeaders(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]string, error) {
+	cached, public, err := ParseHTTPCacheOperations(opts)
+	if err != nil {
+		return nil, err
+	}
+	for selector := range cached {
+		if !public[selector] {
+			return HTTPCacheIdentityHeaders(serviceConfig, opts)
+		}
+	}
+	return nil, nil
+}
+
+// HTTPCacheIdentityHeaders returns the request headers that identify the
+// consumer of the private cached operations: the JWT headers, the API key
+// headers from the service config system parameters, and the headers ESPv2
+// sets for the backend after verifying them.
+//
+// API keys in query parameters are already part of the cache key. Operations
+// whose API key is read from any other location cannot be cached privately.
+func HTTPCacheIdentityHeaders(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]string, error) {
+	cached, public, err := ParseHTTPCacheOperations(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var private []string
+	for selector := range cached {
+		if !public[selector] {
+			private = append(private, selector)
+		}
+	}
+	sort.Strings(private)
+
+	headers := []string{
+		util.DefaultJwtHeaderNameAuthorization,
+		util.DefaultJwtHeaderNameXGoogleIapJwtAssertion,
+	}
+	seen := make(map[string]bool)
+	apiKeySystemParamsBySelector := GetAPIKeySystemParametersBySelectorFromOPConfig(serviceConfig, opts)
+	for _, selector := range private {
+		locations := ExtractAPIKeyLocations(apiKeySystemParamsBySelector[selector])
+		if len(locations) == 0 {
+			// Same default locations as the Service Control filter.
+			locations = []*scpb.ApiKeyLocation{
+				{
+					Key: &scpb.ApiKeyLocation_Header{
+						Header: util.DefaultApiKeyHeaderName,
+					},
+				},
+			}
+		}
+
+		for _, location := range locations {
+			switch key := location.GetKey().(type) {
+			case *scpb.ApiKeyLocation_Query:
+			case *scpb.ApiKeyLocation_Header:
+				if !seen[strings.ToLower(key.Header)] {
+					seen[strings.ToLower(key.Header)] = true
+					headers = append(headers, key.Header)
+				}
+			default:
+				return nil, fmt.Errorf("operation %q in http_cache_operations cannot be cached because its API key location %v is not a header or query parameter, add it to http_cache_public_operations if its responses can be shared across consumers", selector, location)
+			}
+		}
+	}
+
+	headers = append(headers, opts.GeneratedHeaderPrefix+util.JwtAuthnForwardPayloadHeaderSuffix)
+	if opts.ApiKeyStorePath != "" {
+		// Only the local API key store sets the consumer header.
+		headers = append(headers, opts.GeneratedHeaderPrefix+ApiKeyConsumerHeaderSuffix)
+	}
+	return headers, nil
+}
+
+func parseHTTPCacheSelectors(selectorsStr string) map[string]bool {
+	selectors := make(map[string]bool)
+	for _, selector := range strings.Split(selectorsStr, ",") {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			selectors[selector] = true
+		}
+	}
+	return selectors
+}
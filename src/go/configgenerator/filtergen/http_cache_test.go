@@ -0,0 +1,279 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	annotationspb "google.golang.org/genproto/googleapis/api/annotations"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func httpCacheTestServiceConfig() *servicepb.Service {
+	return &servicepb.Service{
+		Http: &annotationspb.Http{
+			Rules: []*annotationspb.HttpRule{
+				{
+					Selector: "bookstore.Bookstore.GetShelf",
+					Pattern: &annotationspb.HttpRule_Get{
+						Get: "/shelves/{shelf}",
+					},
+				},
+				{
+					Selector: "bookstore.Bookstore.ListShelves",
+					Pattern: &annotationspb.HttpRule_Post{
+						Post: "/shelves:list",
+					},
+					AdditionalBindings: []*annotationspb.HttpRule{
+						{
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/shelves",
+							},
+						},
+					},
+				},
+				{
+					Selector: "bookstore.Bookstore.CreateShelf",
+					Pattern: &annotationspb.HttpRule_Post{
+						Post: "/shelves",
+					},
+				},
+			},
+		},
+	}
+}
+
+func httpCacheTestServiceConfigWithAPIKeyHeader() *servicepb.Service {
+	serviceConfig := httpCacheTestServiceConfig()
+	serviceConfig.SystemParameters = &servicepb.SystemParameters{
+		Rules: []*servicepb.SystemParameterRule{
+			{
+				Selector: "bookstore.Bookstore.GetShelf",
+				Parameters: []*servicepb.SystemParameter{
+					{
+						Name:       "api_key",
+						HttpHeader: "X-Tenant-Key",
+					},
+					{
+						Name:              "api_key",
+						UrlQueryParameter: "tenant_key",
+					},
+				},
+			},
+		},
+	}
+	return serviceConfig
+}
+
+func TestNewHTTPCacheFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate with cached operations",
+			ServiceConfigIn: httpCacheTestServiceConfigWithAPIKeyHeader(),
+			OptsIn: options.ConfigGeneratorOptions{
+				HTTPCacheOperations:   "bookstore.Bookstore.GetShelf,bookstore.Bookstore.ListShelves",
+				HTTPCacheMaxBodyBytes: 65536,
+				ApiKeyStorePath:       "/etc/espv2/api_keys.json",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.cache",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.cache.v3.CacheConfig",
+      "typedConfig":{
+         "@type":"type.googleapis.com/envoy.extensions.http.cache.simple_http_cache.v3.SimpleHttpCacheConfig"
+      },
+      "allowedVaryHeaders":[
+         {"exact":"accept"},
+         {"exact":"accept-encoding"},
+         {"exact":"accept-language"},
+         {"exact":"origin"},
+         {"exact":"authorization"},
+         {"exact":"x-goog-iap-jwt-assertion"},
+         {"exact":"x-tenant-key"},
+         {"exact":"x-api-key"},
+         {"exact":"x-endpoint-api-userinfo"},
+         {"exact":"x-endpoint-api-consumer"}
+      ],
+      "maxBodyBytes":65536
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "Consumer header is only used with the local API key store",
+			ServiceConfigIn: httpCacheTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				HTTPCacheOperations: "bookstore.Bookstore.GetShelf",
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"envoy.filters.http.cache",
+   "typedConfig":{
+      "@type":"type.googleapis.com/envoy.extensions.filters.http.cache.v3.CacheConfig",
+      "typedConfig":{
+         "@type":"type.googleapis.com/envoy.extensions.http.cache.simple_http_cache.v3.SimpleHttpCacheConfig"
+      },
+      "allowedVaryHeaders":[
+         {"exact":"accept"},
+         {"exact":"accept-encoding"},
+         {"exact":"accept-language"},
+         {"exact":"origin"},
+         {"exact":"authorization"},
+         {"exact":"x-goog-iap-jwt-assertion"},
+         {"exact":"x-api-key"},
+         {"exact":"x-endpoint-api-userinfo"}
+      ]
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:              "No-op without cached operations",
+			ServiceConfigIn:   httpCacheTestServiceConfig(),
+			OptsIn:            options.ConfigGeneratorOptions{},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewHTTPCacheFilterGensFromOPConfig)
+	}
+}
+
+func TestHTTPCacheGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.HTTPCacheOperations = "bookstore.Bookstore.GetShelf,bookstore.Bookstore.ListShelves"
+
+	gens, err := filtergen.NewHTTPCacheFilterGensFromOPConfig(httpCacheTestServiceConfig(), opts)
+	if err != nil {
+		t.Fatalf("NewHTTPCacheFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewHTTPCacheFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	disabled := `{"disabled": true}`
+
+	testdata := []struct {
+		desc       string
+		selector   string
+		httpRule   *httppattern.Pattern
+		wantConfig string
+	}{
+		{
+			desc:     "GET route of cached operation",
+			selector: "bookstore.Bookstore.GetShelf",
+			httpRule: &httppattern.Pattern{HttpMethod: "GET"},
+		},
+		{
+			desc:       "POST route of cached operation",
+			selector:   "bookstore.Bookstore.ListShelves",
+			httpRule:   &httppattern.Pattern{HttpMethod: "POST"},
+			wantConfig: disabled,
+		},
+		{
+			desc:       "Operation that is not cached",
+			selector:   "bookstore.Bookstore.CreateShelf",
+			httpRule:   &httppattern.Pattern{HttpMethod: "POST"},
+			wantConfig: disabled,
+		},
+		{
+			desc:       "Route without HTTP rule",
+			selector:   "bookstore.Bookstore.GetShelf",
+			wantConfig: disabled,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := gens[0].GenPerRouteConfig(tc.selector, tc.httpRule)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantConfig == "" {
+				if got != nil {
+					t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewHTTPCacheFilterGensFromOPConfig_FactoryError(t *testing.T) {
+	jwtServiceConfig := httpCacheTestServiceConfig()
+	jwtServiceConfig.Authentication = &servicepb.Authentication{
+		Rules: []*servicepb.AuthenticationRule{
+			{
+				Selector: "bookstore.Bookstore.GetShelf",
+				Requirements: []*servicepb.AuthRequirement{
+					{
+						ProviderId: "auth_provider",
+					},
+				},
+			},
+		},
+	}
+
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Operation without GET HTTP rule",
+			ServiceConfigIn: httpCacheTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				HTTPCacheOperations: "bookstore.Bookstore.CreateShelf",
+			},
+			WantFactoryError: `operation "bookstore.Bookstore.CreateShelf" in http_cache_operations cannot be cached because it has no GET HTTP rule`,
+		},
+		{
+			Desc:            "Public operation that is not cached",
+			ServiceConfigIn: httpCacheTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				HTTPCacheOperations:       "bookstore.Bookstore.GetShelf",
+				HTTPCachePublicOperations: "bookstore.Bookstore.ListShelves",
+			},
+			WantFactoryError: `operation "bookstore.Bookstore.ListShelves" in http_cache_public_operations must also be in http_cache_operations`,
+		},
+		{
+			Desc:            "Operation that requires JWT authentication",
+			ServiceConfigIn: jwtServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				HTTPCacheOperations: "bookstore.Bookstore.GetShelf",
+			},
+			WantFactoryError: `operation "bookstore.Bookstore.GetShelf" in http_cache_operations cannot be cached because it requires JWT authentication`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewHTTPCacheFilterGensFromOPConfig)
+	}
+}
@@ -17,17 +17,16 @@ package filtergen
 import (
 	"fmt"
 
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/helpers"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/tracing"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	acpb "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
-	facpb "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
 	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
@@ -40,6 +39,13 @@ const (
 type HTTPConnectionManagerGenerator struct {
 	IsSchemeHeaderOverrideRequired bool
 
+	// IsOAuthScopeCheckRequired adds the `WWW-Authenticate` header to replies
+	// for JWTs without the required OAuth scopes.
+	IsOAuthScopeCheckRequired bool
+
+	// LocalReplyOptions customize the bodies of local replies.
+	LocalReplyOptions *helpers.LocalReplyOptions
+
 	// ESPv2 options
 	EnvoyUseRemoteAddress        bool
 	EnvoyXffNumTrustedHops       int
@@ -48,6 +54,10 @@ type HTTPConnectionManagerGenerator struct {
 	DisallowEscapedSlashesInPath bool
 	AccessLogPath                string
 	AccessLogFormat              string
+	AccessLogJSONFormat          *structpb.Struct
+	AccessLogGrpc                bool
+	AccessLogOpenTelemetryFields *structpb.Struct
+	AccessLogRemoteFilter        *acpb.AccessLogFilter
 	UnderscoresInHeaders         bool
 	EnableGrpcForHttp1           bool
 	TracingOptions               *options.TracingOptions
@@ -65,8 +75,55 @@ func NewHTTPConnectionManagerGenFromOPConfig(serviceConfig *servicepb.Service, o
 		return nil, err
 	}
 
+	scopesBySelector, err := GetOAuthScopesBySelectorFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	localReplyOptions, err := helpers.NewLocalReplyOptionsFromOPConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if (opts.AccessLogJSONAddFields != "" || opts.AccessLogJSONRemoveFields != "") && !opts.AccessLogJSON && opts.AccessLogOpenTelemetryAddress == "" {
+		return nil, fmt.Errorf("access log JSON fields can only be added or removed for the JSON or OpenTelemetry access log")
+	}
+
+	var accessLogJSONFormat *structpb.Struct
+	if opts.AccessLogJSON {
+		if opts.AccessLog == "" {
+			return nil, fmt.Errorf("access log JSON mode requires an access log path")
+		}
+		if opts.AccessLogFormat != "" {
+			return nil, fmt.Errorf("access log JSON mode cannot be used together with an access log format")
+		}
+		accessLogJSONFormat, err = MakeAccessLogJSONFormat(opts.AccessLogJSONAddFields, opts.AccessLogJSONRemoveFields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The OpenTelemetry log attributes use the JSON access log field set.
+	var accessLogOpenTelemetryFields *structpb.Struct
+	if opts.AccessLogOpenTelemetryAddress != "" {
+		accessLogOpenTelemetryFields, err = MakeAccessLogJSONFormat(opts.AccessLogJSONAddFields, opts.AccessLogJSONRemoveFields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var accessLogRemoteFilter *acpb.AccessLogFilter
+	if opts.AccessLogGrpcAddress != "" || opts.AccessLogOpenTelemetryAddress != "" {
+		accessLogRemoteFilter, err = makeRemoteAccessLogFilter(opts.AccessLogMinStatusCode, opts.AccessLogSamplingRate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &HTTPConnectionManagerGenerator{
 		IsSchemeHeaderOverrideRequired: isSchemeHeaderOverrideRequired,
+		IsOAuthScopeCheckRequired:      len(scopesBySelector) > 0,
+		LocalReplyOptions:              localReplyOptions,
 		EnvoyUseRemoteAddress:          opts.EnvoyUseRemoteAddress,
 		EnvoyXffNumTrustedHops:         opts.EnvoyXffNumTrustedHops,
 		NormalizePath:                  opts.NormalizePath,
@@ -74,6 +131,10 @@ func NewHTTPConnectionManagerGenFromOPConfig(serviceConfig *servicepb.Service, o
 		DisallowEscapedSlashesInPath:   opts.DisallowEscapedSlashesInPath,
 		AccessLogPath:                  opts.AccessLog,
 		AccessLogFormat:                opts.AccessLogFormat,
+		AccessLogJSONFormat:            accessLogJSONFormat,
+		AccessLogGrpc:                  opts.AccessLogGrpcAddress != "",
+		AccessLogOpenTelemetryFields:   accessLogOpenTelemetryFields,
+		AccessLogRemoteFilter:          accessLogRemoteFilter,
 		UnderscoresInHeaders:           opts.UnderscoresInHeaders,
 		EnableGrpcForHttp1:             opts.EnableGrpcForHttp1,
 		TracingOptions:                 opts.TracingOptions,
@@ -101,29 +162,11 @@ func (g *HTTPConnectionManagerGenerator) GenFilterConfig() (proto.Message, error
 		MergeSlashes:  g.MergeSlashesInPath,
 	}
 
-	// Converting the error message for requests rejected by Envoy to JSON format:
-	//
-	//    {
-	//       "code": "http-status-code",
-	//       "message": "the error message",
-	//    }
-	//
-	httpConMgr.LocalReplyConfig = &hcmpb.LocalReplyConfig{
-		BodyFormat: &corepb.SubstitutionFormatString{
-			Format: &corepb.SubstitutionFormatString_JsonFormat{
-				JsonFormat: &structpb.Struct{
-					Fields: map[string]*structpb.Value{
-						"code": {
-							Kind: &structpb.Value_StringValue{StringValue: "%RESPONSE_CODE%"},
-						},
-						"message": {
-							Kind: &structpb.Value_StringValue{StringValue: "%LOCAL_REPLY_BODY%"},
-						},
-					},
-				},
-			},
-		},
+	localReplyConfig, err := makeLocalReplyConfig(g.LocalReplyOptions, g.IsOAuthScopeCheckRequired)
+	if err != nil {
+		return nil, err
 	}
+	httpConMgr.LocalReplyConfig = localReplyConfig
 
 	// https://github.com/envoyproxy/envoy/security/advisories/GHSA-4987-27fx-x6cf
 	if g.DisallowEscapedSlashesInPath {
@@ -133,31 +176,43 @@ func (g *HTTPConnectionManagerGenerator) GenFilterConfig() (proto.Message, error
 	}
 
 	if g.AccessLogPath != "" {
-		fileAccessLog := &facpb.FileAccessLog{
-			Path: g.AccessLogPath,
-		}
-
-		if g.AccessLogFormat != "" {
-			fileAccessLog.AccessLogFormat = &facpb.FileAccessLog_LogFormat{
-				LogFormat: &corepb.SubstitutionFormatString{
-					Format: &corepb.SubstitutionFormatString_TextFormat{
-						TextFormat: g.AccessLogFormat,
-					},
+		var logFormat *corepb.SubstitutionFormatString
+		switch {
+		case g.AccessLogJSONFormat != nil:
+			logFormat = &corepb.SubstitutionFormatString{
+				Format: &corepb.SubstitutionFormatString_JsonFormat{
+					JsonFormat: g.AccessLogJSONFormat,
 				},
 			}
+		case g.AccessLogFormat != "":
+			logFormat = &corepb.SubstitutionFormatString{
+				Format: &corepb.SubstitutionFormatString_TextFormat{
+					TextFormat: g.AccessLogFormat,
+				},
+			}
+		}
+
+		accessLog, err := makeAccessLog(g.AccessLogPath, logFormat)
+		if err != nil {
+			return nil, err
 		}
+		httpConMgr.AccessLog = append(httpConMgr.AccessLog, accessLog)
+	}
 
-		serialized, _ := anypb.New(fileAccessLog)
+	if g.AccessLogGrpc {
+		accessLog, err := makeGrpcAccessLog(g.AccessLogRemoteFilter)
+		if err != nil {
+			return nil, err
+		}
+		httpConMgr.AccessLog = append(httpConMgr.AccessLog, accessLog)
+	}
 
-		httpConMgr.AccessLog = []*acpb.AccessLog{
-			{
-				Name:   util.AccessFileLogger,
-				Filter: nil,
-				ConfigType: &acpb.AccessLog_TypedConfig{
-					TypedConfig: serialized,
-				},
-			},
+	if g.AccessLogOpenTelemetryFields != nil {
+		accessLog, err := makeOpenTelemetryAccessLog(g.AccessLogOpenTelemetryFields, g.AccessLogRemoteFilter)
+		if err != nil {
+			return nil, err
 		}
+		httpConMgr.AccessLog = append(httpConMgr.AccessLog, accessLog)
 	}
 
 	if !g.TracingOptions.DisableTracing {
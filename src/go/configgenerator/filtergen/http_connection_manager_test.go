@@ -249,6 +249,439 @@ func TestNewHTTPConnectionManagerGenFromOPConfig_GenConfig(t *testing.T) {
 `,
 			},
 		},
+		{
+			Desc: "Generate HttpConMgr when OAuth scopes are required",
+			ServiceConfigIn: &confpb.Service{
+				Authentication: &confpb.Authentication{
+					Rules: []*confpb.AuthenticationRule{
+						{
+							Selector: "testapi.foo",
+							Oauth: &confpb.OAuthRequirements{
+								CanonicalScopes: "https://www.googleapis.com/auth/calendar",
+							},
+							Requirements: []*confpb.AuthRequirement{
+								{
+									ProviderId: "auth_provider",
+								},
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: true,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "REJECT_REQUEST"
+	},
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		},
+		"mappers": [
+			{
+				"body": {
+					"inlineString": "JWT does not have any of the OAuth scopes required by the operation"
+				},
+				"filter": {
+					"metadataFilter": {
+						"matcher": {
+							"filter": "envoy.filters.http.rbac",
+							"path": [
+								{
+									"key": "enforced_effective_policy_id"
+								}
+							],
+							"value": {
+								"stringMatch": {
+									"exact": "jwt-scopes"
+								}
+							}
+						}
+					}
+				},
+				"headersToAdd": [
+					{
+						"appendAction": "OVERWRITE_IF_EXISTS_OR_ADD",
+						"header": {
+							"key": "WWW-Authenticate",
+							"value": "Bearer error=\"insufficient_scope\""
+						}
+					}
+				]
+			}
+		]
+	},
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"statPrefix": "ingress_http",
+	"upgradeConfigs": [
+		{
+			"upgradeType": "websocket"
+		}
+	],
+	"useRemoteAddress": false
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate HttpConMgr with JSON access log",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLog:     "/foo",
+				AccessLogJSON: true,
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: true,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"accessLog": [
+		{
+			"name": "envoy.access_loggers.file",
+			"typedConfig": {
+				"@type": "type.googleapis.com/envoy.extensions.access_loggers.file.v3.FileAccessLog",
+				"path": "/foo",
+				"logFormat": {
+					"jsonFormat": {
+						"api_consumer": "%DYNAMIC_METADATA(com.google.espv2.filters.http.api_key:consumer)%",
+						"backend_cluster": "%UPSTREAM_CLUSTER%",
+						"bytes_received": "%BYTES_RECEIVED%",
+						"bytes_sent": "%BYTES_SENT%",
+						"duration": "%DURATION%",
+						"grpc_status": "%GRPC_STATUS%",
+						"jwt_failed_status": "%DYNAMIC_METADATA(envoy.filters.http.jwt_authn:jwt_failed_status)%",
+						"jwt_subject": "%DYNAMIC_METADATA(envoy.filters.http.jwt_authn:jwt_payloads:sub)%",
+						"method": "%REQ(:METHOD)%",
+						"operation_name": "%FILTER_STATE(com.google.espv2.filters.http.service_control.api_method:PLAIN)%",
+						"path": "%REQ(X-ENVOY-ORIGINAL-PATH?:PATH)%",
+						"protocol": "%PROTOCOL%",
+						"request_id": "%REQ(X-REQUEST-ID)%",
+						"response_code": "%RESPONSE_CODE%",
+						"response_code_details": "%RESPONSE_CODE_DETAILS%",
+						"response_flags": "%RESPONSE_FLAGS%",
+						"start_time": "%START_TIME%",
+						"trace_id": "%TRACE_ID%",
+						"upstream_attempt_count": "%UPSTREAM_REQUEST_ATTEMPT_COUNT%",
+						"upstream_host": "%UPSTREAM_HOST%",
+						"upstream_latency": "%RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)%",
+						"user_agent": "%REQ(USER-AGENT)%",
+						"x_forwarded_for": "%REQ(X-FORWARDED-FOR)%"
+					}
+				}
+			}
+		}
+	],
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "REJECT_REQUEST"
+	},
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		}
+	},
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"statPrefix": "ingress_http",
+	"upgradeConfigs": [
+		{
+			"upgradeType": "websocket"
+		}
+	],
+	"useRemoteAddress": false
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate HttpConMgr with gRPC and OpenTelemetry access logs",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLogGrpcAddress:          "grpc://als:9000",
+				AccessLogOpenTelemetryAddress: "grpc://otel-collector:4317",
+				AccessLogJSONAddFields:        "region=%REQ(X-REGION)%",
+				AccessLogJSONRemoveFields:     "start_time,path,protocol,response_code_details,response_flags,bytes_received,bytes_sent,duration,api_consumer,jwt_subject,jwt_failed_status,backend_cluster,upstream_host,upstream_latency,upstream_attempt_count,grpc_status,trace_id,request_id,user_agent,x_forwarded_for",
+				AccessLogMinStatusCode:        400,
+				AccessLogSamplingRate:         0.25,
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: true,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"accessLog": [
+		{
+			"name": "envoy.access_loggers.http_grpc",
+			"filter": {
+				"andFilter": {
+					"filters": [
+						{
+							"statusCodeFilter": {
+								"comparison": {
+									"op": "GE",
+									"value": {
+										"defaultValue": 400,
+										"runtimeKey": "espv2.access_log.min_status_code"
+									}
+								}
+							}
+						},
+						{
+							"runtimeFilter": {
+								"runtimeKey": "espv2.access_log.sampling",
+								"percentSampled": {
+									"numerator": 250000,
+									"denominator": "MILLION"
+								}
+							}
+						}
+					]
+				}
+			},
+			"typedConfig": {
+				"@type": "type.googleapis.com/envoy.extensions.access_loggers.grpc.v3.HttpGrpcAccessLogConfig",
+				"commonConfig": {
+					"logName": "espv2",
+					"grpcService": {
+						"envoyGrpc": {
+							"clusterName": "access-log-grpc-cluster"
+						}
+					},
+					"transportApiVersion": "V3",
+					"filterStateObjectsToLog": [
+						"com.google.espv2.filters.http.service_control.api_method"
+					]
+				}
+			}
+		},
+		{
+			"name": "envoy.access_loggers.open_telemetry",
+			"filter": {
+				"andFilter": {
+					"filters": [
+						{
+							"statusCodeFilter": {
+								"comparison": {
+									"op": "GE",
+									"value": {
+										"defaultValue": 400,
+										"runtimeKey": "espv2.access_log.min_status_code"
+									}
+								}
+							}
+						},
+						{
+							"runtimeFilter": {
+								"runtimeKey": "espv2.access_log.sampling",
+								"percentSampled": {
+									"numerator": 250000,
+									"denominator": "MILLION"
+								}
+							}
+						}
+					]
+				}
+			},
+			"typedConfig": {
+				"@type": "type.googleapis.com/envoy.extensions.access_loggers.open_telemetry.v3.OpenTelemetryAccessLogConfig",
+				"commonConfig": {
+					"logName": "espv2",
+					"grpcService": {
+						"envoyGrpc": {
+							"clusterName": "access-log-otel-cluster"
+						}
+					},
+					"transportApiVersion": "V3"
+				},
+				"attributes": {
+					"values": [
+						{
+							"key": "method",
+							"value": {
+								"stringValue": "%REQ(:METHOD)%"
+							}
+						},
+						{
+							"key": "operation_name",
+							"value": {
+								"stringValue": "%FILTER_STATE(com.google.espv2.filters.http.service_control.api_method:PLAIN)%"
+							}
+						},
+						{
+							"key": "region",
+							"value": {
+								"stringValue": "%REQ(X-REGION)%"
+							}
+						},
+						{
+							"key": "response_code",
+							"value": {
+								"stringValue": "%RESPONSE_CODE%"
+							}
+						}
+					]
+				}
+			}
+		}
+	],
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "REJECT_REQUEST"
+	},
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		}
+	},
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"statPrefix": "ingress_http",
+	"upgradeConfigs": [
+		{
+			"upgradeType": "websocket"
+		}
+	],
+	"useRemoteAddress": false
+}
+`,
+			},
+		},
+		{
+			Desc: "Generate HttpConMgr with access log to stdout",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLog:       "stdout",
+				AccessLogFormat: "%START_TIME%",
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing: true,
+					},
+				},
+			},
+			OptsMergeBehavior:     mergo.WithOverwriteWithEmptyValue,
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+	"accessLog": [
+		{
+			"name": "envoy.access_loggers.stdout",
+			"typedConfig": {
+				"@type": "type.googleapis.com/envoy.extensions.access_loggers.stream.v3.StdoutAccessLog",
+				"logFormat": {
+					"textFormat": "%START_TIME%"
+				}
+			}
+		}
+	],
+	"commonHttpProtocolOptions": {
+		"headersWithUnderscoresAction": "REJECT_REQUEST"
+	},
+	"localReplyConfig": {
+		"bodyFormat": {
+			"jsonFormat": {
+				"code": "%RESPONSE_CODE%",
+				"message": "%LOCAL_REPLY_BODY%"
+			}
+		}
+	},
+	"normalizePath": false,
+	"pathWithEscapedSlashesAction": "KEEP_UNCHANGED",
+	"statPrefix": "ingress_http",
+	"upgradeConfigs": [
+		{
+			"upgradeType": "websocket"
+		}
+	],
+	"useRemoteAddress": false
+}
+`,
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, func(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) ([]filtergen.FilterGenerator, error) {
+			gen, err := filtergen.NewHTTPConnectionManagerGenFromOPConfig(serviceConfig, opts)
+			if err != nil {
+				return nil, err
+			}
+
+			return []filtergen.FilterGenerator{
+				gen,
+			}, nil
+		})
+	}
+}
+
+func TestNewHTTPConnectionManagerGenFromOPConfig_FactoryError(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "JSON access log with access log format",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLog:       "/foo",
+				AccessLogFormat: "%START_TIME%",
+				AccessLogJSON:   true,
+			},
+			WantFactoryError: "access log JSON mode cannot be used together with an access log format",
+		},
+		{
+			Desc: "JSON access log without access log path",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLogJSON: true,
+			},
+			WantFactoryError: "access log JSON mode requires an access log path",
+		},
+		{
+			Desc: "JSON access log fields without JSON or OpenTelemetry access log",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLog:                 "/foo",
+				AccessLogJSONRemoveFields: "user_agent",
+			},
+			WantFactoryError: "access log JSON fields can only be added or removed for the JSON or OpenTelemetry access log",
+		},
+		{
+			Desc: "JSON access log with invalid field",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLog:              "/foo",
+				AccessLogJSON:          true,
+				AccessLogJSONAddFields: "region",
+			},
+			WantFactoryError: `invalid access log JSON field "region"`,
+		},
+		{
+			Desc: "Remote access log with invalid sampling rate",
+			OptsIn: options.ConfigGeneratorOptions{
+				AccessLogGrpcAddress:  "grpc://als:9000",
+				AccessLogSamplingRate: 1.5,
+			},
+			WantFactoryError: "invalid access log sampling rate: 1.5",
+		},
 	}
 
 	for _, tc := range testdata {
@@ -15,10 +15,14 @@
 package filtergen
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/helpers"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
@@ -51,6 +55,25 @@ type JwtAuthnGenerator struct {
 	// config.
 	AuthRequiredBySelector map[string]bool
 
+	// AuditOnly runs JWT authentication in audit only mode for all selectors.
+	AuditOnly bool
+
+	// AuditOnlySelectors are the selectors to run JWT authentication in audit
+	// only mode for. Tokens are verified and the outcome is recorded in dynamic
+	// metadata, but requests with missing or failed tokens are allowed.
+	AuditOnlySelectors map[string]bool
+
+	// LocalJwksByProvider maps provider IDs to the JWKS read from a local file.
+	// These providers use `local_jwks` instead of fetching the JWKS remotely.
+	LocalJwksByProvider map[string]string
+
+	// ProviderOptions maps provider IDs to the ESPv2 extensions of their config.
+	ProviderOptions map[string]*helpers.JwtProviderOptions
+
+	// IntrospectionProviders are the IDs of providers with opaque tokens. They
+	// are left to the token introspection filter.
+	IntrospectionProviders map[string]bool
+
 	// General options below.
 
 	HttpRequestTimeout    time.Duration
@@ -85,16 +108,54 @@ func NewJwtAuthnFilterGensFromOPConfig(serviceConfig *confpb.Service, opts optio
 		return nil, nil
 	}
 
+	introspectionProviders, err := clustergen.GetTokenIntrospectionProvidersFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(introspectionProviders) == len(auth.GetProviders()) {
+		glog.Infof("Not adding JWT authn filter gen because all authentication providers use token introspection.")
+		return nil, nil
+	}
+	introspectionProviderIDs := make(map[string]bool)
+	for id := range introspectionProviders {
+		introspectionProviderIDs[id] = true
+	}
+
 	authRequiredBySelector, err := GetAuthRequiredSelectorsFromOPConfig(serviceConfig, opts)
 	if err != nil {
 		return nil, err
 	}
+	for _, rule := range auth.GetRules() {
+		if len(jwtRequirements(rule, introspectionProviderIDs)) == 0 {
+			delete(authRequiredBySelector, rule.GetSelector())
+		}
+	}
+
+	localJwksByProvider, err := GetLocalJwksFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	auditOnlySelectors, err := GetJwtAuthnAuditOnlySelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	providerOptions, err := helpers.GetJwtProviderOptionsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
 
 	return []FilterGenerator{
 		&JwtAuthnGenerator{
 			ServiceName:                        serviceConfig.GetName(),
 			AuthConfig:                         auth,
 			AuthRequiredBySelector:             authRequiredBySelector,
+			AuditOnly:                          opts.JwtAuthnAuditOnly,
+			AuditOnlySelectors:                 auditOnlySelectors,
+			LocalJwksByProvider:                localJwksByProvider,
+			ProviderOptions:                    providerOptions,
+			IntrospectionProviders:             introspectionProviderIDs,
 			HttpRequestTimeout:                 opts.HttpRequestTimeout,
 			GeneratedHeaderPrefix:              opts.GeneratedHeaderPrefix,
 			JwksCacheDurationInS:               opts.JwksCacheDurationInS,
@@ -129,52 +190,17 @@ func (g *JwtAuthnGenerator) GenPerRouteConfig(selector string, httpRule *httppat
 func (g *JwtAuthnGenerator) GenFilterConfig() (proto.Message, error) {
 	providers := make(map[string]*jwtpb.JwtProvider)
 	for _, provider := range g.AuthConfig.GetProviders() {
-		addr, err := util.ExtractAddressFromURI(provider.GetJwksUri())
-		if err != nil {
-			return nil, fmt.Errorf("for provider (%v), failed to parse JWKS URI: %v", provider.Id, err)
+		if g.IntrospectionProviders[provider.GetId()] {
+			continue
 		}
-		clusterName := util.JwtProviderClusterName(addr)
+
 		fromHeaders, fromParams, err := processJwtLocations(provider)
 		if err != nil {
 			return nil, err
 		}
 
-		jwks := &jwtpb.RemoteJwks{
-			HttpUri: &corepb.HttpUri{
-				Uri: provider.GetJwksUri(),
-				HttpUpstreamType: &corepb.HttpUri_Cluster{
-					Cluster: clusterName,
-				},
-				Timeout: durationpb.New(g.HttpRequestTimeout),
-			},
-			CacheDuration: &durationpb.Duration{
-				Seconds: int64(g.JwksCacheDurationInS),
-			},
-		}
-		if !g.DisableJwksAsyncFetch {
-			jwks.AsyncFetch = &jwtpb.JwksAsyncFetch{
-				FastListener: g.JwksAsyncFetchFastListener,
-			}
-		}
-		if g.JwksFetchNumRetries > 0 {
-			// only create a retry policy, evenutally with a backoff if it is required.
-			rp := &corepb.RetryPolicy{
-				NumRetries: &wrapperspb.UInt32Value{
-					Value: uint32(g.JwksFetchNumRetries),
-				},
-				RetryBackOff: &corepb.BackoffStrategy{
-					BaseInterval: durationpb.New(g.JwksFetchRetryBackOffBaseInterval),
-					MaxInterval:  durationpb.New(g.JwksFetchRetryBackOffMaxInterval),
-				},
-			}
-			jwks.RetryPolicy = rp
-		}
-
 		jp := &jwtpb.JwtProvider{
-			Issuer: provider.GetIssuer(),
-			JwksSourceSpecifier: &jwtpb.JwtProvider_RemoteJwks{
-				RemoteJwks: jwks,
-			},
+			Issuer:                  provider.GetIssuer(),
 			FromHeaders:             fromHeaders,
 			FromParams:              fromParams,
 			ForwardPayloadHeader:    g.GeneratedHeaderPrefix + util.JwtAuthnForwardPayloadHeaderSuffix,
@@ -182,6 +208,36 @@ func (g *JwtAuthnGenerator) GenFilterConfig() (proto.Message, error) {
 			PadForwardPayloadHeader: g.JwtPadForwardPayloadHeader,
 		}
 
+		if o := g.ProviderOptions[provider.GetId()]; o != nil {
+			jp.ClockSkewSeconds = o.ClockSkewSeconds
+			if o.IssuerRegex != "" {
+				// Any issuer is accepted here, the claim checker enforces the regex.
+				jp.Issuer = ""
+			}
+		}
+
+		if localJwks, ok := g.LocalJwksByProvider[provider.GetId()]; ok {
+			// The JWKS is inlined rather than referenced by filename. Envoy reads
+			// local JWKS once and does not watch the file, so rotation relies on
+			// the config manager pushing a new listener config when the file
+			// changes. A static bootstrap config does not rotate keys.
+			jp.JwksSourceSpecifier = &jwtpb.JwtProvider_LocalJwks{
+				LocalJwks: &corepb.DataSource{
+					Specifier: &corepb.DataSource_InlineString{
+						InlineString: localJwks,
+					},
+				},
+			}
+		} else {
+			remoteJwks, err := g.makeRemoteJwks(provider)
+			if err != nil {
+				return nil, err
+			}
+			jp.JwksSourceSpecifier = &jwtpb.JwtProvider_RemoteJwks{
+				RemoteJwks: remoteJwks,
+			}
+		}
+
 		if len(provider.GetAudiences()) != 0 {
 			for _, a := range strings.Split(provider.GetAudiences(), ",") {
 				jp.Audiences = append(jp.Audiences, strings.TrimSpace(a))
@@ -204,13 +260,22 @@ func (g *JwtAuthnGenerator) GenFilterConfig() (proto.Message, error) {
 		// the JWT Payload will be send to metadata by envoy and it will be used by service control filter
 		// for logging and setting credential_id
 		jp.PayloadInMetadata = util.JwtPayloadMetadataName
+		if g.AuditOnly || len(g.AuditOnlySelectors) > 0 {
+			// Record why verification failed, as failures are not rejected in
+			// audit only mode.
+			jp.FailedStatusInMetadata = util.JwtFailedStatusMetadataName
+		}
 		providers[provider.GetId()] = jp
 	}
 
 	requirements := make(map[string]*jwtpb.JwtRequirement)
 	for _, rule := range g.AuthConfig.GetRules() {
-		if len(rule.GetRequirements()) > 0 {
-			requirements[rule.GetSelector()] = makeJwtRequirement(rule.GetRequirements(), rule.GetAllowWithoutCredential())
+		if jwtReqs := jwtRequirements(rule, g.IntrospectionProviders); len(jwtReqs) > 0 {
+			requirement := makeJwtRequirement(jwtReqs, rule.GetAllowWithoutCredential())
+			if g.AuditOnly || g.AuditOnlySelectors[rule.GetSelector()] {
+				requirement = makeAuditOnlyJwtRequirement(requirement)
+			}
+			requirements[rule.GetSelector()] = requirement
 		}
 	}
 
@@ -220,6 +285,46 @@ func (g *JwtAuthnGenerator) GenFilterConfig() (proto.Message, error) {
 	}, nil
 }
 
+func (g *JwtAuthnGenerator) makeRemoteJwks(provider *confpb.AuthProvider) (*jwtpb.RemoteJwks, error) {
+	addr, err := util.ExtractAddressFromURI(provider.GetJwksUri())
+	if err != nil {
+		return nil, fmt.Errorf("for provider (%v), failed to parse JWKS URI: %v", provider.Id, err)
+	}
+	clusterName := util.JwtProviderClusterName(addr)
+
+	jwks := &jwtpb.RemoteJwks{
+		HttpUri: &corepb.HttpUri{
+			Uri: provider.GetJwksUri(),
+			HttpUpstreamType: &corepb.HttpUri_Cluster{
+				Cluster: clusterName,
+			},
+			Timeout: durationpb.New(g.HttpRequestTimeout),
+		},
+		CacheDuration: &durationpb.Duration{
+			Seconds: int64(g.JwksCacheDurationInS),
+		},
+	}
+	if !g.DisableJwksAsyncFetch {
+		jwks.AsyncFetch = &jwtpb.JwksAsyncFetch{
+			FastListener: g.JwksAsyncFetchFastListener,
+		}
+	}
+	if g.JwksFetchNumRetries > 0 {
+		// only create a retry policy, evenutally with a backoff if it is required.
+		rp := &corepb.RetryPolicy{
+			NumRetries: &wrapperspb.UInt32Value{
+				Value: uint32(g.JwksFetchNumRetries),
+			},
+			RetryBackOff: &corepb.BackoffStrategy{
+				BaseInterval: durationpb.New(g.JwksFetchRetryBackOffBaseInterval),
+				MaxInterval:  durationpb.New(g.JwksFetchRetryBackOffMaxInterval),
+			},
+		}
+		jwks.RetryPolicy = rp
+	}
+	return jwks, nil
+}
+
 func defaultJwtLocations() ([]*jwtpb.JwtHeader, []string, error) {
 	return []*jwtpb.JwtHeader{
 			{
@@ -310,6 +415,59 @@ func makeJwtRequirement(requirements []*confpb.AuthRequirement, allow_missing bo
 	return requires
 }
 
+// makeAuditOnlyJwtRequirement wraps a requirement so that tokens are still
+// verified, but requests with missing or failed tokens are allowed.
+func makeAuditOnlyJwtRequirement(requirement *jwtpb.JwtRequirement) *jwtpb.JwtRequirement {
+	return &jwtpb.JwtRequirement{
+		RequiresType: &jwtpb.JwtRequirement_RequiresAny{
+			RequiresAny: &jwtpb.JwtRequirementOrList{
+				Requirements: []*jwtpb.JwtRequirement{
+					requirement,
+					{
+						RequiresType: &jwtpb.JwtRequirement_AllowMissingOrFailed{
+							AllowMissingOrFailed: &emptypb.Empty{},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// jwtRequirements returns the requirements of the rule that are verified as
+// JWTs, i.e. without the token introspection providers.
+func jwtRequirements(rule *confpb.AuthenticationRule, introspectionProviders map[string]bool) []*confpb.AuthRequirement {
+	var requirements []*confpb.AuthRequirement
+	for _, r := range rule.GetRequirements() {
+		if !introspectionProviders[r.GetProviderId()] {
+			requirements = append(requirements, r)
+		}
+	}
+	return requirements
+}
+
+// GetJwtAuthnAuditOnlySelectorsFromOPConfig returns the selectors to run JWT
+// authentication in audit only mode for.
+func GetJwtAuthnAuditOnlySelectorsFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) (map[string]bool, error) {
+	authRequiredBySelector, err := GetAuthRequiredSelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	auditOnlySelectors := make(map[string]bool)
+	for _, selector := range strings.Split(opts.JwtAuthnAuditOnlySelectors, ",") {
+		if selector = strings.TrimSpace(selector); selector == "" {
+			continue
+		}
+		if !authRequiredBySelector[selector] {
+			glog.Warningf("JWT authn audit only selector %q has no authentication requirements, ignoring it.", selector)
+			continue
+		}
+		auditOnlySelectors[selector] = true
+	}
+	return auditOnlySelectors, nil
+}
+
 // GetAuthRequiredSelectorsFromOPConfig returns a list of selectors that require
 // per-method level authn config.
 func GetAuthRequiredSelectorsFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) (map[string]bool, error) {
@@ -332,3 +490,25 @@ func GetAuthRequiredSelectorsFromOPConfig(serviceConfig *confpb.Service, opts op
 
 	return authRequiredMethods, nil
 }
+
+// GetLocalJwksFromOPConfig reads the JWKS of all providers that use a local
+// JWKS file, keyed by provider ID.
+func GetLocalJwksFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) (map[string]string, error) {
+	localJwksFiles, err := clustergen.GetLocalJwksFilesFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	localJwksByProvider := make(map[string]string)
+	for id, path := range localJwksFiles {
+		jwks, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error processing authentication provider %q: failed to read local JWKS file: %v", id, err)
+		}
+		if !json.Valid(jwks) {
+			return nil, fmt.Errorf("error processing authentication provider %q: local JWKS file %q is not valid JSON", id, path)
+		}
+		localJwksByProvider[id] = string(jwks)
+	}
+	return localJwksByProvider, nil
+}
@@ -15,6 +15,9 @@
 package filtergen_test
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -698,3 +701,395 @@ func TestNewJwtAuthnFilterGensFromOPConfig_GenConfig(t *testing.T) {
 		tc.RunTest(t, filtergen.NewJwtAuthnFilterGensFromOPConfig)
 	}
 }
+
+func TestNewJwtAuthnFilterGensFromOPConfig_LocalJwks(t *testing.T) {
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksPath, []byte(`{"keys":[]}`), 0644); err != nil {
+		t.Fatalf("fail to write local JWKS file: %v", err)
+	}
+
+	serviceConfigWithJwksUri := func(jwksUri string) *confpb.Service {
+		return &confpb.Service{
+			Name: "bookstore.endpoints.project123.cloud.goog",
+			Authentication: &confpb.Authentication{
+				Providers: []*confpb.AuthProvider{
+					{
+						Id:      "auth_provider",
+						Issuer:  "issuer-0",
+						JwksUri: jwksUri,
+					},
+				},
+			},
+		}
+	}
+
+	wantFilterConfig := `{
+    "name": "envoy.filters.http.jwt_authn",
+    "typedConfig": {
+        "@type": "type.googleapis.com/envoy.extensions.filters.http.jwt_authn.v3.JwtAuthentication",
+        "providers": {
+            "auth_provider": {
+                "audiences": [
+                    "https://bookstore.endpoints.project123.cloud.goog"
+                ],
+                "forward": true,
+                "forwardPayloadHeader": "X-Endpoint-API-UserInfo",
+                "fromHeaders": [
+                    {
+                        "name": "Authorization",
+                        "valuePrefix": "Bearer "
+                    },
+                    {
+                        "name": "X-Goog-Iap-Jwt-Assertion"
+                    }
+                ],
+                "fromParams": [
+                    "access_token"
+                ],
+                "issuer": "issuer-0",
+                "payloadInMetadata": "jwt_payloads",
+                "localJwks": {
+                    "inlineString": "{\"keys\":[]}"
+                },
+                "jwtCacheConfig": {
+                   "jwtCacheSize": 1000
+                }
+            }
+        }
+    }
+}`
+
+	testData := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:              "Success. Local JWKS from a file jwks_uri",
+			ServiceConfigIn:   serviceConfigWithJwksUri("file://" + jwksPath),
+			WantFilterConfigs: []string{wantFilterConfig},
+		},
+		{
+			Desc:            "Success. Local JWKS from option overrides remote jwks_uri",
+			ServiceConfigIn: serviceConfigWithJwksUri("https://fake-jwks.com"),
+			OptsIn: options.ConfigGeneratorOptions{
+				JwksLocalFiles: "auth_provider=" + jwksPath,
+			},
+			WantFilterConfigs: []string{wantFilterConfig},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, filtergen.NewJwtAuthnFilterGensFromOPConfig)
+	}
+}
+
+func TestNewJwtAuthnFilterGensFromOPConfig_BadLocalJwks(t *testing.T) {
+	invalidJwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(invalidJwksPath, []byte(`not-json`), 0644); err != nil {
+		t.Fatalf("fail to write local JWKS file: %v", err)
+	}
+
+	serviceConfigWithJwksUri := func(jwksUri string) *confpb.Service {
+		return &confpb.Service{
+			Authentication: &confpb.Authentication{
+				Providers: []*confpb.AuthProvider{
+					{
+						Id:      "auth_provider",
+						Issuer:  "issuer-0",
+						JwksUri: jwksUri,
+					},
+				},
+			},
+		}
+	}
+
+	testData := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:             "Local JWKS file does not exist",
+			ServiceConfigIn:  serviceConfigWithJwksUri("file:///does/not/exist.json"),
+			WantFactoryError: "failed to read local JWKS file",
+		},
+		{
+			Desc:             "Local JWKS file is not JSON",
+			ServiceConfigIn:  serviceConfigWithJwksUri("file://" + invalidJwksPath),
+			WantFactoryError: "is not valid JSON",
+		},
+		{
+			Desc:            "Malformed local JWKS files option",
+			ServiceConfigIn: serviceConfigWithJwksUri("https://fake-jwks.com"),
+			OptsIn: options.ConfigGeneratorOptions{
+				JwksLocalFiles: "auth_provider",
+			},
+			WantFactoryError: "should be in the format of provider_id=path",
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, filtergen.NewJwtAuthnFilterGensFromOPConfig)
+	}
+}
+
+func TestNewJwtAuthnFilterGensFromOPConfig_AuditOnly(t *testing.T) {
+	serviceConfig := &confpb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Apis: []*apipb.Api{
+			{
+				Name: "testapi",
+				Methods: []*apipb.Method{
+					{
+						Name: "foo",
+					},
+					{
+						Name: "bar",
+					},
+				},
+			},
+		},
+		Authentication: &confpb.Authentication{
+			Providers: []*confpb.AuthProvider{
+				{
+					Id:      "auth_provider",
+					Issuer:  "issuer-0",
+					JwksUri: "https://fake-jwks.com",
+				},
+			},
+			Rules: []*confpb.AuthenticationRule{
+				{
+					Selector: "testapi.foo",
+					Requirements: []*confpb.AuthRequirement{
+						{
+							ProviderId: "auth_provider",
+						},
+					},
+				},
+				{
+					Selector: "testapi.bar",
+					Requirements: []*confpb.AuthRequirement{
+						{
+							ProviderId: "auth_provider",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	wantFilterConfig := func(wantFooRequirement, wantBarRequirement string) string {
+		return fmt.Sprintf(`{
+    "providers": {
+        "auth_provider": {
+            "audiences": [
+                "https://bookstore.endpoints.project123.cloud.goog"
+            ],
+            "failedStatusInMetadata": "jwt_failed_status",
+            "forward": true,
+            "forwardPayloadHeader": "X-Endpoint-API-UserInfo",
+            "fromHeaders": [
+                {
+                    "name": "Authorization",
+                    "valuePrefix": "Bearer "
+                },
+                {
+                    "name": "X-Goog-Iap-Jwt-Assertion"
+                }
+            ],
+            "fromParams": [
+                "access_token"
+            ],
+            "issuer": "issuer-0",
+            "jwtCacheConfig": {
+                "jwtCacheSize": 1000
+            },
+            "payloadInMetadata": "jwt_payloads",
+            "remoteJwks": {
+                "asyncFetch": {},
+                "cacheDuration": "300s",
+                "httpUri": {
+                    "cluster": "jwt-provider-cluster-fake-jwks.com:443",
+                    "timeout": "30s",
+                    "uri": "https://fake-jwks.com"
+                }
+            }
+        }
+    },
+    "requirementMap": {
+        "testapi.bar": %s,
+        "testapi.foo": %s
+    }
+}`, wantBarRequirement, wantFooRequirement)
+	}
+
+	enforcedRequirement := `{
+    "providerName": "auth_provider"
+}`
+	auditOnlyRequirement := `{
+    "requiresAny": {
+        "requirements": [
+            {
+                "providerName": "auth_provider"
+            },
+            {
+                "allowMissingOrFailed": {}
+            }
+        ]
+    }
+}`
+
+	testData := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Success. Audit only mode for the whole service",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtAuthnAuditOnly: true,
+			},
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs:     []string{wantFilterConfig(auditOnlyRequirement, auditOnlyRequirement)},
+		},
+		{
+			Desc:            "Success. Audit only mode for a selector, unknown selectors are ignored",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtAuthnAuditOnlySelectors: "testapi.foo, testapi.unknown",
+			},
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs:     []string{wantFilterConfig(auditOnlyRequirement, enforcedRequirement)},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, filtergen.NewJwtAuthnFilterGensFromOPConfig)
+	}
+}
+
+func TestNewJwtAuthnFilterGensFromOPConfig_ProviderOptions(t *testing.T) {
+	optionsPath := filepath.Join(t.TempDir(), "jwt_provider_options.json")
+	if err := os.WriteFile(optionsPath, []byte(`{"providers": {"auth_provider": {"clock_skew_seconds": 30, "issuer_regex": "issuer-[0-9]+"}}}`), 0644); err != nil {
+		t.Fatalf("fail to write JWT provider options file: %v", err)
+	}
+
+	serviceConfig := &confpb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Authentication: &confpb.Authentication{
+			Providers: []*confpb.AuthProvider{
+				{
+					Id:      "auth_provider",
+					Issuer:  "issuer-0",
+					JwksUri: "https://fake-jwks.com",
+				},
+			},
+		},
+	}
+
+	testData := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Success. Clock skew is set and the issuer is left to the claims filter",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtProviderOptionsPath: optionsPath,
+			},
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{`{
+    "providers": {
+        "auth_provider": {
+            "audiences": [
+                "https://bookstore.endpoints.project123.cloud.goog"
+            ],
+            "clockSkewSeconds": 30,
+            "forward": true,
+            "forwardPayloadHeader": "X-Endpoint-API-UserInfo",
+            "fromHeaders": [
+                {
+                    "name": "Authorization",
+                    "valuePrefix": "Bearer "
+                },
+                {
+                    "name": "X-Goog-Iap-Jwt-Assertion"
+                }
+            ],
+            "fromParams": [
+                "access_token"
+            ],
+            "jwtCacheConfig": {
+                "jwtCacheSize": 1000
+            },
+            "payloadInMetadata": "jwt_payloads",
+            "remoteJwks": {
+                "asyncFetch": {},
+                "cacheDuration": "300s",
+                "httpUri": {
+                    "cluster": "jwt-provider-cluster-fake-jwks.com:443",
+                    "timeout": "30s",
+                    "uri": "https://fake-jwks.com"
+                }
+            }
+        }
+    }
+}`},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, filtergen.NewJwtAuthnFilterGensFromOPConfig)
+	}
+}
+
+func TestNewJwtAuthnFilterGensFromOPConfig_TokenIntrospection(t *testing.T) {
+	providersPath := writeTokenIntrospectionProviders(t, tokenIntrospectionProvidersContent)
+
+	serviceConfig := tokenIntrospectionTestServiceConfig()
+	serviceConfig.Name = "bookstore.endpoints.project123.cloud.goog"
+
+	testData := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Success. Token introspection providers are left to the token introspection filter",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				TokenIntrospectionProvidersPath: providersPath,
+			},
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{`{
+    "providers": {
+        "jwt_provider": {
+            "audiences": [
+                "https://bookstore.endpoints.project123.cloud.goog"
+            ],
+            "forward": true,
+            "forwardPayloadHeader": "X-Endpoint-API-UserInfo",
+            "fromHeaders": [
+                {
+                    "name": "Authorization",
+                    "valuePrefix": "Bearer "
+                },
+                {
+                    "name": "X-Goog-Iap-Jwt-Assertion"
+                }
+            ],
+            "fromParams": [
+                "access_token"
+            ],
+            "issuer": "issuer_0",
+            "jwtCacheConfig": {
+                "jwtCacheSize": 1000
+            },
+            "payloadInMetadata": "jwt_payloads",
+            "remoteJwks": {
+                "asyncFetch": {},
+                "cacheDuration": "300s",
+                "httpUri": {
+                    "cluster": "jwt-provider-cluster-metadata.com:443",
+                    "timeout": "30s",
+                    "uri": "https://metadata.com/pkey"
+                }
+            }
+        }
+    },
+    "requirementMap": {
+        "testapi.foo": {
+            "providerName": "jwt_provider"
+        }
+    }
+}`},
+		},
+	}
+
+	for _, tc := range testData {
+		tc.RunTest(t, filtergen.NewJwtAuthnFilterGensFromOPConfig)
+	}
+}
@@ -0,0 +1,529 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbacfilterpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/glog"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// JwtClaimsFilterName is the Envoy filter name for debug logging.
+	JwtClaimsFilterName = "envoy.filters.http.rbac"
+
+	// jwtClaimsShadowStatPrefix is the stat prefix of claim checks that are
+	// only audited, see `--jwt_authn_audit_only`.
+	jwtClaimsShadowStatPrefix = "jwt_claims_audit_"
+
+	// JwtScopesPolicyID is the ID of the policy that denies JWTs without any of
+	// the OAuth scopes of the operation.
+	JwtScopesPolicyID = "jwt-scopes"
+
+	// rbacEnforcedPolicyIDMetadataName is the dynamic metadata key the RBAC
+	// filter sets to the ID of the policy that denied the request.
+	rbacEnforcedPolicyIDMetadataName = "enforced_effective_policy_id"
+)
+
+// JwtClaimsGenerator checks the claims of verified JWTs that the JWT authn
+// filter cannot check by itself, using the payload it writes to metadata.
+type JwtClaimsGenerator struct {
+	// Providers are the authentication providers with claim checks, sorted by
+	// ID for a stable config.
+	Providers []*servicepb.AuthProvider
+
+	// AllProviders are all the authentication providers, sorted by ID. Their
+	// issuers are the only ones allowed once any provider has an issuer regex.
+	AllProviders []*servicepb.AuthProvider
+
+	// ScopesBySelector maps selectors to the OAuth scopes they accept, from
+	// `AuthenticationRule.oauth.canonical_scopes`.
+	ScopesBySelector map[string][]string
+
+	// ProviderOptions maps provider IDs to the ESPv2 extensions of their config.
+	ProviderOptions map[string]*helpers.JwtProviderOptions
+
+	// AuditOnly and AuditOnlySelectors have the same meaning as in
+	// JwtAuthnGenerator. Failed claim checks are only recorded for them.
+	AuditOnly          bool
+	AuditOnlySelectors map[string]bool
+
+	NoopFilterGenerator
+}
+
+// NewJwtClaimsFilterGensFromOPConfig creates a JwtClaimsGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewJwtClaimsFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.SkipJwtAuthnFilter {
+		glog.Infof("Not adding JWT claims filter gen because the JWT authn filter is disabled by option.")
+		return nil, nil
+	}
+
+	providerOptions, err := helpers.GetJwtProviderOptionsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var providers, allProviders []*servicepb.AuthProvider
+	for _, provider := range serviceConfig.GetAuthentication().GetProviders() {
+		allProviders = append(allProviders, provider)
+		if o := providerOptions[provider.GetId()]; o != nil && o.NeedsClaimCheck() {
+			providers = append(providers, provider)
+		}
+	}
+
+	scopesBySelector, err := GetOAuthScopesBySelectorFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(providers) == 0 && len(scopesBySelector) == 0 {
+		glog.Infof("Not adding JWT claims filter gen because no authentication provider needs claim checks and no operation requires OAuth scopes.")
+		return nil, nil
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].GetId() < providers[j].GetId()
+	})
+	sort.Slice(allProviders, func(i, j int) bool {
+		return allProviders[i].GetId() < allProviders[j].GetId()
+	})
+
+	auditOnlySelectors, err := GetJwtAuthnAuditOnlySelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return []FilterGenerator{
+		&JwtClaimsGenerator{
+			Providers:          providers,
+			AllProviders:       allProviders,
+			ScopesBySelector:   scopesBySelector,
+			ProviderOptions:    providerOptions,
+			AuditOnly:          opts.JwtAuthnAuditOnly,
+			AuditOnlySelectors: auditOnlySelectors,
+		},
+	}, nil
+}
+
+func (g *JwtClaimsGenerator) FilterName() string {
+	return JwtClaimsFilterName
+}
+
+func (g *JwtClaimsGenerator) GenFilterConfig() (proto.Message, error) {
+	rules, err := g.makeRules()
+	if err != nil {
+		return nil, err
+	}
+
+	if g.AuditOnly {
+		return &rbacfilterpb.RBAC{
+			ShadowRules:           rules,
+			ShadowRulesStatPrefix: jwtClaimsShadowStatPrefix,
+		}, nil
+	}
+	return &rbacfilterpb.RBAC{
+		Rules: rules,
+	}, nil
+}
+
+func (g *JwtClaimsGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	scopes := g.ScopesBySelector[selector]
+	auditOnly := g.AuditOnly || g.AuditOnlySelectors[selector]
+	if len(scopes) == 0 && (g.AuditOnly || !auditOnly) {
+		// Same as the filter level config.
+		return nil, nil
+	}
+
+	rules, err := g.makeRules()
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) > 0 {
+		rules.Policies[JwtScopesPolicyID] = makeJwtScopesPolicy(scopes)
+	}
+
+	if auditOnly {
+		return &rbacfilterpb.RBACPerRoute{
+			Rbac: &rbacfilterpb.RBAC{
+				ShadowRules:           rules,
+				ShadowRulesStatPrefix: jwtClaimsShadowStatPrefix,
+			},
+		}, nil
+	}
+	return &rbacfilterpb.RBACPerRoute{
+		Rbac: &rbacfilterpb.RBAC{
+			Rules: rules,
+		},
+	}, nil
+}
+
+// GetOAuthScopesBySelectorFromOPConfig returns the OAuth scopes accepted by
+// each selector that requires authentication. A verified JWT must have at
+// least one of them.
+func GetOAuthScopesBySelectorFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (map[string][]string, error) {
+	if opts.SkipJwtAuthnFilter {
+		return nil, nil
+	}
+
+	scopesBySelector := make(map[string][]string)
+	for _, rule := range serviceConfig.GetAuthentication().GetRules() {
+		selector := rule.GetSelector()
+		if util.ShouldSkipOPDiscoveryAPI(selector, opts.AllowDiscoveryAPIs) || len(rule.GetRequirements()) == 0 {
+			continue
+		}
+
+		var scopes []string
+		for _, scope := range strings.Split(rule.GetOauth().GetCanonicalScopes(), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+		if len(scopes) > 0 {
+			scopesBySelector[selector] = scopes
+		}
+	}
+	return scopesBySelector, nil
+}
+
+// makeJwtScopesPolicy denies verified JWTs that have none of the scopes, in
+// either a space separated `scope` claim or a `scp` claim that is a list or a
+// space separated string.
+func makeJwtScopesPolicy(scopes []string) *rbacpb.Policy {
+	var hasScopes []*rbacpb.Principal
+	for _, scope := range scopes {
+		inScopeString := stringValueMatcher(&matcherpb.StringMatcher{
+			MatchPattern: &matcherpb.StringMatcher_SafeRegex{
+				SafeRegex: &matcherpb.RegexMatcher{
+					Regex: fmt.Sprintf("(.* )?%s( .*)?", regexp.QuoteMeta(scope)),
+				},
+			},
+		})
+		hasScopes = append(hasScopes,
+			jwtClaimPrincipal("scope", inScopeString),
+			jwtClaimPrincipal("scp", inScopeString),
+			jwtClaimPrincipal("scp", &matcherpb.ValueMatcher{
+				MatchPattern: &matcherpb.ValueMatcher_ListMatch{
+					ListMatch: &matcherpb.ListMatcher{
+						MatchPattern: &matcherpb.ListMatcher_OneOf{
+							OneOf: stringValueMatcher(&matcherpb.StringMatcher{
+								MatchPattern: &matcherpb.StringMatcher_Exact{
+									Exact: scope,
+								},
+							}),
+						},
+					},
+				},
+			}),
+		)
+	}
+
+	return &rbacpb.Policy{
+		Permissions: []*rbacpb.Permission{anyPermission()},
+		Principals: []*rbacpb.Principal{
+			andPrincipals(
+				jwtPayloadPresentPrincipal(),
+				notPrincipal(&rbacpb.Principal{
+					Identifier: &rbacpb.Principal_OrIds{
+						OrIds: &rbacpb.Principal_Set{
+							Ids: hasScopes,
+						},
+					},
+				}),
+			),
+		},
+	}
+}
+
+// makeRules denies requests whose verified JWT payload fails the claim checks
+// of the provider that issued it. Requests without a verified JWT are not
+// affected.
+func (g *JwtClaimsGenerator) makeRules() (*rbacpb.RBAC, error) {
+	policies := make(map[string]*rbacpb.Policy)
+
+	hasIssuerRegex := false
+	for _, provider := range g.Providers {
+		o := g.ProviderOptions[provider.GetId()]
+		if o.IssuerRegex != "" {
+			hasIssuerRegex = true
+		}
+		issuerMatcher := g.issuerPrincipal(provider)
+
+		var claimMatchers []*rbacpb.Principal
+		requiredClaims := o.RequiredClaims
+		if o.MaxLifetimeSeconds > 0 {
+			requiredClaims = append([]string{"exp", "iat"}, requiredClaims...)
+		}
+		for _, claim := range requiredClaims {
+			claimMatchers = append(claimMatchers, jwtClaimPrincipal(claim, &matcherpb.ValueMatcher{
+				MatchPattern: &matcherpb.ValueMatcher_PresentMatch{
+					PresentMatch: true,
+				},
+			}))
+		}
+
+		var claims []string
+		for claim := range o.ClaimConstraints {
+			claims = append(claims, claim)
+		}
+		sort.Strings(claims)
+		for _, claim := range claims {
+			c := o.ClaimConstraints[claim]
+			sm := &matcherpb.StringMatcher{
+				MatchPattern: &matcherpb.StringMatcher_Exact{
+					Exact: c.Exact,
+				},
+			}
+			if c.Regex != "" {
+				sm = &matcherpb.StringMatcher{
+					MatchPattern: &matcherpb.StringMatcher_SafeRegex{
+						SafeRegex: &matcherpb.RegexMatcher{
+							Regex: c.Regex,
+						},
+					},
+				}
+			}
+			claimMatchers = append(claimMatchers, jwtClaimPrincipal(claim, stringValueMatcher(sm)))
+		}
+
+		if len(claimMatchers) > 0 {
+			policies[fmt.Sprintf("jwt-claims-%s", provider.GetId())] = &rbacpb.Policy{
+				Permissions: []*rbacpb.Permission{anyPermission()},
+				Principals: []*rbacpb.Principal{
+					andPrincipals(issuerMatcher, notPrincipal(andPrincipals(claimMatchers...))),
+				},
+			}
+		}
+
+		if o.MaxLifetimeSeconds > 0 {
+			policies[fmt.Sprintf("jwt-max-lifetime-%s", provider.GetId())] = &rbacpb.Policy{
+				Permissions: []*rbacpb.Permission{anyPermission()},
+				Principals:  []*rbacpb.Principal{issuerMatcher},
+				Condition:   makeJwtLifetimeExceededCondition(o.MaxLifetimeSeconds),
+			}
+		}
+	}
+
+	if hasIssuerRegex {
+		// Providers with an issuer regex accept any issuer in the JWT authn
+		// filter, so reject verified JWTs that no provider issues. All providers
+		// are allowed, not only the ones with claim checks.
+		var issuerMatchers []*rbacpb.Principal
+		for _, provider := range g.AllProviders {
+			issuerMatchers = append(issuerMatchers, g.issuerPrincipal(provider))
+		}
+		policies["jwt-unknown-issuer"] = &rbacpb.Policy{
+			Permissions: []*rbacpb.Permission{anyPermission()},
+			Principals: []*rbacpb.Principal{
+				andPrincipals(
+					jwtPayloadPresentPrincipal(),
+					notPrincipal(&rbacpb.Principal{
+						Identifier: &rbacpb.Principal_OrIds{
+							OrIds: &rbacpb.Principal_Set{
+								Ids: issuerMatchers,
+							},
+						},
+					}),
+				),
+			},
+		}
+	}
+
+	return &rbacpb.RBAC{
+		Action:   rbacpb.RBAC_DENY,
+		Policies: policies,
+	}, nil
+}
+
+// issuerPrincipal matches verified JWTs issued by the provider, by its issuer
+// regex if it has one.
+func (g *JwtClaimsGenerator) issuerPrincipal(provider *servicepb.AuthProvider) *rbacpb.Principal {
+	if o := g.ProviderOptions[provider.GetId()]; o != nil && o.IssuerRegex != "" {
+		return jwtClaimPrincipal("iss", stringValueMatcher(&matcherpb.StringMatcher{
+			MatchPattern: &matcherpb.StringMatcher_SafeRegex{
+				SafeRegex: &matcherpb.RegexMatcher{
+					Regex: o.IssuerRegex,
+				},
+			},
+		}))
+	}
+	return jwtClaimPrincipal("iss", stringValueMatcher(&matcherpb.StringMatcher{
+		MatchPattern: &matcherpb.StringMatcher_Exact{
+			Exact: provider.GetIssuer(),
+		},
+	}))
+}
+
+func anyPermission() *rbacpb.Permission {
+	return &rbacpb.Permission{
+		Rule: &rbacpb.Permission_Any{
+			Any: true,
+		},
+	}
+}
+
+func andPrincipals(ids ...*rbacpb.Principal) *rbacpb.Principal {
+	return &rbacpb.Principal{
+		Identifier: &rbacpb.Principal_AndIds{
+			AndIds: &rbacpb.Principal_Set{
+				Ids: ids,
+			},
+		},
+	}
+}
+
+func notPrincipal(id *rbacpb.Principal) *rbacpb.Principal {
+	return &rbacpb.Principal{
+		Identifier: &rbacpb.Principal_NotId{
+			NotId: id,
+		},
+	}
+}
+
+func stringValueMatcher(sm *matcherpb.StringMatcher) *matcherpb.ValueMatcher {
+	return &matcherpb.ValueMatcher{
+		MatchPattern: &matcherpb.ValueMatcher_StringMatch{
+			StringMatch: sm,
+		},
+	}
+}
+
+// jwtPayloadPresentPrincipal matches requests with a verified JWT.
+func jwtPayloadPresentPrincipal() *rbacpb.Principal {
+	return &rbacpb.Principal{
+		Identifier: &rbacpb.Principal_Metadata{
+			Metadata: &matcherpb.MetadataMatcher{
+				Filter: JWTAuthnFilterName,
+				Path: []*matcherpb.MetadataMatcher_PathSegment{
+					{
+						Segment: &matcherpb.MetadataMatcher_PathSegment_Key{
+							Key: util.JwtPayloadMetadataName,
+						},
+					},
+				},
+				Value: &matcherpb.ValueMatcher{
+					MatchPattern: &matcherpb.ValueMatcher_PresentMatch{
+						PresentMatch: true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// jwtClaimPrincipal matches a top-level claim of the verified JWT payload.
+func jwtClaimPrincipal(claim string, value *matcherpb.ValueMatcher) *rbacpb.Principal {
+	return &rbacpb.Principal{
+		Identifier: &rbacpb.Principal_Metadata{
+			Metadata: &matcherpb.MetadataMatcher{
+				Filter: JWTAuthnFilterName,
+				Path: []*matcherpb.MetadataMatcher_PathSegment{
+					{
+						Segment: &matcherpb.MetadataMatcher_PathSegment_Key{
+							Key: util.JwtPayloadMetadataName,
+						},
+					},
+					{
+						Segment: &matcherpb.MetadataMatcher_PathSegment_Key{
+							Key: claim,
+						},
+					},
+				},
+				Value: value,
+			},
+		},
+	}
+}
+
+// makeJwtLifetimeExceededCondition creates the parsed CEL expression
+//
+//	metadata.filter_metadata["envoy.filters.http.jwt_authn"]["jwt_payloads"]["exp"] -
+//	  metadata.filter_metadata["envoy.filters.http.jwt_authn"]["jwt_payloads"]["iat"] > maxLifetimeSeconds
+//
+// JSON numbers in metadata are doubles, so the constant is a double too.
+func makeJwtLifetimeExceededCondition(maxLifetimeSeconds int64) *exprpb.Expr {
+	var id int64
+	nextID := func() int64 {
+		id++
+		return id
+	}
+
+	call := func(function string, args ...*exprpb.Expr) *exprpb.Expr {
+		return &exprpb.Expr{
+			Id: nextID(),
+			ExprKind: &exprpb.Expr_CallExpr{
+				CallExpr: &exprpb.Expr_Call{
+					Function: function,
+					Args:     args,
+				},
+			},
+		}
+	}
+	constant := func(c *exprpb.Constant) *exprpb.Expr {
+		return &exprpb.Expr{
+			Id: nextID(),
+			ExprKind: &exprpb.Expr_ConstExpr{
+				ConstExpr: c,
+			},
+		}
+	}
+	stringConstant := func(s string) *exprpb.Expr {
+		return constant(&exprpb.Constant{
+			ConstantKind: &exprpb.Constant_StringValue{
+				StringValue: s,
+			},
+		})
+	}
+	claim := func(name string) *exprpb.Expr {
+		filterMetadata := &exprpb.Expr{
+			Id: nextID(),
+			ExprKind: &exprpb.Expr_SelectExpr{
+				SelectExpr: &exprpb.Expr_Select{
+					Operand: &exprpb.Expr{
+						Id: nextID(),
+						ExprKind: &exprpb.Expr_IdentExpr{
+							IdentExpr: &exprpb.Expr_Ident{
+								Name: "metadata",
+							},
+						},
+					},
+					Field: "filter_metadata",
+				},
+			},
+		}
+		jwtAuthn := call("_[_]", filterMetadata, stringConstant(JWTAuthnFilterName))
+		payload := call("_[_]", jwtAuthn, stringConstant(util.JwtPayloadMetadataName))
+		return call("_[_]", payload, stringConstant(name))
+	}
+
+	lifetime := call("_-_", claim("exp"), claim("iat"))
+	return call("_>_", lifetime, constant(&exprpb.Constant{
+		ConstantKind: &exprpb.Constant_DoubleValue{
+			DoubleValue: float64(maxLifetimeSeconds),
+		},
+	}))
+}
@@ -0,0 +1,565 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func writeJwtProviderOptions(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwt_provider_options.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("fail to write JWT provider options file: %v", err)
+	}
+	return path
+}
+
+func jwtClaimsTestServiceConfig() *confpb.Service {
+	return &confpb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "ListShelves",
+					},
+				},
+			},
+		},
+		Authentication: &confpb.Authentication{
+			Providers: []*confpb.AuthProvider{
+				{
+					Id:      "auth_provider",
+					Issuer:  "issuer-0",
+					JwksUri: "https://fake-jwks.com",
+				},
+			},
+			Rules: []*confpb.AuthenticationRule{
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+					Requirements: []*confpb.AuthRequirement{
+						{
+							ProviderId: "auth_provider",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestNewJwtClaimsFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	claimsPath := writeJwtProviderOptions(t, `{
+  "providers": {
+    "auth_provider": {
+      "required_claims": ["email"],
+      "claim_constraints": {
+        "hd": {"exact": "example.com"},
+        "azp": {"regex": "client-[0-9]+"}
+      }
+    },
+    "unknown_provider": {
+      "required_claims": ["email"]
+    }
+  }
+}`)
+	issuerRegexPath := writeJwtProviderOptions(t, `{
+  "providers": {
+    "auth_provider": {
+      "issuer_regex": "issuer-[0-9]+"
+    }
+  }
+}`)
+	twoProvidersServiceConfig := jwtClaimsTestServiceConfig()
+	twoProvidersServiceConfig.Authentication.Providers = append(twoProvidersServiceConfig.Authentication.Providers, &confpb.AuthProvider{
+		Id:      "another_provider",
+		Issuer:  "another-issuer",
+		JwksUri: "https://another-fake-jwks.com",
+	})
+	maxLifetimePath := writeJwtProviderOptions(t, `{
+  "providers": {
+    "auth_provider": {
+      "max_lifetime_seconds": 3600
+    }
+  }
+}`)
+	clockSkewOnlyPath := writeJwtProviderOptions(t, `{
+  "providers": {
+    "auth_provider": {
+      "clock_skew_seconds": 30
+    }
+  }
+}`)
+
+	claimRules := `
+  "action": "DENY",
+  "policies": {
+    "jwt-claims-auth_provider": {
+      "permissions": [{"any": true}],
+      "principals": [
+        {
+          "andIds": {
+            "ids": [
+              {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "iss"}], "value": {"stringMatch": {"exact": "issuer-0"}}}},
+              {
+                "notId": {
+                  "andIds": {
+                    "ids": [
+                      {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "email"}], "value": {"presentMatch": true}}},
+                      {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "azp"}], "value": {"stringMatch": {"safeRegex": {"regex": "client-[0-9]+"}}}}},
+                      {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "hd"}], "value": {"stringMatch": {"exact": "example.com"}}}}
+                    ]
+                  }
+                }
+              }
+            ]
+          }
+        }
+      ]
+    }
+  }`
+
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Required claims and claim constraints",
+			ServiceConfigIn: jwtClaimsTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtProviderOptionsPath: claimsPath,
+			},
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`{"rules": {` + claimRules + `}}`,
+			},
+		},
+		{
+			Desc:            "Claim checks are shadow rules in audit only mode",
+			ServiceConfigIn: jwtClaimsTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtProviderOptionsPath: claimsPath,
+				JwtAuthnAuditOnly:      true,
+			},
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`{"shadowRules": {` + claimRules + `}, "shadowRulesStatPrefix": "jwt_claims_audit_"}`,
+			},
+		},
+		{
+			Desc:            "Issuer regex rejects unknown issuers",
+			ServiceConfigIn: jwtClaimsTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtProviderOptionsPath: issuerRegexPath,
+			},
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+  "rules": {
+    "action": "DENY",
+    "policies": {
+      "jwt-unknown-issuer": {
+        "permissions": [{"any": true}],
+        "principals": [
+          {
+            "andIds": {
+              "ids": [
+                {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}], "value": {"presentMatch": true}}},
+                {
+                  "notId": {
+                    "orIds": {
+                      "ids": [
+                        {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "iss"}], "value": {"stringMatch": {"safeRegex": {"regex": "issuer-[0-9]+"}}}}}
+                      ]
+                    }
+                  }
+                }
+              ]
+            }
+          }
+        ]
+      }
+    }
+  }
+}`,
+			},
+		},
+		{
+			Desc:            "Issuer regex allows the issuers of providers without options",
+			ServiceConfigIn: twoProvidersServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtProviderOptionsPath: issuerRegexPath,
+			},
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+  "rules": {
+    "action": "DENY",
+    "policies": {
+      "jwt-unknown-issuer": {
+        "permissions": [{"any": true}],
+        "principals": [
+          {
+            "andIds": {
+              "ids": [
+                {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}], "value": {"presentMatch": true}}},
+                {
+                  "notId": {
+                    "orIds": {
+                      "ids": [
+                        {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "iss"}], "value": {"stringMatch": {"exact": "another-issuer"}}}},
+                        {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "iss"}], "value": {"stringMatch": {"safeRegex": {"regex": "issuer-[0-9]+"}}}}}
+                      ]
+                    }
+                  }
+                }
+              ]
+            }
+          }
+        ]
+      }
+    }
+  }
+}`,
+			},
+		},
+		{
+			Desc:            "Max lifetime requires exp and iat and checks their difference",
+			ServiceConfigIn: jwtClaimsTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtProviderOptionsPath: maxLifetimePath,
+			},
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				`
+{
+  "rules": {
+    "action": "DENY",
+    "policies": {
+      "jwt-claims-auth_provider": {
+        "permissions": [{"any": true}],
+        "principals": [
+          {
+            "andIds": {
+              "ids": [
+                {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "iss"}], "value": {"stringMatch": {"exact": "issuer-0"}}}},
+                {
+                  "notId": {
+                    "andIds": {
+                      "ids": [
+                        {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "exp"}], "value": {"presentMatch": true}}},
+                        {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "iat"}], "value": {"presentMatch": true}}}
+                      ]
+                    }
+                  }
+                }
+              ]
+            }
+          }
+        ]
+      },
+      "jwt-max-lifetime-auth_provider": {
+        "permissions": [{"any": true}],
+        "principals": [
+          {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "iss"}], "value": {"stringMatch": {"exact": "issuer-0"}}}}
+        ],
+        "condition": {
+          "id": "19",
+          "callExpr": {
+            "function": "_>_",
+            "args": [
+              {
+                "id": "17",
+                "callExpr": {
+                  "function": "_-_",
+                  "args": [
+                    {"id": "8", "callExpr": {"function": "_[_]", "args": [
+                      {"id": "6", "callExpr": {"function": "_[_]", "args": [
+                        {"id": "4", "callExpr": {"function": "_[_]", "args": [
+                          {"id": "1", "selectExpr": {"operand": {"id": "2", "identExpr": {"name": "metadata"}}, "field": "filter_metadata"}},
+                          {"id": "3", "constExpr": {"stringValue": "envoy.filters.http.jwt_authn"}}
+                        ]}},
+                        {"id": "5", "constExpr": {"stringValue": "jwt_payloads"}}
+                      ]}},
+                      {"id": "7", "constExpr": {"stringValue": "exp"}}
+                    ]}},
+                    {"id": "16", "callExpr": {"function": "_[_]", "args": [
+                      {"id": "14", "callExpr": {"function": "_[_]", "args": [
+                        {"id": "12", "callExpr": {"function": "_[_]", "args": [
+                          {"id": "9", "selectExpr": {"operand": {"id": "10", "identExpr": {"name": "metadata"}}, "field": "filter_metadata"}},
+                          {"id": "11", "constExpr": {"stringValue": "envoy.filters.http.jwt_authn"}}
+                        ]}},
+                        {"id": "13", "constExpr": {"stringValue": "jwt_payloads"}}
+                      ]}},
+                      {"id": "15", "constExpr": {"stringValue": "iat"}}
+                    ]}}
+                  ]
+                }
+              },
+              {"id": "18", "constExpr": {"doubleValue": 3600}}
+            ]
+          }
+        }
+      }
+    }
+  }
+}`,
+			},
+		},
+		{
+			Desc:            "No-op when only the clock skew is set",
+			ServiceConfigIn: jwtClaimsTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtProviderOptionsPath: clockSkewOnlyPath,
+			},
+			WantFilterConfigs: nil,
+		},
+		{
+			Desc:              "No-op without provider options",
+			ServiceConfigIn:   jwtClaimsTestServiceConfig(),
+			WantFilterConfigs: nil,
+		},
+		{
+			Desc:            "No-op when the JWT authn filter is skipped",
+			ServiceConfigIn: jwtClaimsTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtProviderOptionsPath: claimsPath,
+				SkipJwtAuthnFilter:     true,
+			},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewJwtClaimsFilterGensFromOPConfig)
+	}
+}
+
+func TestNewJwtClaimsFilterGensFromOPConfig_BadOptions(t *testing.T) {
+	testdata := []struct {
+		desc    string
+		content string
+		wantErr string
+	}{
+		{
+			desc:    "Not JSON",
+			content: `not-json`,
+			wantErr: "fail to parse JWT provider options file",
+		},
+		{
+			desc:    "Negative max lifetime",
+			content: `{"providers": {"auth_provider": {"max_lifetime_seconds": -1}}}`,
+			wantErr: "max_lifetime_seconds must not be negative",
+		},
+		{
+			desc:    "Invalid issuer regex",
+			content: `{"providers": {"auth_provider": {"issuer_regex": "issuer-["}}}`,
+			wantErr: "invalid issuer_regex",
+		},
+		{
+			desc:    "Claim constraint with both exact and regex",
+			content: `{"providers": {"auth_provider": {"claim_constraints": {"hd": {"exact": "a", "regex": "b"}}}}}`,
+			wantErr: "must set exactly one of exact or regex",
+		},
+	}
+
+	for _, tc := range testdata {
+		errTc := filtergentest.FactoryErrorOPTestCase{
+			Desc:            tc.desc,
+			ServiceConfigIn: jwtClaimsTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				JwtProviderOptionsPath: writeJwtProviderOptions(t, tc.content),
+			},
+			WantFactoryError: tc.wantErr,
+		}
+		errTc.RunTest(t, filtergen.NewJwtClaimsFilterGensFromOPConfig)
+	}
+
+	missingTc := filtergentest.FactoryErrorOPTestCase{
+		Desc:            "Options file does not exist",
+		ServiceConfigIn: jwtClaimsTestServiceConfig(),
+		OptsIn: options.ConfigGeneratorOptions{
+			JwtProviderOptionsPath: "/does/not/exist.json",
+		},
+		WantFactoryError: "fail to read JWT provider options file",
+	}
+	missingTc.RunTest(t, filtergen.NewJwtClaimsFilterGensFromOPConfig)
+}
+
+func TestJwtClaimsGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.JwtProviderOptionsPath = writeJwtProviderOptions(t, `{"providers": {"auth_provider": {"required_claims": ["email"]}}}`)
+	opts.JwtAuthnAuditOnlySelectors = "endpoints.examples.bookstore.Bookstore.ListShelves"
+
+	gens, err := filtergen.NewJwtClaimsFilterGensFromOPConfig(jwtClaimsTestServiceConfig(), opts)
+	if err != nil {
+		t.Fatalf("NewJwtClaimsFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewJwtClaimsFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	got, err := gens[0].GenPerRouteConfig("endpoints.examples.bookstore.Bookstore.Unknown", nil)
+	if err != nil || got != nil {
+		t.Errorf("GenPerRouteConfig() for enforced selector got (%v, %v), want (nil, nil)", got, err)
+	}
+
+	got, err = gens[0].GenPerRouteConfig("endpoints.examples.bookstore.Bookstore.ListShelves", nil)
+	if err != nil {
+		t.Fatalf("GenPerRouteConfig() got error: %v", err)
+	}
+	gotJson, err := util.ProtoToJson(got)
+	if err != nil {
+		t.Fatalf("ProtoToJson() got error: %v", err)
+	}
+	wantJson := `
+{
+  "rbac": {
+    "shadowRules": {
+      "action": "DENY",
+      "policies": {
+        "jwt-claims-auth_provider": {
+          "permissions": [{"any": true}],
+          "principals": [
+            {
+              "andIds": {
+                "ids": [
+                  {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "iss"}], "value": {"stringMatch": {"exact": "issuer-0"}}}},
+                  {
+                    "notId": {
+                      "andIds": {
+                        "ids": [
+                          {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "email"}], "value": {"presentMatch": true}}}
+                        ]
+                      }
+                    }
+                  }
+                ]
+              }
+            }
+          ]
+        }
+      }
+    },
+    "shadowRulesStatPrefix": "jwt_claims_audit_"
+  }
+}`
+	if err := util.JsonEqual(wantJson, gotJson); err != nil {
+		t.Errorf("GenPerRouteConfig() has diff: %v", err)
+	}
+}
+
+func TestJwtClaimsGenerator_GenPerRouteConfig_OAuthScopes(t *testing.T) {
+	serviceConfig := jwtClaimsTestServiceConfig()
+	serviceConfig.Authentication.Rules[0].Oauth = &confpb.OAuthRequirements{
+		CanonicalScopes: "https://www.googleapis.com/auth/calendar, read",
+	}
+
+	wantScopesPolicy := `{
+  "jwt-scopes": {
+    "permissions": [{"any": true}],
+    "principals": [
+      {
+        "andIds": {
+          "ids": [
+            {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}], "value": {"presentMatch": true}}},
+            {
+              "notId": {
+                "orIds": {
+                  "ids": [
+                    {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "scope"}], "value": {"stringMatch": {"safeRegex": {"regex": "(.* )?https://www\\.googleapis\\.com/auth/calendar( .*)?"}}}}},
+                    {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "scp"}], "value": {"stringMatch": {"safeRegex": {"regex": "(.* )?https://www\\.googleapis\\.com/auth/calendar( .*)?"}}}}},
+                    {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "scp"}], "value": {"listMatch": {"oneOf": {"stringMatch": {"exact": "https://www.googleapis.com/auth/calendar"}}}}}},
+                    {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "scope"}], "value": {"stringMatch": {"safeRegex": {"regex": "(.* )?read( .*)?"}}}}},
+                    {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "scp"}], "value": {"stringMatch": {"safeRegex": {"regex": "(.* )?read( .*)?"}}}}},
+                    {"metadata": {"filter": "envoy.filters.http.jwt_authn", "path": [{"key": "jwt_payloads"}, {"key": "scp"}], "value": {"listMatch": {"oneOf": {"stringMatch": {"exact": "read"}}}}}}
+                  ]
+                }
+              }
+            }
+          ]
+        }
+      }
+    ]
+  }
+}`
+
+	testdata := []struct {
+		desc               string
+		auditOnlySelectors string
+		selector           string
+		wantPerRouteConfig string
+	}{
+		{
+			desc:               "Scopes are enforced",
+			selector:           "endpoints.examples.bookstore.Bookstore.ListShelves",
+			wantPerRouteConfig: `{"rbac": {"rules": {"action": "DENY", "policies": ` + wantScopesPolicy + `}}}`,
+		},
+		{
+			desc:               "Scopes are audited for audit only selectors",
+			auditOnlySelectors: "endpoints.examples.bookstore.Bookstore.ListShelves",
+			selector:           "endpoints.examples.bookstore.Bookstore.ListShelves",
+			wantPerRouteConfig: `{"rbac": {"shadowRules": {"action": "DENY", "policies": ` + wantScopesPolicy + `}, "shadowRulesStatPrefix": "jwt_claims_audit_"}}`,
+		},
+		{
+			desc:     "No per route config for selectors without scopes",
+			selector: "endpoints.examples.bookstore.Bookstore.Unknown",
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.JwtAuthnAuditOnlySelectors = tc.auditOnlySelectors
+
+			gens, err := filtergen.NewJwtClaimsFilterGensFromOPConfig(serviceConfig, opts)
+			if err != nil {
+				t.Fatalf("NewJwtClaimsFilterGensFromOPConfig() got error: %v", err)
+			}
+			if len(gens) != 1 {
+				t.Fatalf("NewJwtClaimsFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+			}
+
+			got, err := gens[0].GenPerRouteConfig(tc.selector, nil)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantPerRouteConfig == "" {
+				if got != nil {
+					t.Errorf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantPerRouteConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() has diff: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/helpers"
+	acpb "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// makeLocalReplyConfig converts the error message for requests rejected by
+// Envoy to JSON format by default:
+//
+//	{
+//	   "code": "http-status-code",
+//	   "message": "the error message",
+//	}
+//
+// The body format and the mappers can be customized by localReplyOptions.
+func makeLocalReplyConfig(localReplyOptions *helpers.LocalReplyOptions, isOAuthScopeCheckRequired bool) (*hcmpb.LocalReplyConfig, error) {
+	localReplyConfig := &hcmpb.LocalReplyConfig{
+		BodyFormat: &corepb.SubstitutionFormatString{
+			Format: &corepb.SubstitutionFormatString_JsonFormat{
+				JsonFormat: &structpb.Struct{
+					Fields: map[string]*structpb.Value{
+						"code": {
+							Kind: &structpb.Value_StringValue{StringValue: "%RESPONSE_CODE%"},
+						},
+						"message": {
+							Kind: &structpb.Value_StringValue{StringValue: "%LOCAL_REPLY_BODY%"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if localReplyOptions == nil {
+		// Requests denied by the OAuth scope check get the error of RFC 6750.
+		if isOAuthScopeCheckRequired {
+			localReplyConfig.Mappers = append(localReplyConfig.Mappers, makeOAuthScopeLocalReplyMapper())
+		}
+		return localReplyConfig, nil
+	}
+
+	if localReplyOptions.BodyFormat != nil {
+		bodyFormat, err := makeLocalReplyBodyFormat(localReplyOptions.BodyFormat)
+		if err != nil {
+			return nil, err
+		}
+		localReplyConfig.BodyFormat = bodyFormat
+	}
+
+	// Only the first matching mapper is applied, so each mapper is repeated
+	// with the body format of every format it can be combined with.
+	type format struct {
+		filter     *acpb.AccessLogFilter
+		bodyFormat *corepb.SubstitutionFormatString
+	}
+	var formats []format
+	for i, f := range localReplyOptions.Formats {
+		bodyFormat, err := makeLocalReplyBodyFormat(f.BodyFormat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid local reply format at index %d: %v", i, err)
+		}
+		var filters []*acpb.AccessLogFilter
+		if f.StatusCodes != nil {
+			filters = append(filters, makeStatusCodeRangeFilters(f.StatusCodes, fmt.Sprintf("format_%d", i))...)
+		}
+		if f.Accept != "" {
+			filters = append(filters, makeAcceptHeaderFilter(f.Accept))
+		}
+		formats = append(formats, format{
+			filter:     makeAndFilter(filters),
+			bodyFormat: bodyFormat,
+		})
+	}
+
+	// Requests denied by the OAuth scope check get the error of RFC 6750, in
+	// the body format of the matching format.
+	if isOAuthScopeCheckRequired {
+		for _, f := range formats {
+			mapper := makeOAuthScopeLocalReplyMapper()
+			mapper.Filter = makeAndFilter([]*acpb.AccessLogFilter{mapper.GetFilter(), f.filter})
+			mapper.BodyFormatOverride = f.bodyFormat
+			localReplyConfig.Mappers = append(localReplyConfig.Mappers, mapper)
+		}
+		localReplyConfig.Mappers = append(localReplyConfig.Mappers, makeOAuthScopeLocalReplyMapper())
+	}
+
+	for i, m := range localReplyOptions.Mappers {
+		var filters []*acpb.AccessLogFilter
+		if m.StatusCodes != nil {
+			filters = append(filters, makeStatusCodeRangeFilters(m.StatusCodes, fmt.Sprintf("mapper_%d", i))...)
+		}
+		if len(m.ResponseFlags) > 0 {
+			filters = append(filters, &acpb.AccessLogFilter{
+				FilterSpecifier: &acpb.AccessLogFilter_ResponseFlagFilter{
+					ResponseFlagFilter: &acpb.ResponseFlagFilter{
+						Flags: m.ResponseFlags,
+					},
+				},
+			})
+		}
+
+		for _, f := range formats {
+			mapper := makeLocalReplyMapper(m, makeAndFilter(append(append([]*acpb.AccessLogFilter{}, filters...), f.filter)))
+			mapper.BodyFormatOverride = f.bodyFormat
+			localReplyConfig.Mappers = append(localReplyConfig.Mappers, mapper)
+		}
+		localReplyConfig.Mappers = append(localReplyConfig.Mappers, makeLocalReplyMapper(m, makeAndFilter(filters)))
+	}
+
+	for _, f := range formats {
+		localReplyConfig.Mappers = append(localReplyConfig.Mappers, &hcmpb.ResponseMapper{
+			Filter:             f.filter,
+			BodyFormatOverride: f.bodyFormat,
+		})
+	}
+
+	return localReplyConfig, nil
+}
+
+func makeLocalReplyBodyFormat(f *helpers.LocalReplyBodyFormat) (*corepb.SubstitutionFormatString, error) {
+	switch {
+	case f.Preset == helpers.GoogleRPCStatusPreset:
+		return &corepb.SubstitutionFormatString{
+			Format: &corepb.SubstitutionFormatString_JsonFormat{
+				JsonFormat: makeGoogleRPCStatusJSONFormat(),
+			},
+		}, nil
+	case f.JSONFormat != nil:
+		jsonFormat, err := structpb.NewStruct(f.JSONFormat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid json_format: %v", err)
+		}
+		return &corepb.SubstitutionFormatString{
+			Format: &corepb.SubstitutionFormatString_JsonFormat{
+				JsonFormat: jsonFormat,
+			},
+		}, nil
+	default:
+		return &corepb.SubstitutionFormatString{
+			Format: &corepb.SubstitutionFormatString_TextFormat{
+				TextFormat: f.TextFormat,
+			},
+			ContentType: f.ContentType,
+		}, nil
+	}
+}
+
+// makeGoogleRPCStatusJSONFormat formats local replies like the JSON errors of
+// Google APIs:
+//
+//	{
+//	  "error": {
+//	    "code": 503,
+//	    "status": "UNAVAILABLE",
+//	    "message": "the error message",
+//	    "details": [
+//	      {
+//	        "@type": "type.googleapis.com/google.rpc.RequestInfo",
+//	        "requestId": "the x-request-id header"
+//	      }
+//	    ]
+//	  }
+//	}
+func makeGoogleRPCStatusJSONFormat() *structpb.Struct {
+	return &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"error": structpb.NewStructValue(&structpb.Struct{
+				Fields: map[string]*structpb.Value{
+					"code":    structpb.NewStringValue("%RESPONSE_CODE%"),
+					"status":  structpb.NewStringValue("%GRPC_STATUS(SNAKE_STRING)%"),
+					"message": structpb.NewStringValue("%LOCAL_REPLY_BODY%"),
+					"details": structpb.NewListValue(&structpb.ListValue{
+						Values: []*structpb.Value{
+							structpb.NewStructValue(&structpb.Struct{
+								Fields: map[string]*structpb.Value{
+									"@type":     structpb.NewStringValue("type.googleapis.com/google.rpc.RequestInfo"),
+									"requestId": structpb.NewStringValue("%REQ(X-REQUEST-ID)%"),
+								},
+							}),
+						},
+					}),
+				},
+			}),
+		},
+	}
+}
+
+func makeLocalReplyMapper(m *helpers.LocalReplyMapper, filter *acpb.AccessLogFilter) *hcmpb.ResponseMapper {
+	mapper := &hcmpb.ResponseMapper{
+		Filter: filter,
+	}
+	if m.NewStatusCode != 0 {
+		mapper.StatusCode = &wrapperspb.UInt32Value{Value: m.NewStatusCode}
+	}
+	if m.Body != "" {
+		mapper.Body = &corepb.DataSource{
+			Specifier: &corepb.DataSource_InlineString{
+				InlineString: m.Body,
+			},
+		}
+	}
+
+	// Sort the headers by key, so the config is stable.
+	var keys []string
+	for key := range m.HeadersToAdd {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		mapper.HeadersToAdd = append(mapper.HeadersToAdd, &corepb.HeaderValueOption{
+			Header: &corepb.HeaderValue{
+				Key:   key,
+				Value: m.HeadersToAdd[key],
+			},
+			AppendAction: corepb.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		})
+	}
+	return mapper
+}
+
+// makeStatusCodeRangeFilters matches the status codes in the range. The
+// runtime keys are required by Envoy, but are not used.
+func makeStatusCodeRangeFilters(r *helpers.StatusCodeRange, name string) []*acpb.AccessLogFilter {
+	if r.Min == r.Max {
+		return []*acpb.AccessLogFilter{
+			makeStatusCodeFilter(acpb.ComparisonFilter_EQ, r.Min, fmt.Sprintf("espv2.local_reply.%s.status_code", name)),
+		}
+	}
+	return []*acpb.AccessLogFilter{
+		makeStatusCodeFilter(acpb.ComparisonFilter_GE, r.Min, fmt.Sprintf("espv2.local_reply.%s.min_status_code", name)),
+		makeStatusCodeFilter(acpb.ComparisonFilter_LE, r.Max, fmt.Sprintf("espv2.local_reply.%s.max_status_code", name)),
+	}
+}
+
+func makeStatusCodeFilter(op acpb.ComparisonFilter_Op, code uint32, runtimeKey string) *acpb.AccessLogFilter {
+	return &acpb.AccessLogFilter{
+		FilterSpecifier: &acpb.AccessLogFilter_StatusCodeFilter{
+			StatusCodeFilter: &acpb.StatusCodeFilter{
+				Comparison: &acpb.ComparisonFilter{
+					Op: op,
+					Value: &corepb.RuntimeUInt32{
+						DefaultValue: code,
+						RuntimeKey:   runtimeKey,
+					},
+				},
+			},
+		},
+	}
+}
+
+func makeAcceptHeaderFilter(contentType string) *acpb.AccessLogFilter {
+	return &acpb.AccessLogFilter{
+		FilterSpecifier: &acpb.AccessLogFilter_HeaderFilter{
+			HeaderFilter: &acpb.HeaderFilter{
+				Header: &routepb.HeaderMatcher{
+					Name: "accept",
+					HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+						StringMatch: &matcherpb.StringMatcher{
+							MatchPattern: &matcherpb.StringMatcher_Contains{
+								Contains: contentType,
+							},
+							IgnoreCase: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// makeAndFilter matches when all the filters match.
+func makeAndFilter(filters []*acpb.AccessLogFilter) *acpb.AccessLogFilter {
+	if len(filters) == 1 {
+		return filters[0]
+	}
+	return &acpb.AccessLogFilter{
+		FilterSpecifier: &acpb.AccessLogFilter_AndFilter{
+			AndFilter: &acpb.AndFilter{
+				Filters: filters,
+			},
+		},
+	}
+}
+
+// makeOAuthScopeLocalReplyMapper adds the `WWW-Authenticate` header of RFC 6750
+// to the replies for JWTs without the required OAuth scopes.
+func makeOAuthScopeLocalReplyMapper() *hcmpb.ResponseMapper {
+	return &hcmpb.ResponseMapper{
+		Filter: &acpb.AccessLogFilter{
+			FilterSpecifier: &acpb.AccessLogFilter_MetadataFilter{
+				MetadataFilter: &acpb.MetadataFilter{
+					Matcher: &matcherpb.MetadataMatcher{
+						Filter: JwtClaimsFilterName,
+						Path: []*matcherpb.MetadataMatcher_PathSegment{
+							{
+								Segment: &matcherpb.MetadataMatcher_PathSegment_Key{
+									Key: rbacEnforcedPolicyIDMetadataName,
+								},
+							},
+						},
+						Value: &matcherpb.ValueMatcher{
+							MatchPattern: &matcherpb.ValueMatcher_StringMatch{
+								StringMatch: &matcherpb.StringMatcher{
+									MatchPattern: &matcherpb.StringMatcher_Exact{
+										Exact: JwtScopesPolicyID,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Body: &corepb.DataSource{
+			Specifier: &corepb.DataSource_InlineString{
+				InlineString: "JWT does not have any of the OAuth scopes required by the operation",
+			},
+		},
+		HeadersToAdd: []*corepb.HeaderValueOption{
+			{
+				Header: &corepb.HeaderValue{
+					Key:   "WWW-Authenticate",
+					Value: `Bearer error="insufficient_scope"`,
+				},
+				AppendAction: corepb.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+			},
+		},
+	}
+}
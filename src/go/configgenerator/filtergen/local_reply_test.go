@@ -0,0 +1,396 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func writeLocalReplyConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "local_reply_config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("fail to write local reply config file: %v", err)
+	}
+	return path
+}
+
+func TestHTTPConnectionManagerGenerator_LocalReplyConfig(t *testing.T) {
+	scopesServiceConfig := &servicepb.Service{
+		Authentication: &servicepb.Authentication{
+			Rules: []*servicepb.AuthenticationRule{
+				{
+					Selector: "bookstore.Bookstore.GetShelf",
+					Requirements: []*servicepb.AuthRequirement{
+						{
+							ProviderId: "auth_provider",
+						},
+					},
+					Oauth: &servicepb.OAuthRequirements{
+						CanonicalScopes: "https://www.googleapis.com/auth/read",
+					},
+				},
+			},
+		},
+	}
+
+	testdata := []struct {
+		desc                 string
+		serviceConfig        *servicepb.Service
+		localReplyConfig     string
+		wantLocalReplyConfig string
+	}{
+		{
+			desc:             "Preset body format",
+			localReplyConfig: `{"body_format": {"preset": "google_rpc_status"}}`,
+			wantLocalReplyConfig: `
+{
+  "bodyFormat": {
+    "jsonFormat": {
+      "error": {
+        "code": "%RESPONSE_CODE%",
+        "status": "%GRPC_STATUS(SNAKE_STRING)%",
+        "message": "%LOCAL_REPLY_BODY%",
+        "details": [
+          {
+            "@type": "type.googleapis.com/google.rpc.RequestInfo",
+            "requestId": "%REQ(X-REQUEST-ID)%"
+          }
+        ]
+      }
+    }
+  }
+}`,
+		},
+		{
+			desc:             "Text body format with content type",
+			localReplyConfig: `{"body_format": {"text_format": "%RESPONSE_CODE%: %LOCAL_REPLY_BODY%", "content_type": "text/plain; charset=UTF-8"}}`,
+			wantLocalReplyConfig: `
+{
+  "bodyFormat": {
+    "textFormat": "%RESPONSE_CODE%: %LOCAL_REPLY_BODY%",
+    "contentType": "text/plain; charset=UTF-8"
+  }
+}`,
+		},
+		{
+			desc: "Body format per status code range and content type",
+			localReplyConfig: `
+{
+  "formats": [
+    {
+      "status_codes": {"min": 500, "max": 599},
+      "accept": "text/html",
+      "body_format": {"text_format": "<h1>%RESPONSE_CODE%</h1>", "content_type": "text/html"}
+    },
+    {
+      "status_codes": {"min": 404, "max": 404},
+      "body_format": {"json_format": {"missing": "%REQ(:PATH)%"}}
+    }
+  ]
+}`,
+			wantLocalReplyConfig: `
+{
+  "bodyFormat": {
+    "jsonFormat": {
+      "code": "%RESPONSE_CODE%",
+      "message": "%LOCAL_REPLY_BODY%"
+    }
+  },
+  "mappers": [
+    {
+      "filter": {
+        "andFilter": {
+          "filters": [
+            {"statusCodeFilter": {"comparison": {"op": "GE", "value": {"defaultValue": 500, "runtimeKey": "espv2.local_reply.format_0.min_status_code"}}}},
+            {"statusCodeFilter": {"comparison": {"op": "LE", "value": {"defaultValue": 599, "runtimeKey": "espv2.local_reply.format_0.max_status_code"}}}},
+            {"headerFilter": {"header": {"name": "accept", "stringMatch": {"contains": "text/html", "ignoreCase": true}}}}
+          ]
+        }
+      },
+      "bodyFormatOverride": {
+        "textFormat": "<h1>%RESPONSE_CODE%</h1>",
+        "contentType": "text/html"
+      }
+    },
+    {
+      "filter": {
+        "statusCodeFilter": {"comparison": {"value": {"defaultValue": 404, "runtimeKey": "espv2.local_reply.format_1.status_code"}}}
+      },
+      "bodyFormatOverride": {
+        "jsonFormat": {"missing": "%REQ(:PATH)%"}
+      }
+    }
+  ]
+}`,
+		},
+		{
+			desc: "Mapper is combined with every format",
+			localReplyConfig: `
+{
+  "formats": [
+    {
+      "accept": "text/html",
+      "body_format": {"text_format": "<p>%LOCAL_REPLY_BODY%</p>", "content_type": "text/html"}
+    }
+  ],
+  "mappers": [
+    {
+      "response_flags": ["UH"],
+      "new_status_code": 503,
+      "body": "retry later",
+      "headers_to_add": {"x-b": "2", "retry-after": "5"}
+    }
+  ]
+}`,
+			wantLocalReplyConfig: `
+{
+  "bodyFormat": {
+    "jsonFormat": {
+      "code": "%RESPONSE_CODE%",
+      "message": "%LOCAL_REPLY_BODY%"
+    }
+  },
+  "mappers": [
+    {
+      "filter": {
+        "andFilter": {
+          "filters": [
+            {"responseFlagFilter": {"flags": ["UH"]}},
+            {"headerFilter": {"header": {"name": "accept", "stringMatch": {"contains": "text/html", "ignoreCase": true}}}}
+          ]
+        }
+      },
+      "statusCode": 503,
+      "body": {"inlineString": "retry later"},
+      "headersToAdd": [
+        {"header": {"key": "retry-after", "value": "5"}, "appendAction": "OVERWRITE_IF_EXISTS_OR_ADD"},
+        {"header": {"key": "x-b", "value": "2"}, "appendAction": "OVERWRITE_IF_EXISTS_OR_ADD"}
+      ],
+      "bodyFormatOverride": {
+        "textFormat": "<p>%LOCAL_REPLY_BODY%</p>",
+        "contentType": "text/html"
+      }
+    },
+    {
+      "filter": {"responseFlagFilter": {"flags": ["UH"]}},
+      "statusCode": 503,
+      "body": {"inlineString": "retry later"},
+      "headersToAdd": [
+        {"header": {"key": "retry-after", "value": "5"}, "appendAction": "OVERWRITE_IF_EXISTS_OR_ADD"},
+        {"header": {"key": "x-b", "value": "2"}, "appendAction": "OVERWRITE_IF_EXISTS_OR_ADD"}
+      ]
+    },
+    {
+      "filter": {"headerFilter": {"header": {"name": "accept", "stringMatch": {"contains": "text/html", "ignoreCase": true}}}},
+      "bodyFormatOverride": {
+        "textFormat": "<p>%LOCAL_REPLY_BODY%</p>",
+        "contentType": "text/html"
+      }
+    }
+  ]
+}`,
+		},
+		{
+			desc:          "OAuth scope mapper is combined with every format",
+			serviceConfig: scopesServiceConfig,
+			localReplyConfig: `
+{
+  "formats": [
+    {
+      "accept": "text/html",
+      "body_format": {"text_format": "<p>%LOCAL_REPLY_BODY%</p>", "content_type": "text/html"}
+    }
+  ]
+}`,
+			wantLocalReplyConfig: `
+{
+  "bodyFormat": {
+    "jsonFormat": {
+      "code": "%RESPONSE_CODE%",
+      "message": "%LOCAL_REPLY_BODY%"
+    }
+  },
+  "mappers": [
+    {
+      "filter": {
+        "andFilter": {
+          "filters": [
+            {"metadataFilter": {"matcher": {"filter": "envoy.filters.http.rbac", "path": [{"key": "enforced_effective_policy_id"}], "value": {"stringMatch": {"exact": "jwt-scopes"}}}}},
+            {"headerFilter": {"header": {"name": "accept", "stringMatch": {"contains": "text/html", "ignoreCase": true}}}}
+          ]
+        }
+      },
+      "body": {"inlineString": "JWT does not have any of the OAuth scopes required by the operation"},
+      "headersToAdd": [
+        {"header": {"key": "WWW-Authenticate", "value": "Bearer error=\"insufficient_scope\""}, "appendAction": "OVERWRITE_IF_EXISTS_OR_ADD"}
+      ],
+      "bodyFormatOverride": {
+        "textFormat": "<p>%LOCAL_REPLY_BODY%</p>",
+        "contentType": "text/html"
+      }
+    },
+    {
+      "filter": {"metadataFilter": {"matcher": {"filter": "envoy.filters.http.rbac", "path": [{"key": "enforced_effective_policy_id"}], "value": {"stringMatch": {"exact": "jwt-scopes"}}}}},
+      "body": {"inlineString": "JWT does not have any of the OAuth scopes required by the operation"},
+      "headersToAdd": [
+        {"header": {"key": "WWW-Authenticate", "value": "Bearer error=\"insufficient_scope\""}, "appendAction": "OVERWRITE_IF_EXISTS_OR_ADD"}
+      ]
+    },
+    {
+      "filter": {"headerFilter": {"header": {"name": "accept", "stringMatch": {"contains": "text/html", "ignoreCase": true}}}},
+      "bodyFormatOverride": {
+        "textFormat": "<p>%LOCAL_REPLY_BODY%</p>",
+        "contentType": "text/html"
+      }
+    }
+  ]
+}`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			serviceConfig := tc.serviceConfig
+			if serviceConfig == nil {
+				serviceConfig = &servicepb.Service{}
+			}
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.LocalReplyConfigPath = writeLocalReplyConfig(t, tc.localReplyConfig)
+
+			gen, err := filtergen.NewHTTPConnectionManagerGenFromOPConfig(serviceConfig, opts)
+			if err != nil {
+				t.Fatalf("NewHTTPConnectionManagerGenFromOPConfig() got error: %v", err)
+			}
+			config, err := gen.GenFilterConfig()
+			if err != nil {
+				t.Fatalf("GenFilterConfig() got error: %v", err)
+			}
+
+			gotJson, err := util.ProtoToJson(config.(*hcmpb.HttpConnectionManager).LocalReplyConfig)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantLocalReplyConfig, gotJson); err != nil {
+				t.Errorf("GenFilterConfig() got unexpected local reply config: %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPConnectionManagerGenerator_LocalReplyConfigFactoryError(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc: "Missing local reply config file",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath: "/does/not/exist.json",
+			},
+			WantFactoryError: "fail to read local reply config file",
+		},
+		{
+			Desc: "Malformed local reply config file",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath: writeLocalReplyConfig(t, `{"mappers": {}}`),
+			},
+			WantFactoryError: "fail to parse local reply config file",
+		},
+		{
+			Desc: "Body format with two formats",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath: writeLocalReplyConfig(t, `{"body_format": {"text_format": "a", "preset": "google_rpc_status"}}`),
+			},
+			WantFactoryError: "invalid local reply body_format: exactly one of text_format, json_format and preset must be set",
+		},
+		{
+			Desc: "Unknown preset",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath: writeLocalReplyConfig(t, `{"body_format": {"preset": "html"}}`),
+			},
+			WantFactoryError: `unknown preset "html"`,
+		},
+		{
+			Desc: "Preset with rich error details of transcoded calls",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath:        writeLocalReplyConfig(t, `{"formats": [{"accept": "application/json", "body_format": {"preset": "google_rpc_status"}}]}`),
+				TranscodingRichErrorDetails: true,
+			},
+			WantFactoryError: `local reply preset "google_rpc_status" cannot be used together with --transcoding_rich_error_details`,
+		},
+		{
+			Desc: "Content type with JSON format",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath: writeLocalReplyConfig(t, `{"body_format": {"json_format": {"a": "b"}, "content_type": "text/html"}}`),
+			},
+			WantFactoryError: "content_type can only be used together with text_format",
+		},
+		{
+			Desc: "Format without matcher",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath: writeLocalReplyConfig(t, `{"formats": [{"body_format": {"preset": "google_rpc_status"}}]}`),
+			},
+			WantFactoryError: "invalid local reply format at index 0: at least one of status_codes and accept must be set",
+		},
+		{
+			Desc: "Format with invalid status code range",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath: writeLocalReplyConfig(t, `{"formats": [{"status_codes": {"min": 500, "max": 400}, "body_format": {"preset": "google_rpc_status"}}]}`),
+			},
+			WantFactoryError: "invalid status_codes {min: 500, max: 400}",
+		},
+		{
+			Desc: "Mapper without rewrite",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath: writeLocalReplyConfig(t, `{"mappers": [{"response_flags": ["UH"]}]}`),
+			},
+			WantFactoryError: "invalid local reply mapper at index 0: at least one of new_status_code, body and headers_to_add must be set",
+		},
+		{
+			Desc: "Mapper with invalid new status code",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath: writeLocalReplyConfig(t, `{"mappers": [{"response_flags": ["UH"], "new_status_code": 99}]}`),
+			},
+			WantFactoryError: "invalid new_status_code 99",
+		},
+		{
+			Desc: "Mapper with pseudo header",
+			OptsIn: options.ConfigGeneratorOptions{
+				LocalReplyConfigPath: writeLocalReplyConfig(t, `{"mappers": [{"status_codes": {"min": 503, "max": 503}, "headers_to_add": {":status": "200"}}]}`),
+			},
+			WantFactoryError: `invalid header ":status" in headers_to_add`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, func(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]filtergen.FilterGenerator, error) {
+			gen, err := filtergen.NewHTTPConnectionManagerGenFromOPConfig(serviceConfig, opts)
+			if err != nil {
+				return nil, err
+			}
+
+			return []filtergen.FilterGenerator{
+				gen,
+			}, nil
+		})
+	}
+}
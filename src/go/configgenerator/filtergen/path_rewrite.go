@@ -61,6 +61,10 @@ func NewPathRewriteFilterGensFromOPConfig(serviceConfig *confpb.Service, opts op
 		return nil, nil
 	}
 
+	if err := validateReverseTranscodingTranslationInfo(serviceConfig, opts, info); err != nil {
+		return nil, err
+	}
+
 	return []FilterGenerator{
 		&PathRewriteGenerator{
 			CORSOperationDelimiter:    opts.CorsOperationDelimiter,
@@ -132,6 +136,33 @@ func (g *PathRewriteGenerator) GenPerRouteConfig(selector string, httpRule *http
 	return nil, nil
 }
 
+// validateReverseTranscodingTranslationInfo rejects CONSTANT_ADDRESS for the
+// reverse transcoded operations whose http rule path has variables. Their
+// routes match the gRPC path, which has no variables, so the variables could
+// not be passed to the backend as query parameters.
+func validateReverseTranscodingTranslationInfo(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions, info map[string]TranslationInfo) error {
+	reverseTranscodingSelectors, err := GetReverseTranscodingSelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range serviceConfig.GetHttp().GetRules() {
+		selector := rule.GetSelector()
+		if !reverseTranscodingSelectors[selector] || info[selector].TranslationType != confpb.BackendRule_CONSTANT_ADDRESS {
+			continue
+		}
+
+		uriTemplate, err := httppattern.ParseUriTemplate(httpRulePath(rule))
+		if err != nil {
+			return fmt.Errorf("fail to parse the http rule path of operation %q: %v", selector, err)
+		}
+		if len(uriTemplate.Variables) > 0 {
+			return fmt.Errorf("operation %q cannot use path_translation CONSTANT_ADDRESS with gRPC JSON reverse transcoding because its http rule path %q has variables, use APPEND_PATH_TO_ADDRESS instead", selector, uriTemplate.Origin)
+		}
+	}
+	return nil
+}
+
 // GenTranslationInfoFromOPConfig returns per-route related translation information for each selector.
 //
 // Replaces ServiceInfo::ruleToBackendInfo.
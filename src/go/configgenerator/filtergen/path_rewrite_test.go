@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/google/go-cmp/cmp"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
@@ -236,3 +237,68 @@ func TestGetTranslationInfoFromOPConfig(t *testing.T) {
 		})
 	}
 }
+
+func pathRewriteReverseTranscodingTestServiceConfig(t *testing.T, pathTranslation servicepb.BackendRule_PathTranslation) *servicepb.Service {
+	t.Helper()
+	serviceConfig := reverseTranscoderTestServiceConfig(t, nil)
+	serviceConfig.Backend.Rules = append(serviceConfig.Backend.Rules, &servicepb.BackendRule{
+		Selector:        "bookstore.Bookstore.GetShelf",
+		Address:         "https://my-backend.com:8080/api/get-shelf",
+		PathTranslation: pathTranslation,
+	})
+	return serviceConfig
+}
+
+func TestNewPathRewriteFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	wantFilterConfigs := []string{`
+{
+  "name":"com.google.espv2.filters.http.path_rewrite",
+  "typedConfig":{
+    "@type":"type.googleapis.com/espv2.api.envoy.v12.http.path_rewrite.FilterConfig"
+  }
+}
+`,
+	}
+
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "CONSTANT_ADDRESS is allowed with path variables without reverse transcoding",
+			ServiceConfigIn: pathRewriteReverseTranscodingTestServiceConfig(t, servicepb.BackendRule_CONSTANT_ADDRESS),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "http://127.0.0.1:8080",
+			},
+			WantFilterConfigs: wantFilterConfigs,
+		},
+		{
+			Desc:            "APPEND_PATH_TO_ADDRESS is allowed with path variables for reverse transcoding",
+			ServiceConfigIn: pathRewriteReverseTranscodingTestServiceConfig(t, servicepb.BackendRule_APPEND_PATH_TO_ADDRESS),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:                   "http://127.0.0.1:8080",
+				EnableGrpcJsonReverseTranscoding: true,
+			},
+			WantFilterConfigs: wantFilterConfigs,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewPathRewriteFilterGensFromOPConfig)
+	}
+}
+
+func TestNewPathRewriteFilterGensFromOPConfig_FactoryError(t *testing.T) {
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "CONSTANT_ADDRESS is rejected with path variables for reverse transcoding",
+			ServiceConfigIn: pathRewriteReverseTranscodingTestServiceConfig(t, servicepb.BackendRule_CONSTANT_ADDRESS),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:                   "http://127.0.0.1:8080",
+				EnableGrpcJsonReverseTranscoding: true,
+			},
+			WantFactoryError: `operation "bookstore.Bookstore.GetShelf" cannot use path_translation CONSTANT_ADDRESS with gRPC JSON reverse transcoding because its http rule path "/v1/shelves/{shelf}" has variables`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewPathRewriteFilterGensFromOPConfig)
+	}
+}
@@ -55,6 +55,10 @@ type ServiceControlGenerator struct {
 	CallCredentials   *options.IAMCredentialsOptions
 	AccessToken       *helpers.FilterAccessTokenConfiger
 
+	// UseLocalSink indicates ServiceControlURI is the local Service Control
+	// sink in the config manager, which also serves the access token.
+	UseLocalSink bool
+
 	// General options below.
 
 	DisableTracing          bool
@@ -96,7 +100,7 @@ func NewServiceControlFilterGensFromOPConfig(serviceConfig *confpb.Service, opts
 		return nil, nil
 	}
 
-	if serviceConfig.GetControl().GetEnvironment() == "" {
+	if serviceConfig.GetControl().GetEnvironment() == "" && opts.ServiceControlLocalSink == "" {
 		glog.Infof("Not adding service control (v1) filter gen because the service control URL is not set in OP config.")
 		return nil, nil
 	}
@@ -126,6 +130,7 @@ func NewServiceControlFilterGensFromOPConfig(serviceConfig *confpb.Service, opts
 			ServiceControlURI:           scURL,
 			CallCredentials:             opts.ServiceControlCredentials,
 			AccessToken:                 helpers.NewFilterAccessTokenConfigerFromOPConfig(opts),
+			UseLocalSink:                opts.ServiceControlLocalSink != "",
 			DisableTracing:              opts.CommonOptions.TracingOptions.DisableTracing,
 			TracingProjectID:            opts.CommonOptions.TracingOptions.ProjectId,
 			HttpRequestTimeout:          opts.HttpRequestTimeout,
@@ -214,7 +219,17 @@ func (g *ServiceControlGenerator) GenFilterConfig() (proto.Message, error) {
 	}
 
 	accessTokenConfig := g.AccessToken.MakeAccessTokenConfig()
-	if g.CallCredentials != nil {
+	if g.UseLocalSink {
+		// The local sink does not check credentials, fetch its dummy token
+		// so no Google credentials are needed.
+		filterConfig.AccessToken = &scpb.FilterConfig_ImdsToken{
+			ImdsToken: &commonpb.HttpUri{
+				Uri:     g.ServiceControlURI.String() + util.TokenAgentAccessTokenPath,
+				Cluster: clustergen.ServiceControlClusterName,
+				Timeout: durationpb.New(g.HttpRequestTimeout),
+			},
+		}
+	} else if g.CallCredentials != nil {
 		// Use access token fetched from Google Cloud IAM Server to talk to Service Controller
 		filterConfig.AccessToken = &scpb.FilterConfig_IamToken{
 			IamToken: &commonpb.IamTokenInfo{
@@ -319,6 +334,11 @@ func ParseServiceControlURLFromOPConfig(serviceConfig *confpb.Service, opts opti
 // GetServiceControlURLFromOPConfig chooses the right data source to read the Service
 // Control URL from.
 func GetServiceControlURLFromOPConfig(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) string {
+	// The local sink replaces the Service Control server entirely.
+	if opts.ServiceControlLocalSink != "" {
+		return util.ServiceControlLocalSinkURL(opts.ServiceControlLocalSinkPort)
+	}
+
 	// Ignore value from ServiceConfig if flag is set
 	if uri := opts.ServiceControlURL; uri != "" {
 		return uri
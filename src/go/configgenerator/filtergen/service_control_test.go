@@ -200,6 +200,54 @@ func TestNewServiceControlFilterGensFromOPConfig_GenConfig(t *testing.T) {
       ]
    }
 }
+`,
+				},
+			},
+		},
+		{
+			SuccessOPTestCase: filtergentest.SuccessOPTestCase{
+				Desc: "No methods, local sink without control environment",
+				ServiceConfigIn: &servicepb.Service{
+					Name: "bookstore.endpoints.project123.cloud.goog",
+					Id:   "2019-03-02r0",
+				},
+				OptsIn: options.ConfigGeneratorOptions{
+					ServiceAccountKey:       "this-is-sa-cred",
+					ServiceControlLocalSink: "/var/log/espv2/reports.jsonl",
+				},
+				WantFilterConfigs: []string{`
+{
+   "name":"com.google.espv2.filters.http.service_control",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.service_control.FilterConfig",
+      "depErrorBehavior":"BLOCK_INIT_ON_ANY_ERROR",
+      "generatedHeaderPrefix":"X-Endpoint-",
+      "imdsToken":{
+         "cluster":"service-control-cluster",
+         "timeout":"30s",
+         "uri":"http://127.0.0.1:8792/local/access_token"
+      },
+      "scCallingConfig":{
+         "networkFailOpen":true
+      },
+      "serviceControlUri":{
+         "cluster":"service-control-cluster",
+         "timeout":"30s",
+         "uri":"http://127.0.0.1:8792/v1/services"
+      },
+      "services":[
+         {
+            "backendProtocol":"http1",
+            "jwtPayloadMetadataName":"jwt_payloads",
+            "serviceConfig":{
+               
+            },
+            "serviceConfigId":"2019-03-02r0",
+            "serviceName":"bookstore.endpoints.project123.cloud.goog"
+         }
+      ]
+   }
+}
 `,
 				},
 			},
@@ -602,6 +650,23 @@ func TestParseServiceControlURLFromOPConfig(t *testing.T) {
 				Host:   "servicecontrol.googleapis.com:443",
 			},
 		},
+		{
+			desc: "local sink overrides option and service config",
+			serviceConfigIn: &servicepb.Service{
+				Control: &servicepb.Control{
+					Environment: "https://staging-servicecontrol.sandbox.googleapis.com",
+				},
+			},
+			optionsIn: options.ConfigGeneratorOptions{
+				ServiceControlURL:           "https://servicecontrol.googleapis.com",
+				ServiceControlLocalSink:     "stdout",
+				ServiceControlLocalSinkPort: 9000,
+			},
+			wantServiceControlURI: url.URL{
+				Scheme: "http",
+				Host:   "127.0.0.1:9000",
+			},
+		},
 		{
 			desc:                  "Empty inputs results in empty URL",
 			serviceConfigIn:       &servicepb.Service{},
@@ -0,0 +1,114 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	ssepb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/sse_framing"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// SSEFramingFilterName is the Envoy filter name for debug logging.
+	SSEFramingFilterName = "com.google.espv2.filters.http.sse_framing"
+)
+
+// SSEFramingGenerator frames the transcoded responses of server-streaming
+// methods as server-sent events.
+type SSEFramingGenerator struct {
+	// SSESelectors are the selectors of the server-streaming methods whose
+	// responses are server-sent events.
+	SSESelectors map[string]bool
+
+	NoopFilterGenerator
+}
+
+// NewSSEFramingFilterGensFromOPConfig creates a SSEFramingGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewSSEFramingFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	if opts.LocalHTTPBackendAddress != "" {
+		glog.Info("Not adding SSE framing filter gen because the transcoder is skipped for the local http backend.")
+		return nil, nil
+	}
+
+	isGRPCSupportRequired, err := IsGRPCSupportRequiredForOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !isGRPCSupportRequired {
+		glog.Info("Not adding SSE framing filter gen because gRPC support is not required.")
+		return nil, nil
+	}
+
+	optionsBySelector, err := GetTranscodingOptionsBySelectorFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sseSelectors := make(map[string]bool)
+	for _, api := range serviceConfig.GetApis() {
+		if util.ShouldSkipOPDiscoveryAPI(api.GetName(), opts.AllowDiscoveryAPIs) {
+			continue
+		}
+		for _, method := range api.GetMethods() {
+			if !method.GetResponseStreaming() {
+				continue
+			}
+
+			selector := fmt.Sprintf("%s.%s", api.GetName(), method.GetName())
+			streamSSE := opts.TranscodingStreamSSE
+			if override, ok := optionsBySelector[selector]; ok && override.StreamSSE != nil {
+				streamSSE = *override.StreamSSE
+			}
+			if streamSSE {
+				sseSelectors[selector] = true
+			}
+		}
+	}
+
+	if len(sseSelectors) == 0 {
+		glog.Info("Not adding SSE framing filter gen because no server-streaming method streams server-sent events.")
+		return nil, nil
+	}
+
+	return []FilterGenerator{
+		&SSEFramingGenerator{
+			SSESelectors: sseSelectors,
+		},
+	}, nil
+}
+
+func (g *SSEFramingGenerator) FilterName() string {
+	return SSEFramingFilterName
+}
+
+// GenFilterConfig frames no responses, they are only framed for the routes
+// with a per-route config.
+func (g *SSEFramingGenerator) GenFilterConfig() (proto.Message, error) {
+	return &ssepb.FilterConfig{}, nil
+}
+
+func (g *SSEFramingGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	if !g.SSESelectors[selector] {
+		return nil, nil
+	}
+	return &ssepb.PerRouteFilterConfig{}, nil
+}
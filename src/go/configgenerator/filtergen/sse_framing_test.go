@@ -0,0 +1,171 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func sseFramingTestServiceConfig() *servicepb.Service {
+	return &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "testapi",
+				Methods: []*apipb.Method{
+					{Name: "Get"},
+					{Name: "Watch", ResponseStreaming: true},
+					{Name: "Tail", ResponseStreaming: true},
+				},
+			},
+		},
+	}
+}
+
+func TestNewSSEFramingFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "Generate with server-sent events for all server-streaming methods",
+			ServiceConfigIn: sseFramingTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:       "grpc://127.0.0.1:80",
+				TranscodingStreamSSE: true,
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.sse_framing",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.sse_framing.FilterConfig"
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "Generate with server-sent events for one operation",
+			ServiceConfigIn: sseFramingTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:         "grpc://127.0.0.1:80",
+				TranscodingOptionsPath: writeTranscodingFile(t, "transcoding_options.json", []byte(`{"options": [{"selectors": ["testapi.Tail"], "stream_sse": true}]}`)),
+			},
+			WantFilterConfigs: []string{
+				`
+{
+   "name":"com.google.espv2.filters.http.sse_framing",
+   "typedConfig":{
+      "@type":"type.googleapis.com/espv2.api.envoy.v12.http.sse_framing.FilterConfig"
+   }
+}
+`,
+			},
+		},
+		{
+			Desc:            "No-op without server-sent events",
+			ServiceConfigIn: sseFramingTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "grpc://127.0.0.1:80",
+			},
+			WantFilterConfigs: nil,
+		},
+		{
+			Desc:            "No-op for HTTP backends",
+			ServiceConfigIn: sseFramingTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:       "http://127.0.0.1:80",
+				TranscodingStreamSSE: true,
+			},
+			WantFilterConfigs: nil,
+		},
+		{
+			Desc:            "No-op for the local http backend",
+			ServiceConfigIn: sseFramingTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:          "grpc://127.0.0.1:80",
+				LocalHTTPBackendAddress: "http://127.0.0.1:8080",
+				TranscodingStreamSSE:    true,
+			},
+			WantFilterConfigs: nil,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewSSEFramingFilterGensFromOPConfig)
+	}
+}
+
+func TestSSEFramingGenerator_GenPerRouteConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.BackendAddress = "grpc://127.0.0.1:80"
+	opts.TranscodingStreamSSE = true
+	opts.TranscodingOptionsPath = writeTranscodingFile(t, "transcoding_options.json", []byte(`{"options": [{"selectors": ["testapi.Tail"], "stream_sse": false}]}`))
+
+	gens, err := filtergen.NewSSEFramingFilterGensFromOPConfig(sseFramingTestServiceConfig(), opts)
+	if err != nil {
+		t.Fatalf("NewSSEFramingFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewSSEFramingFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	testdata := []struct {
+		desc       string
+		selector   string
+		wantConfig string
+	}{
+		{
+			desc:       "Server-streaming method streams server-sent events",
+			selector:   "testapi.Watch",
+			wantConfig: `{}`,
+		},
+		{
+			desc:     "Operation option disables server-sent events",
+			selector: "testapi.Tail",
+		},
+		{
+			desc:     "Unary method is not framed",
+			selector: "testapi.Get",
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := gens[0].GenPerRouteConfig(tc.selector, nil)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantConfig == "" {
+				if got != nil {
+					t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	commonpb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/common"
+	tipb "github.com/GoogleCloudPlatform/esp-v2/src/go/proto/api/envoy/v12/http/token_introspection"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// TokenIntrospectionFilterName is the Envoy filter name for debug logging.
+	TokenIntrospectionFilterName = "com.google.espv2.filters.http.token_introspection"
+)
+
+type TokenIntrospectionGenerator struct {
+	// Providers maps provider IDs to their introspection config.
+	Providers map[string]*clustergen.TokenIntrospectionProvider
+
+	// PerRouteConfigBySelector is the introspection requirement of each
+	// selector with an introspection provider in its authentication rule.
+	PerRouteConfigBySelector map[string]*tipb.PerRouteFilterConfig
+
+	HttpRequestTimeout    time.Duration
+	GeneratedHeaderPrefix string
+
+	NoopFilterGenerator
+}
+
+// NewTokenIntrospectionFilterGensFromOPConfig creates a TokenIntrospectionGenerator from
+// OP service config + descriptor + ESPv2 options. It is a FilterGeneratorOPFactory.
+func NewTokenIntrospectionFilterGensFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) ([]FilterGenerator, error) {
+	providers, err := clustergen.GetTokenIntrospectionProvidersFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(providers) == 0 {
+		glog.Infof("Not adding token introspection filter gen because no authentication provider uses token introspection.")
+		return nil, nil
+	}
+
+	perRouteConfigBySelector := make(map[string]*tipb.PerRouteFilterConfig)
+	for _, rule := range serviceConfig.GetAuthentication().GetRules() {
+		selector := rule.GetSelector()
+		if util.ShouldSkipOPDiscoveryAPI(selector, opts.AllowDiscoveryAPIs) {
+			continue
+		}
+
+		var introspectionIDs []string
+		for _, r := range rule.GetRequirements() {
+			if _, ok := providers[r.GetProviderId()]; ok {
+				introspectionIDs = append(introspectionIDs, r.GetProviderId())
+			}
+		}
+		if len(introspectionIDs) == 0 {
+			continue
+		}
+		if len(rule.GetRequirements()) > 1 {
+			return nil, fmt.Errorf("authentication rule for %q uses token introspection provider %q, it cannot have other requirements", selector, introspectionIDs[0])
+		}
+		if rule.GetOauth().GetCanonicalScopes() != "" {
+			// Scopes are only checked on verified JWT payloads.
+			return nil, fmt.Errorf("authentication rule for %q uses token introspection provider %q, it cannot have canonical_scopes", selector, introspectionIDs[0])
+		}
+
+		perRouteConfigBySelector[selector] = &tipb.PerRouteFilterConfig{
+			ProviderId:   introspectionIDs[0],
+			AllowMissing: rule.GetAllowWithoutCredential(),
+		}
+	}
+
+	return []FilterGenerator{
+		&TokenIntrospectionGenerator{
+			Providers:                providers,
+			PerRouteConfigBySelector: perRouteConfigBySelector,
+			HttpRequestTimeout:       opts.HttpRequestTimeout,
+			GeneratedHeaderPrefix:    opts.GeneratedHeaderPrefix,
+		},
+	}, nil
+}
+
+func (g *TokenIntrospectionGenerator) FilterName() string {
+	return TokenIntrospectionFilterName
+}
+
+func (g *TokenIntrospectionGenerator) GenFilterConfig() (proto.Message, error) {
+	var ids []string
+	for id := range g.Providers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	config := &tipb.FilterConfig{
+		ForwardPayloadHeader: g.GeneratedHeaderPrefix + util.JwtAuthnForwardPayloadHeaderSuffix,
+	}
+	for _, id := range ids {
+		provider := g.Providers[id]
+		addr, err := util.ExtractAddressFromURI(provider.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("for token introspection provider %q, failed to parse introspection endpoint: %v", id, err)
+		}
+
+		p := &tipb.IntrospectionProvider{
+			Id: id,
+			IntrospectionUri: &commonpb.HttpUri{
+				Uri:     provider.Endpoint,
+				Cluster: util.TokenIntrospectionClusterName(addr),
+				Timeout: durationpb.New(g.HttpRequestTimeout),
+			},
+			ClientId:         provider.ClientID,
+			ClientSecretPath: provider.ClientSecretPath,
+			CacheSize:        provider.CacheSize,
+		}
+		if provider.CacheDurationSeconds > 0 {
+			p.CacheDuration = durationpb.New(time.Duration(provider.CacheDurationSeconds) * time.Second)
+		}
+		config.Providers = append(config.Providers, p)
+	}
+	return config, nil
+}
+
+func (g *TokenIntrospectionGenerator) GenPerRouteConfig(selector string, httpRule *httppattern.Pattern) (proto.Message, error) {
+	perRoute, ok := g.PerRouteConfigBySelector[selector]
+	if !ok {
+		return nil, nil
+	}
+	return perRoute, nil
+}
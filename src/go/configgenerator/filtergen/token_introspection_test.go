@@ -0,0 +1,229 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filtergen_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen/filtergentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// writeTokenIntrospectionProviders writes the providers file and a client
+// secret file, `%s` in the content is replaced by the secret path.
+func writeTokenIntrospectionProviders(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secretPath, []byte("client-secret\n"), 0600); err != nil {
+		t.Fatalf("fail to write client secret file: %v", err)
+	}
+	path := filepath.Join(dir, "providers.json")
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(content, secretPath)), 0644); err != nil {
+		t.Fatalf("fail to write token introspection providers file: %v", err)
+	}
+	return path
+}
+
+const tokenIntrospectionProvidersContent = `{
+  "providers": {
+    "introspection_provider": {
+      "introspection_endpoint": "https://idp.example.com/oauth2/introspect",
+      "client_id": "espv2",
+      "client_secret_path": %q,
+      "cache_duration_seconds": 60,
+      "cache_size": 100
+    }
+  }
+}`
+
+func tokenIntrospectionTestServiceConfig() *confpb.Service {
+	return &confpb.Service{
+		Authentication: &confpb.Authentication{
+			Providers: []*confpb.AuthProvider{
+				{
+					Id:      "jwt_provider",
+					Issuer:  "issuer_0",
+					JwksUri: "https://metadata.com/pkey",
+				},
+				{
+					Id:     "introspection_provider",
+					Issuer: "https://idp.example.com",
+				},
+			},
+			Rules: []*confpb.AuthenticationRule{
+				{
+					Selector: "testapi.foo",
+					Requirements: []*confpb.AuthRequirement{
+						{
+							ProviderId: "jwt_provider",
+						},
+					},
+				},
+				{
+					Selector:               "testapi.bar",
+					AllowWithoutCredential: true,
+					Requirements: []*confpb.AuthRequirement{
+						{
+							ProviderId: "introspection_provider",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestNewTokenIntrospectionFilterGensFromOPConfig_GenConfig(t *testing.T) {
+	providersPath := writeTokenIntrospectionProviders(t, tokenIntrospectionProvidersContent)
+
+	testdata := []filtergentest.SuccessOPTestCase{
+		{
+			Desc:            "No providers file",
+			ServiceConfigIn: tokenIntrospectionTestServiceConfig(),
+		},
+		{
+			Desc:            "Introspection provider is configured",
+			ServiceConfigIn: tokenIntrospectionTestServiceConfig(),
+			OptsIn: options.ConfigGeneratorOptions{
+				TokenIntrospectionProvidersPath: providersPath,
+			},
+			OnlyCheckFilterConfig: true,
+			WantFilterConfigs: []string{
+				fmt.Sprintf(`
+{
+  "forwardPayloadHeader": "X-Endpoint-API-UserInfo",
+  "providers": [
+    {
+      "id": "introspection_provider",
+      "introspectionUri": {
+        "uri": "https://idp.example.com/oauth2/introspect",
+        "cluster": "token-introspection-cluster-idp.example.com:443",
+        "timeout": "30s"
+      },
+      "clientId": "espv2",
+      "clientSecretPath": %q,
+      "cacheDuration": "60s",
+      "cacheSize": 100
+    }
+  ]
+}`, filepath.Join(filepath.Dir(providersPath), "secret")),
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewTokenIntrospectionFilterGensFromOPConfig)
+	}
+}
+
+func TestNewTokenIntrospectionFilterGensFromOPConfig_BadInputFactory(t *testing.T) {
+	providersPath := writeTokenIntrospectionProviders(t, tokenIntrospectionProvidersContent)
+
+	mixedServiceConfig := tokenIntrospectionTestServiceConfig()
+	mixedServiceConfig.Authentication.Rules[1].Requirements = append(mixedServiceConfig.Authentication.Rules[1].Requirements, &confpb.AuthRequirement{
+		ProviderId: "jwt_provider",
+	})
+	scopesServiceConfig := tokenIntrospectionTestServiceConfig()
+	scopesServiceConfig.Authentication.Rules[1].Oauth = &confpb.OAuthRequirements{
+		CanonicalScopes: "https://www.googleapis.com/auth/read",
+	}
+
+	testdata := []filtergentest.FactoryErrorOPTestCase{
+		{
+			Desc:            "Introspection provider mixed with a JWT provider",
+			ServiceConfigIn: mixedServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				TokenIntrospectionProvidersPath: providersPath,
+			},
+			WantFactoryError: `it cannot have other requirements`,
+		},
+		{
+			Desc:            "Introspection provider with canonical scopes",
+			ServiceConfigIn: scopesServiceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				TokenIntrospectionProvidersPath: providersPath,
+			},
+			WantFactoryError: `it cannot have canonical_scopes`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, filtergen.NewTokenIntrospectionFilterGensFromOPConfig)
+	}
+}
+
+func TestTokenIntrospectionGenerator_GenPerRouteConfig(t *testing.T) {
+	providersPath := writeTokenIntrospectionProviders(t, tokenIntrospectionProvidersContent)
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.TokenIntrospectionProvidersPath = providersPath
+
+	gens, err := filtergen.NewTokenIntrospectionFilterGensFromOPConfig(tokenIntrospectionTestServiceConfig(), opts)
+	if err != nil {
+		t.Fatalf("NewTokenIntrospectionFilterGensFromOPConfig() got error: %v", err)
+	}
+	if len(gens) != 1 {
+		t.Fatalf("NewTokenIntrospectionFilterGensFromOPConfig() got %d generators, want 1", len(gens))
+	}
+
+	testdata := []struct {
+		desc       string
+		selector   string
+		wantConfig string
+	}{
+		{
+			desc:     "JWT provider is not introspected",
+			selector: "testapi.foo",
+		},
+		{
+			desc:     "Introspection provider",
+			selector: "testapi.bar",
+			wantConfig: `
+{
+  "providerId": "introspection_provider",
+  "allowMissing": true
+}`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := gens[0].GenPerRouteConfig(tc.selector, nil)
+			if err != nil {
+				t.Fatalf("GenPerRouteConfig() got error: %v", err)
+			}
+			if tc.wantConfig == "" {
+				if got != nil {
+					t.Fatalf("GenPerRouteConfig() got %v, want nil", got)
+				}
+				return
+			}
+
+			gotJson, err := util.ProtoToJson(got)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantConfig, gotJson); err != nil {
+				t.Errorf("GenPerRouteConfig() got unexpected config: %v", err)
+			}
+		})
+	}
+}
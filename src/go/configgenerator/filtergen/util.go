@@ -25,6 +25,7 @@ import (
 	listenerpb "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	"github.com/golang/glog"
+	ahpb "google.golang.org/genproto/googleapis/api/annotations"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	smpb "google.golang.org/genproto/googleapis/api/servicemanagement/v1"
 	apipb "google.golang.org/genproto/protobuf/api"
@@ -68,6 +69,26 @@ func CORSSelectorToSelector(corsSelector string, corsOperationDelimiter string)
 	return originalSelector, nil
 }
 
+// httpRulePath returns the path of the http rule, whatever its HTTP method.
+func httpRulePath(rule *ahpb.HttpRule) string {
+	switch pattern := rule.GetPattern().(type) {
+	case *ahpb.HttpRule_Get:
+		return pattern.Get
+	case *ahpb.HttpRule_Put:
+		return pattern.Put
+	case *ahpb.HttpRule_Post:
+		return pattern.Post
+	case *ahpb.HttpRule_Delete:
+		return pattern.Delete
+	case *ahpb.HttpRule_Patch:
+		return pattern.Patch
+	case *ahpb.HttpRule_Custom:
+		return pattern.Custom.GetPath()
+	default:
+		return ""
+	}
+}
+
 func ParseDepErrorBehavior(stringVal string) (commonpb.DependencyErrorBehavior, error) {
 	depErrorBehaviorInt, ok := commonpb.DependencyErrorBehavior_value[stringVal]
 	if !ok {
@@ -253,6 +274,78 @@ func GetAPIKeySystemParametersBySelectorFromOPConfig(serviceConfig *servicepb.Se
 	return apiKeySystemParametersBySelector
 }
 
+// SelectorValues are the values that apply to an operation through its API
+// name and through its operation selector, in the order they are listed.
+type SelectorValues[T any] struct {
+	API       []T
+	Operation []T
+}
+
+// First returns the first value for the operation selector, or if there is
+// none, the first value for its API name.
+func (v *SelectorValues[T]) First() T {
+	if len(v.Operation) > 0 {
+		return v.Operation[0]
+	}
+	return v.API[0]
+}
+
+// GetValuesBySelectorFromOPConfig resolves values that are listed for
+// operation selectors or API names, e.g. the policies of a config file, to the
+// values that apply to each operation. selectorsOf returns the selectors of a
+// value, and kind names the values in logs and errors, e.g. "CORS policy".
+//
+// Selectors with no API or operation are ignored with a warning. If unique is
+// set, a selector listed by more than one value is an error. Operations
+// without any value are not included.
+func GetValuesBySelectorFromOPConfig[T any](serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions, kind string, unique bool, values []T, selectorsOf func(T) []string) (map[string]*SelectorValues[T], error) {
+	apiNames := GetAPINamesSetFromOPConfig(serviceConfig, opts)
+	apiNameBySelector := make(map[string]string)
+	for _, api := range serviceConfig.GetApis() {
+		if !apiNames[api.GetName()] {
+			continue
+		}
+		for _, method := range api.GetMethods() {
+			apiNameBySelector[MethodToSelector(api, method)] = api.GetName()
+		}
+	}
+
+	valuesBySelector := make(map[string][]T)
+	valuesByAPIName := make(map[string][]T)
+	for i, value := range values {
+		for _, selector := range selectorsOf(value) {
+			var valuesByName map[string][]T
+			switch {
+			case apiNameBySelector[selector] != "":
+				valuesByName = valuesBySelector
+			case apiNames[selector]:
+				valuesByName = valuesByAPIName
+			default:
+				glog.Warningf("Ignoring selector %q of %s at index %d because there is no API or operation with that name.", selector, kind, i)
+				continue
+			}
+
+			if unique && len(valuesByName[selector]) > 0 {
+				return nil, fmt.Errorf("selector %q is used by more than one %s", selector, kind)
+			}
+			valuesByName[selector] = append(valuesByName[selector], value)
+		}
+	}
+
+	resolved := make(map[string]*SelectorValues[T])
+	for selector, apiName := range apiNameBySelector {
+		apiValues, operationValues := valuesByAPIName[apiName], valuesBySelector[selector]
+		if len(apiValues) == 0 && len(operationValues) == 0 {
+			continue
+		}
+		resolved[selector] = &SelectorValues[T]{
+			API:       apiValues,
+			Operation: operationValues,
+		}
+	}
+	return resolved, nil
+}
+
 // GetDescriptorBinFromOPConfig returns the descriptor bytes extracted from the
 // OP service config.
 func GetDescriptorBinFromOPConfig(serviceConfig *servicepb.Service) ([]byte, error) {
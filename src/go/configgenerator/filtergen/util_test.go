@@ -275,6 +275,94 @@ func TestGetUsageRulesBySelectorFromOPConfig(t *testing.T) {
 	}
 }
 
+func TestGetValuesBySelectorFromOPConfig(t *testing.T) {
+	type value struct {
+		name      string
+		selectors []string
+	}
+	serviceConfig := &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "google.library.Bookstore",
+				Methods: []*apipb.Method{
+					{Name: "GetBook"},
+					{Name: "ListBooks"},
+				},
+			},
+			{
+				Name: "google.library.Library",
+				Methods: []*apipb.Method{
+					{Name: "GetShelf"},
+				},
+			},
+		},
+	}
+	apiValue := &value{name: "api", selectors: []string{"google.library.Bookstore", "google.library.Unknown"}}
+	operationValue := &value{name: "operation", selectors: []string{"google.library.Bookstore.GetBook"}}
+	secondAPIValue := &value{name: "second api", selectors: []string{"google.library.Bookstore"}}
+
+	testdata := []struct {
+		desc      string
+		unique    bool
+		values    []*value
+		want      map[string]*SelectorValues[*value]
+		wantError string
+	}{
+		{
+			desc:   "Values resolved to operations, unknown selectors ignored",
+			unique: true,
+			values: []*value{apiValue, operationValue},
+			want: map[string]*SelectorValues[*value]{
+				"google.library.Bookstore.GetBook": {
+					API:       []*value{apiValue},
+					Operation: []*value{operationValue},
+				},
+				"google.library.Bookstore.ListBooks": {
+					API: []*value{apiValue},
+				},
+			},
+		},
+		{
+			desc:   "Values for the same selector are kept in order",
+			values: []*value{apiValue, secondAPIValue},
+			want: map[string]*SelectorValues[*value]{
+				"google.library.Bookstore.GetBook": {
+					API: []*value{apiValue, secondAPIValue},
+				},
+				"google.library.Bookstore.ListBooks": {
+					API: []*value{apiValue, secondAPIValue},
+				},
+			},
+		},
+		{
+			desc:      "Unique values fail for the same selector",
+			unique:    true,
+			values:    []*value{apiValue, secondAPIValue},
+			wantError: `selector "google.library.Bookstore" is used by more than one test value`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := GetValuesBySelectorFromOPConfig(serviceConfig, options.ConfigGeneratorOptions{}, "test value", tc.unique, tc.values, func(v *value) []string {
+				return v.selectors
+			})
+			if tc.wantError != "" {
+				if err == nil || err.Error() != tc.wantError {
+					t.Fatalf("GetValuesBySelectorFromOPConfig() got error %v, want %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetValuesBySelectorFromOPConfig() got unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(value{})); diff != "" {
+				t.Errorf("GetValuesBySelectorFromOPConfig() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestGetAPIKeySystemParametersBySelectorFromOPConfig(t *testing.T) {
 	testdata := []struct {
 		desc            string
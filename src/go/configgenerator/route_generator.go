@@ -38,6 +38,8 @@ const (
 // MakeRouteGenFactories creates the route generator factories (in order).
 func MakeRouteGenFactories() []routegen.RouteGeneratorOPFactory {
 	return []routegen.RouteGeneratorOPFactory{
+		routegen.NewDenyConnectJSONRouteGenFromOPConfig,
+		routegen.NewProxyConnectJSONRouteGenFromOPConfig,
 		routegen.NewProxyBackendRouteGenFromOPConfig,
 		routegen.NewProxyCORSRouteGenFromOPConfig,
 		routegen.NewDirectResponseHealthCheckRouteGenFromOPConfig,
@@ -0,0 +1,153 @@
+package routegen
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/helpers"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// DenyConnectJSONGenerator is a RouteGenerator that rejects streaming Connect
+// requests with the JSON encoding on the gRPC paths of the methods served by
+// gRPC backends.
+//
+// The Connect gRPC bridge forwards JSON Connect requests as
+// "application/grpc+json", which gRPC backends reject. Unary JSON Connect
+// requests are transcoded instead, see ProxyConnectJSONGenerator. Streaming
+// ones are answered with a clear error.
+type DenyConnectJSONGenerator struct {
+	// GRPCPaths are the sorted "/package.Service/Method" paths of the methods
+	// served by gRPC backends.
+	GRPCPaths []string
+
+	DisallowColonInWildcardPathSegment bool
+
+	*NoopRouteGenerator
+}
+
+// NewDenyConnectJSONRouteGenFromOPConfig creates DenyConnectJSONGenerator
+// from OP service config + ESPv2 options.
+// It is a RouteGeneratorOPFactory.
+func NewDenyConnectJSONRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
+	if !opts.EnableConnectGrpcBridge {
+		glog.Infof("Not adding deny Connect JSON route gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	grpcSelectors, err := filtergen.GetGRPCSelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var grpcPaths []string
+	for _, api := range serviceConfig.GetApis() {
+		if util.ShouldSkipOPDiscoveryAPI(api.GetName(), opts.AllowDiscoveryAPIs) {
+			continue
+		}
+		for _, method := range api.GetMethods() {
+			if grpcSelectors[filtergen.MethodToSelector(api, method)] {
+				grpcPaths = append(grpcPaths, fmt.Sprintf("/%s/%s", api.GetName(), method.GetName()))
+			}
+		}
+	}
+	if len(grpcPaths) == 0 {
+		glog.Infof("No methods are served by gRPC backends, skip deny Connect JSON route gen.")
+		return nil, nil
+	}
+	sort.Strings(grpcPaths)
+
+	return &DenyConnectJSONGenerator{
+		GRPCPaths:                          grpcPaths,
+		DisallowColonInWildcardPathSegment: opts.DisallowColonInWildcardPathSegment,
+	}, nil
+}
+
+// RouteType implements interface RouteGenerator.
+func (g *DenyConnectJSONGenerator) RouteType() string {
+	return "deny_connect_json_routes"
+}
+
+// GenRouteConfig implements interface RouteGenerator.
+func (g *DenyConnectJSONGenerator) GenRouteConfig([]filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	var routes []*routepb.Route
+	for _, grpcPath := range g.GRPCPaths {
+		uriTemplate, err := httppattern.ParseUriTemplate(grpcPath)
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse gRPC path %q: %v", grpcPath, err)
+		}
+
+		routeMatchers, err := helpers.MakeRouteMatchers(&httppattern.Pattern{
+			UriTemplate: uriTemplate,
+			HttpMethod:  util.POST,
+		}, g.DisallowColonInWildcardPathSegment)
+		if err != nil {
+			return nil, fmt.Errorf("fail to make Connect JSON route matchers for gRPC path %q: %v", grpcPath, err)
+		}
+
+		for _, routeMatch := range routeMatchers {
+			routeMatcher := routeMatch.RouteMatch
+			routeMatcher.Headers = makeConnectStreamingJSONHeaderMatchers()
+			routes = append(routes, makeUnsupportedConnectJSONRoute(routeMatcher, grpcPath))
+		}
+	}
+
+	return routes, nil
+}
+
+// makeConnectStreamingJSONHeaderMatchers returns the header matchers of
+// streaming Connect requests with the JSON encoding.
+func makeConnectStreamingJSONHeaderMatchers() []*routepb.HeaderMatcher {
+	return []*routepb.HeaderMatcher{
+		{
+			Name: ":method",
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+				StringMatch: &matcherpb.StringMatcher{
+					MatchPattern: &matcherpb.StringMatcher_Exact{
+						Exact: util.POST,
+					},
+				},
+			},
+		},
+		{
+			Name: "content-type",
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+				StringMatch: &matcherpb.StringMatcher{
+					MatchPattern: &matcherpb.StringMatcher_Prefix{
+						Prefix: "application/connect+json",
+					},
+				},
+			},
+		},
+	}
+}
+
+func makeUnsupportedConnectJSONRoute(routeMatcher *routepb.RouteMatch, grpcPath string) *routepb.Route {
+	spanName := util.MaybeTruncateSpanName(fmt.Sprintf("%s UnsupportedConnectJsonForPath_%s", util.SpanNamePrefix, grpcPath))
+
+	return &routepb.Route{
+		Match: routeMatcher,
+		Action: &routepb.Route_DirectResponse{
+			DirectResponse: &routepb.DirectResponseAction{
+				Status: http.StatusUnsupportedMediaType,
+				Body: &corepb.DataSource{
+					Specifier: &corepb.DataSource_InlineString{
+						InlineString: fmt.Sprintf("The current request is a streaming Connect request with the JSON encoding to the gRPC path \"%s\", but only the proto encoding is supported for streaming", grpcPath),
+					},
+				},
+			},
+		},
+		Decorator: &routepb.Decorator{
+			Operation: spanName,
+		},
+	}
+}
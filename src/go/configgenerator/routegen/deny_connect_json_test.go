@@ -0,0 +1,121 @@
+package routegen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func TestNewDenyConnectJSONRouteGenFromOPConfig(t *testing.T) {
+	serviceConfig := &servicepb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "GetShelf",
+					},
+				},
+			},
+		},
+	}
+
+	testdata := []routegentest.SuccessOPTestCase{
+		{
+			Desc:            "No routes generated when the Connect gRPC bridge is disabled",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "grpc://127.0.0.1:80",
+			},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc:            "No routes generated for HTTP backends",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:          "http://127.0.0.1:80",
+				EnableConnectGrpcBridge: true,
+			},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc:            "Streaming JSON Connect requests to the gRPC paths are rejected",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:          "grpc://127.0.0.1:80",
+				EnableConnectGrpcBridge: true,
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress UnsupportedConnectJsonForPath_/endpoints.examples.bookstore.Bookstore/GetShelf"
+      },
+      "directResponse":{
+        "body":{
+          "inlineString":"The current request is a streaming Connect request with the JSON encoding to the gRPC path \"/endpoints.examples.bookstore.Bookstore/GetShelf\", but only the proto encoding is supported for streaming"
+        },
+        "status":415
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"POST"
+            }
+          },
+          {
+            "name":"content-type",
+            "stringMatch":{
+              "prefix":"application/connect+json"
+            }
+          }
+        ],
+        "path":"/endpoints.examples.bookstore.Bookstore/GetShelf"
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress UnsupportedConnectJsonForPath_/endpoints.examples.bookstore.Bookstore/GetShelf"
+      },
+      "directResponse":{
+        "body":{
+          "inlineString":"The current request is a streaming Connect request with the JSON encoding to the gRPC path \"/endpoints.examples.bookstore.Bookstore/GetShelf\", but only the proto encoding is supported for streaming"
+        },
+        "status":415
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"POST"
+            }
+          },
+          {
+            "name":"content-type",
+            "stringMatch":{
+              "prefix":"application/connect+json"
+            }
+          }
+        ],
+        "path":"/endpoints.examples.bookstore.Bookstore/GetShelf/"
+      }
+    }
+  ]
+}
+			`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewDenyConnectJSONRouteGenFromOPConfig)
+	}
+}
@@ -3,22 +3,29 @@ package routegen
 import (
 	"fmt"
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/helpers"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"github.com/golang/glog"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 // DirectResponseCORSGenerator is a RouteGenerator to configure CORS routes.
 type DirectResponseCORSGenerator struct {
 	Preset string
-	// AllowOrigin should only be set if preset=basic
+	// AllowOrigin should only be set if preset=basic. It is a comma-separated
+	// list of origins.
 	AllowOrigin string
 	// AllowOriginRegex should only be set if preset=cors_with_regex
 	AllowOriginRegex string
@@ -27,6 +34,12 @@ type DirectResponseCORSGenerator struct {
 	// CORS policies to.
 	LocalBackendClusterName string
 
+	// HTTPPatterns are only set if there are per-operation CORS policies. The
+	// preflight requests of these operations are matched before the catch-all
+	// preflight routes.
+	HTTPPatterns                       httppattern.MethodSlice
+	DisallowColonInWildcardPathSegment bool
+
 	*NoopRouteGenerator
 }
 
@@ -39,11 +52,27 @@ func NewDirectResponseCORSRouteGenFromOPConfig(serviceConfig *servicepb.Service,
 		return nil, nil
 	}
 
+	var httpPatterns httppattern.MethodSlice
+	if opts.CorsPoliciesPath != "" {
+		httpPatternsBySelector, err := ParseHTTPPatternsBySelectorFromOPConfig(serviceConfig, opts)
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse http patterns from OP config: %v", err)
+		}
+
+		sortedHTTPPatterns, err := sortHttpPatterns(httpPatternsBySelector)
+		if err != nil {
+			return nil, fmt.Errorf("fail to sort http patterns: %v", err)
+		}
+		httpPatterns = *sortedHTTPPatterns
+	}
+
 	return &DirectResponseCORSGenerator{
-		Preset:                  opts.CorsPreset,
-		AllowOrigin:             opts.CorsAllowOrigin,
-		AllowOriginRegex:        opts.CorsAllowOriginRegex,
-		LocalBackendClusterName: clustergen.MakeLocalBackendClusterName(serviceConfig),
+		Preset:                             opts.CorsPreset,
+		AllowOrigin:                        opts.CorsAllowOrigin,
+		AllowOriginRegex:                   opts.CorsAllowOriginRegex,
+		LocalBackendClusterName:            clustergen.MakeLocalBackendClusterName(serviceConfig),
+		HTTPPatterns:                       httpPatterns,
+		DisallowColonInWildcardPathSegment: opts.DisallowColonInWildcardPathSegment,
 	}, nil
 }
 
@@ -55,7 +84,7 @@ func (g *DirectResponseCORSGenerator) RouteType() string {
 // GenRouteConfig implements interface RouteGenerator.
 //
 // Forked from `route_generator.go: makeRouteCors()
-func (g *DirectResponseCORSGenerator) GenRouteConfig([]filtergen.FilterGenerator) ([]*routepb.Route, error) {
+func (g *DirectResponseCORSGenerator) GenRouteConfig(filterGens []filtergen.FilterGenerator) ([]*routepb.Route, error) {
 	originMatcher := &routepb.HeaderMatcher{
 		Name: "origin",
 	}
@@ -75,32 +104,198 @@ func (g *DirectResponseCORSGenerator) GenRouteConfig([]filtergen.FilterGenerator
 		return nil, fmt.Errorf(`cors_preset must be either "basic" or "cors_with_regex"`)
 	}
 
-	return []*routepb.Route{
+	routes, err := g.genPerOperationPreflightCorsRoutes(filterGens)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(routes,
 		genPreflightCorsRoute(g.LocalBackendClusterName, originMatcher),
 		genPreflightCorsMissingHeadersRoute(),
-	}, nil
+	), nil
+}
+
+// genPerOperationPreflightCorsRoutes generates the preflight routes for
+// operations with their own CORS policy. They match the path of the operation
+// and the requested method, so the origins of the operation policy are checked
+// instead of the global ones.
+func (g *DirectResponseCORSGenerator) genPerOperationPreflightCorsRoutes(filterGens []filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	if len(g.HTTPPatterns) == 0 {
+		return nil, nil
+	}
+
+	var corsGen *filtergen.CORSGenerator
+	for _, filterGen := range filterGens {
+		if gen, ok := filterGen.(*filtergen.CORSGenerator); ok {
+			corsGen = gen
+		}
+	}
+	if corsGen == nil {
+		return nil, fmt.Errorf("per-operation CORS policies require the CORS filter")
+	}
+
+	var routes []*routepb.Route
+	for _, httpPattern := range g.HTTPPatterns {
+		if httpPattern.HttpMethod == util.OPTIONS {
+			continue
+		}
+
+		policy, err := corsGen.PolicyForSelector(httpPattern.Operation)
+		if err != nil {
+			return nil, err
+		}
+		if policy == nil {
+			continue
+		}
+
+		routeMatchers, err := helpers.MakeRouteMatchers(httpPattern.Pattern, g.DisallowColonInWildcardPathSegment)
+		if err != nil {
+			return nil, fmt.Errorf("fail to make CORS preflight route matchers for operation %q: %v", httpPattern.Operation, err)
+		}
+
+		requestMethodMatcher := &routepb.HeaderMatcher{
+			Name: "access-control-request-method",
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_PresentMatch{
+				PresentMatch: true,
+			},
+		}
+		if httpPattern.HttpMethod != httppattern.HttpMethodWildCard {
+			requestMethodMatcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_StringMatch{
+				StringMatch: &matcherpb.StringMatcher{
+					MatchPattern: &matcherpb.StringMatcher_Exact{
+						Exact: httpPattern.HttpMethod,
+					},
+				},
+			}
+		}
+
+		var perFilterConfig map[string]*anypb.Any
+		originMatcher := &routepb.HeaderMatcher{
+			Name: "origin",
+		}
+		if !policy.Disabled {
+			corsPolicy, err := corsGen.GenPerRouteConfig(httpPattern.Operation, httpPattern.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("fail to generate CORS policy for operation %q: %v", httpPattern.Operation, err)
+			}
+			corsPolicyAny, err := anypb.New(corsPolicy)
+			if err != nil {
+				return nil, fmt.Errorf("fail to marshal CORS policy to Any for operation %q: %v", httpPattern.Operation, err)
+			}
+			perFilterConfig = map[string]*anypb.Any{
+				corsGen.FilterName(): corsPolicyAny,
+			}
+
+			if len(policy.AllowOrigins) > 0 || len(policy.AllowOriginRegexes) > 0 {
+				if err := fillOriginMatcher(originMatcher, policy.AllowOrigins, policy.AllowOriginRegexes); err != nil {
+					return nil, fmt.Errorf("fail to fill origin matcher for operation %q: %v", httpPattern.Operation, err)
+				}
+			} else if g.Preset == "basic" {
+				if err := fillBasicOriginMatcher(originMatcher, g.AllowOrigin); err != nil {
+					return nil, fmt.Errorf("fail to fill basic origin matcher: %v", err)
+				}
+			} else if err := fillRegexOriginMatcher(originMatcher, g.AllowOriginRegex); err != nil {
+				return nil, fmt.Errorf("fail to fill regex origin matcher: %v", err)
+			}
+		}
+
+		for _, routeMatcher := range routeMatchers {
+			routeMatcher.Headers = []*routepb.HeaderMatcher{
+				makeOptionsMethodMatcher(),
+				requestMethodMatcher,
+			}
+
+			if !policy.Disabled {
+				match := proto.Clone(routeMatcher.RouteMatch).(*routepb.RouteMatch)
+				match.Headers = append(match.Headers, originMatcher)
+				routes = append(routes, &routepb.Route{
+					Name:  httpPattern.Operation,
+					Match: match,
+					Action: &routepb.Route_Route{
+						Route: &routepb.RouteAction{
+							ClusterSpecifier: &routepb.RouteAction_Cluster{
+								Cluster: g.LocalBackendClusterName,
+							},
+						},
+					},
+					Decorator: &routepb.Decorator{
+						Operation: util.SpanNamePrefix,
+					},
+					TypedPerFilterConfig: perFilterConfig,
+				})
+			}
+
+			body := "The CORS preflight request has an unmatched Origin header."
+			if policy.Disabled {
+				body = "CORS is disabled for the requested operation."
+			}
+			routes = append(routes, &routepb.Route{
+				Name:  httpPattern.Operation,
+				Match: routeMatcher.RouteMatch,
+				Action: &routepb.Route_DirectResponse{
+					DirectResponse: &routepb.DirectResponseAction{
+						Status: http.StatusBadRequest,
+						Body: &corepb.DataSource{
+							Specifier: &corepb.DataSource_InlineString{
+								InlineString: body,
+							},
+						},
+					},
+				},
+				Decorator: &routepb.Decorator{
+					Operation: util.SpanNamePrefix,
+				},
+			})
+		}
+	}
+
+	return routes, nil
 }
 
 func fillBasicOriginMatcher(originMatcher *routepb.HeaderMatcher, allowOrigin string) error {
-	if allowOrigin == "" {
+	origins := filtergen.SplitCORSAllowOrigins(allowOrigin)
+	if len(origins) == 0 {
 		return fmt.Errorf("cors_allow_origin cannot be empty when cors_preset=basic")
 	}
 
-	if allowOrigin == "*" {
-		originMatcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_PresentMatch{
-			PresentMatch: true,
+	return fillOriginMatcher(originMatcher, origins, nil)
+}
+
+// fillOriginMatcher matches any of the exact origins or origin regexes.
+// Multiple origins are matched by a single regex.
+func fillOriginMatcher(originMatcher *routepb.HeaderMatcher, origins []string, regexes []string) error {
+	for _, origin := range origins {
+		if origin == "*" {
+			originMatcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_PresentMatch{
+				PresentMatch: true,
+			}
+			return nil
 		}
-		return nil
 	}
 
-	originMatcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_StringMatch{
-		StringMatch: &matcherpb.StringMatcher{
-			MatchPattern: &matcherpb.StringMatcher_Exact{
-				Exact: allowOrigin,
+	if len(origins) == 1 && len(regexes) == 0 {
+		originMatcher.HeaderMatchSpecifier = &routepb.HeaderMatcher_StringMatch{
+			StringMatch: &matcherpb.StringMatcher{
+				MatchPattern: &matcherpb.StringMatcher_Exact{
+					Exact: origins[0],
+				},
 			},
-		},
+		}
+		return nil
 	}
-	return nil
+
+	if len(origins) == 0 && len(regexes) == 1 {
+		return fillRegexOriginMatcher(originMatcher, regexes[0])
+	}
+
+	var alternatives []string
+	for _, origin := range origins {
+		alternatives = append(alternatives, regexp.QuoteMeta(origin))
+	}
+	for _, regex := range regexes {
+		alternatives = append(alternatives, fmt.Sprintf("(?:%s)", regex))
+	}
+	return fillRegexOriginMatcher(originMatcher, strings.Join(alternatives, "|"))
 }
 
 func fillRegexOriginMatcher(originMatcher *routepb.HeaderMatcher, allowOriginRegex string) error {
@@ -134,16 +329,7 @@ func genPreflightCorsRoute(localBackendClusterName string, originMatcher *routep
 				Prefix: "/",
 			},
 			Headers: []*routepb.HeaderMatcher{
-				{
-					Name: ":method",
-					HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
-						StringMatch: &matcherpb.StringMatcher{
-							MatchPattern: &matcherpb.StringMatcher_Exact{
-								Exact: "OPTIONS",
-							},
-						},
-					},
-				},
+				makeOptionsMethodMatcher(),
 				originMatcher,
 				{
 					Name: "access-control-request-method",
@@ -177,16 +363,7 @@ func genPreflightCorsMissingHeadersRoute() *routepb.Route {
 				Prefix: "/",
 			},
 			Headers: []*routepb.HeaderMatcher{
-				{
-					Name: ":method",
-					HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
-						StringMatch: &matcherpb.StringMatcher{
-							MatchPattern: &matcherpb.StringMatcher_Exact{
-								Exact: "OPTIONS",
-							},
-						},
-					},
-				},
+				makeOptionsMethodMatcher(),
 			},
 		},
 		Action: &routepb.Route_DirectResponse{
@@ -204,3 +381,16 @@ func genPreflightCorsMissingHeadersRoute() *routepb.Route {
 		},
 	}
 }
+
+func makeOptionsMethodMatcher() *routepb.HeaderMatcher {
+	return &routepb.HeaderMatcher{
+		Name: ":method",
+		HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+			StringMatch: &matcherpb.StringMatcher{
+				MatchPattern: &matcherpb.StringMatcher_Exact{
+					Exact: "OPTIONS",
+				},
+			},
+		},
+	}
+}
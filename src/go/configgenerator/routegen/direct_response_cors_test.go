@@ -1,14 +1,19 @@
 package routegen_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
 	"github.com/imdario/mergo"
+	annotationspb "google.golang.org/genproto/googleapis/api/annotations"
 	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
 )
 
 func TestNewCORSRouteGenFromOPConfig(t *testing.T) {
@@ -146,6 +151,77 @@ func TestNewCORSRouteGenFromOPConfig(t *testing.T) {
 			}
 		}
 	]
+}
+			`,
+		},
+		{
+			Desc: "cors multiple exact origins routes",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				CorsPreset:       "basic",
+				CorsAllowOrigin:  "http://example.com,https://example.org",
+				CorsAllowMethods: "GET,POST,PUT,OPTIONS",
+				CorsMaxAge:       2 * time.Minute,
+			},
+			WantHostConfig: `
+{
+	"routes": [
+		{
+			"decorator": {
+				"operation": "ingress"
+			},
+			"match": {
+				"headers": [
+					{
+						"name": ":method",
+						"stringMatch": {
+							"exact": "OPTIONS"
+						}
+					},
+					{
+						"name": "origin",
+						"stringMatch": {
+							"safeRegex": {
+								"regex": "http://example\\.com|https://example\\.org"
+							}
+						}
+					},
+					{
+						"name": "access-control-request-method",
+						"presentMatch": true
+					}
+				],
+				"prefix": "/"
+			},
+			"route": {
+				"cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local"
+			}
+		},
+		{
+			"decorator": {
+				"operation": "ingress"
+			},
+			"directResponse": {
+				"body": {
+					"inlineString": "The CORS preflight request is missing one (or more) of the following required headers [Origin, Access-Control-Request-Method] or has an unmatched Origin header."
+				},
+				"status": 400
+			},
+			"match": {
+				"headers": [
+					{
+						"name": ":method",
+						"stringMatch": {
+							"exact": "OPTIONS"
+						}
+					}
+				],
+				"prefix": "/"
+			}
+		}
+	]
 }
 			`,
 		},
@@ -246,6 +322,335 @@ func TestNewCORSRouteGenFromOPConfig(t *testing.T) {
 	}
 }
 
+func TestNewCORSRouteGenFromOPConfig_PerOperationPolicies(t *testing.T) {
+	policiesPath := filepath.Join(t.TempDir(), "cors_policies.json")
+	policies := `{
+  "policies": [
+    {
+      "selectors": ["endpoints.examples.bookstore.Bookstore.ListShelves"],
+      "allow_origins": ["https://a.example.com", "https://b.example.com"]
+    },
+    {
+      "selectors": ["endpoints.examples.bookstore.Bookstore.DeleteShelf"],
+      "disabled": true
+    }
+  ]
+}`
+	if err := os.WriteFile(policiesPath, []byte(policies), 0644); err != nil {
+		t.Fatalf("fail to write CORS policies file: %v", err)
+	}
+
+	serviceConfig := &servicepb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "ListShelves",
+					},
+					{
+						Name: "DeleteShelf",
+					},
+					{
+						Name: "GetShelf",
+					},
+				},
+			},
+		},
+		Http: &annotationspb.Http{
+			Rules: []*annotationspb.HttpRule{
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+					Pattern: &annotationspb.HttpRule_Get{
+						Get: "/shelves",
+					},
+				},
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.DeleteShelf",
+					Pattern: &annotationspb.HttpRule_Delete{
+						Delete: "/shelves/{shelf}",
+					},
+				},
+				{
+					Selector: "endpoints.examples.bookstore.Bookstore.GetShelf",
+					Pattern: &annotationspb.HttpRule_Get{
+						Get: "/shelves/{shelf}",
+					},
+				},
+			},
+		},
+	}
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.CorsPreset = "basic"
+	opts.CorsAllowOrigin = "https://global.example.com"
+	opts.CorsMaxAge = 2 * time.Minute
+	opts.CorsPoliciesPath = policiesPath
+
+	corsFilterGens, err := filtergen.NewCORSFilterGensFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		t.Fatalf("NewCORSFilterGensFromOPConfig() got error: %v", err)
+	}
+
+	tc := routegentest.SuccessOPTestCase{
+		Desc:            "per-operation CORS policies add preflight routes before the catch-all routes",
+		ServiceConfigIn: serviceConfig,
+		OptsIn:          opts,
+		FilterGens:      corsFilterGens,
+		WantHostConfig: `
+{
+  "routes": [
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          },
+          {
+            "name": "access-control-request-method",
+            "stringMatch": {
+              "exact": "GET"
+            }
+          },
+          {
+            "name": "origin",
+            "stringMatch": {
+              "safeRegex": {
+                "regex": "https://a\\.example\\.com|https://b\\.example\\.com"
+              }
+            }
+          }
+        ],
+        "path": "/shelves"
+      },
+      "name": "endpoints.examples.bookstore.Bookstore.ListShelves",
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local"
+      },
+      "typedPerFilterConfig": {
+        "envoy.filters.http.cors": {
+          "@type": "type.googleapis.com/envoy.extensions.filters.http.cors.v3.CorsPolicy",
+          "allowCredentials": false,
+          "allowHeaders": "DNT,User-Agent,X-User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Range,Authorization",
+          "allowMethods": "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+          "allowOriginStringMatch": [
+            {
+              "exact": "https://a.example.com"
+            },
+            {
+              "exact": "https://b.example.com"
+            }
+          ],
+          "exposeHeaders": "Content-Length,Content-Range",
+          "maxAge": "120"
+        }
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "directResponse": {
+        "body": {
+          "inlineString": "The CORS preflight request has an unmatched Origin header."
+        },
+        "status": 400
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          },
+          {
+            "name": "access-control-request-method",
+            "stringMatch": {
+              "exact": "GET"
+            }
+          }
+        ],
+        "path": "/shelves"
+      },
+      "name": "endpoints.examples.bookstore.Bookstore.ListShelves"
+    },
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          },
+          {
+            "name": "access-control-request-method",
+            "stringMatch": {
+              "exact": "GET"
+            }
+          },
+          {
+            "name": "origin",
+            "stringMatch": {
+              "safeRegex": {
+                "regex": "https://a\\.example\\.com|https://b\\.example\\.com"
+              }
+            }
+          }
+        ],
+        "path": "/shelves/"
+      },
+      "name": "endpoints.examples.bookstore.Bookstore.ListShelves",
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local"
+      },
+      "typedPerFilterConfig": {
+        "envoy.filters.http.cors": {
+          "@type": "type.googleapis.com/envoy.extensions.filters.http.cors.v3.CorsPolicy",
+          "allowCredentials": false,
+          "allowHeaders": "DNT,User-Agent,X-User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Range,Authorization",
+          "allowMethods": "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+          "allowOriginStringMatch": [
+            {
+              "exact": "https://a.example.com"
+            },
+            {
+              "exact": "https://b.example.com"
+            }
+          ],
+          "exposeHeaders": "Content-Length,Content-Range",
+          "maxAge": "120"
+        }
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "directResponse": {
+        "body": {
+          "inlineString": "The CORS preflight request has an unmatched Origin header."
+        },
+        "status": 400
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          },
+          {
+            "name": "access-control-request-method",
+            "stringMatch": {
+              "exact": "GET"
+            }
+          }
+        ],
+        "path": "/shelves/"
+      },
+      "name": "endpoints.examples.bookstore.Bookstore.ListShelves"
+    },
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "directResponse": {
+        "body": {
+          "inlineString": "CORS is disabled for the requested operation."
+        },
+        "status": 400
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          },
+          {
+            "name": "access-control-request-method",
+            "stringMatch": {
+              "exact": "DELETE"
+            }
+          }
+        ],
+        "safeRegex": {
+          "regex": "^/shelves/[^\\/]+\\/?$"
+        }
+      },
+      "name": "endpoints.examples.bookstore.Bookstore.DeleteShelf"
+    },
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          },
+          {
+            "name": "origin",
+            "stringMatch": {
+              "exact": "https://global.example.com"
+            }
+          },
+          {
+            "name": "access-control-request-method",
+            "presentMatch": true
+          }
+        ],
+        "prefix": "/"
+      },
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local"
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress"
+      },
+      "directResponse": {
+        "body": {
+          "inlineString": "The CORS preflight request is missing one (or more) of the following required headers [Origin, Access-Control-Request-Method] or has an unmatched Origin header."
+        },
+        "status": 400
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "OPTIONS"
+            }
+          }
+        ],
+        "prefix": "/"
+      }
+    }
+  ]
+}
+`,
+	}
+	tc.RunTest(t, routegen.NewDirectResponseCORSRouteGenFromOPConfig)
+}
+
 func TestNewCORSRouteGenFromOPConfig_BadInputRouteGen(t *testing.T) {
 	testdata := []routegentest.GenConfigErrorOPTestCase{
 		{
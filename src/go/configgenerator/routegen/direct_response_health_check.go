@@ -55,6 +55,11 @@ func NewDirectResponseHealthCheckRouteGenFromOPConfig(serviceConfig *servicepb.S
 		UriTemplate: uriTemplate,
 	}
 
+	backendRouteGen, err := helpers.NewBackendRouteGeneratorFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	return &DirectResponseHealthCheckGenerator{
 		AutogeneratedOperationPrefix: opts.HealthCheckAutogeneratedOperationPrefix,
 		ESPOperationAPI:              opts.HealthCheckOperation,
@@ -62,7 +67,7 @@ func NewDirectResponseHealthCheckRouteGenFromOPConfig(serviceConfig *servicepb.S
 		// Health check is always against local cluster.
 		// Remote clusters are not supported.
 		LocalBackendClusterName: clustergen.MakeLocalBackendClusterName(serviceConfig),
-		BackendRouteGen:         helpers.NewBackendRouteGeneratorFromOPConfig(opts),
+		BackendRouteGen:         backendRouteGen,
 	}, nil
 }
 
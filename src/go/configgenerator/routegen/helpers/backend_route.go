@@ -10,6 +10,7 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
 	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
@@ -24,18 +25,39 @@ type BackendRouteGenerator struct {
 	HSTSCfg                            *RouteHSTSConfiger
 	OperationNameCfg                   *RouteOperationNameConfiger
 	DeadlineCfg                        *RouteDeadlineConfiger
+	TracingCfg                         *RouteTracingConfiger
+	HeaderRulesCfg                     *RouteHeaderRulesConfiger
+	HTTPCacheCfg                       *RouteHTTPCacheConfiger
+	OperationStatsCfg                  *RouteOperationStatsConfiger
 }
 
 // NewBackendRouteGeneratorFromOPConfig creates a BackendRouteGenerator from
-// ESPv2 options.
-func NewBackendRouteGeneratorFromOPConfig(opts options.ConfigGeneratorOptions) *BackendRouteGenerator {
+// OP service config + ESPv2 options.
+func NewBackendRouteGeneratorFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (*BackendRouteGenerator, error) {
+	headerRulesCfg, err := NewRouteHeaderRulesConfigerFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	httpCacheCfg, err := NewRouteHTTPCacheConfigerFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	tracingCfg, err := NewRouteTracingConfigerFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	return &BackendRouteGenerator{
 		DisallowColonInWildcardPathSegment: opts.DisallowColonInWildcardPathSegment,
 		RetryCfg:                           NewRouteRetryConfigerFromOPConfig(opts),
 		HSTSCfg:                            NewRouteHSTSConfigerFromOPConfig(opts),
 		OperationNameCfg:                   NewRouteOperationNameConfigerFromOPConfig(opts),
 		DeadlineCfg:                        NewRouteDeadlineConfigerFromOPConfig(opts),
-	}
+		TracingCfg:                         tracingCfg,
+		HeaderRulesCfg:                     headerRulesCfg,
+		HTTPCacheCfg:                       httpCacheCfg,
+		OperationStatsCfg:                  NewRouteOperationStatsConfigerFromOPConfig(opts),
+	}, nil
 }
 
 // MethodCfg is all the config needed to generate routes for a single
@@ -103,6 +125,14 @@ func (r *BackendRouteGenerator) GenRoutesForMethod(methodCfg *MethodCfg, filterG
 
 		MaybeAddHSTSHeader(r.HSTSCfg, route)
 		MaybeAddOperationNameHeader(r.OperationNameCfg, route, methodCfg.OperationName)
+		if err := MaybeAddTracing(r.TracingCfg, route, methodCfg.OperationName); err != nil {
+			return nil, err
+		}
+		if err := MaybeAddHeaderRules(r.HeaderRulesCfg, route, methodCfg.OperationName); err != nil {
+			return nil, err
+		}
+		MaybeAddHTTPCacheVaryHeader(r.HTTPCacheCfg, route, methodCfg.OperationName, methodCfg.HTTPPattern)
+		MaybeAddOperationStatPrefix(r.OperationStatsCfg, route, methodCfg.OperationName)
 
 		routes = append(routes, route)
 	}
@@ -0,0 +1,190 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Envoy command operators in header values, e.g. `%DOWNSTREAM_REMOTE_ADDRESS%`
+// or `%REQ(x-request-id)%`. A literal `%` is escaped as `%%`.
+var headerValueCommandOperatorRegex = regexp.MustCompile(`%%|%[A-Z][A-Z0-9_]*(\([^()%]*\)(:[0-9]+)?)?%`)
+
+// HeaderRule adds, appends and removes request and response headers on the
+// routes of some APIs or operations.
+type HeaderRule struct {
+	// Selectors are operation selectors or API names the rule applies to.
+	Selectors []string `json:"selectors"`
+
+	RequestHeadersToAdd     map[string]string `json:"request_headers_to_add"`
+	RequestHeadersToAppend  map[string]string `json:"request_headers_to_append"`
+	RequestHeadersToRemove  []string          `json:"request_headers_to_remove"`
+	ResponseHeadersToAdd    map[string]string `json:"response_headers_to_add"`
+	ResponseHeadersToAppend map[string]string `json:"response_headers_to_append"`
+	ResponseHeadersToRemove []string          `json:"response_headers_to_remove"`
+}
+
+type headerRulesFile struct {
+	Rules []*HeaderRule `json:"rules"`
+}
+
+// RouteHeaderRulesConfiger adds the header rules of an operation to its
+// routes.
+type RouteHeaderRulesConfiger struct {
+	// RulesBySelector are the rules of each operation. Rules for the API of
+	// the operation come before the rules for the operation itself, each in
+	// the order of the rules file.
+	RulesBySelector map[string][]*HeaderRule
+}
+
+// NewRouteHeaderRulesConfigerFromOPConfig creates a RouteHeaderRulesConfiger
+// from OP service config and the rules file at `--header_rules_path`.
+//
+// The file is JSON in the format of:
+//
+//	{
+//	  "rules": [
+//	    {
+//	      "selectors": ["bookstore.Bookstore", "bookstore.Bookstore.GetShelf"],
+//	      "request_headers_to_add": {"x-client-ip": "%DOWNSTREAM_REMOTE_ADDRESS%"},
+//	      "request_headers_to_append": {"x-tag": "bookstore"},
+//	      "request_headers_to_remove": ["x-internal-debug"],
+//	      "response_headers_to_add": {"cache-control": "no-store"},
+//	      "response_headers_to_append": {"vary": "origin"},
+//	      "response_headers_to_remove": ["server", "x-powered-by"]
+//	    }
+//	  ]
+//	}
+func NewRouteHeaderRulesConfigerFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (*RouteHeaderRulesConfiger, error) {
+	if opts.HeaderRulesPath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(opts.HeaderRulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read header rules file: %v", err)
+	}
+
+	var file headerRulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("fail to parse header rules file %q: %v", opts.HeaderRulesPath, err)
+	}
+
+	for i, rule := range file.Rules {
+		if rule == nil {
+			return nil, fmt.Errorf("invalid header rule at index %d: rule must not be empty", i)
+		}
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("invalid header rule at index %d: %v", i, err)
+		}
+	}
+
+	selectorRules, err := filtergen.GetValuesBySelectorFromOPConfig(serviceConfig, opts, "header rule", false, file.Rules, func(r *HeaderRule) []string {
+		return r.Selectors
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rulesBySelector := make(map[string][]*HeaderRule)
+	for selector, rules := range selectorRules {
+		rulesBySelector[selector] = append(rulesBySelector[selector], rules.API...)
+		rulesBySelector[selector] = append(rulesBySelector[selector], rules.Operation...)
+	}
+
+	return &RouteHeaderRulesConfiger{
+		RulesBySelector: rulesBySelector,
+	}, nil
+}
+
+// MaybeAddHeaderRules adds the header rules of the operation to the route.
+func MaybeAddHeaderRules(c *RouteHeaderRulesConfiger, route *routepb.Route, operation string) error {
+	if c == nil {
+		return nil
+	}
+
+	for _, rule := range c.RulesBySelector[operation] {
+		route.RequestHeadersToAdd = append(route.RequestHeadersToAdd, makeHeaderValueOptions(rule.RequestHeadersToAdd, false)...)
+		route.RequestHeadersToAdd = append(route.RequestHeadersToAdd, makeHeaderValueOptions(rule.RequestHeadersToAppend, true)...)
+		route.RequestHeadersToRemove = append(route.RequestHeadersToRemove, rule.RequestHeadersToRemove...)
+		route.ResponseHeadersToAdd = append(route.ResponseHeadersToAdd, makeHeaderValueOptions(rule.ResponseHeadersToAdd, false)...)
+		route.ResponseHeadersToAdd = append(route.ResponseHeadersToAdd, makeHeaderValueOptions(rule.ResponseHeadersToAppend, true)...)
+		route.ResponseHeadersToRemove = append(route.ResponseHeadersToRemove, rule.ResponseHeadersToRemove...)
+	}
+	return nil
+}
+
+// makeHeaderValueOptions sorts the headers by key, so the config is stable.
+func makeHeaderValueOptions(headers map[string]string, appendValue bool) []*corepb.HeaderValueOption {
+	var keys []string
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var headerValueOptions []*corepb.HeaderValueOption
+	for _, key := range keys {
+		headerValueOptions = append(headerValueOptions, &corepb.HeaderValueOption{
+			Header: &corepb.HeaderValue{
+				Key:   key,
+				Value: headers[key],
+			},
+			Append: &wrapperspb.BoolValue{
+				Value: appendValue,
+			},
+		})
+	}
+	return headerValueOptions
+}
+
+func (r *HeaderRule) validate() error {
+	if len(r.Selectors) == 0 {
+		return fmt.Errorf("selectors must not be empty")
+	}
+	for _, selector := range r.Selectors {
+		if selector == "" {
+			return fmt.Errorf("selectors must not contain an empty selector")
+		}
+	}
+
+	for _, headers := range []map[string]string{r.RequestHeadersToAdd, r.RequestHeadersToAppend, r.ResponseHeadersToAdd, r.ResponseHeadersToAppend} {
+		for key, value := range headers {
+			if err := validateHeaderRuleKey(key); err != nil {
+				return err
+			}
+			if rest := headerValueCommandOperatorRegex.ReplaceAllString(value, ""); strings.Contains(rest, "%") {
+				return fmt.Errorf("invalid value %q for header %q, a literal %% must be escaped as %%%%", value, key)
+			}
+		}
+	}
+	for _, headers := range [][]string{r.RequestHeadersToRemove, r.ResponseHeadersToRemove} {
+		for _, key := range headers {
+			if err := validateHeaderRuleKey(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Envoy does not allow pseudo-headers or the host header to be modified by
+// header rules.
+func validateHeaderRuleKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("header key must not be empty")
+	}
+	if strings.HasPrefix(key, ":") || strings.EqualFold(key, "host") {
+		return fmt.Errorf("header %q cannot be modified by header rules", key)
+	}
+	return nil
+}
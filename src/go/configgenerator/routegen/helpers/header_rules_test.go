@@ -0,0 +1,202 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func writeHeaderRules(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "header_rules.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("fail to write header rules file: %v", err)
+	}
+	return path
+}
+
+func TestMaybeAddHeaderRules(t *testing.T) {
+	rulesPath := writeHeaderRules(t, `{
+  "rules": [
+    {
+      "selectors": ["bookstore.Bookstore"],
+      "request_headers_to_add": {
+        "x-client-ip": "%DOWNSTREAM_REMOTE_ADDRESS%",
+        "x-api": "bookstore"
+      },
+      "response_headers_to_remove": ["server", "x-powered-by"]
+    },
+    {
+      "selectors": ["bookstore.Bookstore.GetShelf"],
+      "request_headers_to_append": {"x-tag": "100%% %REQ(x-request-id)%"},
+      "request_headers_to_remove": ["x-internal-debug"],
+      "response_headers_to_add": {"cache-control": "no-store"},
+      "response_headers_to_append": {"vary": "origin"}
+    },
+    {
+      "selectors": ["bookstore.Bookstore.DeleteShelf"],
+      "request_headers_to_remove": ["x-unknown-operation"]
+    }
+  ]
+}`)
+
+	testdata := []struct {
+		desc      string
+		operation string
+		wantRoute string
+	}{
+		{
+			desc:      "Operation without rules",
+			operation: "library.Library.GetBook",
+			wantRoute: `{}`,
+		},
+		{
+			desc:      "Operation with the rules of its API",
+			operation: "bookstore.Bookstore.ListShelves",
+			wantRoute: `
+{
+  "requestHeadersToAdd": [
+    {
+      "append": false,
+      "header": {"key": "x-api", "value": "bookstore"}
+    },
+    {
+      "append": false,
+      "header": {"key": "x-client-ip", "value": "%DOWNSTREAM_REMOTE_ADDRESS%"}
+    }
+  ],
+  "responseHeadersToRemove": ["server", "x-powered-by"]
+}`,
+		},
+		{
+			desc:      "Operation rules are applied after the API rules",
+			operation: "bookstore.Bookstore.GetShelf",
+			wantRoute: `
+{
+  "requestHeadersToAdd": [
+    {
+      "append": false,
+      "header": {"key": "x-api", "value": "bookstore"}
+    },
+    {
+      "append": false,
+      "header": {"key": "x-client-ip", "value": "%DOWNSTREAM_REMOTE_ADDRESS%"}
+    },
+    {
+      "append": true,
+      "header": {"key": "x-tag", "value": "100%% %REQ(x-request-id)%"}
+    }
+  ],
+  "requestHeadersToRemove": ["x-internal-debug"],
+  "responseHeadersToAdd": [
+    {
+      "append": false,
+      "header": {"key": "cache-control", "value": "no-store"}
+    },
+    {
+      "append": true,
+      "header": {"key": "vary", "value": "origin"}
+    }
+  ],
+  "responseHeadersToRemove": ["server", "x-powered-by"]
+}`,
+		},
+	}
+
+	serviceConfig := &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{Name: "ListShelves"},
+					{Name: "GetShelf"},
+				},
+			},
+			{
+				Name: "library.Library",
+				Methods: []*apipb.Method{
+					{Name: "GetBook"},
+				},
+			},
+		},
+	}
+	c, err := NewRouteHeaderRulesConfigerFromOPConfig(serviceConfig, options.ConfigGeneratorOptions{
+		HeaderRulesPath: rulesPath,
+	})
+	if err != nil {
+		t.Fatalf("NewRouteHeaderRulesConfigerFromOPConfig() got error: %v", err)
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			route := &routepb.Route{}
+			if err := MaybeAddHeaderRules(c, route, tc.operation); err != nil {
+				t.Fatalf("MaybeAddHeaderRules() got error: %v", err)
+			}
+
+			gotRoute, err := util.ProtoToJson(route)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantRoute, gotRoute); err != nil {
+				t.Errorf("MaybeAddHeaderRules() got unexpected route: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewRouteHeaderRulesConfigerFromOPConfig(t *testing.T) {
+	testdata := []struct {
+		desc      string
+		content   string
+		wantError string
+	}{
+		{
+			desc:      "Malformed file",
+			content:   `{"rules": {}}`,
+			wantError: "fail to parse header rules file",
+		},
+		{
+			desc:      "Rule without selectors",
+			content:   `{"rules": [{"request_headers_to_remove": ["x-foo"]}]}`,
+			wantError: "invalid header rule at index 0: selectors must not be empty",
+		},
+		{
+			desc:      "Pseudo-header",
+			content:   `{"rules": [{"selectors": ["foo"], "request_headers_to_add": {":path": "/bar"}}]}`,
+			wantError: `header ":path" cannot be modified by header rules`,
+		},
+		{
+			desc:      "Host header",
+			content:   `{"rules": [{"selectors": ["foo"], "request_headers_to_remove": ["Host"]}]}`,
+			wantError: `header "Host" cannot be modified by header rules`,
+		},
+		{
+			desc:      "Unescaped percent sign",
+			content:   `{"rules": [{"selectors": ["foo"], "response_headers_to_add": {"x-discount": "100%"}}]}`,
+			wantError: `invalid value "100%" for header "x-discount"`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := NewRouteHeaderRulesConfigerFromOPConfig(&servicepb.Service{}, options.ConfigGeneratorOptions{
+				HeaderRulesPath: writeHeaderRules(t, tc.content),
+			})
+			if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+				t.Errorf("NewRouteHeaderRulesConfigerFromOPConfig() got error %v, want error to contain %q", err, tc.wantError)
+			}
+		})
+	}
+
+	if c, err := NewRouteHeaderRulesConfigerFromOPConfig(&servicepb.Service{}, options.ConfigGeneratorOptions{}); c != nil || err != nil {
+		t.Errorf("NewRouteHeaderRulesConfigerFromOPConfig() without a rules file got (%v, %v), want (nil, nil)", c, err)
+	}
+}
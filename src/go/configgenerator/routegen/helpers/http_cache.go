@@ -0,0 +1,73 @@
+package helpers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// RouteHTTPCacheConfiger makes the HTTP cache key cached responses of
+// operations that are not public by the consumer identity.
+type RouteHTTPCacheConfiger struct {
+	// PrivateOperations are the cached operations whose responses must not be
+	// shared across consumers.
+	PrivateOperations map[string]bool
+
+	// VaryHeader is the value appended to the Vary response header.
+	VaryHeader string
+}
+
+// NewRouteHTTPCacheConfigerFromOPConfig creates a RouteHTTPCacheConfiger
+// from OP service config + ESPv2 options.
+func NewRouteHTTPCacheConfigerFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (*RouteHTTPCacheConfiger, error) {
+	cached, public, err := filtergen.ParseHTTPCacheOperations(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	privateOperations := make(map[string]bool)
+	for selector := range cached {
+		if !public[selector] {
+			privateOperations[selector] = true
+		}
+	}
+	if len(privateOperations) == 0 {
+		return nil, nil
+	}
+
+	identityHeaders, err := filtergen.HTTPCacheIdentityHeaders(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RouteHTTPCacheConfiger{
+		PrivateOperations: privateOperations,
+		VaryHeader:        strings.Join(identityHeaders, ", "),
+	}, nil
+}
+
+// MaybeAddHTTPCacheVaryHeader appends the consumer identity headers to the
+// Vary response header of the GET routes of private cached operations. The
+// header sanitizer filter removes them before the response leaves the proxy.
+func MaybeAddHTTPCacheVaryHeader(c *RouteHTTPCacheConfiger, route *routepb.Route, operation string, httpPattern *httppattern.Pattern) {
+	if c == nil || !c.PrivateOperations[operation] || httpPattern == nil || httpPattern.HttpMethod != http.MethodGet {
+		return
+	}
+
+	route.ResponseHeadersToAdd = append(route.ResponseHeadersToAdd, &corepb.HeaderValueOption{
+		Header: &corepb.HeaderValue{
+			Key:   "Vary",
+			Value: c.VaryHeader,
+		},
+		Append: &wrapperspb.BoolValue{
+			Value: true,
+		},
+	})
+}
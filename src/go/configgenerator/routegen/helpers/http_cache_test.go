@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func TestMaybeAddHTTPCacheVaryHeader(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.HTTPCacheOperations = "foo.GetPrivate, foo.GetPublic"
+	opts.HTTPCachePublicOperations = "foo.GetPublic"
+	opts.ApiKeyStorePath = "/etc/espv2/api_keys.json"
+
+	serviceConfig := &servicepb.Service{
+		SystemParameters: &servicepb.SystemParameters{
+			Rules: []*servicepb.SystemParameterRule{
+				{
+					Selector: "foo.GetPrivate",
+					Parameters: []*servicepb.SystemParameter{
+						{
+							Name:       "api_key",
+							HttpHeader: "X-Tenant-Key",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	c, err := NewRouteHTTPCacheConfigerFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		t.Fatalf("NewRouteHTTPCacheConfigerFromOPConfig() got error: %v", err)
+	}
+
+	testdata := []struct {
+		desc      string
+		operation string
+		method    string
+		wantRoute string
+	}{
+		{
+			desc:      "GET route of private operation varies on the consumer identity",
+			operation: "foo.GetPrivate",
+			method:    "GET",
+			wantRoute: `
+{
+  "responseHeadersToAdd": [
+    {
+      "append": true,
+      "header": {
+        "key": "Vary",
+        "value": "Authorization, X-Goog-Iap-Jwt-Assertion, X-Tenant-Key, X-Endpoint-API-UserInfo, X-Endpoint-API-Consumer"
+      }
+    }
+  ]
+}`,
+		},
+		{
+			desc:      "Non-GET route of private operation",
+			operation: "foo.GetPrivate",
+			method:    "POST",
+			wantRoute: `{}`,
+		},
+		{
+			desc:      "Public operation",
+			operation: "foo.GetPublic",
+			method:    "GET",
+			wantRoute: `{}`,
+		},
+		{
+			desc:      "Operation that is not cached",
+			operation: "foo.List",
+			method:    "GET",
+			wantRoute: `{}`,
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			route := &routepb.Route{}
+			MaybeAddHTTPCacheVaryHeader(c, route, tc.operation, &httppattern.Pattern{HttpMethod: tc.method})
+
+			gotRoute, err := util.ProtoToJson(route)
+			if err != nil {
+				t.Fatalf("ProtoToJson() got error: %v", err)
+			}
+			if err := util.JsonEqual(tc.wantRoute, gotRoute); err != nil {
+				t.Errorf("MaybeAddHTTPCacheVaryHeader() got unexpected route: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewRouteHTTPCacheConfigerFromOPConfig(t *testing.T) {
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.HTTPCacheOperations = "foo.GetPublic"
+	opts.HTTPCachePublicOperations = "foo.GetPublic"
+	if c, err := NewRouteHTTPCacheConfigerFromOPConfig(&servicepb.Service{}, opts); c != nil || err != nil {
+		t.Errorf("NewRouteHTTPCacheConfigerFromOPConfig() with only public operations got (%v, %v), want (nil, nil)", c, err)
+	}
+
+	opts.HTTPCachePublicOperations = "foo.GetPublic,foo.Other"
+	if _, err := NewRouteHTTPCacheConfigerFromOPConfig(&servicepb.Service{}, opts); err == nil {
+		t.Errorf("NewRouteHTTPCacheConfigerFromOPConfig() with a public operation that is not cached got no error")
+	}
+}
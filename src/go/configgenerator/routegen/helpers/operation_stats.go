@@ -0,0 +1,30 @@
+package helpers
+
+import (
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+// RouteOperationStatsConfiger is a helper to emit the route stats, e.g.
+// request counts and latencies, per operation.
+type RouteOperationStatsConfiger struct{}
+
+// NewRouteOperationStatsConfigerFromOPConfig creates a
+// RouteOperationStatsConfiger from ESPv2 options.
+func NewRouteOperationStatsConfigerFromOPConfig(opts options.ConfigGeneratorOptions) *RouteOperationStatsConfiger {
+	if !opts.EnableOperationStats {
+		return nil
+	}
+
+	return &RouteOperationStatsConfiger{}
+}
+
+// MaybeAddOperationStatPrefix names the route stats after the operation. The
+// routes of one operation share their stats.
+func MaybeAddOperationStatPrefix(c *RouteOperationStatsConfiger, route *routepb.Route, operation string) {
+	if c == nil {
+		return
+	}
+
+	route.StatPrefix = operation
+}
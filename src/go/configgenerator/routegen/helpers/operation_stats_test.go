@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+)
+
+func TestMaybeAddOperationStatPrefix(t *testing.T) {
+	testdata := []struct {
+		desc                 string
+		enableOperationStats bool
+		wantStatPrefix       string
+	}{
+		{
+			desc:                 "Operation stats enabled",
+			enableOperationStats: true,
+			wantStatPrefix:       "foo.Get",
+		},
+		{
+			desc: "Operation stats disabled",
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := options.DefaultConfigGeneratorOptions()
+			opts.EnableOperationStats = tc.enableOperationStats
+
+			route := &routepb.Route{}
+			MaybeAddOperationStatPrefix(NewRouteOperationStatsConfigerFromOPConfig(opts), route, "foo.Get")
+			if route.GetStatPrefix() != tc.wantStatPrefix {
+				t.Errorf("MaybeAddOperationStatPrefix() got stat prefix %q, want %q", route.GetStatPrefix(), tc.wantStatPrefix)
+			}
+		})
+	}
+}
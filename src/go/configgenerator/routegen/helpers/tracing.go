@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/tracing"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+// RouteTracingConfiger is a helper to override the HCM trace sampling rate
+// for individual operations.
+type RouteTracingConfiger struct {
+	// SamplingRateBySelector is the sampling rate of each operation, parsed
+	// from the "selector=rate" pairs of --tracing_operation_sample_rates.
+	SamplingRateBySelector map[string]float64
+}
+
+// NewRouteTracingConfigerFromOPConfig creates a RouteTracingConfiger from
+// OP service config + ESPv2 options.
+func NewRouteTracingConfigerFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (*RouteTracingConfiger, error) {
+	if opts.TracingOptions == nil || opts.TracingOptions.DisableTracing || opts.TracingOptions.OperationSamplingRates == "" {
+		return nil, nil
+	}
+
+	ratesBySelector, err := tracing.ParseOperationSamplingRates(opts.TracingOptions.OperationSamplingRates)
+	if err != nil {
+		return nil, err
+	}
+
+	selectors := make(map[string]bool)
+	for _, api := range serviceConfig.GetApis() {
+		if util.ShouldSkipOPDiscoveryAPI(api.GetName(), opts.AllowDiscoveryAPIs) {
+			continue
+		}
+		for _, method := range api.GetMethods() {
+			selectors[filtergen.MethodToSelector(api, method)] = true
+		}
+	}
+	for selector := range ratesBySelector {
+		if !selectors[selector] {
+			glog.Warningf("Ignoring operation sampling rate for selector %q because there is no operation with that name.", selector)
+			delete(ratesBySelector, selector)
+		}
+	}
+
+	return &RouteTracingConfiger{
+		SamplingRateBySelector: ratesBySelector,
+	}, nil
+}
+
+// MaybeAddTracing adds the per-route tracing config to the route if the
+// operation has a sampling rate override.
+func MaybeAddTracing(c *RouteTracingConfiger, route *routepb.Route, operation string) error {
+	if c == nil {
+		return nil
+	}
+
+	tracingConfig, err := c.MakeTracingConfig(operation)
+	if err != nil {
+		return fmt.Errorf("fail to create tracing config for route: %v", err)
+	}
+
+	route.Tracing = tracingConfig
+	return nil
+}
+
+// MakeTracingConfig creates the per-route tracing config for the operation,
+// or nil if the operation uses the HCM sampling rate.
+//
+// The route overrides both the random and overall sampling, so the operation
+// is sampled at exactly its rate regardless of the HCM sampling rate.
+func (c *RouteTracingConfiger) MakeTracingConfig(operation string) (*routepb.Tracing, error) {
+	rate, ok := c.SamplingRateBySelector[operation]
+	if !ok {
+		return nil, nil
+	}
+
+	percent, err := tracing.SamplingRateToPercent(rate)
+	if err != nil {
+		return nil, err
+	}
+
+	// The percentage has 4 decimal points, so it is exact in parts per million.
+	numerator := uint32(math.Round(percent * 10000))
+	return &routepb.Tracing{
+		RandomSampling: &typepb.FractionalPercent{
+			Numerator:   numerator,
+			Denominator: typepb.FractionalPercent_MILLION,
+		},
+		OverallSampling: &typepb.FractionalPercent{
+			Numerator:   numerator,
+			Denominator: typepb.FractionalPercent_MILLION,
+		},
+	}, nil
+}
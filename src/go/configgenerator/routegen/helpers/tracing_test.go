@@ -0,0 +1,194 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/google/go-cmp/cmp"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestNewRouteTracingConfigerFromOPConfig(t *testing.T) {
+	testdata := []struct {
+		desc        string
+		opts        options.ConfigGeneratorOptions
+		operation   string
+		wantTracing *routepb.Tracing
+		wantError   string
+	}{
+		{
+			desc: "Operation sampled at 0%",
+			opts: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						OperationSamplingRates: "foo.ListBooks=0, foo.Expensive=1",
+					},
+				},
+			},
+			operation: "foo.ListBooks",
+			wantTracing: &routepb.Tracing{
+				RandomSampling: &typepb.FractionalPercent{
+					Denominator: typepb.FractionalPercent_MILLION,
+				},
+				OverallSampling: &typepb.FractionalPercent{
+					Denominator: typepb.FractionalPercent_MILLION,
+				},
+			},
+		},
+		{
+			desc: "Operation sampled at 100%",
+			opts: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						OperationSamplingRates: "foo.ListBooks=0, foo.Expensive=1",
+					},
+				},
+			},
+			operation: "foo.Expensive",
+			wantTracing: &routepb.Tracing{
+				RandomSampling: &typepb.FractionalPercent{
+					Numerator:   1000000,
+					Denominator: typepb.FractionalPercent_MILLION,
+				},
+				OverallSampling: &typepb.FractionalPercent{
+					Numerator:   1000000,
+					Denominator: typepb.FractionalPercent_MILLION,
+				},
+			},
+		},
+		{
+			desc: "Small rate is rounded to parts per million",
+			opts: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						OperationSamplingRates: "foo.Rare=0.0000123",
+					},
+				},
+			},
+			operation: "foo.Rare",
+			wantTracing: &routepb.Tracing{
+				RandomSampling: &typepb.FractionalPercent{
+					Numerator:   12,
+					Denominator: typepb.FractionalPercent_MILLION,
+				},
+				OverallSampling: &typepb.FractionalPercent{
+					Numerator:   12,
+					Denominator: typepb.FractionalPercent_MILLION,
+				},
+			},
+		},
+		{
+			desc: "Operation without override uses the HCM sampling rate",
+			opts: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						OperationSamplingRates: "foo.ListBooks=0",
+					},
+				},
+			},
+			operation: "foo.GetBook",
+		},
+		{
+			desc: "Unknown selector is ignored",
+			opts: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						OperationSamplingRates: "foo.ListBook=0",
+					},
+				},
+			},
+			operation: "foo.ListBooks",
+		},
+		{
+			desc: "No override when tracing is disabled",
+			opts: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						DisableTracing:         true,
+						OperationSamplingRates: "foo.ListBooks=0",
+					},
+				},
+			},
+			operation: "foo.ListBooks",
+		},
+		{
+			desc: "Invalid rate has error",
+			opts: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						OperationSamplingRates: "foo.ListBooks=1.5",
+					},
+				},
+			},
+			operation: "foo.ListBooks",
+			wantError: `for selector "foo.ListBooks", invalid trace sampling rate: 1.5`,
+		},
+		{
+			desc: "Malformed pair has error",
+			opts: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						OperationSamplingRates: "foo.ListBooks",
+					},
+				},
+			},
+			operation: "foo.ListBooks",
+			wantError: "it must be in the format selector=rate",
+		},
+		{
+			desc: "Duplicate selector has error",
+			opts: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						OperationSamplingRates: "foo.ListBooks=0,foo.ListBooks=1",
+					},
+				},
+			},
+			operation: "foo.ListBooks",
+			wantError: `duplicate operation sampling rate for selector "foo.ListBooks"`,
+		},
+	}
+
+	serviceConfig := &servicepb.Service{
+		Apis: []*apipb.Api{
+			{
+				Name: "foo",
+				Methods: []*apipb.Method{
+					{Name: "ListBooks"},
+					{Name: "GetBook"},
+					{Name: "Expensive"},
+					{Name: "Rare"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testdata {
+		t.Run(tc.desc, func(t *testing.T) {
+			c, err := NewRouteTracingConfigerFromOPConfig(serviceConfig, tc.opts)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("NewRouteTracingConfigerFromOPConfig() got error %v, want error to contain %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewRouteTracingConfigerFromOPConfig() got unexpected error: %v", err)
+			}
+
+			route := &routepb.Route{}
+			if err := MaybeAddTracing(c, route, tc.operation); err != nil {
+				t.Fatalf("MaybeAddTracing() got unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.wantTracing, route.Tracing, protocmp.Transform()); diff != "" {
+				t.Errorf("MaybeAddTracing() has unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
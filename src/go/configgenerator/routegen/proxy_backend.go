@@ -44,12 +44,17 @@ func NewProxyBackendRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts
 		return nil, fmt.Errorf("fail to parse backend cluster specifiers from OP config: %v", err)
 	}
 
+	backendRouteGen, err := helpers.NewBackendRouteGeneratorFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	return &ProxyBackendGenerator{
 		HTTPPatterns:                   *httpPatterns,
 		BackendClusterBySelector:       backendClusterBySelector,
 		DeadlineBySelector:             ParseDeadlineSelectorFromOPConfig(serviceConfig, opts),
 		MethodBySelector:               ParseMethodBySelectorFromOPConfig(serviceConfig),
-		BackendRouteGen:                helpers.NewBackendRouteGeneratorFromOPConfig(opts),
+		BackendRouteGen:                backendRouteGen,
 		AllowHostRewriteForHTTPBackend: opts.AllowHostRewriteForHTTPBackend,
 	}, nil
 }
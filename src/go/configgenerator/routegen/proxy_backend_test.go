@@ -1844,6 +1844,163 @@ func TestNewBackendRouteGenFromOPConfig(t *testing.T) {
 		tc.RunTest(t, routegen.NewProxyBackendRouteGenFromOPConfig)
 	}
 }
+
+func TestNewBackendRouteGenFromOPConfig_OperationSamplingRates(t *testing.T) {
+	testdata := []routegentest.SuccessOPTestCase{
+		{
+			Desc: "Per-operation sampling rate overrides the HCM sampling rate",
+			ServiceConfigIn: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "ListBooks",
+							},
+							{
+								Name: "Echo",
+							},
+						},
+					},
+				},
+				Http: &annotationspb.Http{
+					Rules: []*annotationspb.HttpRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.ListBooks",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/books",
+							},
+						},
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.Echo",
+							Pattern: &annotationspb.HttpRule_Post{
+								Post: "/echo/{id}",
+							},
+						},
+					},
+				},
+			},
+			OptsIn: options.ConfigGeneratorOptions{
+				CommonOptions: options.CommonOptions{
+					TracingOptions: &options.TracingOptions{
+						OperationSamplingRates: "endpoints.examples.bookstore.Bookstore.ListBooks=0.25,endpoints.examples.bookstore.Bookstore.Unknown=1",
+					},
+				},
+			},
+			WantHostConfig: `
+{
+  "routes":[
+    {
+      "decorator":{
+        "operation":"ingress ListBooks"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/books"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.ListBooks",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      },
+      "tracing":{
+        "overallSampling":{
+          "denominator":"MILLION",
+          "numerator":250000
+        },
+        "randomSampling":{
+          "denominator":"MILLION",
+          "numerator":250000
+        }
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress ListBooks"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"GET"
+            }
+          }
+        ],
+        "path":"/books/"
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.ListBooks",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      },
+      "tracing":{
+        "overallSampling":{
+          "denominator":"MILLION",
+          "numerator":250000
+        },
+        "randomSampling":{
+          "denominator":"MILLION",
+          "numerator":250000
+        }
+      }
+    },
+    {
+      "decorator":{
+        "operation":"ingress Echo"
+      },
+      "match":{
+        "headers":[
+          {
+            "name":":method",
+            "stringMatch":{
+              "exact":"POST"
+            }
+          }
+        ],
+        "safeRegex":{
+          "regex":"^/echo/[^\\/]+\\/?$"
+        }
+      },
+      "name":"endpoints.examples.bookstore.Bookstore.Echo",
+      "route":{
+        "cluster":"backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout":"300s",
+        "retryPolicy":{
+          "numRetries":1,
+          "retryOn":"reset,connect-failure,refused-stream"
+        },
+        "timeout":"15s"
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewProxyBackendRouteGenFromOPConfig)
+	}
+}
+
 func TestNewBackendRouteGenFromOPConfig_BadInputFactory(t *testing.T) {
 	testdata := []routegentest.FactoryErrorOPTestCase{
 		{
@@ -0,0 +1,132 @@
+package routegen
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util/httppattern"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/golang/glog"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ProxyConnectJSONGenerator is a RouteGenerator to route unary Connect
+// requests with the JSON encoding on the gRPC paths of the methods served by
+// gRPC backends.
+//
+// The routes are the backend routes of the gRPC paths with the Connect gRPC
+// bridge disabled, so the transcoder converts the requests with the default
+// "POST /package.Service/Method" HTTP binding of the methods.
+type ProxyConnectJSONGenerator struct {
+	// BackendGen generates the backend routes of the gRPC paths of the unary
+	// methods served by gRPC backends.
+	BackendGen *ProxyBackendGenerator
+
+	*NoopRouteGenerator
+}
+
+// NewProxyConnectJSONRouteGenFromOPConfig creates ProxyConnectJSONGenerator
+// from OP service config + ESPv2 options.
+// It is a RouteGeneratorOPFactory.
+func NewProxyConnectJSONRouteGenFromOPConfig(serviceConfig *servicepb.Service, opts options.ConfigGeneratorOptions) (RouteGenerator, error) {
+	if !opts.EnableConnectGrpcBridge {
+		glog.Infof("Not adding proxy Connect JSON route gen because the feature is disabled by option.")
+		return nil, nil
+	}
+
+	grpcSelectors, err := filtergen.GetGRPCSelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	gen, err := NewProxyBackendRouteGenFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, err
+	}
+	backendGen := gen.(*ProxyBackendGenerator)
+
+	var unaryGRPCPatterns httppattern.MethodSlice
+	for _, httpPattern := range backendGen.HTTPPatterns {
+		selector := httpPattern.Operation
+		if !grpcSelectors[selector] || httpPattern.HttpMethod != util.POST {
+			continue
+		}
+		if method := backendGen.MethodBySelector[selector]; method.GetRequestStreaming() || method.GetResponseStreaming() {
+			continue
+		}
+
+		isGRPCPath, err := httpPattern.IsGRPCPathForOperation(selector)
+		if err != nil {
+			return nil, fmt.Errorf("fail to check the gRPC path of operation %q: %v", selector, err)
+		}
+		if isGRPCPath {
+			unaryGRPCPatterns = append(unaryGRPCPatterns, httpPattern)
+		}
+	}
+	if len(unaryGRPCPatterns) == 0 {
+		glog.Infof("No unary methods are served by gRPC backends, skip proxy Connect JSON route gen.")
+		return nil, nil
+	}
+	backendGen.HTTPPatterns = unaryGRPCPatterns
+
+	return &ProxyConnectJSONGenerator{
+		BackendGen: backendGen,
+	}, nil
+}
+
+// RouteType implements interface RouteGenerator.
+func (g *ProxyConnectJSONGenerator) RouteType() string {
+	return "proxy_connect_json_routes"
+}
+
+// GenRouteConfig implements interface RouteGenerator.
+func (g *ProxyConnectJSONGenerator) GenRouteConfig(filterGens []filtergen.FilterGenerator) ([]*routepb.Route, error) {
+	routes, err := g.BackendGen.GenRouteConfig(filterGens)
+	if err != nil {
+		return nil, err
+	}
+
+	disabled, err := anypb.New(&routepb.FilterConfig{
+		Disabled: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to marshal per-route config to Any for filter %q: %v", filtergen.ConnectGRPCBridgeFilterName, err)
+	}
+
+	for _, route := range routes {
+		route.Match.Headers = append(route.Match.Headers, makeConnectUnaryJSONHeaderMatchers()...)
+		if route.TypedPerFilterConfig == nil {
+			route.TypedPerFilterConfig = make(map[string]*anypb.Any)
+		}
+		route.TypedPerFilterConfig[filtergen.ConnectGRPCBridgeFilterName] = disabled
+	}
+	return routes, nil
+}
+
+// makeConnectUnaryJSONHeaderMatchers returns the header matchers of unary
+// Connect requests with the JSON encoding. Plain JSON requests without the
+// Connect protocol version header are transcoded by the backend routes.
+func makeConnectUnaryJSONHeaderMatchers() []*routepb.HeaderMatcher {
+	return []*routepb.HeaderMatcher{
+		{
+			Name: "content-type",
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_StringMatch{
+				StringMatch: &matcherpb.StringMatcher{
+					MatchPattern: &matcherpb.StringMatcher_Prefix{
+						Prefix: "application/json",
+					},
+				},
+			},
+		},
+		{
+			Name: "connect-protocol-version",
+			HeaderMatchSpecifier: &routepb.HeaderMatcher_PresentMatch{
+				PresentMatch: true,
+			},
+		},
+	}
+}
@@ -0,0 +1,162 @@
+package routegen_test
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/routegen/routegentest"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	servicepb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	apipb "google.golang.org/genproto/protobuf/api"
+)
+
+func TestNewProxyConnectJSONRouteGenFromOPConfig(t *testing.T) {
+	serviceConfig := &servicepb.Service{
+		Name: "bookstore.endpoints.project123.cloud.goog",
+		Apis: []*apipb.Api{
+			{
+				Name: "endpoints.examples.bookstore.Bookstore",
+				Methods: []*apipb.Method{
+					{
+						Name: "GetShelf",
+					},
+					{
+						Name:              "StreamShelves",
+						ResponseStreaming: true,
+					},
+				},
+			},
+		},
+	}
+
+	testdata := []routegentest.SuccessOPTestCase{
+		{
+			Desc:            "No routes generated when the Connect gRPC bridge is disabled",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress: "grpc://127.0.0.1:80",
+			},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc:            "No routes generated for HTTP backends",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:          "http://127.0.0.1:80",
+				EnableConnectGrpcBridge: true,
+			},
+			WantHostConfig: `{}`,
+		},
+		{
+			Desc:            "Unary JSON Connect requests to the gRPC paths are routed with the bridge disabled",
+			ServiceConfigIn: serviceConfig,
+			OptsIn: options.ConfigGeneratorOptions{
+				BackendAddress:          "grpc://127.0.0.1:80",
+				EnableConnectGrpcBridge: true,
+			},
+			FilterGens: []filtergen.FilterGenerator{
+				&filtergen.ConnectGRPCBridgeGenerator{
+					GRPCSelectors: map[string]bool{
+						"endpoints.examples.bookstore.Bookstore.GetShelf":      true,
+						"endpoints.examples.bookstore.Bookstore.StreamShelves": true,
+					},
+				},
+			},
+			WantHostConfig: `
+{
+  "routes": [
+    {
+      "decorator": {
+        "operation": "ingress GetShelf"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "POST"
+            }
+          },
+          {
+            "name": "content-type",
+            "stringMatch": {
+              "prefix": "application/json"
+            }
+          },
+          {
+            "name": "connect-protocol-version",
+            "presentMatch": true
+          }
+        ],
+        "path": "/endpoints.examples.bookstore.Bookstore/GetShelf"
+      },
+      "name": "endpoints.examples.bookstore.Bookstore.GetShelf",
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout": "300s",
+        "retryPolicy": {
+          "numRetries": 1,
+          "retryOn": "reset,connect-failure,refused-stream"
+        },
+        "timeout": "15s"
+      },
+      "typedPerFilterConfig": {
+        "envoy.filters.http.connect_grpc_bridge": {
+          "@type": "type.googleapis.com/envoy.config.route.v3.FilterConfig",
+          "disabled": true
+        }
+      }
+    },
+    {
+      "decorator": {
+        "operation": "ingress GetShelf"
+      },
+      "match": {
+        "headers": [
+          {
+            "name": ":method",
+            "stringMatch": {
+              "exact": "POST"
+            }
+          },
+          {
+            "name": "content-type",
+            "stringMatch": {
+              "prefix": "application/json"
+            }
+          },
+          {
+            "name": "connect-protocol-version",
+            "presentMatch": true
+          }
+        ],
+        "path": "/endpoints.examples.bookstore.Bookstore/GetShelf/"
+      },
+      "name": "endpoints.examples.bookstore.Bookstore.GetShelf",
+      "route": {
+        "cluster": "backend-cluster-bookstore.endpoints.project123.cloud.goog_local",
+        "idleTimeout": "300s",
+        "retryPolicy": {
+          "numRetries": 1,
+          "retryOn": "reset,connect-failure,refused-stream"
+        },
+        "timeout": "15s"
+      },
+      "typedPerFilterConfig": {
+        "envoy.filters.http.connect_grpc_bridge": {
+          "@type": "type.googleapis.com/envoy.config.route.v3.FilterConfig",
+          "disabled": true
+        }
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, tc := range testdata {
+		tc.RunTest(t, routegen.NewProxyConnectJSONRouteGenFromOPConfig)
+	}
+}
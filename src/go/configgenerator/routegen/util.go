@@ -193,11 +193,16 @@ func ParseHTTPPatternsBySelectorFromOPConfig(serviceConfig *servicepb.Service, o
 	if err != nil {
 		return nil, fmt.Errorf("fail to check if gRPC support is required: %v", err)
 	}
-	if !isGRPCSupportRequired {
+	reverseTranscodingSelectors, err := filtergen.GetReverseTranscodingSelectorsFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get reverse transcoding operations: %v", err)
+	}
+	if !isGRPCSupportRequired && len(reverseTranscodingSelectors) == 0 {
 		return httpPatternsBySelector, nil
 	}
 
-	// Add gRPC paths for gRPC backends.
+	// Add gRPC paths for gRPC backends, and for the HTTP backends of reverse
+	// transcoded operations.
 	for _, api := range serviceConfig.GetApis() {
 		if util.ShouldSkipOPDiscoveryAPI(api.GetName(), opts.AllowDiscoveryAPIs) {
 			glog.Warningf("Skip API %q because discovery API is not supported.", api.GetName())
@@ -206,6 +211,9 @@ func ParseHTTPPatternsBySelectorFromOPConfig(serviceConfig *servicepb.Service, o
 
 		for _, method := range api.GetMethods() {
 			selector := filtergen.MethodToSelector(api, method)
+			if !isGRPCSupportRequired && !reverseTranscodingSelectors[selector] {
+				continue
+			}
 			gRPCPath := fmt.Sprintf("/%s/%s", api.GetName(), method.GetName())
 
 			// For the OP config generated by api compiler, the path/uri template for grpc
@@ -731,6 +731,64 @@ func TestParseHTTPPatternsBySelectorFromOPConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "http_service_reverse_transcoding",
+			serviceConfig: &servicepb.Service{
+				Name: "bookstore.endpoints.project123.cloud.goog",
+				Apis: []*apipb.Api{
+					{
+						Name: "endpoints.examples.bookstore.Bookstore",
+						Methods: []*apipb.Method{
+							{
+								Name: "ListShelves",
+							},
+							{
+								Name:              "StreamShelves",
+								ResponseStreaming: true,
+							},
+						},
+					},
+				},
+				Http: &annotationspb.Http{
+					Rules: []*annotationspb.HttpRule{
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.ListShelves",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/v1/shelves",
+							},
+						},
+						{
+							Selector: "endpoints.examples.bookstore.Bookstore.StreamShelves",
+							Pattern: &annotationspb.HttpRule_Get{
+								Get: "/v1/shelves:stream",
+							},
+						},
+					},
+				},
+			},
+			opts: options.ConfigGeneratorOptions{
+				BackendAddress:                   "http://127.0.0.1:80",
+				EnableGrpcJsonReverseTranscoding: true,
+			},
+			want: map[string][]*httppattern.Pattern{
+				"endpoints.examples.bookstore.Bookstore.ListShelves": {
+					{
+						HttpMethod:  util.GET,
+						UriTemplate: parseUriTemplate(t, "/v1/shelves"),
+					},
+					{
+						HttpMethod:  util.POST,
+						UriTemplate: parseUriTemplate(t, "/endpoints.examples.bookstore.Bookstore/ListShelves"),
+					},
+				},
+				"endpoints.examples.bookstore.Bookstore.StreamShelves": {
+					{
+						HttpMethod:  util.GET,
+						UriTemplate: parseUriTemplate(t, "/v1/shelves:stream"),
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testdata {
@@ -208,8 +208,26 @@ func (s *ServiceInfo) ServiceConfig() *confpb.Service {
 }
 
 func (s *ServiceInfo) processEmptyJwksUriByOpenID() error {
+	localJwksFiles, err := clustergen.GetLocalJwksFilesFromOPConfig(s.serviceConfig, s.Options)
+	if err != nil {
+		return err
+	}
+
+	introspectionProviders, err := clustergen.GetTokenIntrospectionProvidersFromOPConfig(s.serviceConfig, s.Options)
+	if err != nil {
+		return err
+	}
+
 	authn := s.serviceConfig.GetAuthentication()
 	for _, provider := range authn.GetProviders() {
+		if _, ok := localJwksFiles[provider.GetId()]; ok {
+			// JWKS is read from a local file, no need to discover jwksUri.
+			continue
+		}
+		if _, ok := introspectionProviders[provider.GetId()]; ok {
+			// Opaque tokens have no JWKS.
+			continue
+		}
 		jwksUri := provider.GetJwksUri()
 
 		// Note: When jwksUri is empty, proxy will try to find jwksUri using the
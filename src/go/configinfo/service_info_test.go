@@ -3213,6 +3213,7 @@ func TestProcessEmptyJwksUriByOpenID(t *testing.T) {
 		desc                 string
 		fakeServiceConfig    *confpb.Service
 		disableOidcDiscovery bool
+		jwksLocalFiles       string
 		wantedJwksUri        string
 		wantErr              bool
 	}{
@@ -3274,11 +3275,53 @@ func TestProcessEmptyJwksUriByOpenID(t *testing.T) {
 			disableOidcDiscovery: true,
 			wantErr:              true,
 		},
+		{
+			desc: "Success, empty JWKS URI but JWKS is read from a local file, so no discovery.",
+			fakeServiceConfig: &confpb.Service{
+				Apis: []*apipb.Api{
+					{
+						Name: testApiName,
+					},
+				},
+				Authentication: &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id:     "auth_provider",
+							Issuer: "aaaaa.bbbbbb.ccccc/inaccessible_uri/",
+						},
+					},
+				},
+			},
+			jwksLocalFiles:       "auth_provider=/etc/espv2/jwks.json",
+			disableOidcDiscovery: true,
+			wantedJwksUri:        "",
+		},
+		{
+			desc: "Success, file JWKS URI is kept as is.",
+			fakeServiceConfig: &confpb.Service{
+				Apis: []*apipb.Api{
+					{
+						Name: testApiName,
+					},
+				},
+				Authentication: &confpb.Authentication{
+					Providers: []*confpb.AuthProvider{
+						{
+							Id:      "auth_provider",
+							Issuer:  "aaaaa.bbbbbb.ccccc/inaccessible_uri/",
+							JwksUri: "file:///etc/espv2/jwks.json",
+						},
+					},
+				},
+			},
+			wantedJwksUri: "file:///etc/espv2/jwks.json",
+		},
 	}
 
 	for i, tc := range testData {
 		opts := options.DefaultConfigGeneratorOptions()
 		opts.DisableOidcDiscovery = tc.disableOidcDiscovery
+		opts.JwksLocalFiles = tc.jwksLocalFiles
 		serviceInfo, err := NewServiceInfoFromServiceConfig(tc.fakeServiceConfig, opts)
 
 		if tc.wantErr {
@@ -16,14 +16,19 @@ package configmanager
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/clustergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configgenerator/filtergen"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configinfo"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/metadata"
@@ -40,11 +45,15 @@ import (
 	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	rsrc "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
 	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+	smpb "google.golang.org/genproto/googleapis/api/servicemanagement/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 var (
 	// These flags are used by config manage only.
 	checkNewRolloutInterval = flag.Duration("check_rollout_interval", 60*time.Second, `the interval periodically to call servicemanagment to check the latest rolloutil.`)
+	checkLocalFilesInterval = flag.Duration("check_local_files_interval", 10*time.Second, `the interval periodically to check local JWKS and API key store files for changes.`)
 	CheckMetadata           = flag.Bool("check_metadata", false, `enable fetching service name, config ID and rollout strategy from service metadata server`)
 	RolloutStrategy         = flag.String("rollout_strategy", "fixed", `service config rollout strategy, must be either "managed" or "fixed"`)
 	ServiceConfigId         = flag.String("service_config_id", "", "initial service config id")
@@ -70,6 +79,25 @@ type ConfigManager struct {
 	rolloutIdChangeDetector *sc.RolloutIdChangeDetector
 
 	curServiceConfig *confpb.Service
+
+	// localFilesDigest identifies the content of the local JWKS and API key
+	// store files used by the current snapshot. Empty if there are none.
+	localFilesDigest    string
+	localFilesWatchOnce sync.Once
+
+	// descriptorFetcher fetches the proto descriptors from the backend via gRPC
+	// server reflection. Nil if --transcoding_grpc_reflection is not set.
+	descriptorFetcher *sc.ReflectionDescriptorFetcher
+	// backendDescriptorBin is the last proto descriptor set fetched from the
+	// backend, and backendDescriptorDigest identifies it. Empty if none was
+	// fetched yet.
+	backendDescriptorBin       []byte
+	backendDescriptorDigest    string
+	backendDescriptorWatchOnce sync.Once
+
+	// mu serializes applying service configs, which happens on both rollout
+	// changes and local file changes.
+	mu sync.Mutex
 }
 
 // NewConfigManager creates new instance of Config Manager.
@@ -81,6 +109,18 @@ func NewConfigManager(mf *metadata.MetadataFetcher, opts options.ConfigGenerator
 	}
 	m.cache = cache.NewSnapshotCache(true, m, m)
 
+	if opts.TranscodingGrpcReflection {
+		if opts.TranscodingDescriptorPath != "" {
+			return nil, fmt.Errorf("flag --transcoding_grpc_reflection cannot be used together with --transcoding_descriptor_path")
+		}
+
+		var err error
+		m.descriptorFetcher, err = sc.NewReflectionDescriptorFetcher(opts.BackendAddress, opts.SslBackendClientRootCertsPath, opts.HttpRequestTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("fail to init gRPC server reflection, %v", err)
+		}
+	}
+
 	// If service config is provided as a file, just use it and disable managed rollout
 	if *ServicePath != "" {
 		// Following flags will not be used
@@ -236,13 +276,74 @@ func (m *ConfigManager) applyServiceConfig(serviceConfig *confpb.Service) error
 		return fmt.Errorf("applid service config is empty")
 	}
 
+	// Fetch before taking the lock, the backend may take up to the request
+	// timeout to respond.
+	var descriptorBin []byte
+	if m.descriptorFetcher != nil {
+		var err error
+		descriptorBin, err = m.fetchBackendDescriptors(serviceConfig)
+		if err != nil {
+			glog.Errorf("error occurred when fetching proto descriptors from the backend, using the last fetched descriptors: %v", err)
+		}
+	}
+	return m.applyServiceConfigWithDescriptors(serviceConfig, descriptorBin)
+}
+
+// applyServiceConfigWithDescriptors applies the service config with the proto
+// descriptors fetched from the backend. If descriptorBin is nil, the last
+// fetched descriptors are used.
+func (m *ConfigManager) applyServiceConfigWithDescriptors(serviceConfig *confpb.Service, descriptorBin []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.applyServiceConfigLocked(serviceConfig, descriptorBin)
+}
+
+// reapplyServiceConfig re-applies the service config a watcher read as the
+// current one. It is skipped if another service config was applied since, so
+// a watcher never reverts a rollout.
+func (m *ConfigManager) reapplyServiceConfig(serviceConfig *confpb.Service, descriptorBin []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.curServiceConfig != serviceConfig {
+		glog.Infof("service config %v was replaced by %v, skip re-applying it", serviceConfig.GetId(), m.curServiceConfig.GetId())
+		return nil
+	}
+	return m.applyServiceConfigLocked(serviceConfig, descriptorBin)
+}
+
+// applyServiceConfigLocked applies the service config, mu must be held.
+func (m *ConfigManager) applyServiceConfigLocked(serviceConfig *confpb.Service, descriptorBin []byte) error {
 	var err error
 	m.curServiceConfig = serviceConfig
-	m.serviceInfo, err = configinfo.NewServiceInfoFromServiceConfig(serviceConfig, m.envoyConfigOptions)
+
+	// The proto descriptors fetched from the backend replace the descriptor in
+	// the service config. Until the backend was reached once, the descriptor
+	// in the service config is used.
+	if descriptorBin != nil {
+		m.backendDescriptorBin = descriptorBin
+		m.backendDescriptorDigest = descriptorDigest(descriptorBin)
+	}
+	generatorServiceConfig := serviceConfig
+	if m.backendDescriptorBin != nil {
+		generatorServiceConfig, err = serviceConfigWithDescriptor(serviceConfig, m.backendDescriptorBin)
+		if err != nil {
+			return err
+		}
+	}
+
+	m.serviceInfo, err = configinfo.NewServiceInfoFromServiceConfig(generatorServiceConfig, m.envoyConfigOptions)
 	if err != nil {
 		return fmt.Errorf("fail to initialize ServiceInfo, %s", err)
 	}
 
+	// Compute the digest before the snapshot reads the files, so a concurrent
+	// file update is picked up by the next check.
+	m.localFilesDigest, err = localFilesDigest(serviceConfig, m.envoyConfigOptions)
+	if err != nil {
+		return err
+	}
+
 	if m.metadataFetcher != nil {
 		attrs, err := m.metadataFetcher.FetchGCPAttributes()
 		if err != nil {
@@ -262,7 +363,165 @@ func (m *ConfigManager) applyServiceConfig(serviceConfig *confpb.Service) error
 	if err != nil {
 		return fmt.Errorf("fail to make a snapshot, %s", err)
 	}
-	return m.cache.SetSnapshot(context.Background(), m.envoyConfigOptions.Node, snapshot)
+	if err := m.cache.SetSnapshot(context.Background(), m.envoyConfigOptions.Node, snapshot); err != nil {
+		return err
+	}
+
+	m.maybeWatchLocalFiles()
+	m.maybeWatchBackendDescriptors()
+	return nil
+}
+
+// maybeWatchLocalFiles periodically checks the local JWKS and API key store
+// files and re-applies the current service config when their content changes,
+// so keys can be rotated by updating the files.
+func (m *ConfigManager) maybeWatchLocalFiles() {
+	if m.localFilesDigest == "" {
+		return
+	}
+
+	m.localFilesWatchOnce.Do(func() {
+		go func() {
+			interval := *checkLocalFilesInterval
+			glog.Infof("start checking local files every %v", interval)
+			ticker := time.NewTicker(interval)
+
+			for range ticker.C {
+				m.mu.Lock()
+				serviceConfig, curDigest := m.curServiceConfig, m.localFilesDigest
+				m.mu.Unlock()
+
+				latestDigest, err := localFilesDigest(serviceConfig, m.envoyConfigOptions)
+				if err != nil {
+					glog.Errorf("error occurred when checking local files, %v", err)
+					continue
+				}
+				if latestDigest == curDigest {
+					continue
+				}
+
+				glog.Infof("local files changed, re-applying service config %v", serviceConfig.GetId())
+				if err := m.reapplyServiceConfig(serviceConfig, nil); err != nil {
+					glog.Errorf("error occurred when applying local files change, %v", err)
+				}
+			}
+		}()
+	})
+}
+
+// localFilesDigest returns a digest of the content of all local JWKS files and
+// the API key store, or an empty string if none is used.
+func localFilesDigest(serviceConfig *confpb.Service, opts options.ConfigGeneratorOptions) (string, error) {
+	localJwksFiles, err := clustergen.GetLocalJwksFilesFromOPConfig(serviceConfig, opts)
+	if err != nil {
+		return "", err
+	}
+	if len(localJwksFiles) == 0 && opts.ApiKeyStorePath == "" {
+		return "", nil
+	}
+
+	var ids []string
+	for id := range localJwksFiles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	h := sha256.New()
+	for _, id := range ids {
+		jwks, err := ioutil.ReadFile(localJwksFiles[id])
+		if err != nil {
+			return "", fmt.Errorf("fail to read local JWKS file for authentication provider %q: %v", id, err)
+		}
+		fmt.Fprintf(h, "%s\x00%s\x00", id, localJwksFiles[id])
+		h.Write(jwks)
+	}
+
+	if opts.ApiKeyStorePath != "" {
+		apiKeys, err := ioutil.ReadFile(opts.ApiKeyStorePath)
+		if err != nil {
+			return "", fmt.Errorf("fail to read API key store file: %v", err)
+		}
+		fmt.Fprintf(h, "\x00%s\x00", opts.ApiKeyStorePath)
+		h.Write(apiKeys)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// maybeWatchBackendDescriptors periodically fetches the proto descriptors from
+// the backend and re-applies the current service config when they change, so
+// proto changes do not require a new service config.
+func (m *ConfigManager) maybeWatchBackendDescriptors() {
+	if m.descriptorFetcher == nil {
+		return
+	}
+
+	m.backendDescriptorWatchOnce.Do(func() {
+		go func() {
+			glog.Infof("start fetching proto descriptors from the backend every %v", m.envoyConfigOptions.TranscodingGrpcReflectionInterval)
+			ticker := time.NewTicker(m.envoyConfigOptions.TranscodingGrpcReflectionInterval)
+
+			for range ticker.C {
+				m.mu.Lock()
+				serviceConfig, curDigest := m.curServiceConfig, m.backendDescriptorDigest
+				m.mu.Unlock()
+
+				descriptorBin, err := m.fetchBackendDescriptors(serviceConfig)
+				if err != nil {
+					glog.Errorf("error occurred when fetching proto descriptors from the backend, %v", err)
+					continue
+				}
+				if descriptorDigest(descriptorBin) == curDigest {
+					continue
+				}
+
+				glog.Infof("proto descriptors of the backend changed, re-applying service config %v", serviceConfig.GetId())
+				if err := m.reapplyServiceConfig(serviceConfig, descriptorBin); err != nil {
+					glog.Errorf("error occurred when applying proto descriptors change, %v", err)
+				}
+			}
+		}()
+	})
+}
+
+// fetchBackendDescriptors fetches the proto descriptors of the APIs in the
+// service config from the backend.
+func (m *ConfigManager) fetchBackendDescriptors(serviceConfig *confpb.Service) ([]byte, error) {
+	apiNames := filtergen.GetAPINamesListFromOPConfig(serviceConfig, m.envoyConfigOptions)
+	return m.descriptorFetcher.FetchDescriptorSet(apiNames)
+}
+
+// serviceConfigWithDescriptor returns a copy of the service config with its
+// proto descriptor replaced by descriptorBin.
+func serviceConfigWithDescriptor(serviceConfig *confpb.Service, descriptorBin []byte) (*confpb.Service, error) {
+	descriptorFile, err := anypb.New(&smpb.ConfigFile{
+		FilePath:     "backend_reflection_descriptor.pb",
+		FileContents: descriptorBin,
+		FileType:     smpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to marshal proto descriptor from the backend: %v", err)
+	}
+
+	newServiceConfig := proto.Clone(serviceConfig).(*confpb.Service)
+	if newServiceConfig.SourceInfo == nil {
+		newServiceConfig.SourceInfo = &confpb.SourceInfo{}
+	}
+
+	var sourceFiles []*anypb.Any
+	for _, sourceFile := range newServiceConfig.SourceInfo.GetSourceFiles() {
+		configFile := &smpb.ConfigFile{}
+		if err := sourceFile.UnmarshalTo(configFile); err == nil && configFile.GetFileType() == smpb.ConfigFile_FILE_DESCRIPTOR_SET_PROTO {
+			continue
+		}
+		sourceFiles = append(sourceFiles, sourceFile)
+	}
+	newServiceConfig.SourceInfo.SourceFiles = append(sourceFiles, descriptorFile)
+	return newServiceConfig, nil
+}
+
+func descriptorDigest(descriptorBin []byte) string {
+	h := sha256.Sum256(descriptorBin)
+	return hex.EncodeToString(h[:])[:16]
 }
 
 func (m *ConfigManager) makeSnapshot() (*cache.Snapshot, error) {
@@ -292,7 +551,7 @@ func (m *ConfigManager) makeSnapshot() (*cache.Snapshot, error) {
 		listenerResources = append(listenerResources, lis)
 	}
 
-	snapshot, err := cache.NewSnapshot(m.curConfigId(), map[rsrc.Type][]types.Resource{
+	snapshot, err := cache.NewSnapshot(m.snapshotVersion(), map[rsrc.Type][]types.Resource{
 		rsrc.ListenerType: listenerResources,
 		rsrc.ClusterType:  clusterResources,
 	})
@@ -310,6 +569,20 @@ func (m *ConfigManager) curConfigId() string {
 	return m.curServiceConfig.Id
 }
 
+// snapshotVersion is the service config ID, suffixed with the local files
+// digest when local files are used so that Envoy picks up key rotation, and
+// with the backend descriptor digest so that Envoy picks up proto changes.
+func (m *ConfigManager) snapshotVersion() string {
+	version := m.curConfigId()
+	if m.localFilesDigest != "" {
+		version = fmt.Sprintf("%s-%s", version, m.localFilesDigest)
+	}
+	if m.backendDescriptorDigest != "" {
+		version = fmt.Sprintf("%s-%s", version, m.backendDescriptorDigest)
+	}
+	return version
+}
+
 func (m *ConfigManager) ID(node *corepb.Node) string {
 	return node.GetId()
 }
@@ -17,8 +17,11 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
@@ -41,6 +44,10 @@ import (
 	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
 	servicecontrolpb "google.golang.org/genproto/googleapis/api/servicecontrol/v1"
 	smpb "google.golang.org/genproto/googleapis/api/servicemanagement/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
@@ -594,6 +601,349 @@ func TestServiceConfigAutoUpdate(t *testing.T) {
 	})
 }
 
+func TestLocalJwksAutoUpdate(t *testing.T) {
+	var fakeConfig, fakeScReport, fakeRollouts safeData
+
+	testProjectName := "bookstore.endpoints.project123.cloud.goog"
+	testConfigID := "2017-05-01r0"
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksPath, []byte(`{"keys":[]}`), 0644); err != nil {
+		t.Fatalf("fail to write local JWKS file: %v", err)
+	}
+
+	fakeServiceConfig := fmt.Sprintf(`{
+                "name": "%s",
+                "apis":[
+                    {
+                        "name":"endpoints.examples.bookstore.Bookstore",
+                        "methods":[
+                            {
+                                "name": "ListShelves"
+                            }
+                        ]
+                    }
+                ],
+                "authentication": {
+                    "providers": [
+                        {
+                            "id": "local_provider",
+                            "issuer": "local-issuer",
+                            "jwks_uri": "file://%s"
+                        }
+                    ]
+                },
+                "id": "%s"
+            }`, testProjectName, jwksPath, testConfigID)
+	if err := genProtoBinary(fakeServiceConfig, new(confpb.Service), &fakeConfig); err != nil {
+		t.Fatalf("generate fake service config failed: %v", err)
+	}
+
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.BackendAddress = "grpc://127.0.0.1:80"
+	opts.CommonOptions.TracingOptions.DisableTracing = true
+
+	setFlags(testProjectName, testConfigID, util.FixedRolloutStrategy, "100ms", "")
+	_ = flag.Set("check_local_files_interval", "100ms")
+
+	runTest(t, &fakeScReport, &fakeRollouts, &fakeConfig, opts, func(configManager *ConfigManager, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, resp, gotListeners, err := getListeners(configManager, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldVersion, err := resp.GetVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(oldVersion, testConfigID+"-") {
+			t.Errorf("snapshot cache fetch got version: %v, want prefix: %v-", oldVersion, testConfigID)
+		}
+		if !strings.Contains(gotListeners, `{\"keys\":[]}`) {
+			t.Errorf("snapshot cache fetch got listeners without local JWKS: %v", gotListeners)
+		}
+
+		if err := os.WriteFile(jwksPath, []byte(`{"keys":[{"kid":"rotated"}]}`), 0644); err != nil {
+			t.Fatalf("fail to write local JWKS file: %v", err)
+		}
+		time.Sleep(*checkLocalFilesInterval + time.Second)
+
+		_, resp, gotListeners, err = getListeners(configManager, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newVersion, err := resp.GetVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newVersion == oldVersion || !strings.HasPrefix(newVersion, testConfigID+"-") {
+			t.Errorf("snapshot cache fetch got version: %v after local JWKS change, want a new version with prefix: %v-", newVersion, testConfigID)
+		}
+		if !strings.Contains(gotListeners, "rotated") {
+			t.Errorf("snapshot cache fetch got listeners without the rotated local JWKS: %v", gotListeners)
+		}
+	})
+}
+
+func TestApiKeyStoreAutoUpdate(t *testing.T) {
+	var fakeConfig, fakeScReport, fakeRollouts safeData
+
+	testProjectName := "bookstore.endpoints.project123.cloud.goog"
+	testConfigID := "2017-05-01r0"
+	oldKeySha256 := strings.Repeat("a", 64)
+	newKeySha256 := strings.Repeat("b", 64)
+	apiKeyStorePath := filepath.Join(t.TempDir(), "api_keys.json")
+	writeApiKeyStore := func(keySha256 string) {
+		content := fmt.Sprintf(`{"api_keys": [{"key_sha256": %q, "consumer": "mobile-app"}]}`, keySha256)
+		if err := os.WriteFile(apiKeyStorePath, []byte(content), 0644); err != nil {
+			t.Fatalf("fail to write API key store file: %v", err)
+		}
+	}
+	writeApiKeyStore(oldKeySha256)
+
+	fakeServiceConfig := fmt.Sprintf(`{
+                "name": "%s",
+                "apis":[
+                    {
+                        "name":"endpoints.examples.bookstore.Bookstore",
+                        "methods":[
+                            {
+                                "name": "ListShelves"
+                            }
+                        ]
+                    }
+                ],
+                "id": "%s"
+            }`, testProjectName, testConfigID)
+	if err := genProtoBinary(fakeServiceConfig, new(confpb.Service), &fakeConfig); err != nil {
+		t.Fatalf("generate fake service config failed: %v", err)
+	}
+
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.BackendAddress = "grpc://127.0.0.1:80"
+	opts.CommonOptions.TracingOptions.DisableTracing = true
+	opts.ApiKeyStorePath = apiKeyStorePath
+
+	setFlags(testProjectName, testConfigID, util.FixedRolloutStrategy, "100ms", "")
+	_ = flag.Set("check_local_files_interval", "100ms")
+
+	runTest(t, &fakeScReport, &fakeRollouts, &fakeConfig, opts, func(configManager *ConfigManager, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, resp, gotListeners, err := getListeners(configManager, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldVersion, err := resp.GetVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(gotListeners, oldKeySha256) {
+			t.Errorf("snapshot cache fetch got listeners without the API key: %v", gotListeners)
+		}
+
+		writeApiKeyStore(newKeySha256)
+		time.Sleep(*checkLocalFilesInterval + time.Second)
+
+		_, resp, gotListeners, err = getListeners(configManager, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newVersion, err := resp.GetVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newVersion == oldVersion || !strings.HasPrefix(newVersion, testConfigID+"-") {
+			t.Errorf("snapshot cache fetch got version: %v after API key store change, want a new version with prefix: %v-", newVersion, testConfigID)
+		}
+		if !strings.Contains(gotListeners, newKeySha256) {
+			t.Errorf("snapshot cache fetch got listeners without the updated API key: %v", gotListeners)
+		}
+	})
+}
+
+func TestReapplyStaleServiceConfigIsSkipped(t *testing.T) {
+	var fakeConfig, fakeScReport, fakeRollouts safeData
+
+	testProjectName := "bookstore.endpoints.project123.cloud.goog"
+	testConfigID := "2017-05-01r0"
+	newConfigID := "2017-05-01r1"
+
+	fakeServiceConfig := fmt.Sprintf(`{
+                "name": "%s",
+                "apis":[
+                    {
+                        "name":"endpoints.examples.bookstore.Bookstore",
+                        "methods":[
+                            {
+                                "name": "ListShelves"
+                            }
+                        ]
+                    }
+                ],
+                "id": "%s"
+            }`, testProjectName, testConfigID)
+	if err := genProtoBinary(fakeServiceConfig, new(confpb.Service), &fakeConfig); err != nil {
+		t.Fatalf("generate fake service config failed: %v", err)
+	}
+
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.BackendAddress = "grpc://127.0.0.1:80"
+	opts.CommonOptions.TracingOptions.DisableTracing = true
+
+	setFlags(testProjectName, testConfigID, util.FixedRolloutStrategy, "100ms", "")
+
+	runTest(t, &fakeScReport, &fakeRollouts, &fakeConfig, opts, func(configManager *ConfigManager, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A watcher reads the current service config, then a rollout lands
+		// before the watcher re-applies it.
+		staleServiceConfig := configManager.curServiceConfig
+		newServiceConfig := proto.Clone(staleServiceConfig).(*confpb.Service)
+		newServiceConfig.Id = newConfigID
+		if err := configManager.applyServiceConfig(newServiceConfig); err != nil {
+			t.Fatalf("applyServiceConfig() got error: %v", err)
+		}
+
+		if err := configManager.reapplyServiceConfig(staleServiceConfig, nil); err != nil {
+			t.Fatalf("reapplyServiceConfig() got error: %v", err)
+		}
+		if got := configManager.curConfigId(); got != newConfigID {
+			t.Errorf("reapplyServiceConfig() of a stale service config changed the config ID to %v, want %v", got, newConfigID)
+		}
+
+		_, resp, _, err := getListeners(configManager, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, err := resp.GetVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != newConfigID {
+			t.Errorf("snapshot cache fetch got version: %v, want: %v", version, newConfigID)
+		}
+	})
+}
+
+func TestBackendDescriptorAutoUpdate(t *testing.T) {
+	var fakeConfig, fakeScReport, fakeRollouts safeData
+
+	testProjectName := "bookstore.endpoints.project123.cloud.goog"
+	testConfigID := "2017-05-01r0"
+
+	// Reserve a port for the backend, which is not up when the config manager
+	// starts.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("fail to listen: %v", err)
+	}
+	backendAddress := lis.Addr().String()
+	lis.Close()
+
+	fakeServiceConfig := fmt.Sprintf(`{
+                "name": "%s",
+                "apis":[
+                    {
+                        "name":"grpc.health.v1.Health",
+                        "methods":[
+                            {
+                                "name": "Check"
+                            }
+                        ]
+                    }
+                ],
+                "id": "%s"
+            }`, testProjectName, testConfigID)
+	if err := genProtoBinary(fakeServiceConfig, new(confpb.Service), &fakeConfig); err != nil {
+		t.Fatalf("generate fake service config failed: %v", err)
+	}
+
+	opts := options.DefaultConfigGeneratorOptions()
+	opts.BackendAddress = "grpc://" + backendAddress
+	opts.CommonOptions.TracingOptions.DisableTracing = true
+	opts.HttpRequestTimeout = time.Second
+	opts.TranscodingGrpcReflection = true
+	opts.TranscodingGrpcReflectionInterval = 100 * time.Millisecond
+
+	setFlags(testProjectName, testConfigID, util.FixedRolloutStrategy, "100ms", "")
+
+	runTest(t, &fakeScReport, &fakeRollouts, &fakeConfig, opts, func(configManager *ConfigManager, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, resp, gotListeners, err := getListeners(configManager, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldVersion, err := resp.GetVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oldVersion != testConfigID {
+			t.Errorf("snapshot cache fetch got version: %v, want: %v", oldVersion, testConfigID)
+		}
+		if strings.Contains(gotListeners, "envoy.filters.http.grpc_json_transcoder") {
+			t.Errorf("snapshot cache fetch got listeners with transcoder before the backend is up: %v", gotListeners)
+		}
+
+		lis, err := net.Listen("tcp", backendAddress)
+		if err != nil {
+			t.Fatalf("fail to listen: %v", err)
+		}
+		s := grpc.NewServer()
+		healthpb.RegisterHealthServer(s, health.NewServer())
+		reflection.Register(s)
+		go func() {
+			_ = s.Serve(lis)
+		}()
+		defer s.Stop()
+		time.Sleep(opts.TranscodingGrpcReflectionInterval + time.Second)
+
+		_, resp, gotListeners, err = getListeners(configManager, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newVersion, err := resp.GetVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(newVersion, testConfigID+"-") {
+			t.Errorf("snapshot cache fetch got version: %v after the backend is up, want a new version with prefix: %v-", newVersion, testConfigID)
+		}
+		if !strings.Contains(gotListeners, "envoy.filters.http.grpc_json_transcoder") {
+			t.Errorf("snapshot cache fetch got listeners without transcoder after the backend is up: %v", gotListeners)
+		}
+
+		// A failed fetch keeps the last fetched descriptors.
+		s.Stop()
+		if err := configManager.applyServiceConfig(configManager.curServiceConfig); err != nil {
+			t.Fatal(err)
+		}
+		_, resp, gotListeners, err = getListeners(configManager, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotVersion, err := resp.GetVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotVersion != newVersion {
+			t.Errorf("snapshot cache fetch got version: %v after the backend is down, want: %v", gotVersion, newVersion)
+		}
+		if !strings.Contains(gotListeners, "envoy.filters.http.grpc_json_transcoder") {
+			t.Errorf("snapshot cache fetch got listeners without transcoder after the backend is down: %v", gotListeners)
+		}
+	})
+}
+
 func runTest(t *testing.T, fakeScReport, fakeRollouts, fakeConfig *safeData, opts options.ConfigGeneratorOptions, f func(configManager *ConfigManager, err error)) {
 	fakeToken := `{"access_token": "ya29.new", "expires_in":3599, "token_type":"Bearer"}`
 	mockServiceControl := initMockServer(t, fakeScReport)
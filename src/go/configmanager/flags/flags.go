@@ -33,12 +33,13 @@ var (
 	CorsAllowCredentials   = flag.Bool("cors_allow_credentials", defaults.CorsAllowCredentials, "whether include the Access-Control-Allow-Credentials header with the value true in responses or not")
 	CorsAllowHeaders       = flag.String("cors_allow_headers", defaults.CorsAllowHeaders, "set Access-Control-Allow-Headers to the specified HTTP headers")
 	CorsAllowMethods       = flag.String("cors_allow_methods", defaults.CorsAllowMethods, "set Access-Control-Allow-Methods to the specified HTTP methods")
-	CorsAllowOrigin        = flag.String("cors_allow_origin", defaults.CorsAllowOrigin, "set Access-Control-Allow-Origin to a specific origin")
+	CorsAllowOrigin        = flag.String("cors_allow_origin", defaults.CorsAllowOrigin, "set Access-Control-Allow-Origin to the specified origins, separated by comma")
 	CorsAllowOriginRegex   = flag.String("cors_allow_origin_regex", defaults.CorsAllowOriginRegex, "set Access-Control-Allow-Origin to a regular expression")
 	CorsExposeHeaders      = flag.String("cors_expose_headers", defaults.CorsExposeHeaders, "set Access-Control-Expose-Headers to the specified headers")
 	CorsMaxAge             = flag.Duration("cors_max_age", defaults.CorsMaxAge, "set Access-Control-Max-Age response header for CORS preflight request.")
 	CorsPreset             = flag.String("cors_preset", defaults.CorsPreset, `enable CORS support, must be either "basic" or "cors_with_regex"`)
 	CorsOperationDelimiter = flag.String("cors_operation_delimiter", defaults.CorsOperationDelimiter, "Delimiter for cors operations")
+	CorsPoliciesPath       = flag.String("cors_policies_path", defaults.CorsPoliciesPath, `Path to a JSON file with CORS policies that override the global cors flags for APIs or operations, e.g. {"policies": [{"selectors": ["bookstore.Bookstore"], "allow_origins": ["https://a.example.com", "https://b.example.com"]}, {"selectors": ["bookstore.Bookstore.Internal"], "disabled": true}]}. Only works when cors_preset is set.`)
 
 	// Backend routing configurations.
 	BackendDnsLookupFamily = flag.String("backend_dns_lookup_family", defaults.BackendDnsLookupFamily, `Define the dns lookup family for all backends. The options are "auto", "v4only", "v6only", "v4preferred" and "all". The default is "v4preferred". "auto" is a legacy name, it behaves as "v6preferred".`)
@@ -87,6 +88,8 @@ var (
          For example --add_response_headers=key1=value1;key2=value2. If a header is already in the response, its value will be replaced with the new one.`)
 	AppendResponseHeaders = flag.String("append_response_headers", defaults.AppendResponseHeaders, `Append HTTP headers to the response before sent to the upstream backend. Multiple headers are separated by ';'.
          For example --append_response_headers=key1=value1;key2=value2. If a header is already in the response, the new value will be append.`)
+	HeaderRulesPath = flag.String("header_rules_path", defaults.HeaderRulesPath, `Path to a JSON file with request and response header rules for APIs or operations, e.g. {"rules": [{"selectors": ["bookstore.Bookstore"], "request_headers_to_add": {"x-client-ip": "%DOWNSTREAM_REMOTE_ADDRESS%"}, "response_headers_to_remove": ["server"]}]}.
+         Values can use Envoy substitution variables, a literal '%' must be escaped as '%%'. Rules for an API are applied before rules for its operations.`)
 	EnableOperationNameHeader      = flag.Bool("enable_operation_name_header", defaults.EnableOperationNameHeader, "If enabled, the operation name for the matched route will be sent to the upstream as a request header.")
 	AllowHostRewriteForHTTPBackend = flag.Bool("allow_host_rewrite_for_http_backend", defaults.AllowHostRewriteForHTTPBackend, "If enabled, the host/:authority header of the upstream request will be rewritten to the hostname of backend http cluster.")
 
@@ -107,12 +110,30 @@ var (
 						Value must match the enum espv2.api.envoy.v12.http.common.DependencyErrorBehavior.`)
 
 	// Envoy configurations.
-	AccessLog       = flag.String("access_log", defaults.AccessLog, "Path to a local file to which the access log entries will be written")
+	AccessLog = flag.String("access_log", defaults.AccessLog, `Path to a local file to which the access log entries will be written.
+	If set to "stdout", the access log entries are written to the standard output.`)
 	AccessLogFormat = flag.String("access_log_format", defaults.AccessLogFormat, `String format to specify the format of access log.
 	If unset, the following format will be used.
 	https://www.envoyproxy.io/docs/envoy/latest/configuration/observability/access_log#default-format-string
 	For the detailed format grammar, please refer to the following document.
 	https://www.envoyproxy.io/docs/envoy/latest/configuration/observability/access_log#format-strings`)
+	AccessLogJSON = flag.Bool("access_log_json", defaults.AccessLogJSON, `If true, access log entries are written as JSON objects with a default
+	ESPv2 field set: request, response, operation name, API consumer, JWT subject, backend cluster,
+	upstream latency and attempt count, gRPC status and trace ID. The API consumer is only set when the
+	local API key store (--api_key_store_path) is enabled. Requires --access_log, cannot be used with
+	--access_log_format.`)
+	AccessLogJSONAddFields = flag.String("access_log_json_add_fields", defaults.AccessLogJSONAddFields, `Comma-separated list of name=format
+	to add or override fields of the JSON and OpenTelemetry access logs, e.g. "region=%REQ(X-REGION)%".`)
+	AccessLogJSONRemoveFields = flag.String("access_log_json_remove_fields", defaults.AccessLogJSONRemoveFields,
+		"Comma-separated list of default fields to remove from the JSON and OpenTelemetry access logs, e.g. \"api_consumer,user_agent\".")
+	AccessLogGrpcAddress = flag.String("access_log_grpc_address", defaults.AccessLogGrpcAddress, `Address of an Envoy gRPC Access Log Service to stream the access logs to,
+	e.g. "grpc://als:9000". Use the "grpcs" or "https" scheme for TLS.`)
+	AccessLogOpenTelemetryAddress = flag.String("access_log_otel_address", defaults.AccessLogOpenTelemetryAddress, `Address of an OpenTelemetry logs collector (OTLP gRPC) to stream the access logs to,
+	e.g. "grpc://otel-collector:4317". Use the "grpcs" or "https" scheme for TLS. The log attributes are the JSON access log fields.`)
+	AccessLogMinStatusCode = flag.Uint("access_log_min_status_code", defaults.AccessLogMinStatusCode, `If set, only requests with a response code greater than
+	or equal to it are streamed to the gRPC and OpenTelemetry access log sinks.`)
+	AccessLogSamplingRate = flag.Float64("access_log_sampling_rate", defaults.AccessLogSamplingRate, `The fraction of requests streamed to the gRPC and
+	OpenTelemetry access log sinks, in the range of [0.0, 1.0].`)
 
 	EnvoyUseRemoteAddress  = flag.Bool("envoy_use_remote_address", defaults.EnvoyUseRemoteAddress, "Envoy HttpConnectionManager configuration, please refer to envoy documentation for detailed information.")
 	EnvoyXffNumTrustedHops = flag.Int("envoy_xff_num_trusted_hops", defaults.EnvoyXffNumTrustedHops, "Envoy HttpConnectionManager configuration, please refer to envoy documentation for detailed information.")
@@ -153,6 +174,16 @@ Normally JWT based64 encode doesn’t add padding. If this flag is true, the hea
 	JwtCacheSize = flag.Uint("jwt_cache_size", defaults.JwtCacheSize, `Specify JWT cache size, the number of unique JWT tokens in the cache. The cache only stores verified good tokens. If 0, JWT cache is disabled. It limits the memory usage. The cache used memory is roughly (token size + 64 bytes) per token. If not specified, the default is 1000.`)
 
 	DisableJwtAudienceServiceNameCheck = flag.Bool("disable_jwt_audience_service_name_check", defaults.DisableJwtAudienceServiceNameCheck, `Normally JWT "aud" field is checked against audiences specified in OpenAPI "x-google-audiences" field. This flag changes the behaviour when the "x-google-audiences" is not specified. When the "x-google-audiences" is not specified, normally the service name is used to check the JWT "aud" field.  If this flag is true, the service name is not used, JWT "aud" field will not be checked.`)
+	JwksLocalFiles                     = flag.String("jwks_local_files", defaults.JwksLocalFiles, `Read the JWKS of authentication providers from local files instead of fetching them from their jwks_uri, for offline or air-gapped deployments. A semicolon separated list of "provider_id=path" pairs, e.g. "auth0=/etc/espv2/auth0_jwks.json;firebase=/etc/espv2/firebase_jwks.json". Providers whose jwks_uri uses the "file://" scheme are always read from the local file. Local files are watched by the config manager and re-applied when their content changes. Envoy does not watch them itself, so with a static bootstrap config keys are only reloaded when the bootstrap is regenerated and Envoy restarts.`)
+	JwtAuthnAuditOnly                  = flag.Bool("jwt_authn_audit_only", defaults.JwtAuthnAuditOnly, `Run JWT authentication in audit only mode for all operations. Tokens are still verified and the outcome is recorded in the "jwt_failed_status" field of the JSON access log and the "jwt_audit_failed" stat of the Service Control filter, but requests with missing or invalid tokens are allowed.`)
+	JwtAuthnAuditOnlySelectors         = flag.String("jwt_authn_audit_only_selectors", defaults.JwtAuthnAuditOnlySelectors, `A comma separated list of operation selectors to run JWT authentication in audit only mode for. See --jwt_authn_audit_only.`)
+	JwtProviderOptionsPath             = flag.String("jwt_provider_options_path", defaults.JwtProviderOptionsPath, `Path to a JSON file with extended validation options for authentication providers, keyed by provider ID, e.g. {"providers": {"auth0": {"clock_skew_seconds": 30, "max_lifetime_seconds": 3600, "issuer_regex": "https://.*\\.auth0\\.com/", "required_claims": ["email"], "claim_constraints": {"hd": {"exact": "example.com"}}}}}. Tokens that fail the claim checks are rejected with 403.`)
+	TokenIntrospectionProvidersPath    = flag.String("token_introspection_providers_path", defaults.TokenIntrospectionProvidersPath, `Path to a JSON file that marks authentication providers as issuers of opaque access tokens, keyed by provider ID, e.g. {"providers": {"idp": {"introspection_endpoint": "https://idp.example.com/oauth2/introspect", "client_id": "espv2", "client_secret_path": "/etc/espv2/introspection_secret", "cache_duration_seconds": 60, "cache_size": 1000}}}. Tokens for these providers are validated by calling the OAuth 2.0 token introspection endpoint (RFC 7662) instead of being verified as JWTs. The endpoint must use https unless it is a loopback address.`)
+
+	ApiKeyStorePath = flag.String("api_key_store_path", defaults.ApiKeyStorePath, `Path to a JSON file with the API keys that are valid for this service, e.g. {"api_keys": [{"key_sha256": "<lowercase hex SHA-256 of the key>", "consumer": "mobile-app", "allowed_selectors": ["endpoints.examples.bookstore.Bookstore.ListShelves"], "allowed_referrers": ["*.example.com/*"]}]}. API keys are validated locally without Service Control. The consumer label is sent to the backend in the X-Endpoint-API-Consumer header and is available to access logs as %DYNAMIC_METADATA(com.google.espv2.filters.http.api_key:consumer)%. The file is watched for changes.`)
+
+	ServiceControlLocalSink     = flag.String("service_control_local_sink", defaults.ServiceControlLocalSink, `Run a local stand-in for Service Control in the config manager and send the Check, AllocateQuota and Report calls to it instead of the Service Control server. All calls are allowed. The operations of each Report are written as JSON lines to this file path, or to standard output if set to "stdout", with API keys replaced by their SHA-256 fingerprint. Useful for non-GCP or development deployments where Service Control is not reachable.`)
+	ServiceControlLocalSinkPort = flag.Uint("service_control_local_sink_port", defaults.ServiceControlLocalSinkPort, "Port that the config manager uses to serve the local Service Control sink. See --service_control_local_sink.")
 
 	ScCheckTimeoutMs  = flag.Int("service_control_check_timeout_ms", defaults.ScCheckTimeoutMs, `Set the timeout in millisecond for service control Check request. Must be > 0 and the default is 1000 if not set.`)
 	ScQuotaTimeoutMs  = flag.Int("service_control_quota_timeout_ms", defaults.ScQuotaTimeoutMs, `Set the timeout in millisecond for service control Quota request. Must be > 0 and the default is 1000 if not set.`)
@@ -173,10 +204,12 @@ Normally JWT based64 encode doesn’t add padding. If this flag is true, the hea
 	TranscodingAlwaysPrintPrimitiveFields         = flag.Bool("transcoding_always_print_primitive_fields", defaults.TranscodingAlwaysPrintPrimitiveFields, "Whether to always print primitive fields for grpc-json transcoding")
 	TranscodingAlwaysPrintEnumsAsInts             = flag.Bool("transcoding_always_print_enums_as_ints", defaults.TranscodingAlwaysPrintPrimitiveFields, "Whether to always print enums as ints for grpc-json transcoding")
 	TranscodingStreamNewLineDelimited             = flag.Bool("transcoding_stream_newline_delimited", defaults.TranscodingStreamNewLineDelimited, "If true, use new line delimiter to separate response streaming messages. If false, all response streaming messages will be transcoded into a JSON array.")
+	TranscodingStreamSSE                          = flag.Bool("transcoding_stream_sse", defaults.TranscodingStreamSSE, `If true, the responses of server-streaming methods are server-sent events with the content type "text/event-stream", one "data:" event per message, so browsers can use EventSource. It can be overridden per API or operation with "stream_sse" in --transcoding_options_path.`)
 	TranscodingPreserveProtoFieldNames            = flag.Bool("transcoding_preserve_proto_field_names", defaults.TranscodingPreserveProtoFieldNames, "Whether to preserve proto field names for grpc-json transcoding")
 	TranscodingIgnoreQueryParameters              = flag.String("transcoding_ignore_query_parameters", defaults.TranscodingIgnoreQueryParameters, "A list of query parameters(separated by comma) to be ignored for transcoding method mapping in grpc-json transcoding.")
 	TranscodingIgnoreUnknownQueryParameters       = flag.Bool("transcoding_ignore_unknown_query_parameters", defaults.TranscodingIgnoreUnknownQueryParameters, "Whether to ignore query parameters that cannot be mapped to a corresponding protobuf field in grpc-json transcoding.")
 	TranscodingCaseInsensitiveEnumParsing         = flag.Bool("transcoding_case_insensitive_enum_parsing", defaults.TranscodingCaseInsensitiveEnumParsing, "Proto enum values are supposed to be in upper cases when used in JSON. Set this flag to true if your JSON request uses non uppercase enum values.")
+	TranscodingRichErrorDetails                   = flag.Bool("transcoding_rich_error_details", defaults.TranscodingRichErrorDetails, `If true, the google.rpc error detail types, such as BadRequest and RetryInfo, are added to the proto descriptor, so the details of the "grpc-status-details-bin" trailer of failed calls are converted into the "details" of the JSON response body. The HTTP status code follows the google.rpc mapping of the gRPC status code. The body is the bare google.rpc.Status, e.g. {"code": 3, "message": "...", "details": [...]} with the gRPC status code, so it cannot be used together with the "google_rpc_status" local reply preset.`)
 	TranscodingQueryParametersDisableUnescapePlus = flag.Bool("transcoding_query_parameters_disable_unescape_plus", defaults.TranscodingIgnoreUnknownQueryParameters, `By default, unescape "+" to space when extracting variables in
            the query parameters in grpc-json transcoding. This is to support HTML 2.0<https://tools.ietf.org/html/rfc1866#section-8.2.1>. Set this flag to true to disable this feature.`)
 	TranscodingMatchUnregisteredCustomVerb = flag.Bool("transcoding_match_unregistered_custom_verb", defaults.TranscodingMatchUnregisteredCustomVerb, `If true, try to match the custom verb even if it is unregistered. By default, only match when it is registered.
@@ -187,6 +220,14 @@ Normally JWT based64 encode doesn’t add padding. If this flag is true, the hea
 		- if the field is set, *:baz* is treated as custom verb,  so it will NOT match **/foo/{x=*}** since the template doesn't use any custom verb.
 
 	[1](https://github.com/googleapis/googleapis/blob/master/google/api/http.proto#L226-L231)`)
+	TranscodingOptionsPath = flag.String("transcoding_options_path", defaults.TranscodingOptionsPath, `Path to a JSON file that overrides the --transcoding_* flags for some APIs or operations,
+	e.g. {"options": [{"selectors": ["bookstore.Bookstore"], "preserve_proto_field_names": true}, {"selectors": ["bookstore.Bookstore.ListShelves"], "always_print_primitive_fields": true}]}.
+	The options of an operation take precedence over the options of its API.`)
+	TranscodingDescriptorPath = flag.String("transcoding_descriptor_path", defaults.TranscodingDescriptorPath, `Path to a binary FileDescriptorSet used for grpc-json transcoding, instead of the descriptor in the service config.`)
+	TranscodingGrpcReflection = flag.Bool("transcoding_grpc_reflection", defaults.TranscodingGrpcReflection, `If true, fetch the proto descriptors of the APIs from the gRPC backend specified by the flag "--backend_address" via gRPC server reflection,
+	instead of the descriptor in the service config. The descriptors are fetched at startup and every "--transcoding_grpc_reflection_interval", and Envoy is updated when they change.`)
+	TranscodingGrpcReflectionInterval = flag.Duration("transcoding_grpc_reflection_interval", defaults.TranscodingGrpcReflectionInterval, `Specify the interval to fetch the proto descriptors from the backend via gRPC server reflection. Default is 60 seconds.
+	It only applies when the flag "--transcoding_grpc_reflection" is used.`)
 
 	BackendRetryOns = flag.String("backend_retry_ons", defaults.BackendRetryOns,
 		`The conditions under which ESPv2 does retry on the backends. One or more
@@ -213,7 +254,36 @@ Normally JWT based64 encode doesn’t add padding. If this flag is true, the hea
         policies set in "--backend_retry_ons".
         The format is a comma-delimited String, like "501, 503`)
 
-	EnableResponseCompression = flag.Bool("enable_response_compression", defaults.EnableResponseCompression, `Enable gzip,br compression for response data. The default is disabled.`)
+	EnableResponseCompression     = flag.Bool("enable_response_compression", defaults.EnableResponseCompression, `Enable gzip,br compression for response data. The default is disabled.`)
+	ResponseCompressionAlgorithms = flag.String("response_compression_algorithms", defaults.ResponseCompressionAlgorithms, `Comma-separated list of the response compression algorithms, from (gzip|br|zstd).`)
+	ResponseCompressionLevels     = flag.String("response_compression_levels", defaults.ResponseCompressionLevels, `Comma-separated list of algorithm=level to set the compression levels, e.g. "gzip=6,br=4,zstd=3".
+	The level range is [1, 9] for gzip, [0, 11] for br and [1, 22] for zstd. If unset, the Envoy default level is used.`)
+	ResponseCompressionMinContentLength = flag.Uint("response_compression_min_content_length", defaults.ResponseCompressionMinContentLength, `The minimum response size in bytes to compress. If unset, the Envoy default of 30 is used.`)
+	ResponseCompressionContentTypes     = flag.String("response_compression_content_types", defaults.ResponseCompressionContentTypes, `Comma-separated list of the response content types to compress.
+	If unset, the Envoy default content types are compressed.`)
+	ResponseCompressionOperations = flag.String("response_compression_operations", defaults.ResponseCompressionOperations, `Comma-separated list of the operation selectors to compress the responses of.
+	If unset, the responses of all operations are compressed.`)
+	EnableRequestDecompression = flag.Bool("enable_request_decompression", defaults.EnableRequestDecompression, `Enable gzip decompression for request data, so backends receive uncompressed requests. The default is disabled.`)
+
+	MaxRequestBodyBytes           = flag.Uint64("max_request_body_bytes", defaults.MaxRequestBodyBytes, `The maximum request body size in bytes. Larger requests are rejected with 413. The default 0 means no limit.`)
+	MaxRequestBodyBytesOperations = flag.String("max_request_body_bytes_operations", defaults.MaxRequestBodyBytesOperations, `Comma-separated list of "selector=bytes" pairs that override --max_request_body_bytes for individual operations,
+	e.g. "endpoints.examples.bookstore.Bookstore.CreateBook=1048576,endpoints.examples.bookstore.Bookstore.UploadCover=0". A limit of 0 means no limit for the operation.`)
+
+	EnableFaultInjection       = flag.Bool("enable_fault_injection", defaults.EnableFaultInjection, `Enable the fault injection policies in --fault_injection_policies_path. For resilience testing only, do not use it in production. The default is disabled.`)
+	FaultInjectionPoliciesPath = flag.String("fault_injection_policies_path", defaults.FaultInjectionPoliciesPath, `Path to a JSON file with fault injection policies for APIs or operations, e.g. {"policies": [{"selectors": ["bookstore.Bookstore.GetShelf"], "header": "x-chaos-test", "delay": {"duration": "2s", "percentage": 50}, "abort": {"grpc_status": 14, "percentage": 10}}]}.
+	Only works when enable_fault_injection is set.`)
+
+	HTTPCacheOperations = flag.String("http_cache_operations", defaults.HTTPCacheOperations, `Comma-separated list of the selectors of GET operations to cache the responses of in memory. The backend controls caching with the Cache-Control and Vary response headers.
+	Cached responses are only served to the same consumer, identified by the JWT headers, the API key headers from the service config system parameters and, with --api_key_store_path, the API consumer header, unless the operation is in --http_cache_public_operations. Operations that require JWT authentication cannot be cached.`)
+	HTTPCachePublicOperations = flag.String("http_cache_public_operations", defaults.HTTPCachePublicOperations, `Comma-separated list of the selectors in --http_cache_operations whose cached responses are shared across consumers.`)
+	HTTPCacheMaxBodyBytes     = flag.Uint("http_cache_max_body_bytes", defaults.HTTPCacheMaxBodyBytes, `The maximum response size in bytes to cache. If unset, the Envoy default is used.`)
+
+	LocalReplyConfigPath = flag.String("local_reply_config_path", defaults.LocalReplyConfigPath, `Path to a JSON file that customizes the bodies of the error replies generated by ESPv2 and Envoy, with body formats per status code range and per Accept content type, and mappers that rewrite specific failures,
+	e.g. {"body_format": {"preset": "google_rpc_status"}, "mappers": [{"status_codes": {"min": 503, "max": 503}, "response_flags": ["UH"], "body": "Service unavailable, please retry later.", "headers_to_add": {"retry-after": "5"}}]}.
+	If unset, the replies have the JSON body {"code": <status code>, "message": <error message>}.`)
+
+	EnableConnectGrpcBridge          = flag.Bool("enable_connect_grpc_bridge", defaults.EnableConnectGrpcBridge, `Enable the Connect protocol for gRPC backends, so Connect clients, e.g. Connect-Web, can call the gRPC methods at their "/package.Service/Method" paths. Unary Connect requests with the JSON encoding are transcoded, so their errors have the google.rpc.Status body of the transcoder instead of the Connect error body. Streaming Connect requests with the JSON encoding are rejected with 415. The Connect request headers are also allowed by CORS. The default is disabled.`)
+	EnableGrpcJsonReverseTranscoding = flag.Bool("enable_grpc_json_reverse_transcoding", defaults.EnableGrpcJsonReverseTranscoding, `Enable gRPC clients to call the unary methods served by HTTP/JSON backends at their "/package.Service/Method" paths. The gRPC requests are converted into HTTP/JSON requests following the http rules of the methods, using the proto descriptor of the service config. Methods whose http rule path has variables cannot use the CONSTANT_ADDRESS path translation. The default is disabled.`)
 
 	ClientIPFromForwardedHeader = flag.Bool("client_ip_from_forwarded_header", defaults.ClientIPFromForwardedHeader, `If true, extract client ip from "forwarded" header. The default false.`)
 
@@ -230,6 +300,13 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		EnableBackendAddressOverride:                  *EnableBackendAddressOverride,
 		AccessLog:                                     *AccessLog,
 		AccessLogFormat:                               *AccessLogFormat,
+		AccessLogJSON:                                 *AccessLogJSON,
+		AccessLogJSONAddFields:                        *AccessLogJSONAddFields,
+		AccessLogJSONRemoveFields:                     *AccessLogJSONRemoveFields,
+		AccessLogGrpcAddress:                          *AccessLogGrpcAddress,
+		AccessLogOpenTelemetryAddress:                 *AccessLogOpenTelemetryAddress,
+		AccessLogMinStatusCode:                        *AccessLogMinStatusCode,
+		AccessLogSamplingRate:                         *AccessLogSamplingRate,
 		ComputePlatformOverride:                       *ComputePlatformOverride,
 		CorsAllowCredentials:                          *CorsAllowCredentials,
 		CorsAllowHeaders:                              *CorsAllowHeaders,
@@ -240,6 +317,7 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		CorsMaxAge:                                    *CorsMaxAge,
 		CorsPreset:                                    *CorsPreset,
 		CorsOperationDelimiter:                        *CorsOperationDelimiter,
+		CorsPoliciesPath:                              *CorsPoliciesPath,
 		BackendDnsLookupFamily:                        *BackendDnsLookupFamily,
 		ClusterConnectTimeout:                         *ClusterConnectTimeout,
 		StreamIdleTimeout:                             *StreamIdleTimeout,
@@ -269,6 +347,7 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		AppendRequestHeaders:                          *AppendRequestHeaders,
 		AddResponseHeaders:                            *AddResponseHeaders,
 		AppendResponseHeaders:                         *AppendResponseHeaders,
+		HeaderRulesPath:                               *HeaderRulesPath,
 		EnableOperationNameHeader:                     *EnableOperationNameHeader,
 		AllowHostRewriteForHTTPBackend:                *AllowHostRewriteForHTTPBackend,
 		ServiceAccountKey:                             *ServiceAccountKey,
@@ -302,6 +381,14 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		JwtPadForwardPayloadHeader:                    *JwtPatForwardPayloadHeader,
 		JwtCacheSize:                                  *JwtCacheSize,
 		DisableJwtAudienceServiceNameCheck:            *DisableJwtAudienceServiceNameCheck,
+		JwksLocalFiles:                                *JwksLocalFiles,
+		JwtAuthnAuditOnly:                             *JwtAuthnAuditOnly,
+		JwtAuthnAuditOnlySelectors:                    *JwtAuthnAuditOnlySelectors,
+		JwtProviderOptionsPath:                        *JwtProviderOptionsPath,
+		TokenIntrospectionProvidersPath:               *TokenIntrospectionProvidersPath,
+		ApiKeyStorePath:                               *ApiKeyStorePath,
+		ServiceControlLocalSink:                       *ServiceControlLocalSink,
+		ServiceControlLocalSinkPort:                   *ServiceControlLocalSinkPort,
 		BackendRetryOns:                               *BackendRetryOns,
 		BackendRetryNum:                               *BackendRetryNum,
 		BackendPerTryTimeout:                          *BackendPerTryTimeout,
@@ -316,13 +403,35 @@ func EnvoyConfigOptionsFromFlags() options.ConfigGeneratorOptions {
 		TranscodingAlwaysPrintPrimitiveFields:         *TranscodingAlwaysPrintPrimitiveFields,
 		TranscodingAlwaysPrintEnumsAsInts:             *TranscodingAlwaysPrintEnumsAsInts,
 		TranscodingStreamNewLineDelimited:             *TranscodingStreamNewLineDelimited,
+		TranscodingStreamSSE:                          *TranscodingStreamSSE,
 		TranscodingPreserveProtoFieldNames:            *TranscodingPreserveProtoFieldNames,
 		TranscodingIgnoreQueryParameters:              *TranscodingIgnoreQueryParameters,
 		TranscodingIgnoreUnknownQueryParameters:       *TranscodingIgnoreUnknownQueryParameters,
 		TranscodingQueryParametersDisableUnescapePlus: *TranscodingQueryParametersDisableUnescapePlus,
 		TranscodingMatchUnregisteredCustomVerb:        *TranscodingMatchUnregisteredCustomVerb,
 		TranscodingCaseInsensitiveEnumParsing:         *TranscodingCaseInsensitiveEnumParsing,
+		TranscodingRichErrorDetails:                   *TranscodingRichErrorDetails,
+		TranscodingOptionsPath:                        *TranscodingOptionsPath,
+		TranscodingDescriptorPath:                     *TranscodingDescriptorPath,
+		TranscodingGrpcReflection:                     *TranscodingGrpcReflection,
+		TranscodingGrpcReflectionInterval:             *TranscodingGrpcReflectionInterval,
 		EnableResponseCompression:                     *EnableResponseCompression,
+		ResponseCompressionAlgorithms:                 *ResponseCompressionAlgorithms,
+		ResponseCompressionLevels:                     *ResponseCompressionLevels,
+		ResponseCompressionMinContentLength:           *ResponseCompressionMinContentLength,
+		ResponseCompressionContentTypes:               *ResponseCompressionContentTypes,
+		ResponseCompressionOperations:                 *ResponseCompressionOperations,
+		EnableRequestDecompression:                    *EnableRequestDecompression,
+		MaxRequestBodyBytes:                           *MaxRequestBodyBytes,
+		MaxRequestBodyBytesOperations:                 *MaxRequestBodyBytesOperations,
+		EnableFaultInjection:                          *EnableFaultInjection,
+		FaultInjectionPoliciesPath:                    *FaultInjectionPoliciesPath,
+		HTTPCacheOperations:                           *HTTPCacheOperations,
+		HTTPCachePublicOperations:                     *HTTPCachePublicOperations,
+		HTTPCacheMaxBodyBytes:                         *HTTPCacheMaxBodyBytes,
+		LocalReplyConfigPath:                          *LocalReplyConfigPath,
+		EnableConnectGrpcBridge:                       *EnableConnectGrpcBridge,
+		EnableGrpcJsonReverseTranscoding:              *EnableGrpcJsonReverseTranscoding,
 		ClientIPFromForwardedHeader:                   *ClientIPFromForwardedHeader,
 
 		// These options are not for ESPv2 users. They are overridden internally.
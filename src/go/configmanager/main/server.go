@@ -27,7 +27,9 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configmanager"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/configmanager/flags"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/metadata"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/servicecontrolsink"
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/tokengenerator"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	"github.com/golang/glog"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -95,6 +97,22 @@ func main() {
 
 	}
 
+	if opts.ServiceControlLocalSink != "" {
+		// Setup local service control sink server
+		sink, err := servicecontrolsink.NewSink(opts.ServiceControlLocalSink)
+		if err != nil {
+			glog.Exitf("fail to initialize service control local sink: %v", err)
+		}
+		r := sink.MakeHandler()
+		go func() {
+			err := http.ListenAndServe(fmt.Sprintf("%s:%v", util.LoopbackIPv4Addr, opts.ServiceControlLocalSinkPort), r)
+
+			if err != nil {
+				glog.Errorf("service control local sink fail to serve: %v", err)
+			}
+		}()
+	}
+
 	if err := grpcServer.Serve(lis); err != nil {
 		glog.Exitf("Server fail to serve: %v", err)
 	}
@@ -41,14 +41,21 @@ type CommonOptions struct {
 
 	// Whether to disallow colon in the url wildcard path segment.
 	DisallowColonInWildcardPathSegment bool
+
+	// Whether to emit per-operation stats, tagged with the operation and gRPC
+	// method names.
+	EnableOperationStats bool
 }
 
 // TracingOptions are the shared options to create tracing config.
 type TracingOptions struct {
 	DisableTracing           bool
+	Provider                 string
+	CollectorAddress         string
 	ProjectId                string
 	StackdriverAddress       string
 	SamplingRate             float64
+	OperationSamplingRates   string
 	IncomingContext          string
 	OutgoingContext          string
 	MaxNumAttributes         int64
@@ -94,6 +101,7 @@ func DefaultCommonOptions() CommonOptions {
 		Node: "ESPv2",
 		TracingOptions: &TracingOptions{
 			DisableTracing:      false,
+			Provider:            "opencensus",
 			SamplingRate:        0.001,
 			MaxNumAttributes:    32,
 			MaxNumAnnotations:   32,
@@ -38,6 +38,7 @@ type ConfigGeneratorOptions struct {
 	CorsMaxAge             time.Duration
 	CorsPreset             string
 	CorsOperationDelimiter string
+	CorsPoliciesPath       string
 
 	// Backend routing configurations.
 	BackendDnsLookupFamily string
@@ -82,6 +83,7 @@ type ConfigGeneratorOptions struct {
 	AppendRequestHeaders           string
 	AddResponseHeaders             string
 	AppendResponseHeaders          string
+	HeaderRulesPath                string
 	EnableOperationNameHeader      bool
 	AllowHostRewriteForHTTPBackend bool
 
@@ -99,8 +101,17 @@ type ConfigGeneratorOptions struct {
 	SkipServiceControlFilter bool
 
 	// Envoy configurations.
-	AccessLog       string
-	AccessLogFormat string
+	AccessLog                 string
+	AccessLogFormat           string
+	AccessLogJSON             bool
+	AccessLogJSONAddFields    string
+	AccessLogJSONRemoveFields string
+
+	// Remote access log sinks.
+	AccessLogGrpcAddress          string
+	AccessLogOpenTelemetryAddress string
+	AccessLogMinStatusCode        uint
+	AccessLogSamplingRate         float64
 
 	EnvoyUseRemoteAddress  bool
 	EnvoyXffNumTrustedHops int
@@ -130,6 +141,18 @@ type ConfigGeneratorOptions struct {
 	JwtPadForwardPayloadHeader         bool
 	JwtCacheSize                       uint
 	DisableJwtAudienceServiceNameCheck bool
+	JwksLocalFiles                     string
+	JwtAuthnAuditOnly                  bool
+	JwtAuthnAuditOnlySelectors         string
+	JwtProviderOptionsPath             string
+	TokenIntrospectionProvidersPath    string
+
+	// Local API key validation.
+	ApiKeyStorePath string
+
+	// Local Service Control sink.
+	ServiceControlLocalSink     string
+	ServiceControlLocalSinkPort uint
 
 	ScCheckTimeoutMs  int
 	ScQuotaTimeoutMs  int
@@ -146,12 +169,35 @@ type ConfigGeneratorOptions struct {
 	BackendClusterMaxRequests int
 
 	ComputePlatformOverride     string
-	EnableResponseCompression   bool
 	ClientIPFromForwardedHeader bool
 
+	EnableResponseCompression           bool
+	ResponseCompressionAlgorithms       string
+	ResponseCompressionLevels           string
+	ResponseCompressionMinContentLength uint
+	ResponseCompressionContentTypes     string
+	ResponseCompressionOperations       string
+	EnableRequestDecompression          bool
+
+	MaxRequestBodyBytes           uint64
+	MaxRequestBodyBytesOperations string
+
+	EnableFaultInjection       bool
+	FaultInjectionPoliciesPath string
+
+	HTTPCacheOperations       string
+	HTTPCachePublicOperations string
+	HTTPCacheMaxBodyBytes     uint
+
+	LocalReplyConfigPath string
+
+	EnableConnectGrpcBridge          bool
+	EnableGrpcJsonReverseTranscoding bool
+
 	TranscodingAlwaysPrintPrimitiveFields         bool
 	TranscodingAlwaysPrintEnumsAsInts             bool
 	TranscodingStreamNewLineDelimited             bool
+	TranscodingStreamSSE                          bool
 	TranscodingPreserveProtoFieldNames            bool
 	TranscodingIgnoreQueryParameters              string
 	TranscodingIgnoreUnknownQueryParameters       bool
@@ -160,6 +206,11 @@ type ConfigGeneratorOptions struct {
 	TranscodingStrictRequestValidation            bool
 	TranscodingRejectCollision                    bool
 	TranscodingCaseInsensitiveEnumParsing         bool
+	TranscodingRichErrorDetails                   bool
+	TranscodingOptionsPath                        string
+	TranscodingDescriptorPath                     string
+	TranscodingGrpcReflection                     bool
+	TranscodingGrpcReflectionInterval             time.Duration
 	APIAllowList                                  []string
 	AllowDiscoveryAPIs                            bool
 }
@@ -187,6 +238,9 @@ func DefaultConfigGeneratorOptions() ConfigGeneratorOptions {
 		ListenerAddress:                         "0.0.0.0",
 		ListenerPort:                            8080,
 		TokenAgentPort:                          8791,
+		ServiceControlLocalSinkPort:             8792,
+		AccessLogSamplingRate:                   1.0,
+		ResponseCompressionAlgorithms:           "gzip,br",
 		DisableOidcDiscovery:                    false,
 		DependencyErrorBehavior:                 commonpb.DependencyErrorBehavior_BLOCK_INIT_ON_ANY_ERROR.String(),
 		SslSidestreamClientRootCertsPath:        util.DefaultRootCAPaths,
@@ -216,6 +270,7 @@ func DefaultConfigGeneratorOptions() ConfigGeneratorOptions {
 		HealthCheckAutogeneratedOperationPrefix: util.AutogeneratedOperationPrefix,
 		HealthCheckGrpcBackendInterval:          1 * time.Second,
 		HealthCheckGrpcBackendNoTrafficInterval: 60 * time.Second,
+		TranscodingGrpcReflectionInterval:       60 * time.Second,
 		APIAllowList:                            []string{},
 		AllowDiscoveryAPIs:                      false,
 		TranscodingRejectCollision:              false,
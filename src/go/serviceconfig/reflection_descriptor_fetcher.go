@@ -0,0 +1,195 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	descpb "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ReflectionDescriptorFetcher fetches the proto descriptors of gRPC services
+// from the backend via gRPC server reflection.
+type ReflectionDescriptorFetcher struct {
+	target  string
+	creds   credentials.TransportCredentials
+	timeout time.Duration
+}
+
+// NewReflectionDescriptorFetcher creates a ReflectionDescriptorFetcher for the
+// gRPC backend at backendAddress, e.g. "grpc://127.0.0.1:8082". For "grpcs"
+// backends, the server certificate is verified with the root certificates at
+// rootCertsPath.
+func NewReflectionDescriptorFetcher(backendAddress, rootCertsPath string, timeout time.Duration) (*ReflectionDescriptorFetcher, error) {
+	u, err := util.ParseURIIntoURL(backendAddress)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse backend address %q: %v", backendAddress, err)
+	}
+	protocol, useTLS, err := util.ParseBackendProtocol(u.Scheme, "")
+	if err != nil {
+		return nil, err
+	}
+	if protocol != util.GRPC {
+		return nil, fmt.Errorf("gRPC server reflection requires a gRPC backend, got backend address %q", backendAddress)
+	}
+
+	creds := insecure.NewCredentials()
+	if useTLS {
+		caCert, err := os.ReadFile(rootCertsPath)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read backend root certificates: %v", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid PEM certificates in backend root certificates %q", rootCertsPath)
+		}
+		creds = credentials.NewTLS(&tls.Config{
+			RootCAs: caCertPool,
+		})
+	}
+
+	return &ReflectionDescriptorFetcher{
+		target:  u.Host,
+		creds:   creds,
+		timeout: timeout,
+	}, nil
+}
+
+// FetchDescriptorSet returns the serialized FileDescriptorSet with the files
+// that define serviceNames, and all their dependencies. Dependencies come
+// before the files that import them.
+func (f *ReflectionDescriptorFetcher) FetchDescriptorSet(serviceNames []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, f.target, grpc.WithTransportCredentials(f.creds))
+	if err != nil {
+		return nil, fmt.Errorf("fail to connect to backend %q for gRPC server reflection: %v", f.target, err)
+	}
+	defer conn.Close()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fail to call gRPC server reflection on backend %q: %v", f.target, err)
+	}
+	defer stream.CloseSend()
+
+	files := make(map[string]*descpb.FileDescriptorProto)
+	addFiles := func(req *rpb.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			return fmt.Errorf("error code %d: %s", errResp.GetErrorCode(), errResp.GetErrorMessage())
+		}
+		for _, data := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			file := &descpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(data, file); err != nil {
+				return fmt.Errorf("fail to unmarshal file descriptor: %v", err)
+			}
+			files[file.GetName()] = file
+		}
+		return nil
+	}
+
+	for _, serviceName := range serviceNames {
+		err := addFiles(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+				FileContainingSymbol: serviceName,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fail to fetch descriptor of service %q via gRPC server reflection: %v", serviceName, err)
+		}
+	}
+
+	// Servers usually send the dependencies along with the file, but are not
+	// required to, so fetch any missing dependency by name.
+	for {
+		var missing []string
+		for _, file := range files {
+			for _, dep := range file.GetDependency() {
+				if _, ok := files[dep]; !ok {
+					missing = append(missing, dep)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			break
+		}
+
+		sort.Strings(missing)
+		err := addFiles(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{
+				FileByFilename: missing[0],
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fail to fetch descriptor of file %q via gRPC server reflection: %v", missing[0], err)
+		}
+		if _, ok := files[missing[0]]; !ok {
+			return nil, fmt.Errorf("gRPC server reflection did not return the descriptor of file %q", missing[0])
+		}
+	}
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(&descpb.FileDescriptorSet{
+		File: sortFileDescriptors(files),
+	})
+}
+
+// sortFileDescriptors orders the files by name, with every file after its
+// dependencies.
+func sortFileDescriptors(files map[string]*descpb.FileDescriptorProto) []*descpb.FileDescriptorProto {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sorted []*descpb.FileDescriptorProto
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, dep := range files[name].GetDependency() {
+			visit(dep)
+		}
+		sorted = append(sorted, files[name])
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return sorted
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceconfig
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	descpb "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func initReflectionServerForTest(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("fail to listen: %v", err)
+	}
+
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	reflection.Register(s)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	return "grpc://" + lis.Addr().String()
+}
+
+func TestReflectionDescriptorFetcher(t *testing.T) {
+	backendAddress := initReflectionServerForTest(t)
+
+	testData := []struct {
+		desc         string
+		serviceNames []string
+		wantFiles    []string
+		wantError    string
+	}{
+		{
+			desc:         "Success. Fetch the descriptor of a service",
+			serviceNames: []string{"grpc.health.v1.Health"},
+			wantFiles:    []string{"grpc/health/v1/health.proto"},
+		},
+		{
+			desc:         "Success. Fetch the descriptors of several services with dependencies",
+			serviceNames: []string{"grpc.health.v1.Health", "grpc.reflection.v1alpha.ServerReflection"},
+			wantFiles:    []string{"grpc/health/v1/health.proto", "grpc/reflection/v1alpha/reflection.proto"},
+		},
+		{
+			desc:         "Failure. Service is unknown to the backend",
+			serviceNames: []string{"grpc.health.v1.Health", "endpoints.examples.bookstore.Bookstore"},
+			wantError:    `fail to fetch descriptor of service "endpoints.examples.bookstore.Bookstore" via gRPC server reflection`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			fetcher, err := NewReflectionDescriptorFetcher(backendAddress, "", 5*time.Second)
+			if err != nil {
+				t.Fatalf("NewReflectionDescriptorFetcher() got error: %v", err)
+			}
+
+			descriptorBin, err := fetcher.FetchDescriptorSet(tc.serviceNames)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("FetchDescriptorSet() got error: %v, want error containing: %v", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchDescriptorSet() got error: %v", err)
+			}
+
+			fds := &descpb.FileDescriptorSet{}
+			if err := proto.Unmarshal(descriptorBin, fds); err != nil {
+				t.Fatalf("fail to unmarshal FileDescriptorSet: %v", err)
+			}
+
+			seen := make(map[string]bool)
+			for _, file := range fds.GetFile() {
+				for _, dep := range file.GetDependency() {
+					if !seen[dep] {
+						t.Errorf("file %q comes before its dependency %q", file.GetName(), dep)
+					}
+				}
+				seen[file.GetName()] = true
+			}
+			for _, wantFile := range tc.wantFiles {
+				if !seen[wantFile] {
+					t.Errorf("FetchDescriptorSet() got files %v, want file %q", seen, wantFile)
+				}
+			}
+		})
+	}
+}
+
+func TestNewReflectionDescriptorFetcher(t *testing.T) {
+	invalidCertsPath := filepath.Join(t.TempDir(), "invalid.pem")
+	if err := os.WriteFile(invalidCertsPath, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("fail to write %v: %v", invalidCertsPath, err)
+	}
+
+	testData := []struct {
+		desc           string
+		backendAddress string
+		rootCertsPath  string
+		wantError      string
+	}{
+		{
+			desc:           "Success. gRPC backend",
+			backendAddress: "grpc://127.0.0.1:8082",
+		},
+		{
+			desc:           "Failure. HTTP backend",
+			backendAddress: "http://127.0.0.1:8082",
+			wantError:      "gRPC server reflection requires a gRPC backend",
+		},
+		{
+			desc:           "Failure. gRPC TLS backend with missing root certificates",
+			backendAddress: "grpcs://127.0.0.1:8082",
+			rootCertsPath:  "/does/not/exist.pem",
+			wantError:      "fail to read backend root certificates",
+		},
+		{
+			desc:           "Failure. gRPC TLS backend with invalid root certificates",
+			backendAddress: "grpcs://127.0.0.1:8082",
+			rootCertsPath:  invalidCertsPath,
+			wantError:      "no valid PEM certificates in backend root certificates",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := NewReflectionDescriptorFetcher(tc.backendAddress, tc.rootCertsPath, time.Second)
+			if tc.wantError == "" && err != nil {
+				t.Fatalf("NewReflectionDescriptorFetcher() got error: %v", err)
+			}
+			if tc.wantError != "" && (err == nil || !strings.Contains(err.Error(), tc.wantError)) {
+				t.Fatalf("NewReflectionDescriptorFetcher() got error: %v, want error containing: %v", err, tc.wantError)
+			}
+		})
+	}
+}
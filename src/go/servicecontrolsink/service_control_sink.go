@@ -0,0 +1,298 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package servicecontrolsink is a local stand-in for the Service Control
+// server. It allows all Check and AllocateQuota calls and writes the
+// operations of Report calls as JSON lines, so per-operation usage data is
+// kept when the real Service Control server is not reachable.
+package servicecontrolsink
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"github.com/golang/glog"
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	scpb "google.golang.org/genproto/googleapis/api/servicecontrol/v1"
+)
+
+const (
+	// StdoutDestination writes the report lines to standard output.
+	StdoutDestination = "stdout"
+
+	// The sink does not check credentials, so any token works.
+	localAccessToken = "local-service-control-sink"
+
+	latencyMetricSuffix = "_latencies"
+
+	// Where Report operations carry the API key, see
+	// src/api_proxy/service_control/request_builder.cc.
+	consumerIDAPIKeyPrefix   = "api_key:"
+	credentialIDLabel        = "/credential_id"
+	credentialIDAPIKeyPrefix = "apikey:"
+	logEntryAPIKeyField      = "api_key"
+)
+
+// ReportedOperation is the JSON line written for each operation of a Report.
+// API keys are replaced by their fingerprint, see redactAPIKey.
+type ReportedOperation struct {
+	ServiceName   string             `json:"service_name"`
+	OperationID   string             `json:"operation_id,omitempty"`
+	OperationName string             `json:"operation_name"`
+	ConsumerID    string             `json:"consumer_id,omitempty"`
+	StartTime     string             `json:"start_time,omitempty"`
+	EndTime       string             `json:"end_time,omitempty"`
+	Labels        map[string]string  `json:"labels,omitempty"`
+	LatenciesMs   map[string]float64 `json:"latencies_ms,omitempty"`
+	LogEntries    []json.RawMessage  `json:"log_entries,omitempty"`
+}
+
+// Sink serves the Service Control v1 API and writes the reported operations
+// to its output.
+type Sink struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewSink creates a Sink writing to the given file path, or to standard output
+// if the destination is "stdout". The file is opened in append mode.
+func NewSink(destination string) (*Sink, error) {
+	if destination == StdoutDestination {
+		return NewSinkWithWriter(os.Stdout), nil
+	}
+
+	f, err := os.OpenFile(destination, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open service control local sink file %q: %v", destination, err)
+	}
+	return NewSinkWithWriter(f), nil
+}
+
+// NewSinkWithWriter creates a Sink writing to the given writer.
+func NewSinkWithWriter(out io.Writer) *Sink {
+	return &Sink{
+		out: out,
+	}
+}
+
+// MakeHandler creates the handler of the sink.
+//
+// It follows the following scheme:
+// Request: GET /local/access_token, returns a dummy access token in the same
+// format as the token agent.
+// Request: POST /v1/services/{service_name}:check, always allowed.
+// Request: POST /v1/services/{service_name}:allocateQuota, always allowed.
+// Request: POST /v1/services/{service_name}:report, writes the operations.
+func (s *Sink) MakeHandler() http.Handler {
+	r := mux.NewRouter()
+
+	r.Path(util.TokenAgentAccessTokenPath).Methods("GET").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"access_token": "%s", "expires_in": %v}`, localAccessToken, int(time.Hour.Seconds()))))
+	})
+	r.Path("/v1/services/{service}:check").Methods("POST").HandlerFunc(s.handleCheck)
+	r.Path("/v1/services/{service}:allocateQuota").Methods("POST").HandlerFunc(s.handleAllocateQuota)
+	r.Path("/v1/services/{service}:report").Methods("POST").HandlerFunc(s.handleReport)
+
+	return r
+}
+
+func (s *Sink) handleCheck(w http.ResponseWriter, r *http.Request) {
+	req := &scpb.CheckRequest{}
+	if !readRequest(w, r, req) {
+		return
+	}
+
+	writeResponse(w, &scpb.CheckResponse{
+		OperationId:     req.GetOperation().GetOperationId(),
+		ServiceConfigId: req.GetServiceConfigId(),
+	})
+}
+
+func (s *Sink) handleAllocateQuota(w http.ResponseWriter, r *http.Request) {
+	req := &scpb.AllocateQuotaRequest{}
+	if !readRequest(w, r, req) {
+		return
+	}
+
+	writeResponse(w, &scpb.AllocateQuotaResponse{
+		OperationId:     req.GetAllocateOperation().GetOperationId(),
+		ServiceConfigId: req.GetServiceConfigId(),
+	})
+}
+
+func (s *Sink) handleReport(w http.ResponseWriter, r *http.Request) {
+	req := &scpb.ReportRequest{}
+	if !readRequest(w, r, req) {
+		return
+	}
+
+	serviceName := mux.Vars(r)["service"]
+	if err := s.writeOperations(serviceName, req.GetOperations()); err != nil {
+		glog.Errorf("service control local sink fail to write report: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, &scpb.ReportResponse{
+		ServiceConfigId: req.GetServiceConfigId(),
+	})
+}
+
+func (s *Sink) writeOperations(serviceName string, operations []*scpb.Operation) error {
+	var lines []byte
+	for _, op := range operations {
+		line, err := json.Marshal(toReportedOperation(serviceName, op))
+		if err != nil {
+			return fmt.Errorf("fail to marshal operation %q: %v", op.GetOperationId(), err)
+		}
+		lines = append(lines, line...)
+		lines = append(lines, '\n')
+	}
+
+	// Write all operations of a report at once, so lines from concurrent
+	// reports are not interleaved.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.out.Write(lines)
+	return err
+}
+
+func toReportedOperation(serviceName string, op *scpb.Operation) *ReportedOperation {
+	reported := &ReportedOperation{
+		ServiceName:   serviceName,
+		OperationID:   op.GetOperationId(),
+		OperationName: op.GetOperationName(),
+		ConsumerID:    redactPrefixedAPIKey(op.GetConsumerId(), consumerIDAPIKeyPrefix),
+		Labels:        redactLabels(op.GetLabels()),
+	}
+	if op.GetStartTime() != nil {
+		reported.StartTime = op.GetStartTime().AsTime().Format(time.RFC3339Nano)
+	}
+	if op.GetEndTime() != nil {
+		reported.EndTime = op.GetEndTime().AsTime().Format(time.RFC3339Nano)
+	}
+
+	for _, mvs := range op.GetMetricValueSets() {
+		// The same latency is reported by the consumer, producer and
+		// by_consumer metrics, e.g.
+		// serviceruntime.googleapis.com/api/producer/backend_latencies.
+		name := mvs.GetMetricName()
+		name = name[strings.LastIndex(name, "/")+1:]
+		if !strings.HasSuffix(name, latencyMetricSuffix) {
+			continue
+		}
+		name = strings.TrimSuffix(name, latencyMetricSuffix)
+		if _, ok := reported.LatenciesMs[name]; ok {
+			continue
+		}
+		for _, mv := range mvs.GetMetricValues() {
+			dist := mv.GetDistributionValue()
+			if dist == nil || dist.GetCount() == 0 {
+				continue
+			}
+			if reported.LatenciesMs == nil {
+				reported.LatenciesMs = make(map[string]float64)
+			}
+			// Latency distributions are in seconds.
+			reported.LatenciesMs[name] = dist.GetMean() * 1000
+			break
+		}
+	}
+
+	for _, entry := range op.GetLogEntries() {
+		b, err := protojson.Marshal(redactLogEntry(entry))
+		if err != nil {
+			glog.Warningf("service control local sink fail to marshal log entry of operation %q: %v", op.GetOperationId(), err)
+			continue
+		}
+		reported.LogEntries = append(reported.LogEntries, b)
+	}
+	return reported
+}
+
+// redactAPIKey replaces an API key by a fingerprint, so operations of the same
+// key can still be grouped without writing the key itself.
+func redactAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+func redactPrefixedAPIKey(value, prefix string) string {
+	if !strings.HasPrefix(value, prefix) {
+		return value
+	}
+	return prefix + redactAPIKey(strings.TrimPrefix(value, prefix))
+}
+
+func redactLabels(labels map[string]string) map[string]string {
+	credentialID, ok := labels[credentialIDLabel]
+	if !ok {
+		return labels
+	}
+
+	redacted := make(map[string]string, len(labels))
+	for name, value := range labels {
+		redacted[name] = value
+	}
+	redacted[credentialIDLabel] = redactPrefixedAPIKey(credentialID, credentialIDAPIKeyPrefix)
+	return redacted
+}
+
+func redactLogEntry(entry *scpb.LogEntry) *scpb.LogEntry {
+	apiKey, ok := entry.GetStructPayload().GetFields()[logEntryAPIKeyField]
+	if !ok {
+		return entry
+	}
+
+	redacted := proto.Clone(entry).(*scpb.LogEntry)
+	redacted.GetStructPayload().GetFields()[logEntryAPIKeyField] = structpb.NewStringValue(redactAPIKey(apiKey.GetStringValue()))
+	return redacted
+}
+
+func readRequest(w http.ResponseWriter, r *http.Request, req proto.Message) bool {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("fail to read request body: %v", err), http.StatusBadRequest)
+		return false
+	}
+	if err := proto.Unmarshal(body, req); err != nil {
+		http.Error(w, fmt.Sprintf("fail to unmarshal request body: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeResponse(w http.ResponseWriter, resp proto.Message) {
+	b, err := proto.Marshal(resp)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("fail to marshal response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,310 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicecontrolsink
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	scpb "google.golang.org/genproto/googleapis/api/servicecontrol/v1"
+	ltypepb "google.golang.org/genproto/googleapis/logging/type"
+)
+
+func latencyMetric(name string, secs float64) *scpb.MetricValueSet {
+	return &scpb.MetricValueSet{
+		MetricName: name,
+		MetricValues: []*scpb.MetricValue{
+			{
+				Value: &scpb.MetricValue_DistributionValue{
+					DistributionValue: &scpb.Distribution{
+						Count: 1,
+						Mean:  secs,
+					},
+				},
+			},
+		},
+	}
+}
+
+func post(t *testing.T, url string, req proto.Message) *http.Response {
+	t.Helper()
+	body, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("fail to marshal request: %v", err)
+	}
+	resp, err := http.Post(url, "application/x-protobuf", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("fail to send request to %s: %v", url, err)
+	}
+	return resp
+}
+
+func TestSinkAllowsCheckAndAllocateQuota(t *testing.T) {
+	s := httptest.NewServer(NewSinkWithWriter(&bytes.Buffer{}).MakeHandler())
+	defer s.Close()
+
+	resp := post(t, s.URL+"/v1/services/bookstore.endpoints.cloudesf-testing.cloud.goog:check", &scpb.CheckRequest{
+		ServiceConfigId: "2023-01-01r0",
+		Operation: &scpb.Operation{
+			OperationId:   "check-op",
+			OperationName: "endpoints.examples.bookstore.Bookstore.ListShelves",
+		},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("check got status %v, want 200", resp.StatusCode)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	checkResp := &scpb.CheckResponse{}
+	if err := proto.Unmarshal(body, checkResp); err != nil {
+		t.Fatalf("fail to unmarshal check response: %v", err)
+	}
+	if checkResp.GetOperationId() != "check-op" || len(checkResp.GetCheckErrors()) != 0 {
+		t.Errorf("check got response %v, want allowed response for operation check-op", checkResp)
+	}
+
+	resp = post(t, s.URL+"/v1/services/bookstore.endpoints.cloudesf-testing.cloud.goog:allocateQuota", &scpb.AllocateQuotaRequest{
+		AllocateOperation: &scpb.QuotaOperation{
+			OperationId: "quota-op",
+		},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("allocateQuota got status %v, want 200", resp.StatusCode)
+	}
+	body, _ = ioutil.ReadAll(resp.Body)
+	quotaResp := &scpb.AllocateQuotaResponse{}
+	if err := proto.Unmarshal(body, quotaResp); err != nil {
+		t.Fatalf("fail to unmarshal allocateQuota response: %v", err)
+	}
+	if quotaResp.GetOperationId() != "quota-op" || len(quotaResp.GetAllocateErrors()) != 0 {
+		t.Errorf("allocateQuota got response %v, want allowed response for operation quota-op", quotaResp)
+	}
+
+	resp, err := http.Post(s.URL+"/v1/services/bookstore.endpoints.cloudesf-testing.cloud.goog:check", "application/x-protobuf", strings.NewReader("\xff\xff"))
+	if err != nil {
+		t.Fatalf("fail to send check request: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("check with malformed body got status %v, want 400", resp.StatusCode)
+	}
+}
+
+func TestSinkWritesReportOperations(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := httptest.NewServer(NewSinkWithWriter(out).MakeHandler())
+	defer s.Close()
+
+	payload, _ := structpb.NewStruct(map[string]interface{}{
+		"http_response_code": 200,
+	})
+	startTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := post(t, s.URL+"/v1/services/bookstore.endpoints.cloudesf-testing.cloud.goog:report", &scpb.ReportRequest{
+		Operations: []*scpb.Operation{
+			{
+				OperationId:   "op-1",
+				OperationName: "endpoints.examples.bookstore.Bookstore.ListShelves",
+				ConsumerId:    "api_key:key-1",
+				StartTime:     timestamppb.New(startTime),
+				EndTime:       timestamppb.New(startTime.Add(25 * time.Millisecond)),
+				Labels: map[string]string{
+					"/response_code": "200",
+				},
+				MetricValueSets: []*scpb.MetricValueSet{
+					latencyMetric("serviceruntime.googleapis.com/api/consumer/total_latencies", 0.025),
+					latencyMetric("serviceruntime.googleapis.com/api/producer/total_latencies", 0.025),
+					latencyMetric("serviceruntime.googleapis.com/api/producer/backend_latencies", 0.02),
+					{
+						MetricName: "serviceruntime.googleapis.com/api/producer/request_count",
+						MetricValues: []*scpb.MetricValue{
+							{
+								Value: &scpb.MetricValue_Int64Value{Int64Value: 1},
+							},
+						},
+					},
+				},
+				LogEntries: []*scpb.LogEntry{
+					{
+						Name:     "endpoints_log",
+						Severity: ltypepb.LogSeverity_INFO,
+						Payload: &scpb.LogEntry_StructPayload{
+							StructPayload: payload,
+						},
+					},
+				},
+			},
+			{
+				OperationId:   "op-2",
+				OperationName: "endpoints.examples.bookstore.Bookstore.GetShelf",
+			},
+		},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("report got status %v, want 200", resp.StatusCode)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	wantLines := []string{
+		`{
+			"service_name": "bookstore.endpoints.cloudesf-testing.cloud.goog",
+			"operation_id": "op-1",
+			"operation_name": "endpoints.examples.bookstore.Bookstore.ListShelves",
+			"consumer_id": "api_key:sha256:be2974546978e373",
+			"start_time": "2023-01-01T00:00:00Z",
+			"end_time": "2023-01-01T00:00:00.025Z",
+			"labels": {
+				"/response_code": "200"
+			},
+			"latencies_ms": {
+				"total": 25,
+				"backend": 20
+			},
+			"log_entries": [
+				{
+					"name": "endpoints_log",
+					"severity": "INFO",
+					"structPayload": {
+						"http_response_code": 200
+					}
+				}
+			]
+		}`,
+		`{
+			"service_name": "bookstore.endpoints.cloudesf-testing.cloud.goog",
+			"operation_id": "op-2",
+			"operation_name": "endpoints.examples.bookstore.Bookstore.GetShelf"
+		}`,
+	}
+	if len(lines) != len(wantLines) {
+		t.Fatalf("report wrote %d lines, want %d: %s", len(lines), len(wantLines), out.String())
+	}
+	for i := range wantLines {
+		if err := util.JsonEqual(wantLines[i], lines[i]); err != nil {
+			t.Errorf("report line %d is not expected: %v", i, err)
+		}
+	}
+}
+
+func TestSinkRedactsAPIKeys(t *testing.T) {
+	out := &bytes.Buffer{}
+	s := httptest.NewServer(NewSinkWithWriter(out).MakeHandler())
+	defer s.Close()
+
+	const apiKey = "AIzaSyTestKey"
+	payload, _ := structpb.NewStruct(map[string]interface{}{
+		"api_key":            apiKey,
+		"http_response_code": 200,
+	})
+	resp := post(t, s.URL+"/v1/services/bookstore.endpoints.cloudesf-testing.cloud.goog:report", &scpb.ReportRequest{
+		Operations: []*scpb.Operation{
+			{
+				OperationId:   "op-1",
+				OperationName: "endpoints.examples.bookstore.Bookstore.ListShelves",
+				ConsumerId:    "api_key:" + apiKey,
+				Labels: map[string]string{
+					"/credential_id": "apikey:" + apiKey,
+					"/response_code": "200",
+				},
+				LogEntries: []*scpb.LogEntry{
+					{
+						Name: "endpoints_log",
+						Payload: &scpb.LogEntry_StructPayload{
+							StructPayload: payload,
+						},
+					},
+				},
+			},
+		},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("report got status %v, want 200", resp.StatusCode)
+	}
+
+	if strings.Contains(out.String(), apiKey) {
+		t.Errorf("report line contains the API key: %s", out.String())
+	}
+	want := `{
+		"service_name": "bookstore.endpoints.cloudesf-testing.cloud.goog",
+		"operation_id": "op-1",
+		"operation_name": "endpoints.examples.bookstore.Bookstore.ListShelves",
+		"consumer_id": "api_key:sha256:ea4da3ab4c72d7d8",
+		"labels": {
+			"/credential_id": "apikey:sha256:ea4da3ab4c72d7d8",
+			"/response_code": "200"
+		},
+		"log_entries": [
+			{
+				"name": "endpoints_log",
+				"structPayload": {
+					"api_key": "sha256:ea4da3ab4c72d7d8",
+					"http_response_code": 200
+				}
+			}
+		]
+	}`
+	if err := util.JsonEqual(want, strings.TrimSuffix(out.String(), "\n")); err != nil {
+		t.Errorf("report line is not expected: %v", err)
+	}
+}
+
+func TestSinkServesAccessToken(t *testing.T) {
+	s := httptest.NewServer(NewSinkWithWriter(&bytes.Buffer{}).MakeHandler())
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + util.TokenAgentAccessTokenPath)
+	if err != nil {
+		t.Fatalf("fail to get access token: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	want := `{"access_token": "local-service-control-sink", "expires_in": 3600}`
+	if err := util.JsonEqual(want, string(body)); err != nil {
+		t.Errorf("access token response is not expected: %v", err)
+	}
+}
+
+func TestNewSinkAppendsToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports.jsonl")
+	if err := ioutil.WriteFile(path, []byte("existing\n"), 0644); err != nil {
+		t.Fatalf("fail to write file: %v", err)
+	}
+
+	sink, err := NewSink(path)
+	if err != nil {
+		t.Fatalf("NewSink got error: %v", err)
+	}
+	if err := sink.writeOperations("svc", []*scpb.Operation{{OperationName: "op"}}); err != nil {
+		t.Fatalf("writeOperations got error: %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	want := "existing\n" + `{"service_name":"svc","operation_name":"op"}` + "\n"
+	if string(got) != want {
+		t.Errorf("file content got %q, want %q", got, want)
+	}
+
+	if _, err := NewSink(filepath.Join(t.TempDir(), "missing", "reports.jsonl")); err == nil {
+		t.Errorf("NewSink with a missing directory got no error")
+	}
+}
@@ -17,17 +17,34 @@ package tracing
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/options"
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	opencensuspb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	tracepb "github.com/envoyproxy/go-control-plane/envoy/config/trace/v3"
 	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/golang/glog"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
+const (
+	// Tracing providers.
+	OpenCensusProvider    = "opencensus"
+	OpenTelemetryProvider = "opentelemetry"
+	ZipkinProvider        = "zipkin"
+
+	// Service name of the spans exported to the OpenTelemetry collector.
+	openTelemetryServiceName = "espv2"
+
+	// Default path of the Zipkin v2 JSON API.
+	defaultZipkinCollectorPath = "/api/v2/spans"
+)
+
 func createTraceContexts(ctx_str string) ([]tracepb.OpenCensusConfig_TraceContext, error) {
 	var out []tracepb.OpenCensusConfig_TraceContext
 
@@ -108,31 +125,211 @@ func createOpenCensusConfig(opts options.TracingOptions) (*tracepb.OpenCensusCon
 	return cfg, nil
 }
 
+// IsCollectorRequired returns true if the tracing provider exports spans to
+// the collector at --tracing_collector_address.
+func IsCollectorRequired(opts options.TracingOptions) bool {
+	if opts.DisableTracing {
+		return false
+	}
+	return opts.Provider == OpenTelemetryProvider || opts.Provider == ZipkinProvider
+}
+
+// ParseCollectorAddress parses the collector address into scheme, hostname,
+// port and path. If the address has no scheme, https is used.
+func ParseCollectorAddress(opts options.TracingOptions) (string, string, uint32, string, error) {
+	if opts.CollectorAddress == "" {
+		return "", "", 0, "", fmt.Errorf("tracing provider %q requires a collector address", opts.Provider)
+	}
+
+	scheme, hostname, port, path, err := util.ParseURI(opts.CollectorAddress)
+	if err != nil {
+		return "", "", 0, "", fmt.Errorf("failed to parse tracing collector address %q: %v", opts.CollectorAddress, err)
+	}
+	return scheme, hostname, port, path, nil
+}
+
+// checkSingleTraceContext validates the incoming and outgoing trace contexts
+// for tracers that only propagate one context format, `supported` is empty if
+// none of the trace contexts maps onto it. The default contexts are replaced
+// by the tracer's own, any other value must be supported.
+func checkSingleTraceContext(opts options.TracingOptions, supported string, provider string) error {
+	defaults := options.DefaultCommonOptions().TracingOptions
+	for _, c := range []struct {
+		flag         string
+		value        string
+		defaultValue string
+	}{
+		{"--tracing_incoming_context", opts.IncomingContext, defaults.IncomingContext},
+		{"--tracing_outgoing_context", opts.OutgoingContext, defaults.OutgoingContext},
+	} {
+		if _, err := createTraceContexts(c.value); err != nil {
+			return err
+		}
+		if c.value == "" || c.value == c.defaultValue {
+			continue
+		}
+		for _, ctx := range strings.Split(c.value, ",") {
+			if ctx != supported {
+				return fmt.Errorf("trace context %q of %s is not supported by tracing provider %q", ctx, c.flag, provider)
+			}
+		}
+	}
+	return nil
+}
+
+// checkDefaultSpanLimits fails if the span limits are set for tracers that
+// can't configure them.
+func checkDefaultSpanLimits(opts options.TracingOptions, provider string) error {
+	defaults := options.DefaultCommonOptions().TracingOptions
+	for _, l := range []struct {
+		flag         string
+		value        int64
+		defaultValue int64
+	}{
+		{"--tracing_max_num_attributes", opts.MaxNumAttributes, defaults.MaxNumAttributes},
+		{"--tracing_max_num_annotations", opts.MaxNumAnnotations, defaults.MaxNumAnnotations},
+		{"--tracing_max_num_message_events", opts.MaxNumMessageEvents, defaults.MaxNumMessageEvents},
+		{"--tracing_max_num_links", opts.MaxNumLinks, defaults.MaxNumLinks},
+	} {
+		if l.value != 0 && l.value != l.defaultValue {
+			return fmt.Errorf("%s is not supported by tracing provider %q, the span limits must be applied by the collector", l.flag, provider)
+		}
+	}
+	return nil
+}
+
+func createOpenTelemetryConfig(opts options.TracingOptions) (*tracepb.OpenTelemetryConfig, error) {
+	if _, _, _, _, err := ParseCollectorAddress(opts); err != nil {
+		return nil, err
+	}
+
+	// The OpenTelemetry tracer only propagates W3C trace context.
+	if err := checkSingleTraceContext(opts, "traceparent", OpenTelemetryProvider); err != nil {
+		return nil, err
+	}
+	if err := checkDefaultSpanLimits(opts, OpenTelemetryProvider); err != nil {
+		return nil, err
+	}
+
+	return &tracepb.OpenTelemetryConfig{
+		GrpcService: &corepb.GrpcService{
+			TargetSpecifier: &corepb.GrpcService_EnvoyGrpc_{
+				EnvoyGrpc: &corepb.GrpcService_EnvoyGrpc{
+					ClusterName: util.TracingCollectorClusterName,
+				},
+			},
+		},
+		ServiceName: openTelemetryServiceName,
+	}, nil
+}
+
+func createZipkinConfig(opts options.TracingOptions) (*tracepb.ZipkinConfig, error) {
+	_, hostname, _, path, err := ParseCollectorAddress(opts)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		path = defaultZipkinCollectorPath
+	}
+
+	// The Zipkin tracer only propagates B3 headers, none of the trace contexts
+	// map onto it.
+	if err := checkSingleTraceContext(opts, "", ZipkinProvider); err != nil {
+		return nil, err
+	}
+	if err := checkDefaultSpanLimits(opts, ZipkinProvider); err != nil {
+		return nil, err
+	}
+
+	return &tracepb.ZipkinConfig{
+		CollectorCluster:         util.TracingCollectorClusterName,
+		CollectorEndpoint:        path,
+		CollectorEndpointVersion: tracepb.ZipkinConfig_HTTP_JSON,
+		CollectorHostname:        hostname,
+	}, nil
+}
+
+// createTracer creates the tracer name and config for the tracing provider.
+func createTracer(opts options.TracingOptions) (string, proto.Message, error) {
+	switch opts.Provider {
+	case "", OpenCensusProvider:
+		cfg, err := createOpenCensusConfig(opts)
+		return "envoy.tracers.opencensus", cfg, err
+	case OpenTelemetryProvider:
+		cfg, err := createOpenTelemetryConfig(opts)
+		return "envoy.tracers.opentelemetry", cfg, err
+	case ZipkinProvider:
+		cfg, err := createZipkinConfig(opts)
+		return "envoy.tracers.zipkin", cfg, err
+	default:
+		return "", nil, fmt.Errorf("invalid tracing provider: %q. It must be one of (opencensus|opentelemetry|zipkin)", opts.Provider)
+	}
+}
+
+// SamplingRateToPercent validates the sampling rate and converts it to the
+// percentage used by Envoy.
+func SamplingRateToPercent(rate float64) (float64, error) {
+	if rate < 0.0 || rate > 1.0 {
+		return 0, fmt.Errorf("invalid trace sampling rate: %v. It must be >= 0.0 and <= 1.0", rate)
+	}
+
+	// This results in precision errors. Round percentage to 4 decimal points.
+	percent := rate * 100
+	return math.Round(percent*10000) / 10000, nil
+}
+
+// ParseOperationSamplingRates parses the per-operation sampling rates, a comma
+// separated list of "selector=rate" pairs.
+func ParseOperationSamplingRates(rates string) (map[string]float64, error) {
+	ratesBySelector := make(map[string]float64)
+	if rates == "" {
+		return ratesBySelector, nil
+	}
+
+	for _, pair := range strings.Split(rates, ",") {
+		selector, rateStr, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || selector == "" || rateStr == "" {
+			return nil, fmt.Errorf("invalid operation sampling rate %q, it must be in the format selector=rate", pair)
+		}
+		if _, ok := ratesBySelector[selector]; ok {
+			return nil, fmt.Errorf("duplicate operation sampling rate for selector %q", selector)
+		}
+
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trace sampling rate %q for selector %q: %v", rateStr, selector, err)
+		}
+		if _, err := SamplingRateToPercent(rate); err != nil {
+			return nil, fmt.Errorf("for selector %q, %v", selector, err)
+		}
+		ratesBySelector[selector] = rate
+	}
+	return ratesBySelector, nil
+}
+
 // CreateTracing outputs envoy HCM tracing config.
 func CreateTracing(opts options.TracingOptions) (*hcmpb.HttpConnectionManager_Tracing, error) {
-	if opts.ProjectId == "" {
+	// Only the Stackdriver exporter of OpenCensus needs the project ID.
+	if (opts.Provider == "" || opts.Provider == OpenCensusProvider) && opts.ProjectId == "" {
 		glog.Warningf("Not adding tracing config because project ID is empty")
 		return nil, nil
 	}
 
-	openCensusConfig, err := createOpenCensusConfig(opts)
+	tracerName, tracerConfig, err := createTracer(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	typedConfig, err := anypb.New(openCensusConfig)
+	typedConfig, err := anypb.New(tracerConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	if opts.SamplingRate < 0.0 || opts.SamplingRate > 1.0 {
-		return nil, fmt.Errorf("invalid trace sampling rate: %v. It must be >= 0.0 and <= 1.0", opts.SamplingRate)
+	percentSampleRate, err := SamplingRateToPercent(opts.SamplingRate)
+	if err != nil {
+		return nil, err
 	}
 
-	// This results in precision errors. Round percentage to 4 decimal points.
-	percentSampleRate := opts.SamplingRate * 100
-	percentSampleRate = math.Round(percentSampleRate*10000) / 10000
-
 	return &hcmpb.HttpConnectionManager_Tracing{
 		ClientSampling: &typepb.Percent{
 			Value: 0,
@@ -144,7 +341,7 @@ func CreateTracing(opts options.TracingOptions) (*hcmpb.HttpConnectionManager_Tr
 			Value: percentSampleRate,
 		},
 		Provider: &tracepb.Tracing_Http{
-			Name:       "envoy.tracers.opencensus",
+			Name:       tracerName,
 			ConfigType: &tracepb.Tracing_Http_TypedConfig{TypedConfig: typedConfig},
 		},
 		Verbose: opts.EnableVerboseAnnotations,
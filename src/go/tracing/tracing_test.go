@@ -24,9 +24,11 @@ import (
 	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
 
 	opencensuspb "github.com/census-instrumentation/opencensus-proto/gen-go/trace/v1"
+	corepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	tracepb "github.com/envoyproxy/go-control-plane/envoy/config/trace/v3"
 	hcmpb "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 )
@@ -203,6 +205,166 @@ func TestShouldFetchTracingProjectID(t *testing.T) {
 	}
 }
 
+// Tests the OpenTelemetry and Zipkin tracers and the tracer selection.
+func TestCreateTracingProviders(t *testing.T) {
+	testData := []struct {
+		desc           string
+		opts           options.TracingOptions
+		wantTracerName string
+		wantConfig     proto.Message
+		wantError      string
+	}{
+		{
+			desc: "OpenCensus is used when provider is empty",
+			opts: options.TracingOptions{
+				ProjectId: fakeOptsProjectId,
+			},
+			wantTracerName: "envoy.tracers.opencensus",
+			wantConfig: &tracepb.OpenCensusConfig{
+				TraceConfig:                &opencensuspb.TraceConfig{},
+				StackdriverExporterEnabled: true,
+				StackdriverProjectId:       fakeOptsProjectId,
+			},
+		},
+		{
+			desc: "OpenTelemetry without project ID, default contexts and span limits are allowed",
+			opts: options.TracingOptions{
+				Provider:         "opentelemetry",
+				CollectorAddress: "http://otel-collector:4317",
+				IncomingContext:  "traceparent,x-cloud-trace-context",
+				OutgoingContext:  "traceparent",
+				MaxNumAttributes: 32,
+			},
+			wantTracerName: "envoy.tracers.opentelemetry",
+			wantConfig: &tracepb.OpenTelemetryConfig{
+				GrpcService: &corepb.GrpcService{
+					TargetSpecifier: &corepb.GrpcService_EnvoyGrpc_{
+						EnvoyGrpc: &corepb.GrpcService_EnvoyGrpc{
+							ClusterName: "tracing-collector-cluster",
+						},
+					},
+				},
+				ServiceName: "espv2",
+			},
+		},
+		{
+			desc: "Zipkin with default collector path",
+			opts: options.TracingOptions{
+				Provider:         "zipkin",
+				CollectorAddress: "http://zipkin:9411",
+			},
+			wantTracerName: "envoy.tracers.zipkin",
+			wantConfig: &tracepb.ZipkinConfig{
+				CollectorCluster:         "tracing-collector-cluster",
+				CollectorEndpoint:        "/api/v2/spans",
+				CollectorEndpointVersion: tracepb.ZipkinConfig_HTTP_JSON,
+				CollectorHostname:        "zipkin",
+			},
+		},
+		{
+			desc: "Zipkin with custom collector path",
+			opts: options.TracingOptions{
+				Provider:         "zipkin",
+				CollectorAddress: "https://zipkin.example.com/custom/spans",
+			},
+			wantTracerName: "envoy.tracers.zipkin",
+			wantConfig: &tracepb.ZipkinConfig{
+				CollectorCluster:         "tracing-collector-cluster",
+				CollectorEndpoint:        "/custom/spans",
+				CollectorEndpointVersion: tracepb.ZipkinConfig_HTTP_JSON,
+				CollectorHostname:        "zipkin.example.com",
+			},
+		},
+		{
+			desc: "OpenTelemetry requires a collector address",
+			opts: options.TracingOptions{
+				Provider: "opentelemetry",
+			},
+			wantError: `tracing provider "opentelemetry" requires a collector address`,
+		},
+		{
+			desc: "Zipkin fails with invalid trace context",
+			opts: options.TracingOptions{
+				Provider:         "zipkin",
+				CollectorAddress: "http://zipkin:9411",
+				IncomingContext:  "b3",
+			},
+			wantError: "Invalid trace context: b3",
+		},
+		{
+			desc: "OpenTelemetry fails with a trace context it does not propagate",
+			opts: options.TracingOptions{
+				Provider:         "opentelemetry",
+				CollectorAddress: "http://otel-collector:4317",
+				IncomingContext:  "grpc-trace-bin",
+			},
+			wantError: `trace context "grpc-trace-bin" of --tracing_incoming_context is not supported by tracing provider "opentelemetry"`,
+		},
+		{
+			desc: "OpenTelemetry fails with non-default span limits",
+			opts: options.TracingOptions{
+				Provider:         "opentelemetry",
+				CollectorAddress: "http://otel-collector:4317",
+				MaxNumAttributes: 8,
+			},
+			wantError: `--tracing_max_num_attributes is not supported by tracing provider "opentelemetry"`,
+		},
+		{
+			desc: "Zipkin fails with non-default trace context",
+			opts: options.TracingOptions{
+				Provider:         "zipkin",
+				CollectorAddress: "http://zipkin:9411",
+				OutgoingContext:  "traceparent",
+			},
+			wantError: `trace context "traceparent" of --tracing_outgoing_context is not supported by tracing provider "zipkin"`,
+		},
+		{
+			desc: "Zipkin fails with non-default span limits",
+			opts: options.TracingOptions{
+				Provider:         "zipkin",
+				CollectorAddress: "http://zipkin:9411",
+				MaxNumLinks:      1,
+			},
+			wantError: `--tracing_max_num_links is not supported by tracing provider "zipkin"`,
+		},
+		{
+			desc: "Invalid provider has error",
+			opts: options.TracingOptions{
+				Provider:  "jaeger",
+				ProjectId: fakeOptsProjectId,
+			},
+			wantError: `invalid tracing provider: "jaeger"`,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := CreateTracing(tc.opts)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("CreateTracing() got err: %v, want err: %v", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateTracing() got unexpected err: %v", err)
+			}
+
+			if got.GetProvider().GetName() != tc.wantTracerName {
+				t.Errorf("CreateTracing() got tracer %q, want %q", got.GetProvider().GetName(), tc.wantTracerName)
+			}
+
+			gotConfig, err := got.GetProvider().GetTypedConfig().UnmarshalNew()
+			if err != nil {
+				t.Fatalf("fail to unmarshal tracer config: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantConfig, gotConfig, protocmp.Transform()); diff != "" {
+				t.Errorf("CreateTracing() tracer config diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 // Tests the sample rate is correctly populated in the HCM tracing config.
 func TestHcmTracingSampleRate(t *testing.T) {
 
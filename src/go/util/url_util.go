@@ -31,6 +31,9 @@ const (
 
 	// Default port for HTTPS.
 	HTTPSDefaultPort = "443"
+
+	// Prefix of URIs that refer to a file on the local filesystem.
+	FileURIScheme = "file://"
 )
 
 // ParseURI parses uri into scheme, hostname, port, path with err(if exist).
@@ -192,6 +195,12 @@ func IamAccessTokenPath(IamServiceAccount string) string {
 	return fmt.Sprintf("/v1/projects/-/serviceAccounts/%s:generateAccessToken", IamServiceAccount)
 }
 
+// ServiceControlLocalSinkURL is the URL of the local Service Control sink
+// served by the config manager on the given loopback port.
+func ServiceControlLocalSinkURL(port uint) string {
+	return fmt.Sprintf("http://%s:%v", LoopbackIPv4Addr, port)
+}
+
 func ExtractAddressFromURI(uri string) (string, error) {
 	_, hostname, port, _, err := ParseURI(uri)
 	if err != nil {
@@ -200,6 +209,15 @@ func ExtractAddressFromURI(uri string) (string, error) {
 	return fmt.Sprintf("%s:%v", hostname, port), nil
 }
 
+// LocalFilePathFromURI returns the file path of a `file://` URI. The second
+// return value is false if the URI does not use the file scheme.
+func LocalFilePathFromURI(uri string) (string, bool) {
+	if !strings.HasPrefix(strings.ToLower(uri), FileURIScheme) {
+		return "", false
+	}
+	return uri[len(FileURIScheme):], true
+}
+
 var (
 	FetchRolloutIdURL = func(serviceControlUrl, serviceName string) string {
 		return fmt.Sprintf("%v/v1/services/%s:report",
@@ -551,6 +551,43 @@ func TestExtraAddressFromURI(t *testing.T) {
 	}
 }
 
+func TestLocalFilePathFromURI(t *testing.T) {
+	testData := []struct {
+		desc       string
+		uri        string
+		wantPath   string
+		wantIsFile bool
+	}{
+		{
+			desc:       "Absolute file uri",
+			uri:        "file:///etc/espv2/jwks.json",
+			wantPath:   "/etc/espv2/jwks.json",
+			wantIsFile: true,
+		},
+		{
+			desc:       "Scheme is case insensitive",
+			uri:        "FILE:///etc/espv2/jwks.json",
+			wantPath:   "/etc/espv2/jwks.json",
+			wantIsFile: true,
+		},
+		{
+			desc: "Https uri is not a file",
+			uri:  "https://www.googleapis.com/service_accounts/v1/jwk/securetoken@system.gserviceaccount.com",
+		},
+		{
+			desc: "Empty uri is not a file",
+			uri:  "",
+		},
+	}
+
+	for i, tc := range testData {
+		gotPath, gotIsFile := LocalFilePathFromURI(tc.uri)
+		if gotPath != tc.wantPath || gotIsFile != tc.wantIsFile {
+			t.Errorf("Test Desc(%d): %s, LocalFilePathFromURI got: (%v, %v), want: (%v, %v)", i, tc.desc, gotPath, gotIsFile, tc.wantPath, tc.wantIsFile)
+		}
+	}
+}
+
 func TestFetchConfigRelatedUrl(t *testing.T) {
 	sm := "https://servicemanagement.googleapis.com"
 	sn := "service-name"
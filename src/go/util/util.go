@@ -29,6 +29,10 @@ const (
 	// JwtPayloadMetadataName is the field name passed into metadata
 	JwtPayloadMetadataName = "jwt_payloads"
 
+	// JwtFailedStatusMetadataName is the field name of the JWT verification
+	// failure status passed into metadata.
+	JwtFailedStatusMetadataName = "jwt_failed_status"
+
 	// Supported Http Methods.
 
 	GET     = "GET"
@@ -102,6 +106,7 @@ const (
 	// Default api key locations
 	DefaultApiKeyQueryParamKey    = "key"
 	DefaultApiKeyQueryParamApiKey = "api_key"
+	DefaultApiKeyHeaderName       = "X-Api-Key"
 
 	// Strict Transport Security header key and value
 	HSTSHeaderKey   = "Strict-Transport-Security"
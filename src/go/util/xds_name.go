@@ -23,11 +23,27 @@ const (
 	TLSTransportSocket = "envoy.transport_sockets.tls"
 	// AccessFileLogger filter name
 	AccessFileLogger = "envoy.access_loggers.file"
+	// AccessStdoutLogger filter name
+	AccessStdoutLogger = "envoy.access_loggers.stdout"
+	// AccessGrpcLogger filter name
+	AccessGrpcLogger = "envoy.access_loggers.http_grpc"
+	// AccessOpenTelemetryLogger filter name
+	AccessOpenTelemetryLogger = "envoy.access_loggers.open_telemetry"
 	// UpstreamProtocolOptions is the xDS extension name for HTTP options.
 	UpstreamProtocolOptions = "envoy.extensions.upstreams.http.v3.HttpProtocolOptions"
 
 	IngressListenerName  = "ingress_listener"
 	LoopbackListenerName = "loopback_listener"
+
+	// TracingCollectorClusterName is the cluster of the OpenTelemetry or
+	// Zipkin trace collector.
+	TracingCollectorClusterName = "tracing-collector-cluster"
+
+	// AccessLogGrpcClusterName is the cluster of the gRPC Access Log Service.
+	AccessLogGrpcClusterName = "access-log-grpc-cluster"
+	// AccessLogOpenTelemetryClusterName is the cluster of the OpenTelemetry
+	// logs collector.
+	AccessLogOpenTelemetryClusterName = "access-log-otel-cluster"
 )
 
 // Jwt provider cluster's name will be in form of "jwt-provider-cluster-${JWT_PROVIDER_ADDRESS}".
@@ -39,3 +55,8 @@ func JwtProviderClusterName(address string) string {
 func BackendClusterName(address string) string {
 	return fmt.Sprintf("backend-cluster-%s", address)
 }
+
+// Token introspection cluster's name will be in form of "token-introspection-cluster-${INTROSPECTION_ENDPOINT_ADDRESS}".
+func TokenIntrospectionClusterName(address string) string {
+	return fmt.Sprintf("token-introspection-cluster-%s", address)
+}
@@ -34,6 +34,7 @@ import (
 
 	bspbv1 "github.com/GoogleCloudPlatform/esp-v2/tests/endpoints/bookstore_grpc/proto/v1"
 	bspbv2 "github.com/GoogleCloudPlatform/esp-v2/tests/endpoints/bookstore_grpc/proto/v2"
+	errdetailspb "google.golang.org/genproto/googleapis/rpc/errdetails"
 	healthgrpc "google.golang.org/grpc/health/grpc_health_v1"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
@@ -226,6 +227,19 @@ func testDecorator(ctx context.Context) error {
 		return status.New(codes.Internal, first).Err()
 	case "DATA_LOSS":
 		return status.New(codes.DataLoss, first).Err()
+	case "INVALID_ARGUMENT_WITH_DETAILS":
+		st, err := status.New(codes.InvalidArgument, first).WithDetails(&errdetailspb.BadRequest{
+			FieldViolations: []*errdetailspb.BadRequest_FieldViolation{
+				{
+					Field:       "shelf",
+					Description: "must be positive",
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("fail to add error details: %v", err)
+		}
+		return st.Err()
 	default:
 		glog.Warningf("Unknown metadata: %v", first)
 		return nil
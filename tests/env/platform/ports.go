@@ -77,6 +77,7 @@ const (
 	TestGRPCFallback
 	TestGRPCInteropMiniStress
 	TestGRPCInterops
+	TestGRPCJSONReverseTranscoding
 	TestGRPCJwt
 	TestGRPCMetadata
 	TestGRPCMinistress
@@ -165,6 +166,7 @@ const (
 	TestTranscodingErrors
 	TestTranscodingIgnoreQueryParameters
 	TestTranscodingPrintOptions
+	TestTranscodingRichErrorDetails
 	TestWebsocket
 	// The number of total tests. has to be the last one.
 	maxTestNum
@@ -0,0 +1,145 @@
+// Copyright 2023 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc_json_reverse_transcoding_test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/esp-v2/tests/env"
+	"github.com/GoogleCloudPlatform/esp-v2/tests/env/platform"
+	"github.com/GoogleCloudPlatform/esp-v2/tests/env/testdata"
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/http2"
+
+	bspbv1 "github.com/GoogleCloudPlatform/esp-v2/tests/endpoints/bookstore_grpc/proto/v1"
+	confpb "google.golang.org/genproto/googleapis/api/serviceconfig"
+)
+
+func TestGRPCJSONReverseTranscoding(t *testing.T) {
+	t.Parallel()
+
+	// The HTTP/JSON backend replies to the DELETE requests without body.
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/shelves/1":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/shelves/2":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer backend.Close()
+
+	s := env.NewTestEnv(platform.TestGRPCJSONReverseTranscoding, platform.GrpcBookstoreSidecar)
+	s.AppendBackendRules([]*confpb.BackendRule{
+		{
+			Selector:        "endpoints.examples.bookstore.Bookstore.DeleteShelf",
+			Address:         backend.URL,
+			PathTranslation: confpb.BackendRule_APPEND_PATH_TO_ADDRESS,
+		},
+	})
+	defer s.TearDown(t)
+	args := []string{"--service_config_id=test-config-id",
+		"--rollout_strategy=fixed", "--enable_grpc_json_reverse_transcoding"}
+	if err := s.Setup(args); err != nil {
+		t.Fatalf("fail to setup test env, %v", err)
+	}
+
+	testData := []struct {
+		desc           string
+		shelf          int64
+		wantGrpcStatus string
+	}{
+		{
+			desc:           "204 response without body is a trailers-only OK response",
+			shelf:          1,
+			wantGrpcStatus: "0",
+		},
+		{
+			desc:           "503 response without body is a trailers-only UNAVAILABLE response",
+			shelf:          2,
+			wantGrpcStatus: "14",
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			url := fmt.Sprintf("http://%v:%v/endpoints.examples.bookstore.Bookstore/DeleteShelf", platform.GetLoopbackAddress(), s.Ports().ListenerPort)
+			resp, body, err := callGRPC(url, &bspbv1.DeleteShelfRequest{Shelf: tc.shelf})
+			if err != nil {
+				t.Fatalf("fail to call DeleteShelf: %v", err)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("got status code %v, want %v", resp.StatusCode, http.StatusOK)
+			}
+			if got := resp.Header.Get("grpc-status"); got != tc.wantGrpcStatus {
+				t.Errorf("got grpc-status header %q, want %q", got, tc.wantGrpcStatus)
+			}
+			if len(body) != 0 {
+				t.Errorf("got body %q, want a trailers-only response", body)
+			}
+		})
+	}
+}
+
+// callGRPC sends a unary gRPC request over HTTP/2 cleartext and returns the
+// raw response, so the trailers-only responses can be checked.
+func callGRPC(url string, req proto.Message) (*http.Response, []byte, error) {
+	msg, err := proto.Marshal(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	frame = append(frame, msg...)
+
+	httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(frame))
+	if err != nil {
+		return nil, nil, err
+	}
+	httpReq.Header.Set("content-type", "application/grpc")
+	httpReq.Header.Set("te", "trailers")
+	httpReq.Header.Set("x-api-key", "api-key")
+	httpReq.Header.Set("authorization", "Bearer "+testdata.FakeCloudTokenLongClaims)
+
+	cli := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+	resp, err := cli.Do(httpReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
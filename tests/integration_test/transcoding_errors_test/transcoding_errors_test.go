@@ -19,10 +19,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/esp-v2/src/go/util"
 	"github.com/GoogleCloudPlatform/esp-v2/tests/endpoints/bookstore_grpc/client"
 	"github.com/GoogleCloudPlatform/esp-v2/tests/env"
 	"github.com/GoogleCloudPlatform/esp-v2/tests/env/platform"
 	"github.com/GoogleCloudPlatform/esp-v2/tests/env/testdata"
+	"github.com/GoogleCloudPlatform/esp-v2/tests/utils"
 )
 
 type TranscodingTestType struct {
@@ -165,3 +167,68 @@ theme" : "Children"}EXTRA
 		})
 	}
 }
+
+func TestTranscodingRichErrorDetails(t *testing.T) {
+	t.Parallel()
+
+	configID := "test-config-id"
+	args := []string{"--service_config_id=" + configID,
+		"--rollout_strategy=fixed", "--transcoding_rich_error_details"}
+
+	s := env.NewTestEnv(platform.TestTranscodingRichErrorDetails, platform.GrpcBookstoreSidecar)
+	defer s.TearDown(t)
+	if err := s.Setup(args); err != nil {
+		t.Fatalf("fail to setup test env, %v", err)
+	}
+
+	testData := []struct {
+		desc     string
+		headers  map[string]string
+		wantErr  string
+		wantBody string
+	}{
+		{
+			desc: "details of the grpc-status-details-bin trailer are in the bare google.rpc.Status body",
+			headers: map[string]string{
+				client.TestHeaderKey: "INVALID_ARGUMENT_WITH_DETAILS",
+			},
+			wantErr: "400 Bad Request",
+			wantBody: `
+{
+  "code": 3,
+  "message": "INVALID_ARGUMENT_WITH_DETAILS",
+  "details": [
+    {
+      "@type": "type.googleapis.com/google.rpc.BadRequest",
+      "fieldViolations": [
+        {
+          "field": "shelf",
+          "description": "must be positive"
+        }
+      ]
+    }
+  ]
+}`,
+		},
+		{
+			desc: "status without details",
+			headers: map[string]string{
+				client.TestHeaderKey: "ABORTED",
+			},
+			wantErr:  "409 Conflict",
+			wantBody: `{"code": 10, "message": "ABORTED"}`,
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.desc, func(t *testing.T) {
+			url := fmt.Sprintf("http://%v:%v/v1/shelves/100?key=api-key", platform.GetLoopbackAddress(), s.Ports().ListenerPort)
+			_, body, err := utils.DoWithHeaders(url, "GET", "", tc.headers)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Test (%s): failed, expected err: %v, got: %v", tc.desc, tc.wantErr, err)
+			}
+			if err := util.JsonEqual(tc.wantBody, string(body)); err != nil {
+				t.Errorf("Test (%s): got unexpected body: %v", tc.desc, err)
+			}
+		})
+	}
+}
@@ -38,6 +38,10 @@ class TestStartProxy(unittest.TestCase):
              ['bin/bootstrap', '--logtostderr', '--admin_port', '8001',
               '--ads_named_pipe', '@espv2-named-pipe-9',
               '/tmp/bootstrap.json']),
+            (["--enable_operation_stats", "--admin_port=8001"],
+             ['bin/bootstrap', '--logtostderr', '--admin_port', '8001',
+              '--enable_operation_stats',
+              '/tmp/bootstrap.json']),
             ([], ['bin/bootstrap',
                   '--logtostderr', '--admin_port', '0',
                   '/tmp/bootstrap.json']),
@@ -200,6 +204,112 @@ class TestStartProxy(unittest.TestCase):
               '--check_metadata', '--underscores_in_headers',
               '--disable_tracing'
               ]),
+            # jwks_local_files
+            (['-R=managed',
+              '--jwks_local_files=auth0=/etc/espv2/auth0_jwks.json',
+              '--http_port=8079', '--service_control_quota_retries=3',
+              '--service_control_report_timeout_ms=300',
+              '--check_metadata',
+              '--disable_tracing', '--underscores_in_headers'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'managed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--jwks_local_files', 'auth0=/etc/espv2/auth0_jwks.json',
+              '--listener_port', '8079',
+              '--service_control_quota_retries', '3',
+              '--service_control_report_timeout_ms', '300',
+              '--service_control_enable_api_key_uid_reporting',
+              '--check_metadata', '--underscores_in_headers',
+              '--disable_tracing'
+              ]),
+            # jwt_authn_audit_only
+            (['-R=managed',
+              '--jwt_authn_audit_only',
+              '--jwt_authn_audit_only_selectors=api.Foo,api.Bar',
+              '--http_port=8079', '--service_control_quota_retries=3',
+              '--service_control_report_timeout_ms=300',
+              '--check_metadata',
+              '--disable_tracing', '--underscores_in_headers'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'managed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--jwt_authn_audit_only',
+              '--jwt_authn_audit_only_selectors', 'api.Foo,api.Bar',
+              '--listener_port', '8079',
+              '--service_control_quota_retries', '3',
+              '--service_control_report_timeout_ms', '300',
+              '--service_control_enable_api_key_uid_reporting',
+              '--check_metadata', '--underscores_in_headers',
+              '--disable_tracing'
+              ]),
+            # jwt_provider_options_path
+            (['-R=managed',
+              '--jwt_provider_options_path=/etc/espv2/jwt_provider_options.json',
+              '--http_port=8079', '--service_control_quota_retries=3',
+              '--service_control_report_timeout_ms=300',
+              '--check_metadata',
+              '--disable_tracing', '--underscores_in_headers'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'managed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--jwt_provider_options_path', '/etc/espv2/jwt_provider_options.json',
+              '--listener_port', '8079',
+              '--service_control_quota_retries', '3',
+              '--service_control_report_timeout_ms', '300',
+              '--service_control_enable_api_key_uid_reporting',
+              '--check_metadata', '--underscores_in_headers',
+              '--disable_tracing'
+              ]),
+            # token_introspection_providers_path
+            (['-R=managed',
+              '--token_introspection_providers_path=/etc/espv2/token_introspection.json',
+              '--http_port=8079', '--service_control_quota_retries=3',
+              '--service_control_report_timeout_ms=300',
+              '--check_metadata',
+              '--disable_tracing', '--underscores_in_headers'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'managed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--token_introspection_providers_path', '/etc/espv2/token_introspection.json',
+              '--listener_port', '8079',
+              '--service_control_quota_retries', '3',
+              '--service_control_report_timeout_ms', '300',
+              '--service_control_enable_api_key_uid_reporting',
+              '--check_metadata', '--underscores_in_headers',
+              '--disable_tracing'
+              ]),
+            # api_key_store_path
+            (['-R=managed',
+              '--api_key_store_path=/etc/espv2/api_keys.json',
+              '--http_port=8079', '--service_control_quota_retries=3',
+              '--service_control_report_timeout_ms=300',
+              '--check_metadata',
+              '--disable_tracing', '--underscores_in_headers'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'managed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--api_key_store_path', '/etc/espv2/api_keys.json',
+              '--listener_port', '8079',
+              '--service_control_quota_retries', '3',
+              '--service_control_report_timeout_ms', '300',
+              '--service_control_enable_api_key_uid_reporting',
+              '--check_metadata', '--underscores_in_headers',
+              '--disable_tracing'
+              ]),
+            # service_control_local_sink
+            (['-R=managed',
+              '--service_control_local_sink=stdout',
+              '--service_control_local_sink_port=9000',
+              '--http_port=8079', '--service_control_quota_retries=3',
+              '--service_control_report_timeout_ms=300',
+              '--check_metadata',
+              '--disable_tracing', '--underscores_in_headers'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'managed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--listener_port', '8079',
+              '--service_control_local_sink', 'stdout',
+              '--service_control_local_sink_port', '9000',
+              '--service_control_quota_retries', '3',
+              '--service_control_report_timeout_ms', '300',
+              '--service_control_enable_api_key_uid_reporting',
+              '--check_metadata', '--underscores_in_headers',
+              '--disable_tracing'
+              ]),
             # service_control_network_fail_policy=open
             (['-R=managed','--enable_strict_transport_security',
               '--http_port=8079', '--service_control_quota_retries=3',
@@ -470,6 +580,29 @@ class TestStartProxy(unittest.TestCase):
               '--cors_allow_credentials',
               '--service_account_key', '/tmp/service_accout_key', '--non_gcp',
               ]),
+            # Cors: test CORS policies file is passed to config_manager
+            (['--service=test_bookstore.gloud.run',
+              '--backend=https://127.0.0.1', '--cors_preset=basic',
+              '--cors_allow_origin=https://a.example.com,https://b.example.com',
+              '--cors_policies_path=/tmp/cors_policies.json',
+              '--non_gcp', '--version=2019-11-09r0',
+              '--service_account_key', '/tmp/service_accout_key'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'https://127.0.0.1', '--v', '0',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--cors_preset', 'basic',
+              '--cors_allow_origin', 'https://a.example.com,https://b.example.com',
+              '--cors_allow_origin_regex', '',
+              '--cors_allow_methods', 'GET, POST, PUT, PATCH, DELETE, OPTIONS',
+              '--cors_allow_headers', 'DNT,User-Agent,X-User-Agent,X-Requested-With,If-Modified-Since,Cache-Control,Content-Type,Range,Authorization',
+              '--cors_expose_headers', 'Content-Length,Content-Range',
+              '--cors_max_age', "480h",
+              '--cors_policies_path', '/tmp/cors_policies.json',
+              '--service_account_key', '/tmp/service_accout_key', '--non_gcp',
+              ]),
             # backend routing (with deprecated flag)
             (['--backend=https://127.0.0.1:8000', '--enable_backend_routing',
               '--service_json_path=/tmp/service.json',
@@ -574,6 +707,21 @@ class TestStartProxy(unittest.TestCase):
               '--disable_tracing',
               '--transcoding_stream_newline_delimited'
               ]),
+            # json-grpc transcoding_stream_sse
+            (['--service=test_bookstore.gloud.run',
+              '--backend=grpc://127.0.0.1:8000',
+              '--transcoding_stream_sse',
+              '--disable_tracing',
+              '--version=2019-11-09r0',
+              ],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'grpc://127.0.0.1:8000', '--v', '0',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--transcoding_stream_sse'
+              ]),
             # json-grpc transcoding_case_insensitive_enum_parsing
             (['--service=test_bookstore.gloud.run',
               '--backend=grpc://127.0.0.1:8000',
@@ -589,6 +737,55 @@ class TestStartProxy(unittest.TestCase):
               '--disable_tracing',
               '--transcoding_case_insensitive_enum_parsing'
               ]),
+            # json-grpc transcoding_rich_error_details
+            (['--service=test_bookstore.gloud.run',
+              '--backend=grpc://127.0.0.1:8000',
+              '--transcoding_rich_error_details',
+              '--disable_tracing',
+              '--version=2019-11-09r0',
+              ],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'grpc://127.0.0.1:8000', '--v', '0',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--transcoding_rich_error_details'
+              ]),
+            # json-grpc per-method transcoding options and descriptor file
+            (['--service=test_bookstore.gloud.run',
+              '--backend=grpc://127.0.0.1:8000',
+              '--transcoding_options_path=/tmp/transcoding_options.json',
+              '--transcoding_descriptor_path=/tmp/api_descriptor.pb',
+              '--disable_tracing',
+              '--version=2019-11-09r0',
+              ],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'grpc://127.0.0.1:8000', '--v', '0',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--transcoding_options_path', '/tmp/transcoding_options.json',
+              '--transcoding_descriptor_path', '/tmp/api_descriptor.pb',
+              ]),
+            # json-grpc descriptors from gRPC server reflection
+            (['--service=test_bookstore.gloud.run',
+              '--backend=grpc://127.0.0.1:8000',
+              '--transcoding_grpc_reflection',
+              '--transcoding_grpc_reflection_interval=30s',
+              '--disable_tracing',
+              '--version=2019-11-09r0',
+              ],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'grpc://127.0.0.1:8000', '--v', '0',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--disable_tracing',
+              '--transcoding_grpc_reflection',
+              '--transcoding_grpc_reflection_interval', '30s',
+              ]),
             # route_match disallow_colon_in_wildcard_path_segment
             (['--service=test_bookstore.gloud.run',
               '--backend=grpc://127.0.0.1:8000',
@@ -653,6 +850,49 @@ class TestStartProxy(unittest.TestCase):
               '--access_log_format', '%START_TIME%',
               '--disable_tracing',
               ]),
+            (['--service=test_bookstore.gloud.run',
+              '--backend=127.0.0.1:8000',
+              '--access_log=stdout', '--access_log_json',
+              '--access_log_json_add_fields=region=%REQ(X-REGION)%',
+              '--access_log_json_remove_fields=api_consumer,user_agent',
+              '--disable_tracing',
+              '--version=2019-11-09r0',
+              ],
+             ['bin/configmanager', '--logtostderr',
+              '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8000',
+              '--v', '0',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--access_log', 'stdout',
+              '--access_log_json',
+              '--access_log_json_add_fields', 'region=%REQ(X-REGION)%',
+              '--access_log_json_remove_fields', 'api_consumer,user_agent',
+              '--disable_tracing',
+              ]),
+            (['--service=test_bookstore.gloud.run',
+              '--backend=127.0.0.1:8000',
+              '--access_log_grpc_address=grpc://als:9000',
+              '--access_log_otel_address=grpcs://otel-collector:4317',
+              '--access_log_min_status_code=400',
+              '--access_log_sampling_rate=0.5',
+              '--disable_tracing',
+              '--version=2019-11-09r0',
+              ],
+             ['bin/configmanager', '--logtostderr',
+              '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8000',
+              '--v', '0',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--access_log_grpc_address', 'grpc://als:9000',
+              '--access_log_otel_address', 'grpcs://otel-collector:4317',
+              '--access_log_min_status_code', '400',
+              '--access_log_sampling_rate', '0.5',
+              '--disable_tracing',
+              ]),
             # Tracing disabled on non-gcp
             (['--service=test_bookstore.gloud.run',
               '--backend=http://127.0.0.1', '--version=2019-11-09r0',
@@ -690,6 +930,21 @@ class TestStartProxy(unittest.TestCase):
               '--tracing_project_id', 'test_project_1234',
               '--service_account_key', '/tmp/service_accout_key', '--non_gcp',
               ]),
+            # OpenTelemetry tracing enabled without project id on non-gcp.
+            (['--service=test_bookstore.gloud.run',
+              '--backend=http://127.0.0.1', '--version=2019-11-09r0',
+              '--service_account_key', '/tmp/service_accout_key', '--non_gcp',
+              '--tracing_provider=opentelemetry',
+              '--tracing_collector_address=http://otel-collector:4317'],
+             ['bin/configmanager', '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1', '--v', '0',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--tracing_provider', 'opentelemetry',
+              '--tracing_collector_address', 'http://otel-collector:4317',
+              '--service_account_key', '/tmp/service_accout_key', '--non_gcp',
+              ]),
             # Tracing params preserved.
             (['--service=test_bookstore.gloud.run',
               '--backend=grpc://127.0.0.1:8000',
@@ -711,6 +966,23 @@ class TestStartProxy(unittest.TestCase):
               '--tracing_stackdriver_address', 'localhost:9990',
               '--tracing_sample_rate', '1',
               ]),
+            # Per-operation tracing sample rates preserved.
+            (['--service=test_bookstore.gloud.run',
+              '--backend=grpc://127.0.0.1:8000',
+              '--tracing_sample_rate=0.1',
+              '--tracing_operation_sample_rates=foo.ListBooks=0,foo.Expensive=1',
+              '--version=2019-11-09r0',
+              ],
+             ['bin/configmanager', '--logtostderr',
+              '--rollout_strategy', 'fixed',
+              '--backend_address', 'grpc://127.0.0.1:8000',
+              '--v', '0',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              '--tracing_sample_rate', '0.1',
+              '--tracing_operation_sample_rates', 'foo.ListBooks=0,foo.Expensive=1',
+              ]),
             # Enable debug affects tracing.
             (['--service=test_bookstore.gloud.run',
               '--backend=grpc://127.0.0.1:8000',
@@ -945,6 +1217,17 @@ class TestStartProxy(unittest.TestCase):
               '--service_control_enable_api_key_uid_reporting',
               '--service_json_path', '/tmp/service_config.json',
               ]),
+            # Per-operation header rules
+            (['--rollout_strategy=fixed',
+              '--service_json_path=/tmp/service_config.json',
+              '--header_rules_path=/tmp/header_rules.json',
+              ],
+             ['bin/configmanager',  '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--header_rules_path', '/tmp/header_rules.json',
+              '--service_control_enable_api_key_uid_reporting',
+              '--service_json_path', '/tmp/service_config.json',
+              ]),
             # Path security options.
             (['--rollout_strategy=fixed',
               '--service_json_path=/tmp/service_config.json',
@@ -990,6 +1273,126 @@ class TestStartProxy(unittest.TestCase):
               '--service_control_enable_api_key_uid_reporting',
               '--service_json_path', '/tmp/service_config.json',
               ]),
+            # response_compression with options and request decompression.
+            (['--rollout_strategy=fixed',
+              '--service_json_path=/tmp/service_config.json',
+              '--enable_response_compression',
+              '--response_compression_algorithms=zstd,gzip',
+              '--response_compression_levels=gzip=6,zstd=3',
+              '--response_compression_min_content_length=1024',
+              '--response_compression_content_types=application/json',
+              '--response_compression_operations=foo.Bar',
+              '--enable_request_decompression',
+              ],
+             ['bin/configmanager',  '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--enable_response_compression',
+              '--response_compression_algorithms', 'zstd,gzip',
+              '--response_compression_levels', 'gzip=6,zstd=3',
+              '--response_compression_min_content_length', '1024',
+              '--response_compression_content_types', 'application/json',
+              '--response_compression_operations', 'foo.Bar',
+              '--enable_request_decompression',
+              '--service_control_enable_api_key_uid_reporting',
+              '--service_json_path', '/tmp/service_config.json',
+              ]),
+            # max_request_body_bytes with per-operation overrides.
+            (['--rollout_strategy=fixed',
+              '--service_json_path=/tmp/service_config.json',
+              '--max_request_body_bytes=1048576',
+              '--max_request_body_bytes_operations=foo.Bar=0,foo.Baz=1024',
+              ],
+             ['bin/configmanager',  '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--max_request_body_bytes', '1048576',
+              '--max_request_body_bytes_operations', 'foo.Bar=0,foo.Baz=1024',
+              '--service_control_enable_api_key_uid_reporting',
+              '--service_json_path', '/tmp/service_config.json',
+              ]),
+            # fault injection policies.
+            (['--rollout_strategy=fixed',
+              '--service_json_path=/tmp/service_config.json',
+              '--enable_fault_injection',
+              '--fault_injection_policies_path=/tmp/faults.json',
+              ],
+             ['bin/configmanager',  '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--enable_fault_injection',
+              '--fault_injection_policies_path', '/tmp/faults.json',
+              '--service_control_enable_api_key_uid_reporting',
+              '--service_json_path', '/tmp/service_config.json',
+              ]),
+            # HTTP cache for GET operations.
+            (['--rollout_strategy=fixed',
+              '--service_json_path=/tmp/service_config.json',
+              '--http_cache_operations=foo.GetBar,foo.GetBaz',
+              '--http_cache_public_operations=foo.GetBaz',
+              '--http_cache_max_body_bytes=65536',
+              ],
+             ['bin/configmanager',  '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--http_cache_operations', 'foo.GetBar,foo.GetBaz',
+              '--http_cache_public_operations', 'foo.GetBaz',
+              '--http_cache_max_body_bytes', '65536',
+              '--service_control_enable_api_key_uid_reporting',
+              '--service_json_path', '/tmp/service_config.json',
+              ]),
+            # local reply config.
+            (['--rollout_strategy=fixed',
+              '--service_json_path=/tmp/service_config.json',
+              '--local_reply_config_path=/tmp/local_reply.json',
+              ],
+             ['bin/configmanager',  '--logtostderr', '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8082', '--v', '0',
+              '--local_reply_config_path', '/tmp/local_reply.json',
+              '--service_control_enable_api_key_uid_reporting',
+              '--service_json_path', '/tmp/service_config.json',
+              ]),
+            # Connect gRPC bridge.
+            (['--service=test_bookstore.gloud.run',
+              '--backend=grpc://127.0.0.1:8000',
+              '--enable_connect_grpc_bridge',
+              '--version=2019-11-09r0',
+              ],
+             ['bin/configmanager', '--logtostderr',
+              '--rollout_strategy', 'fixed',
+              '--backend_address', 'grpc://127.0.0.1:8000',
+              '--v', '0',
+              '--enable_connect_grpc_bridge',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              ]),
+            # gRPC JSON reverse transcoding.
+            (['--service=test_bookstore.gloud.run',
+              '--backend=http://127.0.0.1:8000',
+              '--enable_grpc_json_reverse_transcoding',
+              '--version=2019-11-09r0',
+              ],
+             ['bin/configmanager', '--logtostderr',
+              '--rollout_strategy', 'fixed',
+              '--backend_address', 'http://127.0.0.1:8000',
+              '--v', '0',
+              '--enable_grpc_json_reverse_transcoding',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              ]),
+            # Operation stats.
+            (['--service=test_bookstore.gloud.run',
+              '--backend=grpc://127.0.0.1:8000',
+              '--enable_operation_stats',
+              '--version=2019-11-09r0',
+              ],
+             ['bin/configmanager', '--logtostderr',
+              '--rollout_strategy', 'fixed',
+              '--backend_address', 'grpc://127.0.0.1:8000',
+              '--v', '0',
+              '--enable_operation_stats',
+              '--service', 'test_bookstore.gloud.run',
+              '--service_config_id', '2019-11-09r0',
+              '--service_control_enable_api_key_uid_reporting',
+              ]),
             # passing the flag --health_check_grp_backend
             (['--service=test_bookstore.gloud.run',
               '--backend=grpc://127.0.0.1:8000',
@@ -1089,7 +1492,23 @@ class TestStartProxy(unittest.TestCase):
             ['--version=2019-11-09r0',
              '--transcoding_ignore_query_parameters=foo,bar',
              '--transcoding_ignore_unknown_query_parameters'],
+            ['--version=2019-11-09r0', '--cors_policies_path=/tmp/cors.json'],
+            ['--version=2019-11-09r0', '--max_request_body_bytes=-1'],
+            ['--version=2019-11-09r0', '--fault_injection_policies_path=/tmp/faults.json'],
+            ['--version=2019-11-09r0', '--http_cache_public_operations=foo.GetBar'],
+            ['--version=2019-11-09r0', '--transcoding_grpc_reflection',
+             '--transcoding_descriptor_path=/tmp/api_descriptor.pb'],
+            ['--version=2019-11-09r0', '--transcoding_grpc_reflection_interval=30s'],
             ['--version=2019-11-09r0', '--access_log_format'],
+            ['--version=2019-11-09r0', '--access_log_json'],
+            ['--version=2019-11-09r0', '--access_log=/foo',
+             '--access_log_json_remove_fields=api_consumer'],
+            ['--version=2019-11-09r0', '--access_log=/foo',
+             '--access_log_json', '--access_log_format=%START_TIME%'],
+            ['--version=2019-11-09r0', '--access_log_sampling_rate=0.5'],
+            ['--version=2019-11-09r0', '--response_compression_levels=gzip=6'],
+            ['--version=2019-11-09r0', '--access_log=/foo',
+             '--access_log_min_status_code=400'],
             ['--version=2019-11-09r0', '--dns=127.0.0.1', '--dns_resolver_address=127.0.0.1'],
             ['--version=2019-11-09r0', '--ssl_client_cert_path=/tmp', '--ssl_backend_client_cert_path=/tmp'],
             # The flag --backend default is using http, but the flag --health_check_grpc_backend requires grpc
@@ -1106,7 +1525,9 @@ class TestStartProxy(unittest.TestCase):
             # The flag --service_account_key and --enable_application_default_credentials cannot be supplied at the same time.
             ['--non_gcp', '--service_account_key=tmp/service_account_key', '--enable_application_default_credentials'],
             # The flag --non_gcp is set without --service_account_key or --enable_application_default_credentials.
-            ['--non_gcp']
+            ['--non_gcp'],
+            # The flag --tracing_provider=zipkin requires the flag --tracing_collector_address
+            ['--version=2019-11-09r0', '--tracing_provider=zipkin'],
           ]
 
         for flags in testcases: